@@ -2,6 +2,8 @@ package common
 
 import (
 	"encoding/gob"
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -9,19 +11,142 @@ import (
 // Point wraps a single data point. It stores database-agnostic data
 // representing one point in time of one measurement.
 //
-// Internally, Point uses byte slices instead of strings to try to minimize
-// overhead.
+// Internally, Point uses byte slices instead of strings, and typed field
+// values instead of interfaces, to try to minimize overhead. A Point is meant
+// to be reused: Reset keeps the backing arrays of its slices.
 type Point struct {
 	MeasurementName []byte
 	TagKeys         [][]byte
 	TagValues       [][]byte
 	FieldKeys       [][]byte
-	FieldValues     []interface{}
+	FieldValues     []FieldValue
 	Timestamp       *time.Time
+	Location        *GeoPoint // optional, also present as latitude/longitude fields
 
 	encoder *gob.Encoder
 }
 
+// ValueKind tells which slot of a FieldValue holds the value.
+type ValueKind uint8
+
+const (
+	ValueKindInt ValueKind = iota
+	ValueKindFloat
+	ValueKindBool
+	ValueKindBytes
+)
+
+// FieldValue is a field value stored without boxing. Bools are stored in Int
+// as 0 or 1.
+type FieldValue struct {
+	Kind  ValueKind
+	Int   int64
+	Float float64
+	Bytes []byte
+}
+
+func IntValue(v int64) FieldValue {
+	return FieldValue{Kind: ValueKindInt, Int: v}
+}
+
+func FloatValue(v float64) FieldValue {
+	return FieldValue{Kind: ValueKindFloat, Float: v}
+}
+
+func BoolValue(v bool) FieldValue {
+	if v {
+		return FieldValue{Kind: ValueKindBool, Int: 1}
+	}
+	return FieldValue{Kind: ValueKindBool}
+}
+
+func BytesValue(v []byte) FieldValue {
+	return FieldValue{Kind: ValueKindBytes, Bytes: v}
+}
+
+// MakeFieldValue converts a value of a Go numeric, bool, string or []byte
+// type. It panics on other types.
+func MakeFieldValue(v interface{}) FieldValue {
+	switch x := v.(type) {
+	case int:
+		return IntValue(int64(x))
+	case int32:
+		return IntValue(int64(x))
+	case int64:
+		return IntValue(x)
+	case float32:
+		return FloatValue(float64(x))
+	case float64:
+		return FloatValue(x)
+	case bool:
+		return BoolValue(x)
+	case []byte:
+		return BytesValue(x)
+	case string:
+		return BytesValue([]byte(x))
+	default:
+		panic(fmt.Sprintf("unknown field type for %#v", v))
+	}
+}
+
+// Numeric returns the value as a float64, for formats that only store
+// floating-point values. ok is false for byte values.
+func (v FieldValue) Numeric() (f float64, ok bool) {
+	switch v.Kind {
+	case ValueKindInt, ValueKindBool:
+		return float64(v.Int), true
+	case ValueKindFloat:
+		return v.Float, true
+	default:
+		return 0, false
+	}
+}
+
+// Interface returns the value as an int64, float64, bool or []byte.
+func (v FieldValue) Interface() interface{} {
+	switch v.Kind {
+	case ValueKindInt:
+		return v.Int
+	case ValueKindFloat:
+		return v.Float
+	case ValueKindBool:
+		return v.Int != 0
+	default:
+		return v.Bytes
+	}
+}
+
+// AppendTo appends the text form of the value to buf. Byte values are
+// quoted, with single quotes if singleQuotesForString is set.
+func (v FieldValue) AppendTo(buf []byte, singleQuotesForString bool) []byte {
+	switch v.Kind {
+	case ValueKindInt:
+		return strconv.AppendInt(buf, v.Int, 10)
+	case ValueKindFloat:
+		return strconv.AppendFloat(buf, v.Float, 'f', 16, 64)
+	case ValueKindBool:
+		return strconv.AppendBool(buf, v.Int != 0)
+	default:
+		quotationChar := byte('"')
+		if singleQuotesForString {
+			quotationChar = '\''
+		}
+		buf = append(buf, quotationChar)
+		buf = append(buf, v.Bytes...)
+		return append(buf, quotationChar)
+	}
+}
+
+// GeoPoint is a WGS84 position in decimal degrees.
+type GeoPoint struct {
+	Lat, Lon float64
+}
+
+var (
+	LatitudeFieldKey  = []byte("latitude")
+	LongitudeFieldKey = []byte("longitude")
+)
+
 // Using these literals prevents the slices from escaping to the heap, saving
 // a few micros per call:
 var ()
@@ -40,6 +165,7 @@ func (p *Point) Reset() {
 	p.FieldKeys = p.FieldKeys[:0]
 	p.FieldValues = p.FieldValues[:0]
 	p.Timestamp = nil
+	p.Location = nil
 }
 
 func (p *Point) SetTimestamp(t *time.Time) {
@@ -55,7 +181,38 @@ func (p *Point) AppendTag(key, value []byte) {
 	p.TagValues = append(p.TagValues, value)
 }
 
+// AppendField appends a field of any type supported by MakeFieldValue. The
+// typed variants below avoid boxing the value and should be preferred.
 func (p *Point) AppendField(key []byte, value interface{}) {
+	p.AppendFieldValue(key, MakeFieldValue(value))
+}
+
+func (p *Point) AppendFieldValue(key []byte, value FieldValue) {
 	p.FieldKeys = append(p.FieldKeys, key)
 	p.FieldValues = append(p.FieldValues, value)
 }
+
+func (p *Point) AppendFieldInt(key []byte, value int64) {
+	p.AppendFieldValue(key, IntValue(value))
+}
+
+func (p *Point) AppendFieldFloat(key []byte, value float64) {
+	p.AppendFieldValue(key, FloatValue(value))
+}
+
+func (p *Point) AppendFieldBool(key []byte, value bool) {
+	p.AppendFieldValue(key, BoolValue(value))
+}
+
+func (p *Point) AppendFieldBytes(key []byte, value []byte) {
+	p.AppendFieldValue(key, BytesValue(value))
+}
+
+// SetLocation attaches a position to the Point. The position is also appended
+// as latitude and longitude fields, so that formats without a geo type carry
+// it as plain values.
+func (p *Point) SetLocation(loc *GeoPoint) {
+	p.Location = loc
+	p.AppendFieldFloat(LatitudeFieldKey, loc.Lat)
+	p.AppendFieldFloat(LongitudeFieldKey, loc.Lon)
+}
@@ -0,0 +1,81 @@
+package common_test
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/vehicle"
+)
+
+// The benchmarks generate and serialize the points of the vehicle use case,
+// which have 60 integer fields, the way bulk_data_gen does: one Point is
+// reset and reused for every point.
+
+const benchVehicles = 1000
+
+func newVehicleSimulator() common.Simulator {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := vehicle.VehicleSimulatorConfig{
+		Start:         start,
+		End:           start.Add(365 * 24 * time.Hour),
+		VehicleCount:  benchVehicles,
+		StartVinIndex: 100000,
+	}
+	return cfg.ToSimulator()
+}
+
+func BenchmarkVehicleNext(b *testing.B) {
+	sim := newVehicleSimulator()
+	p := common.MakeUsablePoint()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Reset()
+		sim.Next(p)
+	}
+}
+
+func benchmarkSerialize(b *testing.B, s common.Serializer) {
+	sim := newVehicleSimulator()
+	p := common.MakeUsablePoint()
+	sim.Next(p)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := s.SerializePoint(ioutil.Discard, p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSerializeInflux(b *testing.B) {
+	benchmarkSerialize(b, common.NewSerializerInflux())
+}
+
+func BenchmarkSerializeElastic(b *testing.B) {
+	benchmarkSerialize(b, common.NewSerializerElastic("7x"))
+}
+
+func benchmarkGenerate(b *testing.B, s common.Serializer) {
+	sim := newVehicleSimulator()
+	p := common.MakeUsablePoint()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Reset()
+		sim.Next(p)
+		if err := s.SerializePoint(ioutil.Discard, p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenerateInflux(b *testing.B) {
+	benchmarkGenerate(b, common.NewSerializerInflux())
+}
+
+func BenchmarkGenerateElastic(b *testing.B) {
+	benchmarkGenerate(b, common.NewSerializerElastic("7x"))
+}
@@ -16,8 +16,3 @@ var bufPool = &sync.Pool{
 		return []byte{}
 	},
 }
-var bufPool8 = &sync.Pool{
-	New: func() interface{} {
-		return make([]byte, 8)
-	},
-}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Use cases and output formats register themselves here, usually from an
+// init function, so that bulk_data_gen picks up any package linked into it:
+//
+//	func init() {
+//		common.RegisterSimulator("my-use-case", func(c *common.SimulatorConfig) common.Simulator {
+//			return newMySimulator(c.Start, c.End, c.ScaleVar)
+//		})
+//		common.RegisterSerializer("my-format", func() common.Serializer {
+//			return &mySerializer{}
+//		})
+//	}
+
+// SimulatorConfig holds the settings shared by all use cases. Use case
+// specific settings are package variables of the use case.
+type SimulatorConfig struct {
+	Start time.Time
+	End   time.Time
+
+	ScaleVar       int64
+	ScaleVarOffset int64
+}
+
+type SimulatorFactory func(c *SimulatorConfig) Simulator
+
+type SerializerFactory func() Serializer
+
+var (
+	registryMu  sync.RWMutex
+	simulators  = map[string]SimulatorFactory{}
+	serializers = map[string]SerializerFactory{}
+)
+
+// RegisterSimulator makes a use case available by name. It panics if the
+// name is already registered.
+func RegisterSimulator(name string, factory SimulatorFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := simulators[name]; dup {
+		panic("RegisterSimulator called twice for use case " + name)
+	}
+	simulators[name] = factory
+	if indexOf(UseCaseChoices, name) < 0 {
+		UseCaseChoices = append(UseCaseChoices, name)
+	}
+}
+
+// RegisterSerializer makes an output format available by name. It panics if
+// the name is already registered.
+func RegisterSerializer(name string, factory SerializerFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := serializers[name]; dup {
+		panic("RegisterSerializer called twice for format " + name)
+	}
+	serializers[name] = factory
+}
+
+// NewSimulator creates a simulator of the named use case.
+func NewSimulator(name string, c *SimulatorConfig) (Simulator, error) {
+	registryMu.RLock()
+	factory, ok := simulators[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown use case %q", name)
+	}
+	return factory(c), nil
+}
+
+// NewSerializer creates a serializer of the named format.
+func NewSerializer(name string) (Serializer, error) {
+	registryMu.RLock()
+	factory, ok := serializers[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", name)
+	}
+	return factory(), nil
+}
+
+// SimulatorNames returns the sorted names of the registered use cases.
+func SimulatorNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(simulators))
+	for name := range simulators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SerializerNames returns the sorted names of the registered formats.
+func SerializerNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(serializers))
+	for name := range serializers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
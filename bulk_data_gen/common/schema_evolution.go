@@ -0,0 +1,261 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema changes simulate firmware upgrades rolling out during a run: from a
+// given time on, a fraction of the entities of a measurement report a new
+// field, stop reporting a field, or report a field with another type.
+//
+// Changes are written as op:measurement.field[:type]@time[/fraction], e.g.
+//
+//	add:cpu.usage_extra:float@2018-01-01T06:00:00Z/0.5
+//	drop:cpu.usage_steal@2018-01-01T12:00:00Z
+//	retype:vehicle.value7:string@2018-01-01T18:00:00Z/0.1
+//
+// Entities are told apart by their tag values, and are picked by hash so the
+// entities of a 10% change are also part of any 50% change.
+type SchemaChangeOp int
+
+const (
+	SchemaAddField SchemaChangeOp = iota
+	SchemaDropField
+	SchemaRetypeField
+)
+
+var schemaChangeOpNames = []string{"add", "drop", "retype"}
+
+func (op SchemaChangeOp) String() string {
+	return schemaChangeOpNames[op]
+}
+
+type FieldType int
+
+const (
+	FieldTypeFloat FieldType = iota
+	FieldTypeInt
+	FieldTypeString
+)
+
+var fieldTypeNames = []string{"float", "int", "string"}
+
+func (t FieldType) String() string {
+	return fieldTypeNames[t]
+}
+
+// Values of added string fields.
+var schemaStringValues = [][]byte{[]byte("ok"), []byte("degraded"), []byte("fault")}
+
+type SchemaChange struct {
+	Op          SchemaChangeOp
+	Measurement []byte
+	Field       []byte
+	Type        FieldType // of added or retyped fields
+	At          time.Time
+	Fraction    float64 // of entities
+}
+
+func (c *SchemaChange) String() string {
+	s := fmt.Sprintf("%s:%s.%s", c.Op, c.Measurement, c.Field)
+	if c.Op != SchemaDropField {
+		s += ":" + c.Type.String()
+	}
+	return fmt.Sprintf("%s@%s/%g", s, c.At.Format(time.RFC3339), c.Fraction)
+}
+
+// ParseSchemaChanges parses a comma-separated list of schema changes.
+func ParseSchemaChanges(s string) ([]SchemaChange, error) {
+	var changes []SchemaChange
+	if s == "" {
+		return changes, nil
+	}
+	for _, spec := range strings.Split(s, ",") {
+		c, err := parseSchemaChange(spec)
+		if err != nil {
+			return nil, fmt.Errorf("bad schema change %q: %v", spec, err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+func parseSchemaChange(spec string) (SchemaChange, error) {
+	c := SchemaChange{Fraction: 1}
+
+	at := strings.SplitN(spec, "@", 2)
+	if len(at) != 2 {
+		return c, fmt.Errorf("missing @time")
+	}
+
+	parts := strings.Split(at[0], ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return c, fmt.Errorf("want op:measurement.field[:type]")
+	}
+	op := indexOf(schemaChangeOpNames, parts[0])
+	if op < 0 {
+		return c, fmt.Errorf("unknown op %q (choices: %s)", parts[0], strings.Join(schemaChangeOpNames, ", "))
+	}
+	c.Op = SchemaChangeOp(op)
+
+	dot := strings.Index(parts[1], ".")
+	if dot <= 0 || dot == len(parts[1])-1 {
+		return c, fmt.Errorf("want measurement.field, got %q", parts[1])
+	}
+	c.Measurement = []byte(parts[1][:dot])
+	c.Field = []byte(parts[1][dot+1:])
+
+	if c.Op == SchemaDropField {
+		if len(parts) == 3 {
+			return c, fmt.Errorf("drop takes no type")
+		}
+	} else {
+		if len(parts) != 3 {
+			return c, fmt.Errorf("%s needs a type", c.Op)
+		}
+		t := indexOf(fieldTypeNames, parts[2])
+		if t < 0 {
+			return c, fmt.Errorf("unknown type %q (choices: %s)", parts[2], strings.Join(fieldTypeNames, ", "))
+		}
+		c.Type = FieldType(t)
+	}
+
+	when := strings.SplitN(at[1], "/", 2)
+	var err error
+	c.At, err = time.Parse(time.RFC3339, when[0])
+	if err != nil {
+		return c, err
+	}
+	if len(when) == 2 {
+		c.Fraction, err = strconv.ParseFloat(when[1], 64)
+		if err != nil {
+			return c, err
+		}
+		if c.Fraction < 0 || c.Fraction > 1 {
+			return c, fmt.Errorf("fraction must be in [0, 1]")
+		}
+	}
+	return c, nil
+}
+
+func indexOf(choices []string, s string) int {
+	for i, c := range choices {
+		if c == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// SchemaEvolution applies schema changes to generated points.
+type SchemaEvolution struct {
+	Changes []SchemaChange
+
+	valuesDelta int64
+}
+
+func NewSchemaEvolution(changes []SchemaChange) *SchemaEvolution {
+	return &SchemaEvolution{Changes: changes}
+}
+
+// ValuesDelta returns the number of values added minus the number of values
+// dropped so far, to correct the value count of the simulator.
+func (e *SchemaEvolution) ValuesDelta() int64 {
+	return e.valuesDelta
+}
+
+// Apply alters the fields of p according to the changes in effect for its
+// entity at its timestamp.
+func (e *SchemaEvolution) Apply(p *Point) {
+	var share float64
+	hashed := false
+	for i := range e.Changes {
+		c := &e.Changes[i]
+		if p.Timestamp.Before(c.At) || !bytes.Equal(p.MeasurementName, c.Measurement) {
+			continue
+		}
+		if !hashed {
+			share = entityShare(p)
+			hashed = true
+		}
+		if share >= c.Fraction {
+			continue
+		}
+
+		idx := fieldIndex(p, c.Field)
+		switch c.Op {
+		case SchemaAddField:
+			if idx < 0 {
+				p.AppendFieldValue(c.Field, randFieldValue(c.Type))
+				e.valuesDelta++
+			}
+		case SchemaDropField:
+			if idx >= 0 {
+				p.FieldKeys = append(p.FieldKeys[:idx], p.FieldKeys[idx+1:]...)
+				p.FieldValues = append(p.FieldValues[:idx], p.FieldValues[idx+1:]...)
+				e.valuesDelta--
+			}
+		case SchemaRetypeField:
+			if idx >= 0 {
+				p.FieldValues[idx] = convertFieldValue(p.FieldValues[idx], c.Type)
+			}
+		}
+	}
+}
+
+// entityShare maps the tag values of a point to [0, 1).
+func entityShare(p *Point) float64 {
+	h := fnv.New32a()
+	for _, v := range p.TagValues {
+		h.Write(v)
+		h.Write([]byte{0})
+	}
+	return float64(h.Sum32()) / (1 << 32)
+}
+
+func fieldIndex(p *Point, key []byte) int {
+	for i, k := range p.FieldKeys {
+		if bytes.Equal(k, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func randFieldValue(t FieldType) FieldValue {
+	switch t {
+	case FieldTypeFloat:
+		return FloatValue(rand.Float64() * 100)
+	case FieldTypeInt:
+		return IntValue(rand.Int63n(1000))
+	case FieldTypeString:
+		return BytesValue(schemaStringValues[rand.Intn(len(schemaStringValues))])
+	default:
+		panic("unreachable")
+	}
+}
+
+func convertFieldValue(v FieldValue, t FieldType) FieldValue {
+	f, ok := v.Numeric()
+	if !ok {
+		f, _ = strconv.ParseFloat(string(v.Bytes), 64)
+	}
+
+	switch t {
+	case FieldTypeFloat:
+		return FloatValue(f)
+	case FieldTypeInt:
+		return IntValue(int64(math.Round(f)))
+	case FieldTypeString:
+		return BytesValue(strconv.AppendFloat(nil, f, 'f', -1, 64))
+	default:
+		panic("unreachable")
+	}
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 	"regexp"
@@ -13,6 +14,43 @@ type Serializer interface {
 	SerializeSize(w io.Writer, points int64, values int64) error
 }
 
+// FileSerializer is implemented by the serializers of file formats, such as
+// csv with its header or parquet with its row groups and footer, which write
+// more than one line per point. bulk_data_gen calls Open before the first
+// point, Flush every -flush-points points and Close after the last point,
+// always with the writer given to SerializePoint. Serializers may buffer
+// points until Flush.
+type FileSerializer interface {
+	Serializer
+	Open(w io.Writer) error
+	Flush(w io.Writer) error
+	Close(w io.Writer) error
+}
+
+// BatchSerializer is implemented by the serializers of binary formats which
+// can frame several points at once, with their names written once and the
+// values laid out compactly. With -batch-points, bulk_data_gen calls
+// BeginBatch before every batch of points and EndBatch after it, and the
+// serializer writes the points of the batch in EndBatch. Outside of a batch,
+// SerializePoint writes a frame per point.
+type BatchSerializer interface {
+	Serializer
+	BeginBatch(w io.Writer) error
+	EndBatch(w io.Writer) error
+}
+
+// writeFrame writes payload prefixed by its length, as a little endian
+// uint64 ORed with flags, which is how the binary formats frame their items.
+func writeFrame(w io.Writer, payload []byte, flags uint64) error {
+	var lenBuf [8]byte
+	binary.LittleEndian.PutUint64(lenBuf[:], uint64(len(payload))|flags)
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
 const DatasetSizeMarker = "dataset-size:"
 
 var DatasetSizeMarkerRE = regexp.MustCompile(DatasetSizeMarker + `(\d+),(\d+)`)
@@ -27,37 +65,6 @@ func serializeSizeInText(w io.Writer, points int64, values int64) error {
 	return nil
 }
 
-func fastFormatAppend(v interface{}, buf []byte, singleQuotesForString bool) []byte {
-	var quotationChar = "\""
-	if singleQuotesForString {
-		quotationChar = "'"
-	}
-	switch v.(type) {
-	case int:
-		return strconv.AppendInt(buf, int64(v.(int)), 10)
-	case int64:
-		return strconv.AppendInt(buf, v.(int64), 10)
-	case float64:
-		return strconv.AppendFloat(buf, v.(float64), 'f', 16, 64)
-	case float32:
-		return strconv.AppendFloat(buf, float64(v.(float32)), 'f', 16, 32)
-	case bool:
-		return strconv.AppendBool(buf, v.(bool))
-	case []byte:
-		buf = append(buf, quotationChar...)
-		buf = append(buf, v.([]byte)...)
-		buf = append(buf, quotationChar...)
-		return buf
-	case string:
-		buf = append(buf, quotationChar...)
-		buf = append(buf, v.(string)...)
-		buf = append(buf, quotationChar...)
-		return buf
-	default:
-		panic(fmt.Sprintf("unknown field type for %#v", v))
-	}
-}
-
 func CheckTotalValues(line string) (totalPoints, totalValues int64, err error) {
 	if strings.HasPrefix(line, DatasetSizeMarker) {
 		parts := DatasetSizeMarkerRE.FindAllStringSubmatch(line, -1)
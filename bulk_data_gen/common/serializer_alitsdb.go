@@ -2,14 +2,12 @@ package common
 
 import (
 	"bytes"
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"sort"
 	"strings"
-	"sync"
 
 	alitsdb_serialization "github.com/caict-benchmark/BDC-TS/alitsdb_serializaition"
 	cmap "github.com/orcaman/concurrent-map"
@@ -63,9 +61,14 @@ func NewSortedTagKeys(tagkeys [][]byte) *SortedTagKeys {
 type SerializerAliTSDBHttp struct {
 }
 
+// SerializerAliTSDB writes Points as length prefixed MputRequests of the
+// AliTSDB RPC interface.
 type SerializerAliTSDB struct {
-	lock sync.Mutex
-	fields bool
+	inBatch bool
+	// the requests of the current batch, one per measurement and field names,
+	// in the order they were first seen
+	requests []*alitsdb_serialization.MputRequest
+	index    map[string]int
 }
 
 func NewSerializerAliTSDBHttp() *SerializerAliTSDBHttp {
@@ -73,11 +76,14 @@ func NewSerializerAliTSDBHttp() *SerializerAliTSDBHttp {
 }
 
 func NewSerializerAliTSDB() *SerializerAliTSDB {
-	serializerAliTSDB := &SerializerAliTSDB{}
-
-	serializerAliTSDB.fields = false
+	return &SerializerAliTSDB{
+		index: map[string]int{},
+	}
+}
 
-	return serializerAliTSDB
+func init() {
+	RegisterSerializer("alitsdb-http", func() Serializer { return NewSerializerAliTSDBHttp() })
+	RegisterSerializer("alitsdb", func() Serializer { return NewSerializerAliTSDB() })
 }
 
 // MultiFieldsJSONPoint defines the data structure of AliTSDB mput interface
@@ -120,18 +126,11 @@ func (m *SerializerAliTSDBHttp) SerializePoint(w io.Writer, p *Point) (err error
 
 	// for each Value, generate a new line in the output:
 	for i := 0; i < len(p.FieldKeys); i++ {
-		switch x := p.FieldValues[i].(type) {
-		case int:
-			wp.Fields[string(p.FieldKeys[i])] = float64(x)
-		case int64:
-			wp.Fields[string(p.FieldKeys[i])] = float64(x)
-		case float32:
-			wp.Fields[string(p.FieldKeys[i])] = float64(x)
-		case float64:
-			wp.Fields[string(p.FieldKeys[i])] = float64(x)
-		default:
+		value, ok := p.FieldValues[i].Numeric()
+		if !ok {
 			panic("bad numeric value for AliTSDB serialization")
 		}
+		wp.Fields[string(p.FieldKeys[i])] = value
 	}
 	err = encoder.Encode(wp)
 	if err != nil {
@@ -146,17 +145,9 @@ func (s *SerializerAliTSDBHttp) SerializeSize(w io.Writer, points int64, values
 	return nil
 }
 
-type task struct {
-	point *Point
-	w io.Writer
-}
-
-func (m *SerializerAliTSDB) handleTask(w io.Writer, p *Point) {
-	var mp alitsdb_serialization.MputRequest
+// mputPoint converts p to the point and the field names of an MputRequest.
+func (m *SerializerAliTSDB) mputPoint(p *Point) (*alitsdb_serialization.MputPoint, []string) {
 	var wp alitsdb_serialization.MputPoint
-	mp.Points = make([]*alitsdb_serialization.MputPoint, 1)
-	mp.Points[0] = &wp
-	//wp.Reset()
 
 	// Timestamps in AliTSDB must be millisecond precision:
 	wp.Timestamp = p.Timestamp.UTC().UnixNano() / 1e6
@@ -197,41 +188,72 @@ func (m *SerializerAliTSDB) handleTask(w io.Writer, p *Point) {
 	wp.Serieskey = serieskeyBuf.String()
 
 	// fields allocation
-	mp.Fnames = make([]string, len(p.FieldKeys))
+	fnames := make([]string, len(p.FieldKeys))
 	wp.Fvalues = make([]float64, len(p.FieldKeys))
 
 	// for each Value, generate a new line in the output:
 	for i := 0; i < len(p.FieldKeys); i++ {
-		mp.Fnames[i] = string(p.FieldKeys[i])
-		switch x := p.FieldValues[i].(type) {
-		case int:
-			wp.Fvalues[i] = float64(x)
-		case int64:
-			wp.Fvalues[i] = float64(x)
-		case float32:
-			wp.Fvalues[i] = float64(x)
-		case float64:
-			wp.Fvalues[i] = float64(x)
-		default:
+		fnames[i] = string(p.FieldKeys[i])
+		value, ok := p.FieldValues[i].Numeric()
+		if !ok {
 			panic("bad numeric value for AliTSDB serialization")
 		}
+		wp.Fvalues[i] = value
 	}
+	return &wp, fnames
+}
 
-	// write to the out stream
-	out, err := mp.Marshal()
-	if err != nil {
-		log.Fatal(err)
+// SerializePoint writes an MputRequest holding p, or adds p to the request of
+// its measurement and field names in a batch.
+func (m *SerializerAliTSDB) SerializePoint(w io.Writer, p *Point) (err error) {
+	wp, fnames := m.mputPoint(p)
+	if !m.inBatch {
+		return writeMputRequest(w, &alitsdb_serialization.MputRequest{
+			Fnames: fnames,
+			Points: []*alitsdb_serialization.MputPoint{wp},
+		})
 	}
-	s := uint64(len(out))
-	binary.Write(w, binary.LittleEndian, s)
-	w.Write(out)
+
+	key := string(p.MeasurementName) + "\x00" + strings.Join(fnames, "\x00")
+	i, ok := m.index[key]
+	if !ok {
+		i = len(m.requests)
+		m.index[key] = i
+		m.requests = append(m.requests, &alitsdb_serialization.MputRequest{Fnames: fnames})
+	}
+	m.requests[i].Points = append(m.requests[i].Points, wp)
+	return nil
 }
 
-func (m *SerializerAliTSDB) SerializePoint(w io.Writer, p *Point) (err error) {
-	m.handleTask(w, p)
+func (m *SerializerAliTSDB) BeginBatch(w io.Writer) error {
+	m.inBatch = true
+	return nil
+}
+
+// EndBatch writes the requests of the batch, each with the points of one
+// measurement, so that the field names are written once per batch.
+func (m *SerializerAliTSDB) EndBatch(w io.Writer) error {
+	m.inBatch = false
+	for _, mp := range m.requests {
+		if err := writeMputRequest(w, mp); err != nil {
+			return err
+		}
+	}
+	m.requests = m.requests[:0]
+	for key := range m.index {
+		delete(m.index, key)
+	}
 	return nil
 }
 
+func writeMputRequest(w io.Writer, mp *alitsdb_serialization.MputRequest) error {
+	out, err := mp.Marshal()
+	if err != nil {
+		return err
+	}
+	return writeFrame(w, out, 0)
+}
+
 func (m *SerializerAliTSDB) SerializeSize(w io.Writer, points int64, values int64) error {
 	//return serializeSizeInText(w, points, values)
 	return nil
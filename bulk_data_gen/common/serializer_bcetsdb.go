@@ -12,6 +12,10 @@ func NewSerializerBceTSDB() *SerializerBceTSDB {
 	return &SerializerBceTSDB{}
 }
 
+func init() {
+	RegisterSerializer("bcetsdb", func() Serializer { return NewSerializerBceTSDB() })
+}
+
 // This function writes JSON lines that looks like:
 // { <metric>, <timestamp>, <value>, <tags> }
 //
@@ -46,18 +50,11 @@ func (m *SerializerBceTSDB) SerializePoint(w io.Writer, p *Point) (err error) {
 		for j := 0; j < int(1); j++ {
 			tv := make([]interface{}, 2)
 			tv[0] = p.Timestamp.UTC().UnixNano()/1e6 + int64(j)
-			switch x := p.FieldValues[i].(type) {
-			case int:
-				tv[1] = float64(x)
-			case int64:
-				tv[1] = float64(x)
-			case float32:
-				tv[1] = float64(x)
-			case float64:
-				tv[1] = float64(x)
-			default:
+			value, ok := p.FieldValues[i].Numeric()
+			if !ok {
 				panic("bad numeric value for BceTSDB serialization")
 			}
+			tv[1] = value
 			vp.Values = append(vp.Values, tv)
 		}
 		vp.Tags = make(map[string]string, len(p.TagKeys))
@@ -2,6 +2,7 @@ package common
 
 import (
 	"io"
+	"strconv"
 )
 
 type serializerBceTSDBBulk struct {
@@ -11,6 +12,10 @@ func NewSerializerBceTSDBBulk() *serializerBceTSDBBulk {
 	return &serializerBceTSDBBulk{}
 }
 
+func init() {
+	RegisterSerializer("bcetsdb-bulk", func() Serializer { return NewSerializerBceTSDBBulk() })
+}
+
 func (s *serializerBceTSDBBulk) SerializePoint(w io.Writer, p *Point) (err error) {
 	buf := scratchBufPool.Get().([]byte)
 
@@ -23,8 +28,7 @@ func (s *serializerBceTSDBBulk) SerializePoint(w io.Writer, p *Point) (err error
 	}
 
 	for i := 0; i < len(p.FieldKeys); i++ {
-		v := p.FieldValues[i]
-		buf = fastFormatAppend(v, buf, false)
+		buf = p.FieldValues[i].AppendTo(buf, false)
 
 		if i+1 < len(p.FieldKeys) {
 			buf = append(buf, ',')
@@ -32,7 +36,7 @@ func (s *serializerBceTSDBBulk) SerializePoint(w io.Writer, p *Point) (err error
 	}
 
 	buf = append(buf, ',')
-	buf = fastFormatAppend(p.Timestamp.UTC().UnixNano()/1e6, buf, true)
+	buf = strconv.AppendInt(buf, p.Timestamp.UTC().UnixNano()/1e6, 10)
 	buf = append(buf, '\n')
 	_, err = w.Write(buf)
 
@@ -12,6 +12,10 @@ func NewSerializerCassandra() *SerializerCassandra {
 	return &SerializerCassandra{}
 }
 
+func init() {
+	RegisterSerializer("cassandra", func() Serializer { return NewSerializerCassandra() })
+}
+
 // SerializeCassandra writes Point data to the given writer, conforming to the
 // Cassandra query format.
 //
@@ -48,8 +52,7 @@ func (m *SerializerCassandra) SerializePoint(w io.Writer, p *Point) (err error)
 
 	for i := 0; i < len(p.FieldValues); i++ {
 		buf = append(buf, ","...)
-		v := p.FieldValues[i]
-		buf = fastFormatAppendCassandra(v, buf, true)
+		buf = fastFormatAppendCassandra(p.FieldValues[i], buf, true)
 	}
 	buf = append(buf, []byte(");\n")...)
 
@@ -61,32 +64,28 @@ func (m *SerializerCassandra) SerializePoint(w io.Writer, p *Point) (err error)
 	return nil
 }
 
-func fastFormatAppendCassandra(v interface{}, buf []byte, singleQuotesForString bool) []byte {
-	switch v.(type) {
-	case []byte, string:
+func fastFormatAppendCassandra(v FieldValue, buf []byte, singleQuotesForString bool) []byte {
+	switch v.Kind {
+	case ValueKindBytes:
 		buf = append(buf, []byte("textasblob(")...)
-		buf = fastFormatAppend(v, buf, singleQuotesForString)
+		buf = v.AppendTo(buf, singleQuotesForString)
 		buf = append(buf, []byte(")")...)
 		return buf
 	default:
-		return fastFormatAppend(v, buf, singleQuotesForString)
+		return v.AppendTo(buf, singleQuotesForString)
 	}
 }
 
-func typeNameForCassandra(v interface{}) string {
-	switch v.(type) {
-	case int, int64:
+func typeNameForCassandra(v FieldValue) string {
+	switch v.Kind {
+	case ValueKindInt:
 		return "bigint"
-	case float64:
+	case ValueKindFloat:
 		return "double"
-	case float32:
-		return "float"
-	case bool:
+	case ValueKindBool:
 		return "boolean"
-	case []byte, string:
-		return "blob"
 	default:
-		panic(fmt.Sprintf("unknown field type for %#v", v))
+		return "blob"
 	}
 }
 
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"bytes"
+	"io"
+)
+
+// SerializerClickHouse writes Points as ClickHouse TabSeparated rows.
+type SerializerClickHouse struct {
+	headers map[string][]byte
+	header  []byte
+	buf     []byte
+}
+
+func NewSerializerClickHouse() *SerializerClickHouse {
+	return &SerializerClickHouse{
+		headers: map[string][]byte{},
+	}
+}
+
+func init() {
+	RegisterSerializer("clickhouse", func() Serializer { return NewSerializerClickHouse() })
+}
+
+// SerializePoint writes a TabSeparated row per Point, prefixed by its table,
+// the measurement. The columns are the tags, the time and the fields. Before
+// the first row of a table, and whenever its columns change, a header line
+// starting with # lists the columns with their ClickHouse types, from which
+// bulk_load_clickhouse creates (or alters) the table.
+//
+// This function writes output that looks like:
+// #<measurement>	<tag> LowCardinality(String)	...	time DateTime64(3, 'UTC')	<field> <type>	...
+// <measurement>	<tag value>	...	<time>	<field value>	...
+//
+// For example:
+// #cpu	hostname LowCardinality(String)	...	time DateTime64(3, 'UTC')	usage_user Float64	...
+// cpu	host_0	...	2018-01-01 00:00:00.000	58.1300000000000026	...
+func (s *SerializerClickHouse) SerializePoint(w io.Writer, p *Point) error {
+	header := s.header[:0]
+	header = append(header, '#')
+	header = append(header, p.MeasurementName...)
+	for i := 0; i < len(p.TagKeys); i++ {
+		header = append(header, '\t')
+		header = append(header, p.TagKeys[i]...)
+		header = append(header, " LowCardinality(String)"...)
+	}
+	header = append(header, "\ttime DateTime64(3, 'UTC')"...)
+	for i := 0; i < len(p.FieldKeys); i++ {
+		header = append(header, '\t')
+		header = append(header, p.FieldKeys[i]...)
+		header = append(header, ' ')
+		header = append(header, clickHouseType(p.FieldValues[i].Kind)...)
+	}
+	header = append(header, '\n')
+	s.header = header
+
+	if last, ok := s.headers[string(p.MeasurementName)]; !ok || !bytes.Equal(last, header) {
+		s.headers[string(p.MeasurementName)] = append([]byte(nil), header...)
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+	}
+
+	buf := s.buf[:0]
+	buf = append(buf, p.MeasurementName...)
+	for i := 0; i < len(p.TagValues); i++ {
+		buf = append(buf, '\t')
+		buf = appendClickHouseString(buf, p.TagValues[i])
+	}
+	buf = append(buf, '\t')
+	buf = p.Timestamp.UTC().AppendFormat(buf, "2006-01-02 15:04:05.000")
+	for i := 0; i < len(p.FieldValues); i++ {
+		buf = append(buf, '\t')
+		if p.FieldValues[i].Kind == ValueKindBytes {
+			buf = appendClickHouseString(buf, p.FieldValues[i].Bytes)
+		} else {
+			buf = p.FieldValues[i].AppendTo(buf, false)
+		}
+	}
+	buf = append(buf, '\n')
+	s.buf = buf
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func (s *SerializerClickHouse) SerializeSize(w io.Writer, points int64, values int64) error {
+	return serializeSizeInText(w, points, values)
+}
+
+func clickHouseType(kind ValueKind) string {
+	switch kind {
+	case ValueKindInt:
+		return "Int64"
+	case ValueKindFloat:
+		return "Float64"
+	case ValueKindBool:
+		return "Bool"
+	default:
+		return "String"
+	}
+}
+
+// appendClickHouseString appends value escaped for the TabSeparated format.
+func appendClickHouseString(buf []byte, value []byte) []byte {
+	for _, c := range value {
+		switch c {
+		case '\t':
+			buf = append(buf, '\\', 't')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\\':
+			buf = append(buf, '\\', '\\')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
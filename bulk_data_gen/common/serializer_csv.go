@@ -0,0 +1,126 @@
+package common
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// SerializerCSV writes Points as rows of a wide CSV file.
+type SerializerCSV struct {
+	table  wideTable
+	out    io.Writer
+	writer *csv.Writer
+	record []string
+	buf    []byte
+}
+
+func NewSerializerCSV() *SerializerCSV {
+	return &SerializerCSV{}
+}
+
+func init() {
+	RegisterSerializer("csv", func() Serializer { return NewSerializerCSV() })
+}
+
+func (s *SerializerCSV) Open(w io.Writer) error {
+	return nil
+}
+
+// SerializePoint writes a row per Point with a column per tag and field of all
+// the measurements, after a header naming the columns. A point lacking a tag
+// or field leaves its cell empty. The time is in UTC with microseconds, which
+// COPY, LOAD DATA and clickhouse-client parse as they are. The header is
+// written on the first flush, until then the rows are kept in memory: a tag or
+// field first seen after it is an error.
+//
+// This function writes output that looks like:
+// measurement,time,<tag>,...,<field>,...
+// <measurement>,<time>,<tag value>,...,<field value>,...
+//
+// For example:
+// measurement,time,hostname,...,usage_user,...
+// cpu,2018-01-01 00:00:00.000000,host_0,...,58.1300000000000026,...
+func (s *SerializerCSV) SerializePoint(w io.Writer, p *Point) error {
+	row, err := s.table.MakeRow(p)
+	if err != nil {
+		return err
+	}
+	if !s.table.Frozen {
+		s.table.Pending = append(s.table.Pending, row)
+		return nil
+	}
+	return s.writeRow(w, &row)
+}
+
+func (s *SerializerCSV) SerializeSize(w io.Writer, points int64, values int64) error {
+	//return serializeSizeInText(w, points, values)
+	return nil
+}
+
+// Flush writes the header and the pending rows on the first call.
+func (s *SerializerCSV) Flush(w io.Writer) error {
+	if !s.table.Frozen {
+		rows := s.table.Freeze()
+		record := []string{"measurement", "time"}
+		for _, c := range s.table.Columns {
+			record = append(record, c.Name)
+		}
+		if err := s.csvWriter(w).Write(record); err != nil {
+			return err
+		}
+		for i := range rows {
+			if err := s.writeRow(w, &rows[i]); err != nil {
+				return err
+			}
+		}
+	}
+	s.csvWriter(w).Flush()
+	return s.writer.Error()
+}
+
+func (s *SerializerCSV) Close(w io.Writer) error {
+	return s.Flush(w)
+}
+
+func (s *SerializerCSV) csvWriter(w io.Writer) *csv.Writer {
+	if s.writer == nil || s.out != w {
+		s.out = w
+		s.writer = csv.NewWriter(w)
+	}
+	return s.writer
+}
+
+func (s *SerializerCSV) writeRow(w io.Writer, row *wideRow) error {
+	// the cells are slices of one buffer, converted to strings once it is
+	// complete, as growing it moves it
+	buf := s.buf[:0]
+	ends := make([]int, 0, len(s.table.Columns)+2)
+	buf = append(buf, row.Measurement...)
+	ends = append(ends, len(buf))
+	buf = row.Timestamp.UTC().AppendFormat(buf, "2006-01-02 15:04:05.000000")
+	ends = append(ends, len(buf))
+	for i := range s.table.Columns {
+		if v, ok := row.Value(i); ok {
+			v, err := s.table.Columns[i].convert(v)
+			if err != nil {
+				return err
+			}
+			if v.Kind == ValueKindBytes {
+				buf = append(buf, v.Bytes...)
+			} else {
+				buf = v.AppendTo(buf, false)
+			}
+		}
+		ends = append(ends, len(buf))
+	}
+	s.buf = buf
+
+	record := s.record[:0]
+	start := 0
+	for _, end := range ends {
+		record = append(record, string(buf[start:end]))
+		start = end
+	}
+	s.record = record
+	return s.csvWriter(w).Write(record)
+}
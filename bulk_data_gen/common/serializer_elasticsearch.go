@@ -2,6 +2,7 @@ package common
 
 import (
 	"io"
+	"strconv"
 	"strings"
 )
 
@@ -9,15 +10,41 @@ var typeName5x = []byte("point")
 var typeName6x = []byte("_doc")
 
 type SerializerElastic struct {
+	// typeName is nil for the typeless actions of ElasticSearch 7 and later.
 	typeName []byte
+	// action is index, or create for data streams.
+	action       []byte
+	timestampKey []byte
 }
 
+// NewSerializerElastic returns the serializer for the ElasticSearch version:
+// 5x, 6x, 7x for ElasticSearch 7 and later or OpenSearch, or ds for the data
+// streams of ElasticSearch 7.9 and later, which take create actions and an
+// @timestamp field.
 func NewSerializerElastic(version string) *SerializerElastic {
-	typeName := typeName5x
-	if strings.HasPrefix(version, "6") {
-		typeName = typeName6x
+	s := &SerializerElastic{
+		typeName:     typeName5x,
+		action:       []byte("index"),
+		timestampKey: []byte("timestamp"),
 	}
-	return &SerializerElastic{typeName: typeName}
+	switch {
+	case strings.HasPrefix(version, "6"):
+		s.typeName = typeName6x
+	case strings.HasPrefix(version, "7"):
+		s.typeName = nil
+	case version == "ds":
+		s.typeName = nil
+		s.action = []byte("create")
+		s.timestampKey = []byte("@timestamp")
+	}
+	return s
+}
+
+func init() {
+	RegisterSerializer("es-bulk", func() Serializer { return NewSerializerElastic("5x") })
+	RegisterSerializer("es-bulk6x", func() Serializer { return NewSerializerElastic("6x") })
+	RegisterSerializer("es-bulk7x", func() Serializer { return NewSerializerElastic("7x") })
+	RegisterSerializer("es-bulk-ds", func() Serializer { return NewSerializerElastic("ds") })
 }
 
 // SerializeESBulk writes Point data to the given writer, conforming to the
@@ -31,15 +58,26 @@ func NewSerializerElastic(version string) *SerializerElastic {
 // { "index" : { "_index" : "measurement_otqio", "_type" : "point" } }\n
 // { "tag_launx": "btkuw", "tag_gaijk": "jiypr", "field_wokxf": 0.08463898963964356, "field_zqstf": -0.043641533500086316, "timestamp": 171300 }\n
 //
+// Typeless actions have no _type, and data streams get lines like:
+// { "create" : { "_index" : "measurement_otqio" } }\n
+// { "tag_launx": "btkuw", ..., "@timestamp": 171300 }\n
+//
+// Points with a location also get a "location": { "lat": ..., "lon": ... }
+// object, mapped as a geo_point.
+//
 // TODO(rw): Speed up this function. The bulk of time is spent in strconv.
 
 func (s *SerializerElastic) SerializePoint(w io.Writer, p *Point) error {
 	buf := scratchBufPool.Get().([]byte)
 
-	buf = append(buf, "{ \"index\" : { \"_index\" : \""...)
+	buf = append(buf, "{ \""...)
+	buf = append(buf, s.action...)
+	buf = append(buf, "\" : { \"_index\" : \""...)
 	buf = append(buf, p.MeasurementName...)
-	buf = append(buf, "\", \"_type\" : \""...)
-	buf = append(buf, s.typeName...)
+	if s.typeName != nil {
+		buf = append(buf, "\", \"_type\" : \""...)
+		buf = append(buf, s.typeName...)
+	}
 	buf = append(buf, "\" } }\n"...)
 
 	buf = append(buf, '{')
@@ -68,16 +106,27 @@ func (s *SerializerElastic) SerializePoint(w io.Writer, p *Point) error {
 		buf = append(buf, p.FieldKeys[i]...)
 		buf = append(buf, "\": "...)
 
-		v := p.FieldValues[i]
-		buf = fastFormatAppend(v, buf, false)
+		buf = p.FieldValues[i].AppendTo(buf, false)
+	}
+
+	// Locations are indexed as a geo_point, see the bulk_load_es templates.
+	// SetLocation always appends fields, so a separator is needed:
+	if p.Location != nil {
+		buf = append(buf, ", \"location\": { \"lat\": "...)
+		buf = FloatValue(p.Location.Lat).AppendTo(buf, false)
+		buf = append(buf, ", \"lon\": "...)
+		buf = FloatValue(p.Location.Lon).AppendTo(buf, false)
+		buf = append(buf, " }"...)
 	}
 
 	if len(p.TagKeys) > 0 || len(p.FieldKeys) > 0 {
 		buf = append(buf, ", "...)
 	}
 	// Timestamps in ES must be millisecond precision:
-	buf = append(buf, "\"timestamp\": "...)
-	buf = fastFormatAppend(p.Timestamp.UTC().UnixNano()/1e6, buf, true)
+	buf = append(buf, '"')
+	buf = append(buf, s.timestampKey...)
+	buf = append(buf, "\": "...)
+	buf = strconv.AppendInt(buf, p.Timestamp.UTC().UnixNano()/1e6, 10)
 	buf = append(buf, " }\n"...)
 
 	_, err := w.Write(buf)
@@ -15,6 +15,10 @@ func NewSerializerGraphiteLine() *SerializerGraphiteLine {
 	}
 }
 
+func init() {
+	RegisterSerializer("graphite-line", func() Serializer { return NewSerializerGraphiteLine() })
+}
+
 // SerializePoint writes Point data to the given writer, conforming to the
 // Graphite plain text line protocol.
 func (s *SerializerGraphiteLine) SerializePoint(w io.Writer, p *Point) (err error) {
@@ -31,8 +35,7 @@ func (s *SerializerGraphiteLine) SerializePoint(w io.Writer, p *Point) (err erro
 			buf = append(buf, p.TagValues[i]...)
 		}
 		buf = append(buf, " "...)
-		v := p.FieldValues[i]
-		buf = fastFormatAppend(v, buf, true)
+		buf = p.FieldValues[i].AppendTo(buf, true)
 		buf = append(buf, " "...)
 		buf = append(buf, []byte(fmt.Sprintf("%d", timestamp))...)
 		buf = append(buf, "\n"...)
@@ -2,6 +2,7 @@ package common
 
 import (
 	"io"
+	"strconv"
 )
 
 type serializerInflux struct {
@@ -11,6 +12,10 @@ func NewSerializerInflux() *serializerInflux {
 	return &serializerInflux{}
 }
 
+func init() {
+	RegisterSerializer("influx-bulk", func() Serializer { return NewSerializerInflux() })
+}
+
 // SerializeInfluxBulk writes Point data to the given writer, conforming to the
 // InfluxDB wire protocol.
 //
@@ -41,11 +46,10 @@ func (s *serializerInflux) SerializePoint(w io.Writer, p *Point) (err error) {
 		buf = append(buf, '=')
 
 		v := p.FieldValues[i]
-		buf = fastFormatAppend(v, buf, false)
+		buf = v.AppendTo(buf, false)
 
 		// Influx uses 'i' to indicate integers:
-		switch v.(type) {
-		case int, int64:
+		if v.Kind == ValueKindInt {
 			buf = append(buf, 'i')
 		}
 
@@ -55,7 +59,7 @@ func (s *serializerInflux) SerializePoint(w io.Writer, p *Point) (err error) {
 	}
 
 	buf = append(buf, ' ')
-	buf = fastFormatAppend(p.Timestamp.UTC().UnixNano(), buf, true)
+	buf = strconv.AppendInt(buf, p.Timestamp.UTC().UnixNano(), 10)
 	buf = append(buf, '\n')
 	_, err = w.Write(buf)
 
@@ -0,0 +1,156 @@
+package common
+
+import (
+	"io"
+	"strconv"
+)
+
+// SerializerIoTDB writes Points as IoTDB aligned device records.
+type SerializerIoTDB struct {
+	buf []byte
+}
+
+func NewSerializerIoTDB() *SerializerIoTDB {
+	return &SerializerIoTDB{}
+}
+
+func init() {
+	RegisterSerializer("iotdb", func() Serializer { return NewSerializerIoTDB() })
+}
+
+// SerializePoint writes one JSON record per line, which bulk_load_iotdb
+// groups by device into insertTablet requests. The device path is
+// root.<measurement>.<first tag value>, the VIN or the host, and the fields
+// are the aligned timeseries of the device. The other tags are attributes of
+// the device, which bulk_load_iotdb writes as TEXT timeseries.
+//
+// This function writes output that looks like:
+// {"device":"root.<measurement>.<first tag value>","timestamp":<timestamp in milliseconds>,"measurements":["<field>",...],"data_types":["<type>",...],"values":[<value>,...],"attributes":{"<tag>":"<tag value>",...}}
+//
+// For example:
+// {"device":"root.vehicle.LSV1A23B4C5D67890","timestamp":1514764800000,"measurements":["value1",...],"data_types":["DOUBLE",...],"values":[58.1300000000000026,...],"attributes":{"manufacturer":"SAIC",...}}
+func (s *SerializerIoTDB) SerializePoint(w io.Writer, p *Point) error {
+	buf := s.buf[:0]
+
+	buf = append(buf, `{"device":"root.`...)
+	buf = AppendIoTDBPathNode(buf, p.MeasurementName)
+	if len(p.TagValues) > 0 {
+		buf = append(buf, '.')
+		buf = AppendIoTDBPathNode(buf, p.TagValues[0])
+	}
+
+	buf = append(buf, `","timestamp":`...)
+	buf = strconv.AppendInt(buf, p.Timestamp.UTC().UnixNano()/1e6, 10)
+
+	buf = append(buf, `,"measurements":[`...)
+	for i := 0; i < len(p.FieldKeys); i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, '"')
+		buf = AppendIoTDBPathNode(buf, p.FieldKeys[i])
+		buf = append(buf, '"')
+	}
+
+	buf = append(buf, `],"data_types":[`...)
+	for i := 0; i < len(p.FieldValues); i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, '"')
+		buf = append(buf, iotdbDataType(p.FieldValues[i].Kind)...)
+		buf = append(buf, '"')
+	}
+
+	buf = append(buf, `],"values":[`...)
+	for i := 0; i < len(p.FieldValues); i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		if p.FieldValues[i].Kind == ValueKindBytes {
+			buf = appendJSONString(buf, p.FieldValues[i].Bytes)
+		} else {
+			buf = p.FieldValues[i].AppendTo(buf, false)
+		}
+	}
+	buf = append(buf, ']')
+
+	if len(p.TagKeys) > 1 {
+		buf = append(buf, `,"attributes":{`...)
+		for i := 1; i < len(p.TagKeys); i++ {
+			if i > 1 {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, '"')
+			buf = AppendIoTDBPathNode(buf, p.TagKeys[i])
+			buf = append(buf, '"', ':')
+			buf = appendJSONString(buf, p.TagValues[i])
+		}
+		buf = append(buf, '}')
+	}
+	buf = append(buf, "}\n"...)
+
+	s.buf = buf
+	_, err := w.Write(buf)
+	return err
+}
+
+func (s *SerializerIoTDB) SerializeSize(w io.Writer, points int64, values int64) error {
+	return serializeSizeInText(w, points, values)
+}
+
+func iotdbDataType(kind ValueKind) string {
+	switch kind {
+	case ValueKindInt:
+		return "INT64"
+	case ValueKindFloat:
+		return "DOUBLE"
+	case ValueKindBool:
+		return "BOOLEAN"
+	default:
+		return "TEXT"
+	}
+}
+
+// appendJSONString appends s as a JSON string, escaping the quotes,
+// backslashes and control characters.
+func appendJSONString(buf []byte, s []byte) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for _, c := range s {
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c < 0x20:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
+
+// AppendIoTDBPathNode appends name as a node of an IoTDB path. Names which
+// are not plain identifiers, such as eu-west-1 or 2015, are quoted with
+// backquotes. The query generators quote the nodes they match the same way.
+func AppendIoTDBPathNode(buf []byte, name []byte) []byte {
+	plain := len(name) > 0 && !(name[0] >= '0' && name[0] <= '9')
+	for _, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return append(buf, name...)
+	}
+
+	buf = append(buf, '`')
+	for _, c := range name {
+		if c == '`' {
+			buf = append(buf, '`')
+		}
+		buf = append(buf, c)
+	}
+	return append(buf, '`')
+}
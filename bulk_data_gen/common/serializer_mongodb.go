@@ -1,46 +1,115 @@
 package common
 
 import (
-	"encoding/binary"
 	"fmt"
 	"github.com/google/flatbuffers/go"
 	"github.com/caict-benchmark/BDC-TS/mongo_serialization"
 	"io"
-	"reflect"
 )
 
 type SerializerMongo struct {
+	// the builder of the current batch, nil outside of a batch
+	builder *flatbuffers.Builder
+	items   []flatbuffers.UOffsetT
+	// the byte vectors of the names already in the builder
+	names map[string]flatbuffers.UOffsetT
 }
 
 func NewSerializerMongo() *SerializerMongo {
 	return &SerializerMongo{}
 }
 
+func init() {
+	RegisterSerializer("mongo", func() Serializer { return NewSerializerMongo() })
+}
+
 // SerializeMongo writes Point data to the given writer, conforming to the
 // mongo_serialization FlatBuffers format.
+//
+// In a batch, the Items are written together as one ItemBatch, with
+// mongo_serialization.BatchFrameFlag set in the length prefix. The measurement
+// names, tag keys and field keys are then written once per batch.
 func (s *SerializerMongo) SerializePoint(w io.Writer, p *Point) (err error) {
-	// Prepare the series id prefix, which is the set of tags associated
-	// with this point. The series id prefix is the base of each value's
-	// particular collection name:
-	lenBuf := bufPool8.Get().([]byte)
+	if s.builder != nil {
+		s.items = append(s.items, s.buildItem(s.builder, p))
+		return nil
+	}
+
+	// Fetch a flatbuffers builder from a pool:
+	builder := fbBuilderPool.Get().(*flatbuffers.Builder)
+
+	rootTable := s.buildItem(builder, p)
+	builder.Finish(rootTable)
+
+	// Access the finished byte slice representing this flatbuffer, and write
+	// it with its length:
+	err = writeFrame(w, builder.FinishedBytes(), 0)
+
+	// Give the flatbuffers builder back to a pool:
+	builder.Reset()
+	fbBuilderPool.Put(builder)
+
+	return err
+}
+
+func (s *SerializerMongo) BeginBatch(w io.Writer) error {
+	s.builder = fbBuilderPool.Get().(*flatbuffers.Builder)
+	s.names = map[string]flatbuffers.UOffsetT{}
+	return nil
+}
+
+// EndBatch writes the ItemBatch of the items of the batch.
+func (s *SerializerMongo) EndBatch(w io.Writer) error {
+	builder := s.builder
+	mongo_serialization.ItemBatchStartItemsVector(builder, len(s.items))
+	for i := len(s.items) - 1; i >= 0; i-- {
+		builder.PrependUOffsetT(s.items[i])
+	}
+	itemsVecOffset := builder.EndVector(len(s.items))
+	mongo_serialization.ItemBatchStart(builder)
+	mongo_serialization.ItemBatchAddItems(builder, itemsVecOffset)
+	builder.Finish(mongo_serialization.ItemBatchEnd(builder))
+
+	err := writeFrame(w, builder.FinishedBytes(), mongo_serialization.BatchFrameFlag)
+
+	builder.Reset()
+	fbBuilderPool.Put(builder)
+	s.builder = nil
+	s.items = s.items[:0]
+	s.names = nil
+	return err
+}
+
+// name returns the byte vector of a measurement name, tag key or field key,
+// which is shared by the items of a batch.
+func (s *SerializerMongo) name(builder *flatbuffers.Builder, name []byte) flatbuffers.UOffsetT {
+	if s.names == nil {
+		return builder.CreateByteVector(name)
+	}
+	if offset, ok := s.names[string(name)]; ok {
+		return offset
+	}
+	offset := builder.CreateByteVector(name)
+	s.names[string(name)] = offset
+	return offset
+}
 
+// buildItem builds the Item table of p.
+func (s *SerializerMongo) buildItem(builder *flatbuffers.Builder, p *Point) flatbuffers.UOffsetT {
 	// Prepare the timestamp, which is the same for each value in this
 	// Point:
 	timestampNanos := p.Timestamp.UTC().UnixNano()
 
-	// Fetch a flatbuffers builder from a pool:
-	builder := fbBuilderPool.Get().(*flatbuffers.Builder)
-
 	// For each field in this Point, serialize its:
 	// collection name (series id prefix + the name of the value)
 	// timestamp in nanos (int64)
-	// numeric value (int, int64, or float64 -- determined by reflection)
+	// value (int64, float64 or string -- determined by its kind)
 	tagOffsets := make([]flatbuffers.UOffsetT, 0, len(p.TagKeys))
 	fieldOffsets := make([]flatbuffers.UOffsetT, 0, len(p.FieldKeys))
 
 	// write the tag data, which must be separate:
 	for i := 0; i < len(p.TagKeys); i++ {
-		keyData := builder.CreateByteVector(p.TagKeys[i])
+		keyData := s.name(builder, p.TagKeys[i])
 		valData := builder.CreateByteVector(p.TagValues[i])
 		mongo_serialization.TagStart(builder)
 		mongo_serialization.TagAddKey(builder, keyData)
@@ -56,39 +125,26 @@ func (s *SerializerMongo) SerializePoint(w io.Writer, p *Point) (err error) {
 
 	// write the field data, which must be separate:
 	for i := 0; i < len(p.FieldKeys); i++ {
-		keyData := builder.CreateByteVector(p.FieldKeys[i])
+		v := p.FieldValues[i]
+		var stringOffset flatbuffers.UOffsetT
+		if v.Kind == ValueKindBytes {
+			stringOffset = builder.CreateByteVector(v.Bytes)
+		}
+		keyData := s.name(builder, p.FieldKeys[i])
 		mongo_serialization.FieldStart(builder)
 		mongo_serialization.FieldAddKey(builder, keyData)
-		genericValue := p.FieldValues[i]
-		switch v := genericValue.(type) {
-		// (We can't switch on sets of types (e.g. int, int64) because that does not make v concrete.)
-		case int:
-			mongo_serialization.FieldAddValueType(builder, mongo_serialization.ValueTypeInt)
-			mongo_serialization.FieldAddIntValue(builder, int32(v))
-		case int32:
-			mongo_serialization.FieldAddValueType(builder, mongo_serialization.ValueTypeInt)
-			mongo_serialization.FieldAddIntValue(builder, v)
-		case int64:
+		switch v.Kind {
+		case ValueKindInt, ValueKindBool:
 			mongo_serialization.FieldAddValueType(builder, mongo_serialization.ValueTypeLong)
-			mongo_serialization.FieldAddLongValue(builder, v)
-		case float32:
-			mongo_serialization.FieldAddValueType(builder, mongo_serialization.ValueTypeFloat)
-			mongo_serialization.FieldAddFloatValue(builder, v)
-		case float64:
+			mongo_serialization.FieldAddLongValue(builder, v.Int)
+		case ValueKindFloat:
 			mongo_serialization.FieldAddValueType(builder, mongo_serialization.ValueTypeDouble)
-			mongo_serialization.FieldAddDoubleValue(builder, v)
-		case string, []byte:
-			var stringOffset flatbuffers.UOffsetT
-			switch v2 := v.(type) {
-			case string:
-				stringOffset = builder.CreateString(v2)
-			case []byte:
-				stringOffset = builder.CreateByteVector(v2)
-			}
+			mongo_serialization.FieldAddDoubleValue(builder, v.Float)
+		case ValueKindBytes:
 			mongo_serialization.FieldAddValueType(builder, mongo_serialization.ValueTypeString)
 			mongo_serialization.FieldAddStringValue(builder, stringOffset)
 		default:
-			panic(fmt.Sprintf("logic error in mongo serialization, %s", reflect.TypeOf(v)))
+			panic(fmt.Sprintf("logic error in mongo serialization, kind %d", v.Kind))
 		}
 		fieldOffset := mongo_serialization.FieldEnd(builder)
 		fieldOffsets = append(fieldOffsets, fieldOffset)
@@ -100,7 +156,7 @@ func (s *SerializerMongo) SerializePoint(w io.Writer, p *Point) (err error) {
 	fieldsVecOffset := builder.EndVector(len(fieldOffsets))
 
 	// build the flatbuffer representing this point:
-	measurementNameOffset := builder.CreateByteVector(p.MeasurementName)
+	measurementNameOffset := s.name(builder, p.MeasurementName)
 
 	mongo_serialization.ItemStart(builder)
 	mongo_serialization.ItemAddTimestampNanos(builder, timestampNanos)
@@ -108,33 +164,7 @@ func (s *SerializerMongo) SerializePoint(w io.Writer, p *Point) (err error) {
 	mongo_serialization.ItemAddTags(builder, tagsVecOffset)
 	mongo_serialization.ItemAddFields(builder, fieldsVecOffset)
 
-	rootTable := mongo_serialization.ItemEnd(builder)
-	builder.Finish(rootTable)
-
-	// Access the finished byte slice representing this flatbuffer:
-	buf := builder.FinishedBytes()
-
-	// Write the metadata for the flatbuffer object:
-	binary.LittleEndian.PutUint64(lenBuf, uint64(len(buf)))
-	_, err = w.Write(lenBuf)
-	if err != nil {
-		return err
-	}
-
-	// Write the flatbuffer object:
-	_, err = w.Write(buf)
-	if err != nil {
-		return err
-	}
-
-	// Give the flatbuffers builder back to a pool:
-	builder.Reset()
-	fbBuilderPool.Put(builder)
-
-	// Give the 8-byte buf back to a pool:
-	bufPool8.Put(lenBuf)
-
-	return nil
+	return mongo_serialization.ItemEnd(builder)
 }
 
 func (s *SerializerMongo) SerializeSize(w io.Writer, points int64, values int64) error {
@@ -13,6 +13,10 @@ func NewSerializerOpenTSDB() *SerializerOpenTSDB {
 	return &SerializerOpenTSDB{}
 }
 
+func init() {
+	RegisterSerializer("opentsdb", func() Serializer { return NewSerializerOpenTSDB() })
+}
+
 // SerializeOpenTSDBBulk writes Point data to the given writer, conforming to
 // the OpenTSDB bulk load protocol (the /api/put endpoint). Note that no line
 // has a trailing comma. Downstream programs are responsible for creating
@@ -60,18 +64,11 @@ func (m *SerializerOpenTSDB) SerializePoint(w io.Writer, p *Point) (err error) {
 	// for each Value, generate a new line in the output:
 	for i := 0; i < len(p.FieldKeys); i++ {
 		wp.Metric = metricBase + "." + string(p.FieldKeys[i])
-		switch x := p.FieldValues[i].(type) {
-		case int:
-			wp.Value = float64(x)
-		case int64:
-			wp.Value = float64(x)
-		case float32:
-			wp.Value = float64(x)
-		case float64:
-			wp.Value = float64(x)
-		default:
+		value, ok := p.FieldValues[i].Numeric()
+		if !ok {
 			panic("bad numeric value for OpenTSDB serialization")
 		}
+		wp.Value = value
 
 		err := encoder.Encode(wp)
 		if err != nil {
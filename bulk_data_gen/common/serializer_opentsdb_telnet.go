@@ -0,0 +1,86 @@
+package common
+
+import (
+	"io"
+	"strconv"
+)
+
+// SerializerOpenTSDBTelnet writes Points as OpenTSDB telnet put commands.
+type SerializerOpenTSDBTelnet struct {
+	buf []byte
+}
+
+func NewSerializerOpenTSDBTelnet() *SerializerOpenTSDBTelnet {
+	return &SerializerOpenTSDBTelnet{}
+}
+
+func init() {
+	RegisterSerializer("opentsdb-telnet", func() Serializer { return NewSerializerOpenTSDBTelnet() })
+}
+
+// SerializePoint writes Point data to the given writer, conforming to the
+// OpenTSDB telnet protocol (the put command), one line per field. As for the
+// opentsdb format, timestamps have millisecond precision and values are
+// numeric, booleans are written as 0 or 1. Characters OpenTSDB does not accept
+// in metric names and tags, such as spaces, are replaced by underscores.
+//
+// This function writes output that looks like:
+// put <metric> <timestamp> <value> <tag>=<tag value> ...
+//
+// For example:
+// put cpu.usage_user 1451606400000 99.5170917755353770 hostname=host_01 region=ap-southeast-2 datacenter=ap-southeast-2a
+func (s *SerializerOpenTSDBTelnet) SerializePoint(w io.Writer, p *Point) error {
+	// Timestamps in OpenTSDB must be millisecond precision:
+	timestamp := p.Timestamp.UTC().UnixNano() / 1e6
+
+	buf := s.buf[:0]
+	for i := 0; i < len(p.FieldKeys); i++ {
+		buf = append(buf, "put "...)
+		buf = appendOpenTSDBName(buf, p.MeasurementName)
+		buf = append(buf, '.')
+		buf = appendOpenTSDBName(buf, p.FieldKeys[i])
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, timestamp, 10)
+		buf = append(buf, ' ')
+		switch v := p.FieldValues[i]; v.Kind {
+		case ValueKindInt, ValueKindFloat:
+			buf = v.AppendTo(buf, false)
+		case ValueKindBool:
+			buf = strconv.AppendInt(buf, v.Int, 10)
+		default:
+			panic("bad numeric value for OpenTSDB serialization")
+		}
+		for j := 0; j < len(p.TagKeys); j++ {
+			buf = append(buf, ' ')
+			buf = appendOpenTSDBName(buf, p.TagKeys[j])
+			buf = append(buf, '=')
+			buf = appendOpenTSDBName(buf, p.TagValues[j])
+		}
+		buf = append(buf, '\n')
+	}
+	s.buf = buf
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func (s *SerializerOpenTSDBTelnet) SerializeSize(w io.Writer, points int64, values int64) error {
+	//return serializeSizeInText(w, points, values)
+	return nil
+}
+
+// appendOpenTSDBName appends name with the characters OpenTSDB does not allow
+// in metric names, tag keys and tag values replaced by underscores. Besides
+// ASCII letters and digits, OpenTSDB allows -, _, ., / and Unicode letters.
+func appendOpenTSDBName(buf []byte, name []byte) []byte {
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '/', c >= 0x80:
+			buf = append(buf, c)
+		default:
+			buf = append(buf, '_')
+		}
+	}
+	return buf
+}
@@ -0,0 +1,134 @@
+package common
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/xitongsys/parquet-go-source/writerfile"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// SerializerParquet writes Points as rows of a wide Parquet file.
+type SerializerParquet struct {
+	table  wideTable
+	file   source.ParquetFile
+	writer *writer.CSVWriter
+}
+
+func NewSerializerParquet() *SerializerParquet {
+	return &SerializerParquet{}
+}
+
+func init() {
+	RegisterSerializer("parquet", func() Serializer { return NewSerializerParquet() })
+}
+
+func (s *SerializerParquet) Open(w io.Writer) error {
+	s.file = writerfile.NewWriterFile(w)
+	return nil
+}
+
+// SerializePoint adds a row per Point to the current row group, with the
+// columns of the csv format: measurement, time (INT64 TIMESTAMP_MICROS, UTC)
+// and an optional column per tag (UTF8) and field (INT64, DOUBLE, BOOLEAN or
+// UTF8) of all the measurements. Every flush ends a row group, the schema is
+// written on the first one and the rows before it are kept in memory: a tag or
+// field first seen after it is an error. Pages are compressed with snappy.
+func (s *SerializerParquet) SerializePoint(w io.Writer, p *Point) error {
+	row, err := s.table.MakeRow(p)
+	if err != nil {
+		return err
+	}
+	if !s.table.Frozen {
+		s.table.Pending = append(s.table.Pending, row)
+		return nil
+	}
+	return s.writeRow(&row)
+}
+
+func (s *SerializerParquet) SerializeSize(w io.Writer, points int64, values int64) error {
+	//return serializeSizeInText(w, points, values)
+	return nil
+}
+
+// Flush ends the current row group, creating the writer with the schema on
+// the first call.
+func (s *SerializerParquet) Flush(w io.Writer) error {
+	if !s.table.Frozen {
+		rows := s.table.Freeze()
+		var err error
+		if s.writer, err = writer.NewCSVWriter(s.schema(), s.file, 4); err != nil {
+			return err
+		}
+		for i := range rows {
+			if err := s.writeRow(&rows[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return s.writer.Flush(true)
+}
+
+// Close writes the last row group and the footer.
+func (s *SerializerParquet) Close(w io.Writer) error {
+	if err := s.Flush(w); err != nil {
+		return err
+	}
+	if err := s.writer.WriteStop(); err != nil {
+		return err
+	}
+	return s.file.Close()
+}
+
+// schema returns the metadata of the columns, in the form of the CSVWriter.
+func (s *SerializerParquet) schema() []string {
+	md := []string{
+		"name=measurement, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY",
+		"name=time, type=INT64, convertedtype=TIMESTAMP_MICROS",
+	}
+	for _, c := range s.table.Columns {
+		switch {
+		case c.Tag:
+			md = append(md, fmt.Sprintf("name=%s, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, repetitiontype=OPTIONAL", c.Name))
+		case c.Kind == ValueKindInt:
+			md = append(md, fmt.Sprintf("name=%s, type=INT64, repetitiontype=OPTIONAL", c.Name))
+		case c.Kind == ValueKindFloat:
+			md = append(md, fmt.Sprintf("name=%s, type=DOUBLE, repetitiontype=OPTIONAL", c.Name))
+		case c.Kind == ValueKindBool:
+			md = append(md, fmt.Sprintf("name=%s, type=BOOLEAN, repetitiontype=OPTIONAL", c.Name))
+		default:
+			md = append(md, fmt.Sprintf("name=%s, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL", c.Name))
+		}
+	}
+	return md
+}
+
+func (s *SerializerParquet) writeRow(row *wideRow) error {
+	// the writer keeps the records of the row group, so each needs its own
+	record := make([]interface{}, 0, len(s.table.Columns)+2)
+	record = append(record, string(row.Measurement))
+	record = append(record, row.Timestamp.UTC().UnixNano()/1e3)
+	for i := range s.table.Columns {
+		v, ok := row.Value(i)
+		if !ok {
+			record = append(record, nil)
+			continue
+		}
+		v, err := s.table.Columns[i].convert(v)
+		if err != nil {
+			return err
+		}
+		switch v.Kind {
+		case ValueKindInt:
+			record = append(record, v.Int)
+		case ValueKindFloat:
+			record = append(record, v.Float)
+		case ValueKindBool:
+			record = append(record, v.Int != 0)
+		default:
+			record = append(record, string(v.Bytes))
+		}
+	}
+	return s.writer.Write(record)
+}
@@ -0,0 +1,137 @@
+package common
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// SerializerPrometheus writes Points as Prometheus remote write requests.
+type SerializerPrometheus struct {
+	req     prompb.WriteRequest
+	series  []prompb.TimeSeries
+	samples []prompb.Sample
+	labels  []prompb.Label
+	names   []byte
+	buf     []byte
+	snapped []byte
+	lenBuf  [8]byte
+}
+
+func NewSerializerPrometheus() *SerializerPrometheus {
+	return &SerializerPrometheus{}
+}
+
+func init() {
+	RegisterSerializer("prometheus-remote-write", func() Serializer { return NewSerializerPrometheus() })
+}
+
+// SerializePoint writes one snappy compressed WriteRequest protobuf per
+// Point, prefixed by its length as a little endian uint64. Each numeric field
+// becomes a series named <measurement>_<field>, labelled with the tags of the
+// Point. Byte fields are skipped, Prometheus only stores floats.
+//
+// For example, the cpu usage_user field of a devops host becomes:
+// cpu_usage_user{arch="x64",datacenter="eu-west-1b",hostname="host_0",...} 58.1 @1514764800000
+//
+// Concatenated WriteRequest protobufs form a valid WriteRequest, which lets
+// bulk_load_prometheus batch points without decoding them.
+func (s *SerializerPrometheus) SerializePoint(w io.Writer, p *Point) error {
+	timestamp := p.Timestamp.UTC().UnixNano() / 1e6
+
+	// labels are shared by the series of the point, except for the name,
+	// and must be sorted by name:
+	tags := len(p.TagKeys)
+	s.labels = s.labels[:0]
+	for i := 0; i < tags; i++ {
+		s.labels = append(s.labels, prompb.Label{
+			Name:  prometheusName(p.TagKeys[i]),
+			Value: string(p.TagValues[i]),
+		})
+	}
+	sort.Sort(labelsByName(s.labels))
+
+	s.series = s.series[:0]
+	s.samples = s.samples[:0]
+	for i := range p.FieldKeys {
+		value, ok := p.FieldValues[i].Numeric()
+		if !ok {
+			continue
+		}
+		s.names = s.names[:0]
+		s.names = append(s.names, p.MeasurementName...)
+		s.names = append(s.names, '_')
+		s.names = append(s.names, p.FieldKeys[i]...)
+
+		// "__name__" sorts before any other label
+		labels := make([]prompb.Label, 0, tags+1)
+		labels = append(labels, prompb.Label{Name: "__name__", Value: prometheusName(s.names)})
+		labels = append(labels, s.labels...)
+
+		s.samples = append(s.samples, prompb.Sample{Value: value, Timestamp: timestamp})
+		s.series = append(s.series, prompb.TimeSeries{Labels: labels})
+	}
+	for i := range s.series {
+		s.series[i].Samples = s.samples[i : i+1]
+	}
+	s.req.Timeseries = s.series
+
+	size := s.req.Size()
+	if cap(s.buf) < size {
+		s.buf = make([]byte, size)
+	}
+	n, err := s.req.MarshalTo(s.buf[:size])
+	if err != nil {
+		return err
+	}
+	s.snapped = snappy.Encode(s.snapped[:cap(s.snapped)], s.buf[:n])
+
+	binary.LittleEndian.PutUint64(s.lenBuf[:], uint64(len(s.snapped)))
+	if _, err := w.Write(s.lenBuf[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(s.snapped)
+	return err
+}
+
+func (s *SerializerPrometheus) SerializeSize(w io.Writer, points int64, values int64) error {
+	//return serializeSizeInText(w, points, values)
+	return nil
+}
+
+type labelsByName []prompb.Label
+
+func (l labelsByName) Len() int           { return len(l) }
+func (l labelsByName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l labelsByName) Less(i, j int) bool { return l[i].Name < l[j].Name }
+
+// prometheusName replaces the characters not allowed in metric and label
+// names by underscores.
+func prometheusName(name []byte) string {
+	valid := true
+	for i, c := range name {
+		if !prometheusNameChar(c, i) {
+			valid = false
+			break
+		}
+	}
+	if valid {
+		return string(name)
+	}
+	sanitized := make([]byte, len(name))
+	for i, c := range name {
+		if prometheusNameChar(c, i) {
+			sanitized[i] = c
+		} else {
+			sanitized[i] = '_'
+		}
+	}
+	return string(sanitized)
+}
+
+func prometheusNameChar(c byte, i int) bool {
+	return c == '_' || c == ':' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9'
+}
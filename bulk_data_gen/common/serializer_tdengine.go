@@ -0,0 +1,137 @@
+package common
+
+import (
+	"hash/fnv"
+	"io"
+	"strconv"
+)
+
+// SerializerTDengine writes Points as TDengine multi-table insert clauses.
+type SerializerTDengine struct {
+	buf []byte
+}
+
+func NewSerializerTDengine() *SerializerTDengine {
+	return &SerializerTDengine{}
+}
+
+func init() {
+	RegisterSerializer("tdengine", func() Serializer { return NewSerializerTDengine() })
+}
+
+// SerializePoint writes one line per Point, to be joined after a single
+// INSERT INTO by bulk_load_tdengine. The measurement is a super table, its
+// tags are the TAGS of the super table and its fields are columns. Each
+// series, that is each VIN or host (and disk, interface, ...), is a sub table
+// named after the measurement, the first tag value and a hash of all the tag
+// values, so that the sub table is created on its first insert.
+//
+// This function writes output that looks like:
+// <sub table> USING <measurement> (`<tag>`,...) TAGS ('<tag value>',...) (`ts`,`<field>`,...) VALUES (<timestamp in milliseconds>,<field value>,...)
+//
+// For example:
+// cpu_host_0_<hash> USING cpu (`hostname`,`region`,...) TAGS ('host_0','eu-west-1',...) (`ts`,`usage_user`,...) VALUES (1514764800000,58.1300000000000026,...)
+func (s *SerializerTDengine) SerializePoint(w io.Writer, p *Point) error {
+	buf := s.buf[:0]
+
+	buf = appendTDengineSubTable(buf, p)
+	buf = append(buf, " USING "...)
+	buf = appendTDengineName(buf, p.MeasurementName)
+
+	buf = append(buf, " ("...)
+	for i := 0; i < len(p.TagKeys); i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendTDengineColumn(buf, p.TagKeys[i])
+	}
+	buf = append(buf, ") TAGS ("...)
+	for i := 0; i < len(p.TagValues); i++ {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendTDengineString(buf, p.TagValues[i])
+	}
+
+	buf = append(buf, ") (`ts`"...)
+	for i := 0; i < len(p.FieldKeys); i++ {
+		buf = append(buf, ',')
+		buf = appendTDengineColumn(buf, p.FieldKeys[i])
+	}
+	buf = append(buf, ") VALUES ("...)
+	buf = strconv.AppendInt(buf, p.Timestamp.UTC().UnixNano()/1e6, 10)
+	for i := 0; i < len(p.FieldValues); i++ {
+		buf = append(buf, ',')
+		if p.FieldValues[i].Kind == ValueKindBytes {
+			buf = appendTDengineString(buf, p.FieldValues[i].Bytes)
+		} else {
+			buf = p.FieldValues[i].AppendTo(buf, true)
+		}
+	}
+	buf = append(buf, ")\n"...)
+
+	s.buf = buf
+	_, err := w.Write(buf)
+	return err
+}
+
+func (s *SerializerTDengine) SerializeSize(w io.Writer, points int64, values int64) error {
+	return serializeSizeInText(w, points, values)
+}
+
+// appendTDengineSubTable appends the name of the sub table of the series of p.
+func appendTDengineSubTable(buf []byte, p *Point) []byte {
+	h := fnv.New32a()
+	for i := 0; i < len(p.TagValues); i++ {
+		h.Write(p.TagValues[i])
+		h.Write([]byte{0})
+	}
+
+	buf = appendTDengineName(buf, p.MeasurementName)
+	if len(p.TagValues) > 0 {
+		buf = append(buf, '_')
+		buf = appendTDengineName(buf, p.TagValues[0])
+	}
+	buf = append(buf, '_')
+	sum := h.Sum32()
+	for shift := 28; shift >= 0; shift -= 4 {
+		buf = append(buf, "0123456789abcdef"[(sum>>uint(shift))&0xf])
+	}
+	return buf
+}
+
+// appendTDengineName appends name as a lower case table name, replacing the
+// characters TDengine does not allow in unquoted names by underscores.
+func appendTDengineName(buf []byte, name []byte) []byte {
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '_':
+			buf = append(buf, c)
+		case c >= 'A' && c <= 'Z':
+			buf = append(buf, c+'a'-'A')
+		default:
+			buf = append(buf, '_')
+		}
+	}
+	return buf
+}
+
+// appendTDengineColumn appends a quoted column name: field names such as
+// state or level are TDengine keywords.
+func appendTDengineColumn(buf []byte, name []byte) []byte {
+	buf = append(buf, '`')
+	buf = append(buf, name...)
+	return append(buf, '`')
+}
+
+// appendTDengineString appends a single quoted string literal.
+func appendTDengineString(buf []byte, value []byte) []byte {
+	buf = append(buf, '\'')
+	for _, c := range value {
+		if c == '\'' || c == '\\' {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, c)
+	}
+	return append(buf, '\'')
+}
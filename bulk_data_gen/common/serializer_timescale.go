@@ -1,12 +1,10 @@
 package common
 
 import (
-	"encoding/binary"
 	"fmt"
 	"github.com/caict-benchmark/BDC-TS/timescale_serializaition"
 	"io"
-	"log"
-	"reflect"
+	"strings"
 )
 
 type SerializerTimescaleSql struct {
@@ -17,10 +15,22 @@ func NewSerializerTimescaleSql() *SerializerTimescaleSql {
 }
 
 type SerializerTimescaleBin struct {
+	inBatch bool
+	// the points of the current batch, one per measurement and columns, in
+	// the order they were first seen
+	points []*timescale_serialization.FlatPoint
+	index  map[string]int
 }
 
 func NewSerializerTimescaleBin() *SerializerTimescaleBin {
-	return &SerializerTimescaleBin{}
+	return &SerializerTimescaleBin{
+		index: map[string]int{},
+	}
+}
+
+func init() {
+	RegisterSerializer("timescaledb-sql", func() Serializer { return NewSerializerTimescaleSql() })
+	RegisterSerializer("timescaledb-copyFrom", func() Serializer { return NewSerializerTimescaleBin() })
 }
 
 // SerializeTimeScale writes Point data to the given writer, conforming to the
@@ -55,8 +65,7 @@ func (s *SerializerTimescaleSql) SerializePoint(w io.Writer, p *Point) (err erro
 
 	for i := 0; i < len(p.FieldValues); i++ {
 		buf = append(buf, ","...)
-		v := p.FieldValues[i]
-		buf = fastFormatAppend(v, buf, true)
+		buf = p.FieldValues[i].AppendTo(buf, true)
 	}
 	buf = append(buf, []byte(");\n")...)
 
@@ -75,71 +84,107 @@ func (s *SerializerTimescaleSql) SerializeSize(w io.Writer, points int64, values
 // SerializeTimeScaleBin writes Point data to the given writer, conforming to the
 // Binary GOP encoded format to write
 //
-//
+// In a batch, the points of each measurement and columns are written as one
+// FlatPoint whose values hold the rows column-major: the values of the first
+// column for every row, then those of the second column and so on. A
+// FlatPoint has several rows when it has more values than columns.
 func (t *SerializerTimescaleBin) SerializePoint(w io.Writer, p *Point) (err error) {
-
-	var f timescale_serialization.FlatPoint
-	f.MeasurementName = string(p.MeasurementName)
-	// Write the batch.
-	f.Columns = make([]string, len(p.TagKeys)+len(p.FieldKeys)+1)
-	c := 0
+	columns := make([]string, 0, len(p.TagKeys)+len(p.FieldKeys)+1)
 	for i := 0; i < len(p.TagKeys); i++ {
-		f.Columns[c] = string(p.TagKeys[i])
-		c++
+		columns = append(columns, string(p.TagKeys[i]))
 	}
 	for i := 0; i < len(p.FieldKeys); i++ {
-		f.Columns[c] = string(p.FieldKeys[i])
-		c++
+		columns = append(columns, string(p.FieldKeys[i]))
 	}
-	f.Columns[c] = "time"
+	columns = append(columns, "time")
 
-	c = 0
-	f.Values = make([]*timescale_serialization.FlatPoint_FlatPointValue, len(p.TagValues)+len(p.FieldValues)+1)
+	values := make([]*timescale_serialization.FlatPoint_FlatPointValue, 0, len(columns))
 	for i := 0; i < len(p.TagValues); i++ {
 		v := timescale_serialization.FlatPoint_FlatPointValue{}
 		v.Type = timescale_serialization.FlatPoint_STRING
 		v.StringVal = string(p.TagValues[i])
-		f.Values[c] = &v
-		c++
+		values = append(values, &v)
 	}
 	for i := 0; i < len(p.FieldValues); i++ {
 		v := timescale_serialization.FlatPoint_FlatPointValue{}
-		switch p.FieldValues[i].(type) {
-		case int64:
-			v.Type = timescale_serialization.FlatPoint_INTEGER
-			v.IntVal = p.FieldValues[i].(int64)
-			break
-		case int:
+		switch fv := p.FieldValues[i]; fv.Kind {
+		case ValueKindInt, ValueKindBool:
 			v.Type = timescale_serialization.FlatPoint_INTEGER
-			v.IntVal = int64(p.FieldValues[i].(int))
-			break
-		case float64:
+			v.IntVal = fv.Int
+		case ValueKindFloat:
 			v.Type = timescale_serialization.FlatPoint_FLOAT
-			v.DoubleVal = p.FieldValues[i].(float64)
-			break
-		case string:
+			v.DoubleVal = fv.Float
+		case ValueKindBytes:
 			v.Type = timescale_serialization.FlatPoint_STRING
-			v.StringVal = p.FieldValues[i].(string)
-			break
+			v.StringVal = string(fv.Bytes)
 		default:
-			panic(fmt.Sprintf("logic error in timescale serialization, %s", reflect.TypeOf(v)))
+			panic(fmt.Sprintf("logic error in timescale serialization, kind %d", fv.Kind))
 		}
-		f.Values[c] = &v
-		c++
+		values = append(values, &v)
 	}
 	timeVal := timescale_serialization.FlatPoint_FlatPointValue{}
 	timeVal.Type = timescale_serialization.FlatPoint_INTEGER
 	timeVal.IntVal = p.Timestamp.UnixNano()
-	f.Values[c] = &timeVal
+	values = append(values, &timeVal)
+
+	if !t.inBatch {
+		return writeFlatPoint(w, &timescale_serialization.FlatPoint{
+			MeasurementName: string(p.MeasurementName),
+			Columns:         columns,
+			Values:          values,
+		})
+	}
+
+	key := string(p.MeasurementName) + "\x00" + strings.Join(columns, "\x00")
+	i, ok := t.index[key]
+	if !ok {
+		i = len(t.points)
+		t.index[key] = i
+		t.points = append(t.points, &timescale_serialization.FlatPoint{
+			MeasurementName: string(p.MeasurementName),
+			Columns:         columns,
+		})
+	}
+	// row-major until EndBatch
+	t.points[i].Values = append(t.points[i].Values, values...)
+	return nil
+}
 
+func (t *SerializerTimescaleBin) BeginBatch(w io.Writer) error {
+	t.inBatch = true
+	return nil
+}
+
+// EndBatch writes a FlatPoint per measurement and columns of the batch.
+func (t *SerializerTimescaleBin) EndBatch(w io.Writer) error {
+	t.inBatch = false
+	for _, f := range t.points {
+		columns := len(f.Columns)
+		rows := len(f.Values) / columns
+		values := make([]*timescale_serialization.FlatPoint_FlatPointValue, len(f.Values))
+		for r := 0; r < rows; r++ {
+			for c := 0; c < columns; c++ {
+				values[c*rows+r] = f.Values[r*columns+c]
+			}
+		}
+		f.Values = values
+		if err := writeFlatPoint(w, f); err != nil {
+			return err
+		}
+	}
+	t.points = t.points[:0]
+	for key := range t.index {
+		delete(t.index, key)
+	}
+	return nil
+}
+
+func writeFlatPoint(w io.Writer, f *timescale_serialization.FlatPoint) error {
 	out, err := f.Marshal()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	s := uint64(len(out))
-	binary.Write(w, binary.LittleEndian, s)
-	w.Write(out)
-	return nil
+	return writeFrame(w, out, 0)
 }
 
 func (s *SerializerTimescaleBin) SerializeSize(w io.Writer, points int64, values int64) error {
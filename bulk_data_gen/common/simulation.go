@@ -30,14 +30,15 @@ type SimulatedMeasurement interface {
 	ToPoint(*Point) bool //returns true if point if properly filled, false means, that point should be skipped
 }
 
-// MakeUsablePoint allocates a new Point ready for use by a Simulator.
+// MakeUsablePoint allocates a new Point ready for use by a Simulator. The
+// Point can be reused for the next one after calling Reset.
 func MakeUsablePoint() *Point {
 	return &Point{
 		MeasurementName: nil,
 		TagKeys:         make([][]byte, 0),
 		TagValues:       make([][]byte, 0),
 		FieldKeys:       make([][]byte, 0),
-		FieldValues:     make([]interface{}, 0),
+		FieldValues:     make([]FieldValue, 0),
 		Timestamp:       &time.Time{},
 	}
 }
@@ -0,0 +1,130 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// wideColumn is a tag or field column of a wideTable.
+type wideColumn struct {
+	Name string
+	Tag  bool
+	Kind ValueKind
+}
+
+// wideRow is a Point laid out on the columns of a wideTable. Values[i] is the
+// value of Columns[i] if Set[i], rows made before a column was added are
+// shorter than Columns.
+type wideRow struct {
+	Measurement []byte
+	Timestamp   time.Time
+	Values      []FieldValue
+	Set         []bool
+}
+
+// Value returns the value of column i, ok is false if the row has none.
+func (r *wideRow) Value(i int) (v FieldValue, ok bool) {
+	if i >= len(r.Values) || !r.Set[i] {
+		return FieldValue{}, false
+	}
+	return r.Values[i], true
+}
+
+// wideTable lays out Points of all measurements on the union of their tags
+// and fields, for the formats with one column per tag and field such as csv
+// and parquet. Columns are added as they are seen, and fields seen with
+// several types become Float64 (for integers and floats) or strings. Because
+// those formats state their columns once, before the rows, the rows are kept
+// in Pending until the serializer freezes the columns, usually on its first
+// flush. From then on, a Point with a new column is an error.
+type wideTable struct {
+	Columns []wideColumn
+	Frozen  bool
+	Pending []wideRow
+
+	index map[string]int
+}
+
+// MakeRow copies p into a new row, adding its columns to the table unless it
+// is frozen.
+func (t *wideTable) MakeRow(p *Point) (wideRow, error) {
+	if t.index == nil {
+		t.index = map[string]int{}
+	}
+
+	row := wideRow{
+		Measurement: append([]byte(nil), p.MeasurementName...),
+		Timestamp:   *p.Timestamp,
+		Values:      make([]FieldValue, len(t.Columns), len(t.Columns)+len(p.TagKeys)+len(p.FieldKeys)),
+		Set:         make([]bool, len(t.Columns), len(t.Columns)+len(p.TagKeys)+len(p.FieldKeys)),
+	}
+	for i := range p.TagKeys {
+		if err := t.set(&row, p.TagKeys[i], true, BytesValue(p.TagValues[i])); err != nil {
+			return row, err
+		}
+	}
+	for i := range p.FieldKeys {
+		if err := t.set(&row, p.FieldKeys[i], false, p.FieldValues[i]); err != nil {
+			return row, err
+		}
+	}
+	return row, nil
+}
+
+func (t *wideTable) set(row *wideRow, name []byte, tag bool, v FieldValue) error {
+	i, ok := t.index[string(name)]
+	if !ok {
+		if t.Frozen {
+			return fmt.Errorf("column %s first seen after the columns were written, flush later (bulk_data_gen -flush-points)", name)
+		}
+		i = len(t.Columns)
+		t.index[string(name)] = i
+		t.Columns = append(t.Columns, wideColumn{Name: string(name), Tag: tag, Kind: v.Kind})
+	}
+	for len(row.Values) <= i {
+		row.Values = append(row.Values, FieldValue{})
+		row.Set = append(row.Set, false)
+	}
+
+	c := &t.Columns[i]
+	if c.Kind != v.Kind && !t.Frozen {
+		if isNumericKind(c.Kind) && isNumericKind(v.Kind) {
+			c.Kind = ValueKindFloat
+		} else {
+			c.Kind = ValueKindBytes
+		}
+	}
+	if v.Kind == ValueKindBytes {
+		v.Bytes = append([]byte(nil), v.Bytes...)
+	}
+	row.Values[i] = v
+	row.Set[i] = true
+	return nil
+}
+
+// Freeze fixes the columns and returns the pending rows.
+func (t *wideTable) Freeze() []wideRow {
+	t.Frozen = true
+	rows := t.Pending
+	t.Pending = nil
+	return rows
+}
+
+// convert returns v as a value of column c: integers become floats in Float64
+// columns, and any value becomes its text in string columns.
+func (c *wideColumn) convert(v FieldValue) (FieldValue, error) {
+	switch {
+	case v.Kind == c.Kind:
+		return v, nil
+	case c.Kind == ValueKindFloat && isNumericKind(v.Kind):
+		return FloatValue(float64(v.Int)), nil
+	case c.Kind == ValueKindBytes:
+		return BytesValue(v.AppendTo(nil, false)), nil
+	default:
+		return v, fmt.Errorf("column %s changed type after the columns were written, flush later (bulk_data_gen -flush-points)", c.Name)
+	}
+}
+
+func isNumericKind(kind ValueKind) bool {
+	return kind == ValueKindInt || kind == ValueKindFloat
+}
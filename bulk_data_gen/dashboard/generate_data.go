@@ -48,6 +48,19 @@ type DashboardSimulatorConfig struct {
 	HostOffset int64
 }
 
+func init() {
+	RegisterSimulator(UseCaseDashboard, func(c *SimulatorConfig) Simulator {
+		cfg := &DashboardSimulatorConfig{
+			Start: c.Start,
+			End:   c.End,
+
+			HostCount:  c.ScaleVar,
+			HostOffset: c.ScaleVarOffset,
+		}
+		return cfg.ToSimulator()
+	})
+}
+
 func (d *DashboardSimulatorConfig) ToSimulator() *DashboardSimulator {
 	hostInfos := make([]Host, d.HostCount)
 	for i := 0; i < len(hostInfos); i++ {
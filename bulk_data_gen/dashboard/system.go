@@ -60,9 +60,9 @@ func (m *SystemMeasurement) ToPoint(p *Point) bool {
 	p.SetMeasurementName(SystemByteString)
 	p.SetTimestamp(&m.timestamp)
 
-	p.AppendField(NCPUsFieldKey, m.ncpus)
+	p.AppendFieldInt(NCPUsFieldKey, int64(m.ncpus))
 	for i := range m.distributions {
-		p.AppendField(LoadFieldKeys[i], m.distributions[i].Get())
+		p.AppendFieldFloat(LoadFieldKeys[i], m.distributions[i].Get())
 	}
 	return true
 }
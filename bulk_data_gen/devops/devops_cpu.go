@@ -63,7 +63,7 @@ func (m *CPUMeasurement) ToPoint(p *Point) bool {
 	p.SetTimestamp(&m.timestamp)
 
 	for i := range m.distributions {
-		p.AppendField(CPUFieldKeys[i], m.distributions[i].Get())
+		p.AppendFieldFloat(CPUFieldKeys[i], m.distributions[i].Get())
 	}
 	return true
 }
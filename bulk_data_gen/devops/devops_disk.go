@@ -78,12 +78,12 @@ func (m *DiskMeasurement) ToPoint(p *Point) bool {
 	inodesFree := free / 4096
 	inodesUsed := used / 4096
 
-	p.AppendField(TotalByteString, total)
-	p.AppendField(FreeByteString, free)
-	p.AppendField(UsedByteString, used)
-	p.AppendField(UsedPercentByteString, usedPercent)
-	p.AppendField(INodesTotalByteString, inodesTotal)
-	p.AppendField(INodesFreeByteString, inodesFree)
-	p.AppendField(INodesUsedByteString, inodesUsed)
+	p.AppendFieldInt(TotalByteString, total)
+	p.AppendFieldInt(FreeByteString, free)
+	p.AppendFieldInt(UsedByteString, used)
+	p.AppendFieldInt(UsedPercentByteString, usedPercent)
+	p.AppendFieldInt(INodesTotalByteString, inodesTotal)
+	p.AppendFieldInt(INodesFreeByteString, inodesFree)
+	p.AppendFieldInt(INodesUsedByteString, inodesUsed)
 	return true
 }
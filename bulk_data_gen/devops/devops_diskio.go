@@ -59,7 +59,7 @@ func (m *DiskIOMeasurement) ToPoint(p *Point) bool {
 	p.AppendTag(SerialByteString, m.serial)
 
 	for i := range m.distributions {
-		p.AppendField(DiskIOFields[i].Label, int64(m.distributions[i].Get()))
+		p.AppendFieldInt(DiskIOFields[i].Label, int64(m.distributions[i].Get()))
 	}
 	return true
 }
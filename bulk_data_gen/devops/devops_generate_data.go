@@ -47,6 +47,19 @@ type DevopsSimulatorConfig struct {
 	HostOffset int64
 }
 
+func init() {
+	RegisterSimulator(UseCaseDevOps, func(c *SimulatorConfig) Simulator {
+		cfg := &DevopsSimulatorConfig{
+			Start: c.Start,
+			End:   c.End,
+
+			HostCount:  c.ScaleVar,
+			HostOffset: c.ScaleVarOffset,
+		}
+		return cfg.ToSimulator()
+	})
+}
+
 func (d *DevopsSimulatorConfig) ToSimulator() *DevopsSimulator {
 	hostInfos := make([]Host, d.HostCount)
 	for i := 0; i < len(hostInfos); i++ {
@@ -53,9 +53,9 @@ func (m *KernelMeasurement) ToPoint(p *Point) bool {
 	p.SetMeasurementName(KernelByteString)
 	p.SetTimestamp(&m.timestamp)
 
-	p.AppendField(BootTimeByteString, m.bootTime)
+	p.AppendFieldInt(BootTimeByteString, m.bootTime)
 	for i := range m.distributions {
-		p.AppendField(KernelFields[i].Label, int64(m.distributions[i].Get()))
+		p.AppendFieldInt(KernelFields[i].Label, int64(m.distributions[i].Get()))
 	}
 	return true
 }
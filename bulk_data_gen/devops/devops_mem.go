@@ -92,14 +92,14 @@ func (m *MemMeasurement) ToPoint(p *Point) bool {
 	cached := m.bytesCachedDist.Get()
 	buffered := m.bytesBufferedDist.Get()
 
-	p.AppendField(MemoryFieldKeys[0], total)
-	p.AppendField(MemoryFieldKeys[1], int(math.Floor(float64(total)-used)))
-	p.AppendField(MemoryFieldKeys[2], int(math.Floor(used)))
-	p.AppendField(MemoryFieldKeys[3], int(math.Floor(cached)))
-	p.AppendField(MemoryFieldKeys[4], int(math.Floor(buffered)))
-	p.AppendField(MemoryFieldKeys[5], int(math.Floor(used)))
-	p.AppendField(MemoryFieldKeys[6], 100.0*(used/float64(total)))
-	p.AppendField(MemoryFieldKeys[7], 100.0*(float64(total)-used)/float64(total))
-	p.AppendField(MemoryFieldKeys[8], 100.0*(float64(total)-buffered)/float64(total))
+	p.AppendFieldInt(MemoryFieldKeys[0], total)
+	p.AppendFieldInt(MemoryFieldKeys[1], int64(math.Floor(float64(total)-used)))
+	p.AppendFieldInt(MemoryFieldKeys[2], int64(math.Floor(used)))
+	p.AppendFieldInt(MemoryFieldKeys[3], int64(math.Floor(cached)))
+	p.AppendFieldInt(MemoryFieldKeys[4], int64(math.Floor(buffered)))
+	p.AppendFieldInt(MemoryFieldKeys[5], int64(math.Floor(used)))
+	p.AppendFieldFloat(MemoryFieldKeys[6], 100.0*(used/float64(total)))
+	p.AppendFieldFloat(MemoryFieldKeys[7], 100.0*(float64(total)-used)/float64(total))
+	p.AppendFieldFloat(MemoryFieldKeys[8], 100.0*(float64(total)-buffered)/float64(total))
 	return true
 }
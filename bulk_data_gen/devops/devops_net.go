@@ -63,7 +63,7 @@ func (m *NetMeasurement) ToPoint(p *Point) bool {
 	p.AppendTag(NetTags[0], m.interfaceName)
 
 	for i := range m.distributions {
-		p.AppendField(NetFields[i].Label, int64(m.distributions[i].Get()))
+		p.AppendFieldInt(NetFields[i].Label, int64(m.distributions[i].Get()))
 	}
 	return true
 }
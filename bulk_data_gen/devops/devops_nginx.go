@@ -66,7 +66,7 @@ func (m *NginxMeasurement) ToPoint(p *Point) bool {
 	p.AppendTag(NginxTags[1], m.serverName)
 
 	for i := range m.distributions {
-		p.AppendField(NginxFields[i].Label, int64(m.distributions[i].Get()))
+		p.AppendFieldInt(NginxFields[i].Label, int64(m.distributions[i].Get()))
 	}
 	return true
 }
@@ -57,7 +57,7 @@ func (m *PostgresqlMeasurement) ToPoint(p *Point) bool {
 	p.SetTimestamp(&m.timestamp)
 
 	for i := range m.distributions {
-		p.AppendField(PostgresqlFields[i].Label, int64(m.distributions[i].Get()))
+		p.AppendFieldInt(PostgresqlFields[i].Label, int64(m.distributions[i].Get()))
 	}
 	return true
 }
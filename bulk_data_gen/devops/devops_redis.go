@@ -102,9 +102,9 @@ func (m *RedisMeasurement) ToPoint(p *Point) bool {
 	p.AppendTag(RedisTags[0], m.port)
 	p.AppendTag(RedisTags[1], m.serverName)
 
-	p.AppendField(RedisUptime, int64(m.uptime.Seconds()))
+	p.AppendFieldInt(RedisUptime, int64(m.uptime.Seconds()))
 	for i := range m.distributions {
-		p.AppendField(RedisFields[i].Label, int64(m.distributions[i].Get()))
+		p.AppendFieldInt(RedisFields[i].Label, int64(m.distributions[i].Get()))
 	}
 	return true
 }
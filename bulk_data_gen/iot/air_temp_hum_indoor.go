@@ -52,7 +52,7 @@ func (m *AirConditionRoomMeasurement) ToPoint(p *Point) bool {
 	p.SetTimestamp(&m.timestamp)
 	p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
 	for i := range m.distributions {
-		p.AppendField(AirConditionRoomFieldKeys[i], m.distributions[i].Get())
+		p.AppendFieldFloat(AirConditionRoomFieldKeys[i], m.distributions[i].Get())
 	}
 	return true
 }
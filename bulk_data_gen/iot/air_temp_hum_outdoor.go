@@ -52,7 +52,7 @@ func (m *AirConditionOutdoorMeasurement) ToPoint(p *Point) bool {
 	p.SetTimestamp(&m.timestamp)
 	p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
 	for i := range m.distributions {
-		p.AppendField(AirConditionOutdoorFieldKeys[i], m.distributions[i].Get())
+		p.AppendFieldFloat(AirConditionOutdoorFieldKeys[i], m.distributions[i].Get())
 	}
 	return true
 }
@@ -99,8 +99,8 @@ func (m *CameraDetectionMeasurement) ToPoint(p *Point) bool {
 	p.SetMeasurementName(CameraDetectionByteString)
 	p.SetTimestamp(&m.timestamp)
 	p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
-	p.AppendField(CameraDetectionFieldKeys[0], m.object)
-	p.AppendField(CameraDetectionFieldKeys[1], m.kind)
-	p.AppendField(CameraDetectionFieldKeys[2], m.batteryDist.Get())
+	p.AppendFieldBytes(CameraDetectionFieldKeys[0], m.object)
+	p.AppendFieldBytes(CameraDetectionFieldKeys[1], m.kind)
+	p.AppendFieldFloat(CameraDetectionFieldKeys[2], m.batteryDist.Get())
 	return true
 }
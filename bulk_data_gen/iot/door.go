@@ -53,7 +53,7 @@ func (m *DoorMeasurement) ToPoint(p *Point) bool {
 	p.AppendTag(DoorTagKey, m.doorId)
 	p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
 	for i := range m.distributions {
-		p.AppendField(DoorFieldKeys[i], m.distributions[i].Get())
+		p.AppendFieldFloat(DoorFieldKeys[i], m.distributions[i].Get())
 	}
 	return true
 }
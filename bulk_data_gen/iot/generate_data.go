@@ -15,6 +15,19 @@ type IotSimulatorConfig struct {
 	SmartHomeOffset int64
 }
 
+func init() {
+	RegisterSimulator(UseCaseIot, func(c *SimulatorConfig) Simulator {
+		cfg := &IotSimulatorConfig{
+			Start: c.Start,
+			End:   c.End,
+
+			SmartHomeCount:  c.ScaleVar,
+			SmartHomeOffset: c.ScaleVarOffset,
+		}
+		return cfg.ToSimulator()
+	})
+}
+
 func (d *IotSimulatorConfig) ToSimulator() *IotSimulator {
 	homeInfos := make([]*SmartHome, d.SmartHomeCount)
 	var measNum int64
@@ -55,7 +55,7 @@ func (m *HomeConfigMeasurement) ToPoint(p *Point) bool {
 		p.SetMeasurementName(HomeConfigByteString)
 		p.SetTimestamp(&m.timestamp)
 		p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
-		p.AppendField(HomeConfigFieldKeys[0], m.config)
+		p.AppendFieldBytes(HomeConfigFieldKeys[0], m.config)
 	}
 	return m.updateValue
 }
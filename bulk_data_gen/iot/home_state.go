@@ -47,7 +47,7 @@ func (m *HomeStateMeasurement) ToPoint(p *Point) bool {
 	p.SetMeasurementName(HomeStateByteString)
 	p.SetTimestamp(&m.timestamp)
 	p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
-	p.AppendField(HomeStateFieldKeys[0], m.state)
-	p.AppendField(HomeStateFieldKeys[1], HomeStates[m.state])
+	p.AppendFieldInt(HomeStateFieldKeys[0], m.state)
+	p.AppendFieldBytes(HomeStateFieldKeys[1], HomeStates[m.state])
 	return true
 }
@@ -49,7 +49,7 @@ func (m *LightLevelRoomMeasurement) ToPoint(p *Point) bool {
 	p.SetTimestamp(&m.timestamp)
 	p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
 	for i := range m.distributions {
-		p.AppendField(LightLevelRoomFieldKeys[i], m.distributions[i].Get())
+		p.AppendFieldFloat(LightLevelRoomFieldKeys[i], m.distributions[i].Get())
 	}
 	return true
 }
@@ -53,7 +53,7 @@ func (m *RadiatorValveRoomMeasurement) ToPoint(p *Point) bool {
 	p.AppendTag(RadiatorTagKey, m.randiatorId)
 	p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
 	for i := range m.distributions {
-		p.AppendField(RadiatorValveRoomFieldKeys[i], m.distributions[i].Get())
+		p.AppendFieldFloat(RadiatorValveRoomFieldKeys[i], m.distributions[i].Get())
 	}
 	return true
 }
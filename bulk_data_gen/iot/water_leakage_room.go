@@ -52,7 +52,7 @@ func (m *WaterLeakageRoomMeasurement) ToPoint(p *Point) bool {
 	p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
 	p.AppendTag(RoomTagKey, m.roomId)
 	for i := range m.distributions {
-		p.AppendField(WaterLeakageRoomFieldKeys[i], m.distributions[i].Get())
+		p.AppendFieldFloat(WaterLeakageRoomFieldKeys[i], m.distributions[i].Get())
 	}
 	return true
 }
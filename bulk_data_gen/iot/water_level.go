@@ -49,7 +49,7 @@ func (m *WaterLevelMeasurement) ToPoint(p *Point) bool {
 	p.SetTimestamp(&m.timestamp)
 	p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
 	for i := range m.distributions {
-		p.AppendField(WaterLevelFieldKeys[i], m.distributions[i].Get())
+		p.AppendFieldFloat(WaterLevelFieldKeys[i], m.distributions[i].Get())
 	}
 	return true
 }
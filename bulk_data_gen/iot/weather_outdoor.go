@@ -58,7 +58,7 @@ func (m *WeatherOutdoorMeasurement) ToPoint(p *Point) bool {
 	p.SetTimestamp(&m.timestamp)
 	p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
 	for i := range m.distributions {
-		p.AppendField(WeatherOutdoorFieldKeys[i], m.distributions[i].Get())
+		p.AppendFieldFloat(WeatherOutdoorFieldKeys[i], m.distributions[i].Get())
 	}
 	return true
 }
@@ -53,7 +53,7 @@ func (m *WindowMeasurement) ToPoint(p *Point) bool {
 	p.AppendTag(SensorHomeTagKeys[0], m.sensorId)
 	p.AppendTag(WindowTagKey, m.windowId)
 	for i := range m.distributions {
-		p.AppendField(WindowFieldKeys[i], m.distributions[i].Get())
+		p.AppendFieldFloat(WindowFieldKeys[i], m.distributions[i].Get())
 	}
 	return true
 }
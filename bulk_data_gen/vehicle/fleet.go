@@ -0,0 +1,185 @@
+package vehicle
+
+import (
+	"fmt"
+	. "github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	"math/rand"
+	"strconv"
+)
+
+// Fleet tags are static per vehicle and let queries slice the fleet by
+// manufacturer, model, location, fuel type or model year.
+var (
+	FleetTagKeys = [][]byte{
+		[]byte("manufacturer"),
+		[]byte("model"),
+		[]byte("province"),
+		[]byte("city"),
+		[]byte("fuel_type"),
+		[]byte("model_year"),
+	}
+)
+
+type Manufacturer struct {
+	Name   []byte
+	WMI    []byte
+	VDS    []byte
+	Models [][]byte
+}
+
+type City struct {
+	Province []byte
+	Name     []byte
+	Center   GeoPoint
+}
+
+var (
+	// Choices of manufacturers, with their world manufacturer identifiers
+	// and models.
+	Manufacturers = []Manufacturer{
+		{[]byte("SAIC-Volkswagen"), []byte("LSV"), []byte("NV218"), [][]byte{[]byte("Lavida"), []byte("Passat"), []byte("Tiguan"), []byte("Santana")}},
+		{[]byte("FAW-Volkswagen"), []byte("LFV"), []byte("2A21K"), [][]byte{[]byte("Jetta"), []byte("Sagitar"), []byte("Magotan"), []byte("Bora")}},
+		{[]byte("SAIC-GM"), []byte("LSG"), []byte("WB54E"), [][]byte{[]byte("Excelle"), []byte("Regal"), []byte("LaCrosse"), []byte("Envision")}},
+		{[]byte("Dongfeng-Nissan"), []byte("LGB"), []byte("H12E2"), [][]byte{[]byte("Sylphy"), []byte("Teana"), []byte("Qashqai"), []byte("X-Trail")}},
+		{[]byte("GAC-Honda"), []byte("LHG"), []byte("CR285"), [][]byte{[]byte("Accord"), []byte("Fit"), []byte("Crider"), []byte("Vezel")}},
+		{[]byte("BYD"), []byte("LGX"), []byte("C16DG"), [][]byte{[]byte("Qin"), []byte("Tang"), []byte("Song"), []byte("Han")}},
+		{[]byte("Geely"), []byte("L6T"), []byte("7824Z"), [][]byte{[]byte("Emgrand"), []byte("Boyue"), []byte("Vision"), []byte("Binrui")}},
+		{[]byte("Changan"), []byte("LS5"), []byte("A3DE2"), [][]byte{[]byte("Eado"), []byte("CS75"), []byte("CS55"), []byte("Raeton")}},
+		{[]byte("Great-Wall"), []byte("LGW"), []byte("EF4EA"), [][]byte{[]byte("Haval-H6"), []byte("Haval-F7"), []byte("Wingle"), []byte("Poer")}},
+		{[]byte("BAIC"), []byte("LNB"), []byte("MDE1Z"), [][]byte{[]byte("EU5"), []byte("EX360"), []byte("Senova-D50"), []byte("Senova-X55")}},
+		{[]byte("SAIC-Motor"), []byte("LSJ"), []byte("A24U6"), [][]byte{[]byte("RX5"), []byte("i5"), []byte("Ei5"), []byte("Marvel-X")}},
+		{[]byte("BMW-Brilliance"), []byte("LBV"), []byte("8Y109"), [][]byte{[]byte("3-Series"), []byte("5-Series"), []byte("X1"), []byte("X3")}},
+	}
+
+	// Choices of cities, with their provinces and city centers.
+	Cities = []City{
+		{[]byte("Beijing"), []byte("Beijing"), GeoPoint{Lat: 39.9042, Lon: 116.4074}},
+		{[]byte("Shanghai"), []byte("Shanghai"), GeoPoint{Lat: 31.2304, Lon: 121.4737}},
+		{[]byte("Tianjin"), []byte("Tianjin"), GeoPoint{Lat: 39.3434, Lon: 117.3616}},
+		{[]byte("Chongqing"), []byte("Chongqing"), GeoPoint{Lat: 29.5630, Lon: 106.5516}},
+		{[]byte("Guangdong"), []byte("Guangzhou"), GeoPoint{Lat: 23.1291, Lon: 113.2644}},
+		{[]byte("Guangdong"), []byte("Shenzhen"), GeoPoint{Lat: 22.5431, Lon: 114.0579}},
+		{[]byte("Guangdong"), []byte("Dongguan"), GeoPoint{Lat: 23.0207, Lon: 113.7518}},
+		{[]byte("Zhejiang"), []byte("Hangzhou"), GeoPoint{Lat: 30.2741, Lon: 120.1551}},
+		{[]byte("Zhejiang"), []byte("Ningbo"), GeoPoint{Lat: 29.8683, Lon: 121.5440}},
+		{[]byte("Jiangsu"), []byte("Nanjing"), GeoPoint{Lat: 32.0603, Lon: 118.7969}},
+		{[]byte("Jiangsu"), []byte("Suzhou"), GeoPoint{Lat: 31.2989, Lon: 120.5853}},
+		{[]byte("Jiangsu"), []byte("Wuxi"), GeoPoint{Lat: 31.4912, Lon: 120.3119}},
+		{[]byte("Shandong"), []byte("Jinan"), GeoPoint{Lat: 36.6512, Lon: 117.1201}},
+		{[]byte("Shandong"), []byte("Qingdao"), GeoPoint{Lat: 36.0671, Lon: 120.3826}},
+		{[]byte("Sichuan"), []byte("Chengdu"), GeoPoint{Lat: 30.5728, Lon: 104.0668}},
+		{[]byte("Hubei"), []byte("Wuhan"), GeoPoint{Lat: 30.5928, Lon: 114.3055}},
+		{[]byte("Hunan"), []byte("Changsha"), GeoPoint{Lat: 28.2282, Lon: 112.9388}},
+		{[]byte("Henan"), []byte("Zhengzhou"), GeoPoint{Lat: 34.7466, Lon: 113.6254}},
+		{[]byte("Shaanxi"), []byte("Xian"), GeoPoint{Lat: 34.3416, Lon: 108.9398}},
+		{[]byte("Fujian"), []byte("Xiamen"), GeoPoint{Lat: 24.4798, Lon: 118.0894}},
+		{[]byte("Fujian"), []byte("Fuzhou"), GeoPoint{Lat: 26.0745, Lon: 119.2965}},
+		{[]byte("Liaoning"), []byte("Shenyang"), GeoPoint{Lat: 41.8057, Lon: 123.4315}},
+		{[]byte("Liaoning"), []byte("Dalian"), GeoPoint{Lat: 38.9140, Lon: 121.6147}},
+		{[]byte("Anhui"), []byte("Hefei"), GeoPoint{Lat: 31.8206, Lon: 117.2272}},
+		{[]byte("Hebei"), []byte("Shijiazhuang"), GeoPoint{Lat: 38.0428, Lon: 114.5149}},
+		{[]byte("Heilongjiang"), []byte("Harbin"), GeoPoint{Lat: 45.8038, Lon: 126.5350}},
+		{[]byte("Jilin"), []byte("Changchun"), GeoPoint{Lat: 43.8171, Lon: 125.3235}},
+		{[]byte("Yunnan"), []byte("Kunming"), GeoPoint{Lat: 24.8801, Lon: 102.8329}},
+		{[]byte("Guangxi"), []byte("Nanning"), GeoPoint{Lat: 22.8170, Lon: 108.3665}},
+		{[]byte("Jiangxi"), []byte("Nanchang"), GeoPoint{Lat: 28.6820, Lon: 115.8579}},
+		{[]byte("Shanxi"), []byte("Taiyuan"), GeoPoint{Lat: 37.8706, Lon: 112.5489}},
+		{[]byte("Guizhou"), []byte("Guiyang"), GeoPoint{Lat: 26.6470, Lon: 106.6302}},
+	}
+
+	FuelTypeChoices = [][]byte{
+		[]byte("gasoline"),
+		[]byte("diesel"),
+		[]byte("bev"),
+		[]byte("phev"),
+		[]byte("hev"),
+	}
+
+	ModelYearMin = 2010
+	ModelYearMax = 2018
+)
+
+// FleetConfig sets the cardinality of the fleet tags. Each count selects the
+// first N entries of the corresponding choice table.
+type FleetConfig struct {
+	Manufacturers int
+	Models        int // per manufacturer
+	Cities        int
+}
+
+var DefaultFleetConfig = FleetConfig{
+	Manufacturers: len(Manufacturers),
+	Models:        4,
+	Cities:        len(Cities),
+}
+
+func (c *FleetConfig) Validate() error {
+	if c.Manufacturers < 1 || c.Manufacturers > len(Manufacturers) {
+		return fmt.Errorf("fleet manufacturers must be in [1, %d]", len(Manufacturers))
+	}
+	for _, m := range Manufacturers[:c.Manufacturers] {
+		if c.Models < 1 || c.Models > len(m.Models) {
+			return fmt.Errorf("fleet models must be in [1, %d]", len(m.Models))
+		}
+	}
+	if c.Cities < 1 || c.Cities > len(Cities) {
+		return fmt.Errorf("fleet cities must be in [1, %d]", len(Cities))
+	}
+	return nil
+}
+
+// RandManufacturer picks one of the configured manufacturers.
+func (c *FleetConfig) RandManufacturer() *Manufacturer {
+	return &Manufacturers[rand.Intn(c.Manufacturers)]
+}
+
+// RandModel picks one of the configured models of a manufacturer.
+func (c *FleetConfig) RandModel(m *Manufacturer) []byte {
+	return m.Models[rand.Intn(c.Models)]
+}
+
+// RandCity picks one of the configured cities.
+func (c *FleetConfig) RandCity() *City {
+	return &Cities[rand.Intn(c.Cities)]
+}
+
+// RandModelYear picks a model year in [ModelYearMin, ModelYearMax].
+func RandModelYear() int {
+	return ModelYearMin + rand.Intn(ModelYearMax-ModelYearMin+1)
+}
+
+// FleetTags holds the static fleet tag values of one vehicle, in the order
+// of FleetTagKeys.
+type FleetTags struct {
+	Manufacturer *Manufacturer
+	Model        []byte
+	City         *City
+	FuelType     []byte
+	ModelYear    int
+
+	values [][]byte
+}
+
+func NewFleetTags(c *FleetConfig) *FleetTags {
+	t := &FleetTags{
+		Manufacturer: c.RandManufacturer(),
+		City:         c.RandCity(),
+		FuelType:     FuelTypeChoices[rand.Intn(len(FuelTypeChoices))],
+		ModelYear:    RandModelYear(),
+	}
+	t.Model = c.RandModel(t.Manufacturer)
+	t.values = [][]byte{
+		t.Manufacturer.Name,
+		t.Model,
+		t.City.Province,
+		t.City.Name,
+		t.FuelType,
+		[]byte(strconv.Itoa(t.ModelYear)),
+	}
+	return t
+}
+
+// Values returns the tag values in the order of FleetTagKeys.
+func (t *FleetTags) Values() [][]byte {
+	return t.values
+}
@@ -1,7 +1,6 @@
 package vehicle
 
 import (
-	"fmt"
 	. "github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
 	"time"
 )
@@ -15,6 +14,29 @@ type VehicleSimulatorConfig struct {
 	VehicleOffset int64
 
 	StartVinIndex int
+
+	// Fleet enables the static fleet tags when non-nil.
+	Fleet *FleetConfig
+
+	// GPS adds latitude and longitude fields along a synthetic road graph.
+	GPS bool
+}
+
+// Options holds the vehicle specific settings of the registered simulator,
+// which takes Start, End and the vehicle counts from its SimulatorConfig.
+var Options = VehicleSimulatorConfig{
+	StartVinIndex: 100000,
+}
+
+func init() {
+	RegisterSimulator(UseCaseVehicle, func(c *SimulatorConfig) Simulator {
+		cfg := Options
+		cfg.Start = c.Start
+		cfg.End = c.End
+		cfg.VehicleCount = c.ScaleVar
+		cfg.VehicleOffset = c.ScaleVarOffset
+		return cfg.ToSimulator()
+	})
 }
 
 func (d *VehicleSimulatorConfig) ToSimulator() *VehicleSimulator {
@@ -23,7 +45,7 @@ func (d *VehicleSimulatorConfig) ToSimulator() *VehicleSimulator {
 
 	for i := 0; i < len(vehicleInfos); i++ {
 		//vehicleInfos[i] = NewSmartHome(i, int(d.SmartHomeOffset), d.Start)
-		vehicleInfos[i] = NewVehicle(i, int(d.VehicleOffset), d.Start)
+		vehicleInfos[i] = NewVehicle(i, int(d.VehicleOffset), d.Start, d.StartVinIndex+i, d.Fleet, d.GPS)
 		measNum += int64(vehicleInfos[i].NumMeasurements())
 	}
 
@@ -40,8 +62,6 @@ func (d *VehicleSimulatorConfig) ToSimulator() *VehicleSimulator {
 		timestampNow:   d.Start,
 		timestampStart: d.Start,
 		timestampEnd:   d.End,
-
-		startVinIndex: d.StartVinIndex,
 	}
 
 	return dg
@@ -62,8 +82,6 @@ type VehicleSimulator struct {
 	timestampNow   time.Time
 	timestampStart time.Time
 	timestampEnd   time.Time
-
-	startVinIndex int
 }
 
 func (g *VehicleSimulator) SeenPoints() int64 {
@@ -100,21 +118,19 @@ func (v *VehicleSimulator) Next(p *Point) {
 
 	vehicle := &v.vehicles[v.currentVehicleIndex]
 
-	// Populate host-specific tags: for example, LSVNV2182E2100001
-	vin := fmt.Sprintf("LSVNV2182E2%d", v.startVinIndex + v.currentVehicleIndex)
-	p.AppendTag([]byte("VIN"), []byte(vin))
-	//p.AppendTag(MachineTagKeys[1], host.Region)
-	//p.AppendTag(MachineTagKeys[2], host.Datacenter)
-	//p.AppendTag(MachineTagKeys[3], host.Rack)
-	//p.AppendTag(MachineTagKeys[4], host.OS)
-	//p.AppendTag(MachineTagKeys[5], host.Arch)
-	//p.AppendTag(MachineTagKeys[6], host.Team)
-	//p.AppendTag(MachineTagKeys[7], host.Service)
-	//p.AppendTag(MachineTagKeys[8], host.ServiceVersion)
-	//p.AppendTag(MachineTagKeys[9], host.ServiceEnvironment)
+	// Populate vehicle-specific tags: for example, LSVNV2187E2100001
+	p.AppendTag(VinTagKey, vehicle.Vin)
+	if vehicle.Fleet != nil {
+		for i, value := range vehicle.Fleet.Values() {
+			p.AppendTag(FleetTagKeys[i], value)
+		}
+	}
 
 	// Populate measurement-specific tags and fields:
 	vehicle.SimulatedMeasurements[v.simulatedMeasurementIndex].ToPoint(p)
+	if vehicle.Trajectory != nil {
+		p.SetLocation(&vehicle.Trajectory.Location)
+	}
 
 	v.madePoints++
 	v.currentVehicleIndex++
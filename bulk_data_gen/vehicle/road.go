@@ -0,0 +1,158 @@
+package vehicle
+
+import (
+	. "github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Road graphs are jittered grids of intersections centered on a city, with a
+// few road segments removed so that routes are not perfectly regular.
+const (
+	RoadGridSize       = 16
+	RoadGridSpacing    = 1000.0 // meters between neighbouring intersections
+	roadGridJitter     = 0.2    // fraction of RoadGridSpacing
+	roadRemovedEdgeP   = 0.1
+	earthRadius        = 6371000.0 // meters
+	minVehicleSpeed    = 0.0       // m/s
+	maxVehicleSpeed    = 33.0      // m/s, about 120 km/h
+	vehicleSpeedStdDev = 1.5       // m/s change per tick
+)
+
+// RoadGraphRadius is half the side of a road graph, in meters.
+const RoadGraphRadius = RoadGridSpacing * (RoadGridSize - 1) / 2
+
+type RoadNode struct {
+	GeoPoint
+	Edges []int // indexes of neighbouring nodes
+}
+
+type RoadGraph struct {
+	Nodes []RoadNode
+}
+
+// roadGraphs caches the graph of each city, so vehicles of the same city
+// share one road network.
+var roadGraphs = map[*City]*RoadGraph{}
+
+// RoadGraphOf returns the road graph of a city. Graphs are derived from the
+// city name only, so they are the same from one run to the next.
+func RoadGraphOf(c *City) *RoadGraph {
+	if g, ok := roadGraphs[c]; ok {
+		return g
+	}
+	h := fnv.New64a()
+	h.Write(c.Name)
+	g := NewRoadGraph(c.Center, rand.New(rand.NewSource(int64(h.Sum64()))))
+	roadGraphs[c] = g
+	return g
+}
+
+func NewRoadGraph(center GeoPoint, r *rand.Rand) *RoadGraph {
+	g := &RoadGraph{Nodes: make([]RoadNode, RoadGridSize*RoadGridSize)}
+	for y := 0; y < RoadGridSize; y++ {
+		for x := 0; x < RoadGridSize; x++ {
+			north := (float64(y) - (RoadGridSize-1)/2.0 + (r.Float64()*2-1)*roadGridJitter) * RoadGridSpacing
+			east := (float64(x) - (RoadGridSize-1)/2.0 + (r.Float64()*2-1)*roadGridJitter) * RoadGridSpacing
+			g.Nodes[y*RoadGridSize+x].GeoPoint = Offset(center, north, east)
+		}
+	}
+
+	connect := func(a, b int) {
+		g.Nodes[a].Edges = append(g.Nodes[a].Edges, b)
+		g.Nodes[b].Edges = append(g.Nodes[b].Edges, a)
+	}
+	for y := 0; y < RoadGridSize; y++ {
+		for x := 0; x < RoadGridSize; x++ {
+			i := y*RoadGridSize + x
+			if x+1 < RoadGridSize && r.Float64() >= roadRemovedEdgeP {
+				connect(i, i+1)
+			}
+			if y+1 < RoadGridSize && r.Float64() >= roadRemovedEdgeP {
+				connect(i, i+RoadGridSize)
+			}
+		}
+	}
+	return g
+}
+
+// Offset moves a position by the given distances in meters, using an
+// equirectangular approximation that is accurate enough at city scale.
+func Offset(p GeoPoint, north, east float64) GeoPoint {
+	lat := p.Lat + north/earthRadius*180/math.Pi
+	lon := p.Lon + east/(earthRadius*math.Cos(p.Lat*math.Pi/180))*180/math.Pi
+	return GeoPoint{Lat: lat, Lon: lon}
+}
+
+// Distance returns the approximate distance in meters between two nearby
+// positions.
+func Distance(a, b GeoPoint) float64 {
+	north := (b.Lat - a.Lat) * math.Pi / 180 * earthRadius
+	east := (b.Lon - a.Lon) * math.Pi / 180 * earthRadius * math.Cos((a.Lat+b.Lat)/2*math.Pi/180)
+	return math.Hypot(north, east)
+}
+
+// Trajectory drives a vehicle along a road graph at a randomly varying speed.
+type Trajectory struct {
+	Location GeoPoint
+
+	graph    *RoadGraph
+	from, to int
+	progress float64 // meters travelled from 'from' towards 'to'
+	speed    Distribution
+}
+
+func NewTrajectory(g *RoadGraph) *Trajectory {
+	t := &Trajectory{
+		graph: g,
+		speed: CWD(ND(0, vehicleSpeedStdDev), minVehicleSpeed, maxVehicleSpeed, rand.Float64()*maxVehicleSpeed),
+	}
+	// start from a connected intersection
+	for {
+		t.from = rand.Intn(len(g.Nodes))
+		if len(g.Nodes[t.from].Edges) > 0 {
+			break
+		}
+	}
+	t.to = t.nextNode(-1)
+	t.Location = g.Nodes[t.from].GeoPoint
+	return t
+}
+
+// nextNode picks the road to take at intersection t.from, avoiding a U-turn
+// back to prev unless it is a dead end.
+func (t *Trajectory) nextNode(prev int) int {
+	edges := t.graph.Nodes[t.from].Edges
+	if len(edges) == 1 {
+		return edges[0]
+	}
+	for {
+		n := edges[rand.Intn(len(edges))]
+		if n != prev {
+			return n
+		}
+	}
+}
+
+// Tick advances the vehicle along the road graph.
+func (t *Trajectory) Tick(d time.Duration) {
+	t.speed.Advance()
+	t.progress += t.speed.Get() * d.Seconds()
+
+	from, to := &t.graph.Nodes[t.from], &t.graph.Nodes[t.to]
+	length := Distance(from.GeoPoint, to.GeoPoint)
+	for t.progress >= length {
+		t.progress -= length
+		prev := t.from
+		t.from = t.to
+		t.to = t.nextNode(prev)
+		from, to = &t.graph.Nodes[t.from], &t.graph.Nodes[t.to]
+		length = Distance(from.GeoPoint, to.GeoPoint)
+	}
+
+	f := t.progress / length
+	t.Location.Lat = from.Lat + (to.Lat-from.Lat)*f
+	t.Location.Lon = from.Lon + (to.Lon-from.Lon)*f
+}
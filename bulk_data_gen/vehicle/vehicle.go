@@ -3,6 +3,7 @@ package vehicle
 import (
 	"fmt"
 	. "github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	"math/rand"
 	"time"
 )
 
@@ -32,6 +33,10 @@ type Vehicle struct {
 
 	// These are all assigned once, at Host creation:
 	Name         []byte
+	Vin          []byte
+	Fleet        *FleetTags // nil unless fleet tags are generated
+
+	Trajectory *Trajectory // nil unless GPS positions are generated
 }
 
 func NewHostMeasurements(start time.Time) []SimulatedMeasurement {
@@ -45,7 +50,7 @@ func NewHostMeasurements(start time.Time) []SimulatedMeasurement {
 	return sm
 }
 
-func NewVehicle(i int, offset int, start time.Time) Vehicle {
+func NewVehicle(i int, offset int, start time.Time, vinSerial int, fleet *FleetConfig, gps bool) Vehicle {
 	sm := NewHostMeasurements(start)
 
 	h := Vehicle{
@@ -54,6 +59,27 @@ func NewVehicle(i int, offset int, start time.Time) Vehicle {
 		SimulatedMeasurements: sm,
 	}
 
+	var err error
+	if fleet != nil {
+		h.Fleet = NewFleetTags(fleet)
+		m := h.Fleet.Manufacturer
+		h.Vin, err = NewVin(m.WMI, m.VDS, h.Fleet.ModelYear, DefaultVinPlant, vinSerial)
+	} else {
+		h.Vin, err = DefaultVin(vinSerial)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("logic error: %s", err))
+	}
+
+	if gps {
+		// vehicles drive in their home city, or anywhere without fleet tags
+		city := &Cities[rand.Intn(len(Cities))]
+		if h.Fleet != nil {
+			city = h.Fleet.City
+		}
+		h.Trajectory = NewTrajectory(RoadGraphOf(city))
+	}
+
 	return h
 }
 
@@ -62,6 +88,9 @@ func (v *Vehicle) TickAll(d time.Duration) {
 	for i := range v.SimulatedMeasurements {
 		v.SimulatedMeasurements[i].Tick(d)
 	}
+	if v.Trajectory != nil {
+		v.Trajectory.Tick(d)
+	}
 }
 
 func (v *Vehicle) NumMeasurements() int {
@@ -128,7 +128,7 @@ func (m *EntityMeasurement) ToPoint(p *Point) bool {
 	//}
 	for i := range m.values {
 		index := rand.Intn(100)
-		p.AppendField(EntityFieldKeys[i], randomNumbers[i][index])
+		p.AppendFieldInt(EntityFieldKeys[i], randomNumbers[i][index])
 	}
 	return true
 }
@@ -0,0 +1,128 @@
+package vehicle
+
+import (
+	"fmt"
+)
+
+// A VIN (ISO 3779) is laid out as:
+//
+//	1-3   world manufacturer identifier (WMI)
+//	4-8   vehicle descriptor section (VDS)
+//	9     check digit
+//	10    model year
+//	11    plant code
+//	12-17 serial number
+//
+// The check digit is mandatory for VINs issued in China (GB 16735) and North
+// America, and is computed from the other 16 characters.
+const (
+	VinLength    = 17
+	VinSerialMax = 999999
+
+	vinCheckDigitPos = 8
+)
+
+var (
+	// Defaults used when no fleet tags are generated, matching the
+	// historical LSVNV2182E2xxxxxx VINs.
+	DefaultVinWMI       = []byte("LSV")
+	DefaultVinVDS       = []byte("NV218")
+	DefaultVinModelYear = 2014
+	DefaultVinPlant     = byte('2')
+
+	VinTagKey = []byte("VIN")
+)
+
+// vinYearCodes cycles every 30 years, starting with 'A' for 1980.
+const vinYearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+var vinWeights = [VinLength]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// vinTransliteration maps each allowed VIN character to its numeric value.
+// I, O and Q are not allowed and map to -1.
+var vinTransliteration = func() [256]int {
+	var t [256]int
+	for i := range t {
+		t[i] = -1
+	}
+	for c := '0'; c <= '9'; c++ {
+		t[c] = int(c - '0')
+	}
+	for i, c := range "ABCDEFGH" {
+		t[c] = i + 1
+	}
+	for i, c := range "JKLMN" {
+		t[c] = i + 1
+	}
+	t['P'] = 7
+	t['R'] = 9
+	for i, c := range "STUVWXYZ" {
+		t[c] = i + 2
+	}
+	return t
+}()
+
+// VinModelYearCode returns the position 10 character for a model year.
+func VinModelYearCode(year int) byte {
+	return vinYearCodes[((year-1980)%30+30)%30]
+}
+
+// VinCheckDigit computes the position 9 check digit of a 17 character VIN.
+// The character currently in position 9 is ignored.
+func VinCheckDigit(vin []byte) (byte, error) {
+	if len(vin) != VinLength {
+		return 0, fmt.Errorf("VIN %q has length %d, want %d", vin, len(vin), VinLength)
+	}
+	sum := 0
+	for i, c := range vin {
+		if i == vinCheckDigitPos {
+			continue
+		}
+		v := vinTransliteration[c]
+		if v < 0 {
+			return 0, fmt.Errorf("VIN %q has invalid character %q at position %d", vin, c, i+1)
+		}
+		sum += v * vinWeights[i]
+	}
+	r := sum % 11
+	if r == 10 {
+		return 'X', nil
+	}
+	return byte('0' + r), nil
+}
+
+// ValidVin reports whether vin is well-formed and carries the right check digit.
+func ValidVin(vin []byte) bool {
+	c, err := VinCheckDigit(vin)
+	return err == nil && vin[vinCheckDigitPos] == c
+}
+
+// NewVin assembles a VIN from its sections and fills in the check digit.
+func NewVin(wmi, vds []byte, modelYear int, plant byte, serial int) ([]byte, error) {
+	if len(wmi) != 3 || len(vds) != 5 {
+		return nil, fmt.Errorf("bad VIN sections WMI %q, VDS %q", wmi, vds)
+	}
+	if serial < 0 || serial > VinSerialMax {
+		return nil, fmt.Errorf("VIN serial %d out of range [0, %d]", serial, VinSerialMax)
+	}
+
+	vin := make([]byte, 0, VinLength)
+	vin = append(vin, wmi...)
+	vin = append(vin, vds...)
+	vin = append(vin, '0')
+	vin = append(vin, VinModelYearCode(modelYear), plant)
+	vin = append(vin, fmt.Sprintf("%06d", serial)...)
+
+	c, err := VinCheckDigit(vin)
+	if err != nil {
+		return nil, err
+	}
+	vin[vinCheckDigitPos] = c
+	return vin, nil
+}
+
+// DefaultVin returns the VIN of the vehicle with the given serial when no
+// fleet tags are generated.
+func DefaultVin(serial int) ([]byte, error) {
+	return NewVin(DefaultVinWMI, DefaultVinVDS, DefaultVinModelYear, DefaultVinPlant, serial)
+}
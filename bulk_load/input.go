@@ -0,0 +1,125 @@
+package bulk_load
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Input names the files a load reads, stdin by default. Each file is read
+// by its own goroutine, so that parsing the input keeps up with many
+// workers, and the dataset size markers of the files add up: the data of
+// bulk_data_gen may be generated in shards, each with its marker, or split
+// afterwards, the last file having the marker.
+type Input struct {
+	// Files are comma-separated paths or glob patterns, - is stdin.
+	Files string
+}
+
+// AddFlags registers the flags of the Input in fs.
+func (in *Input) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&in.Files, "input", "", "Comma-separated files or glob patterns to read instead of stdin, each by its own goroutine (- is stdin). Gzip and zstd compressed files are decompressed.")
+}
+
+// Paths returns the files of the Input, - for stdin.
+func (in *Input) Paths() ([]string, error) {
+	if in.Files == "" {
+		return []string{"-"}, nil
+	}
+	var paths []string
+	for _, pattern := range strings.Split(in.Files, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		if pattern == "-" {
+			paths = append(paths, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad input pattern %s: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no input file matches %s", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("missing input files")
+	}
+	return paths, nil
+}
+
+// OpenInput opens the file at path, - for stdin, decompressed if it is gzip
+// or zstd compressed.
+func OpenInput(path string) (io.ReadCloser, error) {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		f, err = os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r, err := Decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &inputFile{Reader: r, closers: []io.Closer{r, f}}, nil
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Decompress returns the data of r, decompressed if r starts like gzip or
+// zstd compressed data.
+func Decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReaderSize(r, 4*1024*1024)
+	// a short input is not compressed, and its error comes with its reads:
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return ioutil.NopCloser(br), nil
+	}
+}
+
+// inputFile reads a decompressed file, and closes the decompressor and the
+// file.
+type inputFile struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (f *inputFile) Close() error {
+	var err error
+	for _, c := range f.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,600 @@
+package bulk_load
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/rpc"
+	"os"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/caict-benchmark/BDC-TS/util/report"
+	"github.com/pkg/profile"
+)
+
+// ErrBackoff is returned by a Writer when the database asks to slow down. The
+// batch is written again after backing off, see RetryPolicy.
+var ErrBackoff = errors.New("backpressure is needed")
+
+// Batch is the body of a write request, made of Items items of the input
+// holding Values values.
+type Batch struct {
+	Buffer *bytes.Buffer
+	Items  int
+	Values int
+}
+
+// Writer writes batches to a database. Each worker has its own Writer, so a
+// Writer need not be safe for concurrent use.
+type Writer interface {
+	// WriteBatch writes b. It returns ErrBackoff when the database asks to
+	// slow down, or a RetryableError when the response is retryable, and b
+	// is then written again as the RetryPolicy allows.
+	WriteBatch(b *Batch) error
+	Close() error
+}
+
+// Driver adapts the Loader to a database: how the input is read, how the
+// items are put together into batches, and how batches are written.
+type Driver interface {
+	// NewDecoder returns a Decoder reading the items of the input r.
+	NewDecoder(r io.Reader) Decoder
+	// NewEncoder returns an Encoder building batches out of items.
+	NewEncoder() Encoder
+	// NewWriter returns the Writer of worker, writing to the daemon at url.
+	NewWriter(worker int, url string) Writer
+}
+
+// Config holds the options common to all the loaders, see AddFlags.
+type Config struct {
+	Urls                  string
+	Workers               int
+	BatchSize             int
+	ItemLimit             int64
+	Retry                 RetryPolicy
+	Rate                  RateLimit
+	Input                 Input
+	Verify                Verify
+	Ramp                  Ramp
+	ProgressInterval      time.Duration
+	PrintInterval         uint64
+	MovingAverageInterval time.Duration
+	ClientIndex           int
+	NotificationPort      int
+	DoLoad                bool
+	MemProfile            bool
+	CPUProfile            string
+	TelemetryStderr       bool
+	TelemetryBatchSize    uint64
+	ReportDatabase        string
+	ReportHost            string
+	ReportUser            string
+	ReportPassword        string
+	ReportTagsCSV         string
+	ReportTelemetry       bool
+	ResultsFile           string
+}
+
+// Loader runs a bulk load: it reads the input, batches the items, and has
+// workers write the batches, while keeping statistics of the load.
+//
+// A loader sets the defaults of Urls and BatchSize, and Verifiable if its
+// Driver is a Verifier, then calls AddFlags and Init around flag.Parse, and
+// Run and Finish in main.
+type Loader struct {
+	Config
+
+	// DBType names the database in messages and reports.
+	DBType string
+
+	// ValuesPerItem is the expected number of values of an item, used to
+	// size batches under an ingest rate limit in values.
+	ValuesPerItem float64
+
+	// Verifiable tells that the Driver is a Verifier, the --verify flags are
+	// only registered then.
+	Verifiable bool
+
+	// Summarize, if set, completes the summary of the load written to the
+	// results file with the database specific outcome.
+	Summarize func(s *report.Summary)
+
+	DaemonUrls     []string
+	ReportTags     [][2]string
+	ReportHostname string
+
+	bufPool           sync.Pool
+	batchChan         chan Batch
+	workersGroup      sync.WaitGroup
+	statPool          sync.Pool
+	statChan          chan *Stat
+	statGroup         sync.WaitGroup
+	statMapping       statsMap
+	movingAverageStat *TimedStatGroup
+	latencies         []*report.LatencyRecorder
+	workerStats       []workerStats
+	intervalLatencies *report.Histogram
+	telemetryChan     chan *report.Point
+	telemetryDone     chan struct{}
+
+	progressIntervalItems uint64
+
+	maxBatchSize   int
+	speedUpRequest int32
+	itemsScanned   int64
+	verifier       Verifier
+	activeWorkers  int32
+
+	stop               chan struct{}
+	stopOnce           sync.Once
+	prematureEndReason string
+	workerErr          error
+}
+
+// RateControlMinBatchSize is the smallest batch size under an ingest rate
+// limit.
+const RateControlMinBatchSize = 100
+
+// AddFlags registers the flags of the Config in fs. The current Urls and
+// BatchSize are the defaults of their flags.
+func (l *Loader) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&l.Urls, "urls", l.Urls, fmt.Sprintf("%s URLs, comma-separated. Will be used in a round-robin fashion.", l.DBType))
+	fs.IntVar(&l.Workers, "workers", 1, "Number of parallel requests to make.")
+	fs.IntVar(&l.BatchSize, "batch-size", l.BatchSize, "Batch size (input items).")
+	fs.Int64Var(&l.ItemLimit, "item-limit", -1, "Number of items to read from the input before quitting.")
+	l.Input.AddFlags(fs)
+	if l.Verifiable {
+		l.Verify.AddFlags(fs)
+	}
+	l.Retry.AddFlags(fs)
+	l.Rate.AddFlags(fs)
+	l.Ramp.AddFlags(fs)
+	fs.DurationVar(&l.ProgressInterval, "progress-interval", -1, "Duration between printing progress messages.")
+	fs.Uint64Var(&l.PrintInterval, "print-interval", 1000, "Print timing stats to stderr after this many batches (0 to disable)")
+	fs.DurationVar(&l.MovingAverageInterval, "moving-average-interval", time.Second*30, "Interval of measuring mean write rate on which moving average is calculated.")
+	fs.IntVar(&l.ClientIndex, "client-index", 0, "Index of a client host running this tool. Used to distribute load")
+	fs.IntVar(&l.NotificationPort, "notification-port", -1, "Listen port for remote notification messages. Used to remotely finish benchmark. -1 to disable feature")
+	fs.BoolVar(&l.DoLoad, "do-load", true, "Whether to write data. Set this flag to false to check input read speed.")
+	fs.BoolVar(&l.MemProfile, "memprofile", false, "Whether to write a memprofile (file automatically determined).")
+	fs.StringVar(&l.CPUProfile, "cpu-profile", "", "Write cpu profile to `file`")
+	fs.BoolVar(&l.TelemetryStderr, "telemetry-stderr", false, "Whether to write telemetry also to stderr.")
+	fs.Uint64Var(&l.TelemetryBatchSize, "telemetry-batch-size", 1, "Telemetry batch size (lines).")
+	fs.StringVar(&l.ReportDatabase, "report-database", "database_benchmarks", "Database name where to store result metrics")
+	fs.StringVar(&l.ReportHost, "report-host", "", "Host to send result metrics")
+	fs.StringVar(&l.ReportUser, "report-user", "", "User for host to send result metrics")
+	fs.StringVar(&l.ReportPassword, "report-password", "", "User password for Host to send result metrics")
+	fs.StringVar(&l.ReportTagsCSV, "report-tags", "", "Comma separated k:v tags to send  alongside result metrics")
+	fs.BoolVar(&l.ReportTelemetry, "report-telemetry", false, "Turn on/off reporting telemetry")
+	fs.StringVar(&l.ResultsFile, "results-file", "", "Write a summary of the run to this file, as JSON or as CSV if it ends with .csv.")
+}
+
+// Init checks the parsed flags and prepares the Loader.
+func (l *Loader) Init() {
+	l.DaemonUrls = strings.Split(l.Urls, ",")
+	if len(l.DaemonUrls) == 0 {
+		log.Fatal("missing 'urls' flag")
+	}
+	fmt.Printf("daemon URLs: %v\n", l.DaemonUrls)
+
+	if l.Workers < 1 {
+		log.Fatalf("invalid number of workers: %d\n", l.Workers)
+	}
+	if l.BatchSize < 1 {
+		log.Fatalf("invalid batch size: %d\n", l.BatchSize)
+	}
+	if err := l.Retry.Init(); err != nil {
+		log.Fatal(err)
+	}
+	if err := l.Rate.Init(); err != nil {
+		log.Fatal(err)
+	}
+	if err := l.Ramp.Init(l.Workers, &l.Rate); err != nil {
+		log.Fatal(err)
+	}
+
+	if l.ReportHost != "" {
+		fmt.Printf("results report destination: %v\n", l.ReportHost)
+		fmt.Printf("results report database: %v\n", l.ReportDatabase)
+
+		var err error
+		l.ReportHostname, err = os.Hostname()
+		if err != nil {
+			log.Fatalf("os.Hostname() error: %s", err.Error())
+		}
+		fmt.Printf("hostname for results report: %v\n", l.ReportHostname)
+
+		if l.ReportTagsCSV != "" {
+			pairs := strings.Split(l.ReportTagsCSV, ",")
+			for _, pair := range pairs {
+				fields := strings.SplitN(pair, ":", 2)
+				tagpair := [2]string{fields[0], fields[1]}
+				l.ReportTags = append(l.ReportTags, tagpair)
+			}
+		}
+		fmt.Printf("results report tags: %v\n", l.ReportTags)
+	}
+
+	l.maxBatchSize = l.BatchSize
+	if l.Rate.Enabled() {
+		valuesPerItem := l.ValuesPerItem
+		if valuesPerItem <= 0 || l.Rate.Unit == RateUnitPoints {
+			valuesPerItem = 1
+		}
+		// batches start at a fifth of a second of the rate of a worker, and
+		// grow while the workers keep up, see batchSizeHint:
+		recommendedBatchSize := int(l.Rate.Limit / float64(l.Workers) / valuesPerItem * 0.20)
+		log.Printf("Calculated batch size hint: %v (allowed min: %v max: %v)", recommendedBatchSize, RateControlMinBatchSize, l.BatchSize)
+		if recommendedBatchSize < RateControlMinBatchSize {
+			recommendedBatchSize = RateControlMinBatchSize
+		} else if recommendedBatchSize > l.BatchSize {
+			recommendedBatchSize = l.BatchSize
+		}
+		if recommendedBatchSize != l.BatchSize {
+			log.Printf("Adjusting batchSize from %v to %v (%v values in 1 batch)", l.BatchSize, recommendedBatchSize, float64(recommendedBatchSize)*valuesPerItem)
+			l.BatchSize = recommendedBatchSize
+		}
+	} else {
+		log.Printf("Ingestion rate control is off")
+	}
+}
+
+// Result sums up a load.
+type Result struct {
+	ItemsRead  int64
+	BytesRead  int64
+	ValuesRead int64
+	Start      time.Time
+	Took       time.Duration
+
+	// DatasetPoints and DatasetValues are read from the dataset size marker,
+	// -1 if the input has none.
+	DatasetPoints int64
+	DatasetValues int64
+
+	// BackoffSecs is the time the workers spent backing off, after Backoffs
+	// backoff responses.
+	BackoffSecs float64
+	Backoffs    int64
+
+	// Latencies are the durations of the writes of all the workers.
+	Latencies *report.Histogram
+
+	EndedPrematurely   bool
+	PrematureEndReason string
+
+	// Err is the error a worker stopped on, if any.
+	Err error
+
+	// Verify is the outcome of --verify, nil if off.
+	Verify *VerifyResult
+
+	Workers []report.WorkerSummary
+}
+
+// Run loads the input read with d, and prints the outcome.
+func (l *Loader) Run(d Driver) *Result {
+	if l.MemProfile {
+		p := profile.Start(profile.MemProfile)
+		defer p.Stop()
+	}
+	if l.Verify.Enabled {
+		v, ok := d.(Verifier)
+		if !ok {
+			log.Fatalf("--verify is not supported by the %s loader", l.DBType)
+		}
+		l.verifier = v
+	}
+	if l.CPUProfile != "" {
+		f, err := os.Create(l.CPUProfile)
+		if err != nil {
+			log.Fatal("could not create CPU profile: ", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			log.Fatal("could not start CPU profile: ", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	l.bufPool = sync.Pool{
+		New: func() interface{} {
+			return bytes.NewBuffer(make([]byte, 0, 4*1024*1024))
+		},
+	}
+	l.statPool = sync.Pool{
+		New: func() interface{} {
+			return &Stat{}
+		},
+	}
+	l.movingAverageStat = NewTimedStatGroup(l.MovingAverageInterval, int(l.MovingAverageInterval.Seconds()))
+	// a ramp of the workers starts more workers as it goes:
+	maxWorkers := l.Ramp.maxWorkers(l.Workers)
+	l.latencies = make([]*report.LatencyRecorder, maxWorkers)
+	for i := range l.latencies {
+		l.latencies[i] = report.NewLatencyRecorder()
+	}
+	l.workerStats = make([]workerStats, maxWorkers)
+	l.intervalLatencies = report.NewHistogram()
+
+	l.batchChan = make(chan Batch, l.Workers)
+	l.stop = make(chan struct{})
+
+	if l.ReportHost != "" && l.ReportTelemetry {
+		telemetryCollector := report.NewCollector(l.ReportHost, l.ReportDatabase, l.ReportUser, l.ReportPassword)
+		err := telemetryCollector.CreateDatabase()
+		if err != nil {
+			log.Fatalf("Error creating telemetry db: %v\n", err)
+		}
+		l.telemetryChan, l.telemetryDone = report.TelemetryRunAsync(telemetryCollector, l.TelemetryBatchSize, l.TelemetryStderr, 0)
+	}
+
+	if l.NotificationPort > 0 {
+		l.listenNotifications()
+	}
+
+	l.statChan = make(chan *Stat, l.Workers)
+	l.statGroup.Add(1)
+	go l.processStats()
+
+	backingOffChans := make([]chan bool, maxWorkers)
+	backingOffDones := make([]chan struct{}, maxWorkers)
+	backingOffSecs := make([]float64, maxWorkers)
+	startWorker := func(i int) {
+		daemonUrl := l.DaemonUrls[(i+l.ClientIndex)%len(l.DaemonUrls)]
+		backingOffChans[i] = make(chan bool, 100)
+		backingOffDones[i] = make(chan struct{})
+		l.workersGroup.Add(1)
+		w := d.NewWriter(i, daemonUrl)
+		go l.processBatches(w, i, backingOffChans[i])
+		go func() {
+			backingOffSecs[i] = processBackoffMessages(i, backingOffChans[i])
+			close(backingOffDones[i])
+		}()
+		atomic.AddInt32(&l.activeWorkers, 1)
+	}
+	for i := 0; i < l.Workers; i++ {
+		startWorker(i)
+	}
+	fmt.Printf("Started load with %d workers\n", l.Workers)
+
+	scanDone := make(chan struct{})
+	rampDone := make(chan struct{})
+	if l.Ramp.Enabled() {
+		go func() {
+			l.ramp(scanDone, startWorker)
+			close(rampDone)
+		}()
+	} else {
+		close(rampDone)
+	}
+
+	if l.ProgressInterval >= 0 {
+		go func() {
+			start := time.Now()
+			for end := range time.NewTicker(l.ProgressInterval).C {
+				n := atomic.SwapUint64(&l.progressIntervalItems, 0)
+				absoluteMillis := start.UTC().UnixNano() / 1e6
+				fmt.Printf("[interval_progress_items] %dms, %d\n", absoluteMillis, n)
+				start = end
+			}
+		}()
+	}
+
+	start := time.Now()
+	sr := l.scanInputs(d)
+	itemsRead, totalPoints := sr.itemsRead, sr.totalPoints
+	// no worker starts once the ramp is done:
+	close(scanDone)
+	<-rampDone
+	workers := int(atomic.LoadInt32(&l.activeWorkers))
+	close(l.batchChan)
+
+	l.workersGroup.Wait()
+
+	close(l.statChan)
+	l.statGroup.Wait()
+	l.Workers = workers
+
+	for i := 0; i < workers; i++ {
+		close(backingOffChans[i])
+		<-backingOffDones[i]
+	}
+
+	res := &Result{
+		ItemsRead:          itemsRead,
+		BytesRead:          sr.bytesRead,
+		ValuesRead:         sr.valuesRead,
+		Start:              start,
+		Took:               time.Since(start),
+		DatasetPoints:      totalPoints,
+		DatasetValues:      sr.totalValues,
+		EndedPrematurely:   l.prematureEndReason != "",
+		PrematureEndReason: l.prematureEndReason,
+		Err:                l.workerErr,
+		Latencies:          l.totalLatencies(),
+	}
+	for _, secs := range backingOffSecs {
+		res.BackoffSecs += secs
+	}
+	for i, ws := range l.workerStats[:workers] {
+		res.Backoffs += ws.backoffs
+		res.Workers = append(res.Workers, report.WorkerSummary{
+			Worker:   i,
+			Requests: ws.batches,
+			Items:    ws.items,
+			Values:   ws.values,
+			Bytes:    ws.bytes,
+			Errors:   ws.errors,
+			Latency:  l.latencies[i].Total().Summary(),
+		})
+	}
+
+	if totalPoints >= 0 && itemsRead != totalPoints && !res.EndedPrematurely && l.ItemLimit < 0 {
+		log.Fatalf("Incorrent number of read points: %d, expected: %d:", itemsRead, totalPoints)
+	}
+
+	if l.telemetryChan != nil {
+		close(l.telemetryChan)
+		<-l.telemetryDone
+	}
+	if res.Err != nil {
+		fmt.Println(res.Err.Error())
+	}
+	if res.EndedPrematurely {
+		fmt.Printf("load finished prematurely: %s\n", res.PrematureEndReason)
+	}
+
+	if l.verifier != nil && l.DoLoad {
+		expected := totalPoints
+		if res.EndedPrematurely || l.ItemLimit >= 0 {
+			expected = -1
+		}
+		url := l.DaemonUrls[0]
+		res.Verify = l.Verify.Run(sr.measurements, expected, func(measurement string) (int64, error) {
+			return l.verifier.CountPoints(url, measurement)
+		})
+	}
+
+	if l.Ramp.Enabled() {
+		l.Ramp.PrintResult()
+	}
+
+	took := res.Took.Seconds()
+	fmt.Printf("loaded %d items in %fsec with %d workers (mean point rate %f/sec, mean value rate %f/s, %.2fMB/sec from the input)\n", res.ItemsRead, took, l.Workers, float64(res.ItemsRead)/took, float64(res.ValuesRead)/took, float64(res.BytesRead)/took/(1<<20))
+	return res
+}
+
+// Finish reports res to the report host and writes its summary to the
+// results file, if any, with the database specific tags and values, and exits
+// if a worker failed.
+func (l *Loader) Finish(res *Result, isGzip bool, tags [][2]string, extraVals ...report.ExtraVal) {
+	tags = append(append(append(append([][2]string{}, l.ReportTags...), tags...), l.Rate.Tags()...), l.Ramp.Tags()...)
+	extraVals = append(append(extraVals, l.Rate.ExtraVals()...), l.Ramp.ExtraVals()...)
+	if res.BackoffSecs > 0 {
+		extraVals = append(extraVals, report.ExtraVal{Name: "total_backoff_secs", Value: res.BackoffSecs})
+	}
+	if res.Verify != nil {
+		extraVals = append(extraVals, res.Verify.ExtraVals()...)
+	}
+	// the summary has the latencies in full:
+	summaryVals := extraVals
+	if res.Latencies.Count() > 0 {
+		extraVals = append(extraVals, res.Latencies.ExtraVals("write_latency")...)
+	}
+
+	if l.ReportHost != "" {
+		reportTags := tags
+		if res.EndedPrematurely {
+			reportTags = append(reportTags, [2]string{"premature_end_reason", report.Escape(res.PrematureEndReason)})
+		}
+
+		reportParams := &report.LoadReportParams{
+			ReportParams: report.ReportParams{
+				DBType:             l.DBType,
+				ReportDatabaseName: l.ReportDatabase,
+				ReportHost:         l.ReportHost,
+				ReportUser:         l.ReportUser,
+				ReportPassword:     l.ReportPassword,
+				ReportTags:         reportTags,
+				Hostname:           l.ReportHostname,
+				DestinationUrl:     l.Urls,
+				Workers:            l.Workers,
+				ItemLimit:          int(l.ItemLimit),
+			},
+			IsGzip:    isGzip,
+			BatchSize: l.BatchSize,
+		}
+		took := res.Took.Seconds()
+		err := report.ReportLoadResult(reportParams, res.ItemsRead, float64(res.ValuesRead)/took, float64(res.BytesRead)/took, res.Took, extraVals...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if l.ResultsFile != "" {
+		s := report.NewLoadSummary(l.DBType, res.Start, res.Took, res.ItemsRead, res.ValuesRead, res.BytesRead)
+		if res.DatasetPoints >= 0 {
+			s.Dataset.ExpectedItems = res.DatasetPoints
+			s.Dataset.ExpectedValues = res.DatasetValues
+		}
+		s.AddTags(append(tags, [2]string{"gzip", strconv.FormatBool(isGzip)}))
+		if res.EndedPrematurely {
+			s.AddTags([][2]string{{"premature_end_reason", res.PrematureEndReason}})
+		}
+		s.AddExtraVals(summaryVals)
+		s.Latency = res.Latencies.Summary()
+		s.Errors["backoffs"] = res.Backoffs
+		for _, w := range res.Workers {
+			s.Errors["write_errors"] += w.Errors
+		}
+		s.Workers = res.Workers
+		if res.Verify != nil {
+			res.Verify.AddErrors(s)
+		}
+		if l.Summarize != nil {
+			l.Summarize(s)
+		}
+		if err := s.WriteFile(l.ResultsFile); err != nil {
+			log.Fatalf("Error writing results file: %v", err)
+		}
+	}
+
+	if res.Err != nil {
+		os.Exit(1)
+	}
+}
+
+// endPrematurely stops the scan of the input, for reason. err is the error of
+// a failed worker.
+func (l *Loader) endPrematurely(reason string, err error) {
+	l.stopOnce.Do(func() {
+		l.prematureEndReason = reason
+		l.workerErr = err
+		close(l.stop)
+	})
+}
+
+func (l *Loader) notifyHandler(arg int) (int, error) {
+	if arg != 0 {
+		return 0, fmt.Errorf("unknown notification code: %d", arg)
+	}
+	fmt.Println("Received external finish request")
+	l.endPrematurely("External notification", nil)
+	return 0, nil
+}
+
+// listenNotifications serves the NotifyReceiver, so that the load can be
+// finished remotely.
+func (l *Loader) listenNotifications() {
+	notif := new(NotifyReceiver)
+	rpc.Register(notif)
+	rpc.HandleHTTP()
+	RegisterHandler(l.notifyHandler)
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", l.NotificationPort))
+	if err != nil {
+		log.Fatal("listen error:", err)
+	}
+	log.Println("Listening for incoming notification")
+	go http.Serve(ln, nil)
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,307 @@
+package bulk_load
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/caict-benchmark/BDC-TS/util/report"
+)
+
+// Ramp modes: what grows at each step of the ramp.
+const (
+	RampRate    = "rate"
+	RampWorkers = "workers"
+)
+
+// Ramp searches the maximum sustainable throughput of a database, like
+// --grad-workers-inc with --response-time-limit of the query benchmarkers:
+// every Interval, the ingest rate limit, or the number of workers, grows by
+// Step, until the write latencies or the errors of a step exceed the SLO.
+// The load then ends, and the highest rate achieved by a step within the SLO
+// is the sustainable one.
+type Ramp struct {
+	Mode     string
+	Interval time.Duration
+	// Step is the increase of the rate limit, in the unit of the RateLimit,
+	// or of the number of workers. 0 is the starting rate limit or number
+	// of workers.
+	Step float64
+	// Max is the maximum rate limit or number of workers, not limited if not
+	// positive.
+	Max float64
+
+	// SLO: the Percentile of the write latencies of a step must not be above
+	// Latency, and the fraction of its writes which fail or are retried not
+	// above ErrorRate.
+	Latency    time.Duration
+	Percentile float64
+	ErrorRate  float64
+
+	// Steps are the steps run so far.
+	Steps []RampStep
+
+	unit      string
+	latencies *report.LatencyRecorder
+	writes    int64
+	errors    int64
+	items     int64
+	values    int64
+}
+
+// RampStep is the outcome of a step of a Ramp.
+type RampStep struct {
+	// Target is the rate limit of the step, 0 when the workers ramp.
+	Target  float64
+	Workers int
+	// Rate is the rate written during the step, in the unit of the
+	// RateLimit.
+	Rate      float64
+	Latency   time.Duration
+	ErrorRate float64
+	// Failure tells why the step is not within the SLO, empty if it is.
+	Failure string
+}
+
+// AddFlags registers the flags of the Ramp in fs.
+func (r *Ramp) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&r.Mode, "ramp", "", "Search the maximum sustainable throughput, growing step by step the ingest rate limit or the number of workers until the SLO is exceeded (choices: rate, workers).")
+	fs.DurationVar(&r.Interval, "ramp-interval", 30*time.Second, "Duration of a step of --ramp.")
+	fs.Float64Var(&r.Step, "ramp-step", 0, "Increase of --ingest-rate-limit or of --workers at each step of --ramp (0 = their starting value).")
+	fs.Float64Var(&r.Max, "ramp-max", -1, "Maximum of --ingest-rate-limit or of --workers reached by --ramp (-1 = no limit, required with --ramp=workers).")
+	fs.DurationVar(&r.Latency, "slo-latency", 500*time.Millisecond, "Write latency objective of --ramp: maximum --slo-percentile of the write latencies of a step.")
+	fs.Float64Var(&r.Percentile, "slo-percentile", 99, "Percentile of the write latencies checked against --slo-latency.")
+	fs.Float64Var(&r.ErrorRate, "slo-error-rate", 0.01, "Error objective of --ramp: maximum fraction of the writes of a step that fail or are retried.")
+}
+
+// Init checks the parsed flags, for a load starting with workers workers
+// under the rate limit rate.
+func (r *Ramp) Init(workers int, rate *RateLimit) error {
+	r.unit = rate.Unit
+	switch r.Mode {
+	case "":
+		return nil
+	case RampRate:
+		if !rate.Enabled() {
+			return fmt.Errorf("--ramp=rate needs --ingest-rate-limit, the starting rate")
+		}
+		if r.Step <= 0 {
+			r.Step = rate.Limit
+		}
+	case RampWorkers:
+		if r.Max < float64(workers) {
+			return fmt.Errorf("--ramp=workers needs --ramp-max, the maximum number of workers, of at least --workers")
+		}
+		if r.Step <= 0 {
+			r.Step = float64(workers)
+		}
+	default:
+		return fmt.Errorf("invalid ramp: %s", r.Mode)
+	}
+	if r.Interval <= 0 {
+		return fmt.Errorf("invalid ramp interval: %v", r.Interval)
+	}
+	if r.Percentile <= 0 || r.Percentile > 100 {
+		return fmt.Errorf("invalid SLO percentile: %v", r.Percentile)
+	}
+	r.latencies = report.NewLatencyRecorder()
+	fmt.Printf("ramp: %s by %v every %v, SLO: p%v write latency <= %v, error rate <= %v\n", r.Mode, r.Step, r.Interval, r.Percentile, r.Latency, r.ErrorRate)
+	return nil
+}
+
+// Enabled tells whether the load ramps.
+func (r *Ramp) Enabled() bool {
+	return r.Mode != ""
+}
+
+// maxWorkers returns the number of workers the load may reach.
+func (r *Ramp) maxWorkers(workers int) int {
+	if r.Mode == RampWorkers {
+		return int(r.Max)
+	}
+	return workers
+}
+
+// recordWrite records a write attempt of a worker, which took d and failed
+// if err is not nil, retryable or not.
+func (r *Ramp) recordWrite(d time.Duration, err error) {
+	r.latencies.Record(d)
+	atomic.AddInt64(&r.writes, 1)
+	if err != nil {
+		atomic.AddInt64(&r.errors, 1)
+	}
+}
+
+// recordBatch records a batch written by a worker.
+func (r *Ramp) recordBatch(items, values int) {
+	atomic.AddInt64(&r.items, int64(items))
+	atomic.AddInt64(&r.values, int64(values))
+}
+
+// endStep checks the writes since the previous step, which took took, with
+// target and workers, against the SLO.
+func (r *Ramp) endStep(target float64, workers int, took time.Duration) RampStep {
+	latencies := report.NewHistogram()
+	r.latencies.TakeInterval(latencies)
+	writes := atomic.SwapInt64(&r.writes, 0)
+	errors := atomic.SwapInt64(&r.errors, 0)
+	n := atomic.SwapInt64(&r.values, 0)
+	if items := atomic.SwapInt64(&r.items, 0); r.unit == RateUnitPoints {
+		n = items
+	}
+
+	step := RampStep{
+		Target:  target,
+		Workers: workers,
+		Rate:    float64(n) / took.Seconds(),
+		Latency: latencies.Percentile(r.Percentile),
+	}
+	if writes > 0 {
+		step.ErrorRate = float64(errors) / float64(writes)
+	}
+	switch {
+	case writes == 0:
+		step.Failure = "no write completed"
+	case step.Latency > r.Latency:
+		step.Failure = fmt.Sprintf("p%v write latency %v above %v", r.Percentile, step.Latency, r.Latency)
+	case step.ErrorRate > r.ErrorRate:
+		step.Failure = fmt.Sprintf("error rate %.4f above %v", step.ErrorRate, r.ErrorRate)
+	case r.Mode == RampRate && step.Rate < 0.9*target:
+		// the database does not keep up with the limit
+		step.Failure = fmt.Sprintf("rate %.2f below 90%% of the target", step.Rate)
+	}
+	r.Steps = append(r.Steps, step)
+	return step
+}
+
+// Best returns the step within the SLO with the highest rate, nil if none.
+func (r *Ramp) Best() *RampStep {
+	var best *RampStep
+	for i := range r.Steps {
+		s := &r.Steps[i]
+		if s.Failure == "" && (best == nil || s.Rate > best.Rate) {
+			best = s
+		}
+	}
+	return best
+}
+
+// String describes the step for the ramp of unit unit.
+func (s RampStep) String(unit string) string {
+	out := fmt.Sprintf("workers: %d, rate: %.2f %s/s, write latency: %v, error rate: %.4f", s.Workers, s.Rate, unit, s.Latency, s.ErrorRate)
+	if s.Target > 0 {
+		out = fmt.Sprintf("target: %.2f %s/s, %s", s.Target, unit, out)
+	}
+	if s.Failure != "" {
+		return out + ": " + s.Failure
+	}
+	return out + ": ok"
+}
+
+// PrintResult prints the steps run and the sustainable rate found.
+func (r *Ramp) PrintResult() {
+	for i, s := range r.Steps {
+		fmt.Printf("ramp step %d: %s\n", i+1, s.String(r.unit))
+	}
+	if len(r.Steps) == 0 {
+		fmt.Println("ramp: the input ended before the first step")
+		return
+	}
+	best := r.Best()
+	if best == nil {
+		fmt.Println("ramp: no step within the SLO")
+		return
+	}
+	fmt.Printf("maximum sustainable rate: %.2f %s/s with %d workers\n", best.Rate, r.unit, best.Workers)
+	if last := r.Steps[len(r.Steps)-1]; last.Failure == "" {
+		fmt.Println("ramp: the input ended before the SLO was exceeded")
+	}
+}
+
+// Tags returns the report tags of the ramp.
+func (r *Ramp) Tags() [][2]string {
+	if !r.Enabled() {
+		return nil
+	}
+	return [][2]string{
+		{"ramp", r.Mode},
+		{"slo_latency", r.Latency.String()},
+		{"slo_percentile", strconv.FormatFloat(r.Percentile, 'f', -1, 64)},
+	}
+}
+
+// ExtraVals returns the report values of the sustainable rate found.
+func (r *Ramp) ExtraVals() []report.ExtraVal {
+	best := r.Best()
+	if !r.Enabled() || best == nil {
+		return nil
+	}
+	vals := []report.ExtraVal{
+		{Name: "sustainable_rate_" + r.unit, Value: best.Rate},
+		{Name: "sustainable_workers", Value: best.Workers},
+		{Name: "ramp_steps", Value: len(r.Steps)},
+	}
+	if best.Target > 0 {
+		vals = append(vals, report.ExtraVal{Name: "sustainable_rate_limit", Value: best.Target})
+	}
+	return vals
+}
+
+// ramp runs the steps of the Ramp until done is closed or the SLO is
+// exceeded, which ends the load. startWorker starts the worker i.
+func (l *Loader) ramp(done <-chan struct{}, startWorker func(i int)) {
+	r := &l.Ramp
+	target := l.Rate.Limit
+	maxWorkers := r.maxWorkers(l.Workers)
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case <-l.stop:
+			return
+		case now := <-ticker.C:
+			workers := int(atomic.LoadInt32(&l.activeWorkers))
+			step := r.endStep(target, workers, now.Sub(last))
+			last = now
+			log.Printf("ramp step %d: %s\n", len(r.Steps), step.String(r.unit))
+			if step.Failure != "" {
+				l.endPrematurely("SLO exceeded", nil)
+				return
+			}
+
+			switch r.Mode {
+			case RampRate:
+				next := target + r.Step
+				if r.Max > 0 && next > r.Max {
+					next = r.Max
+				}
+				if next == target {
+					log.Printf("Maximum rate %v already reached\n", r.Max)
+					continue
+				}
+				target = next
+				l.Rate.SetLimit(target)
+			case RampWorkers:
+				next := workers + int(r.Step)
+				if next > maxWorkers {
+					next = maxWorkers
+				}
+				if next == workers {
+					log.Printf("Maximum %d workers already reached\n", maxWorkers)
+					continue
+				}
+				for i := workers; i < next; i++ {
+					startWorker(i)
+				}
+				log.Printf("Added %d workers, total: %d\n", next-workers, next)
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package bulk_load
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/caict-benchmark/BDC-TS/util/report"
+)
+
+// Rate limit units: the limit counts the values or the points (items) of the
+// batches sent.
+const (
+	RateUnitValues = "values"
+	RateUnitPoints = "points"
+)
+
+// RateLimit paces a load to a target ingest rate and ends it after a time
+// limit, so that databases can be compared under the same load. It is a
+// token bucket shared by the senders of batches, usually the goroutine
+// scanning the input: each batch takes its points or values from the
+// bucket, which fills at Limit per second and holds at most one second of
+// them.
+type RateLimit struct {
+	// Limit is the rate in Unit per second, not limited if not positive.
+	Limit     float64
+	Unit      string
+	TimeLimit time.Duration
+
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+	deadline time.Time
+}
+
+// AddFlags registers the flags of the RateLimit in fs.
+func (r *RateLimit) AddFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&r.Limit, "ingest-rate-limit", -1, "Ingest rate limit in --ingest-rate-unit per second (-1 = no limit).")
+	fs.StringVar(&r.Unit, "ingest-rate-unit", RateUnitValues, "Unit of --ingest-rate-limit (choices: values, points).")
+	fs.DurationVar(&r.TimeLimit, "time-limit", -1, "Maximum duration to run (-1 is the default: no limit).")
+}
+
+// Init checks the parsed flags.
+func (r *RateLimit) Init() error {
+	if r.Unit != RateUnitValues && r.Unit != RateUnitPoints {
+		return fmt.Errorf("invalid ingest rate unit: %s", r.Unit)
+	}
+	if r.Enabled() {
+		fmt.Printf("ingest rate limit: %v %s/s\n", r.Limit, r.Unit)
+	}
+	if r.TimeLimit > 0 {
+		fmt.Printf("time limit: %v\n", r.TimeLimit)
+	}
+	return nil
+}
+
+// Enabled tells whether the rate is limited.
+func (r *RateLimit) Enabled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Limit > 0
+}
+
+// SetLimit changes the rate limit of a running load, see Ramp.
+func (r *RateLimit) SetLimit(limit float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Limit = limit
+	if r.tokens > limit {
+		r.tokens = limit
+	}
+}
+
+// Start starts the time limit, and the bucket empty.
+func (r *RateLimit) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last = time.Now()
+	r.tokens = 0
+	if r.TimeLimit > 0 {
+		r.deadline = r.last.Add(r.TimeLimit)
+	}
+}
+
+// Wait takes a batch of points points holding values values from the
+// bucket, sleeping until the bucket has them, and returns the time slept.
+// A batch larger than the bucket leaves it in debt, which the next batches
+// wait for.
+func (r *RateLimit) Wait(points, values int) time.Duration {
+	n := float64(values)
+	if r.Unit == RateUnitPoints {
+		n = float64(points)
+	}
+
+	r.mu.Lock()
+	if r.Limit <= 0 {
+		r.mu.Unlock()
+		return 0
+	}
+	now := time.Now()
+	if r.last.IsZero() {
+		r.last = now
+	}
+	r.tokens += now.Sub(r.last).Seconds() * r.Limit
+	if r.tokens > r.Limit {
+		r.tokens = r.Limit
+	}
+	r.last = now
+	r.tokens -= n
+	var delay time.Duration
+	if r.tokens < 0 {
+		delay = time.Duration(-r.tokens / r.Limit * float64(time.Second))
+	}
+	r.mu.Unlock()
+
+	time.Sleep(delay)
+	return delay
+}
+
+// Expired tells whether the time limit has elapsed since Start.
+func (r *RateLimit) Expired() bool {
+	return r.TimeLimit > 0 && !r.deadline.IsZero() && time.Now().After(r.deadline)
+}
+
+// Tags returns the report tags of the limits in use.
+func (r *RateLimit) Tags() [][2]string {
+	var tags [][2]string
+	if r.TimeLimit > 0 {
+		tags = append(tags, [2]string{"time_limit", r.TimeLimit.String()})
+	}
+	if r.Enabled() {
+		tags = append(tags, [2]string{"ingest_rate_unit", r.Unit})
+	}
+	return tags
+}
+
+// ExtraVals returns the report values of the limits in use.
+func (r *RateLimit) ExtraVals() []report.ExtraVal {
+	if !r.Enabled() {
+		return nil
+	}
+	return []report.ExtraVal{{Name: "ingest_rate_limit_" + r.Unit, Value: r.Limit}}
+}
@@ -0,0 +1,170 @@
+package bulk_load
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryableError is returned by a Writer when the response of the database
+// is retryable according to the RetryPolicy, like ErrBackoff. It keeps the
+// response for the error message of the last attempt.
+type RetryableError struct {
+	Status int
+	Body   string
+}
+
+func (e *RetryableError) Error() string {
+	return fmt.Sprintf("retryable write response (status %d): %s", e.Status, e.Body)
+}
+
+// IsRetryable tells whether the write that failed with err is worth retrying.
+func IsRetryable(err error) bool {
+	if err == ErrBackoff {
+		return true
+	}
+	_, ok := err.(*RetryableError)
+	return ok
+}
+
+// RetryPolicy decides which failed writes are written again, how often, and
+// how long a worker backs off before each attempt. The delay grows
+// exponentially from Backoff up to MaxBackoff, randomized by Jitter.
+type RetryPolicy struct {
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Multiplier float64
+	// Jitter is the fraction of the delay by which it is randomly made
+	// shorter or longer, so that the workers do not retry all at once.
+	Jitter float64
+	// MaxAttempts is the number of attempts to write a batch, 0 for no limit.
+	MaxAttempts int
+	// StatusesCSV and BodyPatternsCSV classify the retryable responses, see
+	// Retryable.
+	StatusesCSV     string
+	BodyPatternsCSV string
+
+	statuses     map[int]bool
+	bodyPatterns [][]byte
+}
+
+// AddFlags registers the flags of the RetryPolicy in fs.
+func (p *RetryPolicy) AddFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&p.Backoff, "backoff", time.Second, "Time to sleep between requests when server indicates backpressure is needed.")
+	fs.DurationVar(&p.MaxBackoff, "backoff-max", 10*time.Second, "Maximum time to sleep between the attempts to write a batch.")
+	fs.Float64Var(&p.Multiplier, "backoff-multiplier", 2, "Factor by which the backoff grows after each retry.")
+	fs.Float64Var(&p.Jitter, "backoff-jitter", 0.2, "Fraction by which each backoff is randomly made shorter or longer (0 to disable).")
+	fs.IntVar(&p.MaxAttempts, "retry-max-attempts", 0, "Maximum number of attempts to write a batch before giving up (0 = no limit).")
+	fs.StringVar(&p.StatusesCSV, "retry-statuses", "429,503", "Comma separated HTTP status codes on which a batch is written again.")
+	fs.StringVar(&p.BodyPatternsCSV, "retry-body-patterns", "engine: cache maximum memory size exceeded,es_rejected_execution_exception", "Comma separated texts of error responses on which a batch is written again.")
+}
+
+// Init checks the parsed flags and prepares the RetryPolicy.
+func (p *RetryPolicy) Init() error {
+	if p.Backoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("invalid backoff: %v, max %v", p.Backoff, p.MaxBackoff)
+	}
+	if p.MaxBackoff < p.Backoff {
+		p.MaxBackoff = p.Backoff
+	}
+	if p.Multiplier < 1 {
+		return fmt.Errorf("invalid backoff multiplier: %v", p.Multiplier)
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("invalid backoff jitter: %v", p.Jitter)
+	}
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("invalid retry max attempts: %d", p.MaxAttempts)
+	}
+	p.statuses = map[int]bool{}
+	for _, s := range strings.Split(p.StatusesCSV, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		status, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid retry status %q: %v", s, err)
+		}
+		p.statuses[status] = true
+	}
+	p.bodyPatterns = nil
+	for _, pattern := range strings.Split(p.BodyPatternsCSV, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			p.bodyPatterns = append(p.bodyPatterns, []byte(pattern))
+		}
+	}
+	return nil
+}
+
+// Retryable tells whether the error response of a write with the HTTP
+// status and body is retryable: its status is one of the retry statuses, or
+// its body contains one of the retry body patterns.
+func (p *RetryPolicy) Retryable(status int, body []byte) bool {
+	if p.statuses[status] {
+		return true
+	}
+	for _, pattern := range p.bodyPatterns {
+		if bytes.Contains(body, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify returns a RetryableError if the error response with the HTTP
+// status and body is retryable, nil otherwise.
+func (p *RetryPolicy) Classify(status int, body []byte) error {
+	if !p.Retryable(status, body) {
+		return nil
+	}
+	return &RetryableError{Status: status, Body: string(body)}
+}
+
+// Exhausted tells whether no attempt is left after attempts attempts.
+func (p *RetryPolicy) Exhausted(attempts int) bool {
+	return p.MaxAttempts > 0 && attempts >= p.MaxAttempts
+}
+
+// Delay returns the time to sleep after the failed attempt attempt, counted
+// from 1.
+func (p *RetryPolicy) Delay(attempt int) time.Duration {
+	d := float64(p.Backoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// Do calls write until it succeeds, fails with an error that is not
+// retryable, or the attempts are exhausted, sleeping between the attempts.
+// It returns the number of retries and the time from the first retryable
+// failure to the end of the last attempt, which is the backoff time.
+func (p *RetryPolicy) Do(write func() error) (retries int, backoff time.Duration, err error) {
+	var backoffStart time.Time
+	for attempt := 1; ; attempt++ {
+		err = write()
+		if retries > 0 {
+			backoff = time.Since(backoffStart)
+		}
+		if !IsRetryable(err) {
+			return retries, backoff, err
+		}
+		if p.Exhausted(attempt) {
+			return retries, backoff, fmt.Errorf("giving up after %d attempts: %v", attempt, err)
+		}
+		if retries == 0 {
+			backoffStart = time.Now()
+		}
+		retries++
+		time.Sleep(p.Delay(attempt))
+	}
+}
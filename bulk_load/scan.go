@@ -0,0 +1,351 @@
+package bulk_load
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+)
+
+// Decoder reads the items of the input, one at a time.
+type Decoder interface {
+	// Decode returns the next item and the number of values it holds, or
+	// io.EOF at the end of the input. The item is only valid until the next
+	// call.
+	Decode() (item []byte, values int, err error)
+}
+
+// DatasetSizer is implemented by Decoders of inputs that end with a dataset
+// size marker (see bulk_data_gen/common.DatasetSizeMarker). The number of
+// items read is then checked against it.
+type DatasetSizer interface {
+	// DatasetSize returns the points and values of the marker, or -1 when no
+	// marker was read.
+	DatasetSize() (points, values int64)
+}
+
+// PointsDecoder is implemented by Decoders whose items may hold several data
+// points, like the batched requests of bulk_data_gen --batch-points. The
+// points of the items are then counted instead of the items, and make up the
+// batches.
+type PointsDecoder interface {
+	// Points returns the number of data points of the last item decoded.
+	Points() int
+}
+
+// Encoder builds the body of batches out of items.
+type Encoder interface {
+	// Reset starts the body of a new batch in buf.
+	Reset(buf *bytes.Buffer)
+	// Append adds item to the body in buf, which holds n items. It returns
+	// false, leaving buf as is, when the item does not fit: the batch is then
+	// sent and the item goes to the next one.
+	Append(buf *bytes.Buffer, n int, item []byte) bool
+	// Finish completes the body in buf of n items before it is sent.
+	Finish(buf *bytes.Buffer, n int)
+}
+
+// scanResult sums up the scan of the inputs.
+type scanResult struct {
+	itemsRead  int64
+	bytesRead  int64
+	valuesRead int64
+	// totalPoints and totalValues add up the dataset size markers of the
+	// inputs, -1 if none has one.
+	totalPoints int64
+	totalValues int64
+	// measurements counts the items read of each measurement, for --verify.
+	measurements map[string]int64
+}
+
+// scanInputs scans the files of the Input concurrently, each with its own
+// Decoder of d, until they end or the load ends prematurely.
+func (l *Loader) scanInputs(d Driver) scanResult {
+	paths, err := l.Input.Paths()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(paths) > 1 {
+		fmt.Printf("reading %d input files\n", len(paths))
+	}
+
+	l.Rate.Start()
+
+	results := make([]scanResult, len(paths))
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			f, err := OpenInput(path)
+			if err != nil {
+				log.Fatalf("Error opening input: %v", err)
+			}
+			defer f.Close()
+			in := &countingReader{r: f}
+			res := &results[i]
+			var counts map[string]*int64
+			if l.verifier != nil {
+				counts = map[string]*int64{}
+			}
+			res.itemsRead, res.valuesRead, res.totalPoints, res.totalValues = l.scan(d.NewDecoder(in), d.NewEncoder(), counts)
+			res.bytesRead = in.n
+			res.measurements = map[string]int64{}
+			for m, n := range counts {
+				res.measurements[m] = *n
+			}
+		}(i, path)
+	}
+	wg.Wait()
+
+	total := scanResult{totalPoints: -1, totalValues: -1, measurements: map[string]int64{}}
+	for _, res := range results {
+		for m, n := range res.measurements {
+			total.measurements[m] += n
+		}
+		total.itemsRead += res.itemsRead
+		total.bytesRead += res.bytesRead
+		total.valuesRead += res.valuesRead
+		if res.totalPoints >= 0 {
+			if total.totalPoints < 0 {
+				total.totalPoints, total.totalValues = 0, 0
+			}
+			total.totalPoints += res.totalPoints
+			total.totalValues += res.totalValues
+		}
+	}
+	return total
+}
+
+// scan reads the items of an input with dec, and sends batches of them built
+// with enc over batchChan for the workers to write. It returns the items and
+// values read, and the points and values of the dataset size marker or -1.
+// The scans of several inputs share the item limit. The items read of each
+// measurement are counted in counts, unless nil.
+func (l *Loader) scan(dec Decoder, enc Encoder, counts map[string]*int64) (int64, int64, int64, int64) {
+	var n, values int
+	var itemsRead, valuesRead int64
+	itemsPerBatch := l.BatchSize
+	pointsDec, _ := dec.(PointsDecoder)
+
+	buf := l.bufPool.Get().(*bytes.Buffer)
+	enc.Reset(buf)
+	send := func() {
+		enc.Finish(buf, n)
+		atomic.AddUint64(&l.progressIntervalItems, uint64(n))
+		valuesRead += int64(values)
+		// under a rate limit, not having to wait means the workers lag
+		// behind, and bigger batches may help:
+		if l.Rate.Wait(n, values) == 0 && l.Rate.Enabled() {
+			atomic.AddInt32(&l.speedUpRequest, 1)
+		}
+		l.batchChan <- Batch{buf, n, values}
+		buf = l.bufPool.Get().(*bytes.Buffer)
+		enc.Reset(buf)
+		n = 0
+		values = 0
+	}
+
+outer:
+	for {
+		item, v, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Error reading input after %d items: %s", itemsRead, err.Error())
+		}
+		points := 1
+		if pointsDec != nil {
+			points = pointsDec.Points()
+		}
+		if l.ItemLimit >= 0 && atomic.AddInt64(&l.itemsScanned, int64(points)) > l.ItemLimit {
+			break
+		}
+
+		if !enc.Append(buf, n, item) {
+			if n == 0 {
+				log.Fatalf("Error reading input: item %d does not fit in a batch", itemsRead)
+			}
+			send()
+			if !enc.Append(buf, n, item) {
+				log.Fatalf("Error reading input: item %d does not fit in a batch", itemsRead)
+			}
+		}
+
+		if counts != nil {
+			m := l.verifier.Measurement(item)
+			c := counts[string(m)]
+			if c == nil {
+				c = new(int64)
+				counts[string(m)] = c
+			}
+			*c += int64(points)
+		}
+
+		itemsRead += int64(points)
+		values += v
+		n += points
+		if n >= itemsPerBatch {
+			send()
+
+			if l.Rate.Expired() {
+				l.endPrematurely("Timeout elapsed", nil)
+				break outer
+			}
+
+			itemsPerBatch = l.batchSizeHint(itemsPerBatch)
+		}
+
+		select {
+		case <-l.stop:
+			break outer
+		default:
+		}
+	}
+
+	// Finished reading input, make sure last batch goes out.
+	if n > 0 {
+		send()
+	}
+	buf.Reset()
+	l.bufPool.Put(buf)
+
+	totalPoints, totalValues := int64(-1), int64(-1)
+	if s, ok := dec.(DatasetSizer); ok {
+		totalPoints, totalValues = s.DatasetSize()
+	}
+	return itemsRead, valuesRead, totalPoints, totalValues
+}
+
+// LineDecoder decodes items of one line each. Dataset size marker lines are
+// not items.
+type LineDecoder struct {
+	scanner     *bufio.Scanner
+	countValues func(line []byte) int
+	totalPoints int64
+	totalValues int64
+}
+
+// NewLineDecoder returns a LineDecoder of r, reading lines of up to maxLen
+// bytes. countValues returns the number of values of a line, nil counts one
+// value per line.
+func NewLineDecoder(r io.Reader, maxLen int, countValues func(line []byte) int) *LineDecoder {
+	scanner := bufio.NewScanner(bufio.NewReaderSize(r, 4*1024*1024))
+	if maxLen > bufio.MaxScanTokenSize {
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLen)
+	}
+	return &LineDecoder{
+		scanner:     scanner,
+		countValues: countValues,
+		totalPoints: -1,
+		totalValues: -1,
+	}
+}
+
+var datasetSizeMarker = []byte(common.DatasetSizeMarker)
+
+func (d *LineDecoder) Decode() ([]byte, int, error) {
+	for d.scanner.Scan() {
+		line := d.scanner.Bytes()
+		if bytes.HasPrefix(line, datasetSizeMarker) {
+			points, values, err := common.CheckTotalValues(string(line))
+			if err != nil {
+				return nil, 0, err
+			}
+			d.totalPoints, d.totalValues = points, values
+			continue
+		}
+		if d.countValues == nil {
+			return line, 1, nil
+		}
+		return line, d.countValues(line), nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	return nil, 0, io.EOF
+}
+
+func (d *LineDecoder) DatasetSize() (int64, int64) {
+	return d.totalPoints, d.totalValues
+}
+
+// FrameDecoder decodes items framed by their length, as a little endian
+// uint64.
+type FrameDecoder struct {
+	r           *bufio.Reader
+	lenBuf      []byte
+	item        []byte
+	countValues func(item []byte) (int, error)
+}
+
+// NewFrameDecoder returns a FrameDecoder of r. countValues returns the number
+// of values of an item, nil counts one value per item.
+func NewFrameDecoder(r io.Reader, countValues func(item []byte) (int, error)) *FrameDecoder {
+	return &FrameDecoder{
+		r:           bufio.NewReaderSize(r, 4*1024*1024),
+		lenBuf:      make([]byte, 8),
+		countValues: countValues,
+	}
+}
+
+func (d *FrameDecoder) Decode() ([]byte, int, error) {
+	// get the serialized item length (this is the framing format)
+	_, err := io.ReadFull(d.r, d.lenBuf)
+	if err != nil {
+		return nil, 0, err
+	}
+	l := int(binary.LittleEndian.Uint64(d.lenBuf))
+	if cap(d.item) < l {
+		d.item = make([]byte, l)
+	}
+	d.item = d.item[:l]
+	if _, err := io.ReadFull(d.r, d.item); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	if d.countValues == nil {
+		return d.item, 1, nil
+	}
+	values, err := d.countValues(d.item)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot decode item: %s", err.Error())
+	}
+	return d.item, values, nil
+}
+
+// LineEncoder joins items into bodies like Prefix item Separator item ...
+// Suffix.
+type LineEncoder struct {
+	Prefix    []byte
+	Separator []byte
+	Suffix    []byte
+}
+
+// NewlineEncoder joins items with newlines, and ends the body with one.
+var NewlineEncoder = &LineEncoder{Separator: []byte("\n"), Suffix: []byte("\n")}
+
+func (e *LineEncoder) Reset(buf *bytes.Buffer) {
+	buf.Write(e.Prefix)
+}
+
+func (e *LineEncoder) Append(buf *bytes.Buffer, n int, item []byte) bool {
+	if n > 0 {
+		buf.Write(e.Separator)
+	}
+	buf.Write(item)
+	return true
+}
+
+func (e *LineEncoder) Finish(buf *bytes.Buffer, n int) {
+	buf.Write(e.Suffix)
+}
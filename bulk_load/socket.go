@@ -0,0 +1,153 @@
+package bulk_load
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"time"
+)
+
+// SocketWriterConfig is the configuration used to create a SocketWriter.
+type SocketWriterConfig struct {
+	// Address of the listener, in form "example.com:9009", see SocketAddress.
+	Address string
+
+	// Timeout of connecting and of writing a batch, 0 for none.
+	Timeout time.Duration
+
+	// Number of consecutive failed writes after which the writer gives up.
+	MaxReconnects int
+
+	// Debug label for more informative errors.
+	DebugInfo string
+
+	// Read, if not nil, is started on each new connection to read what the
+	// server sends back. It returns a channel closed once it is done
+	// reading, which happens when the connection is closed.
+	Read func(conn net.Conn) <-chan struct{}
+}
+
+// SocketWriter is a Writer that writes the bodies of the batches as they are
+// over a persistent TCP connection, for the line protocols without
+// acknowledgement: a batch is written once the connection took it. When a
+// write fails, the connection is closed and a new one is made before the
+// batch is written again, so lines of the failed write which did reach the
+// server may be written twice.
+type SocketWriter struct {
+	c        SocketWriterConfig
+	conn     net.Conn
+	readDone <-chan struct{}
+
+	failures   int
+	reconnects int64
+	bytes      int64
+}
+
+// NewSocketWriter returns a new SocketWriter from the supplied
+// SocketWriterConfig. The connection is made by the first write.
+func NewSocketWriter(c SocketWriterConfig) *SocketWriter {
+	return &SocketWriter{
+		c: c,
+	}
+}
+
+// Write writes body to the listener described in the Writer's SocketWriterConfig.
+// It returns the latency in nanoseconds and ErrBackoff if the write failed but is worth retrying
+// on a new connection, or the error of the last attempt once MaxReconnects writes failed in a row.
+func (w *SocketWriter) Write(body []byte) (int64, error) {
+	start := time.Now()
+	err := w.write(body)
+	lat := time.Since(start).Nanoseconds()
+	if err == nil {
+		w.failures = 0
+		w.bytes += int64(len(body))
+		return lat, nil
+	}
+
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	w.failures++
+	if w.failures > w.c.MaxReconnects {
+		return lat, fmt.Errorf("[DebugInfo: %s] Write failed %d times in a row: %s", w.c.DebugInfo, w.failures, err.Error())
+	}
+	w.reconnects++
+	log.Printf("[DebugInfo: %s] reconnecting, reason: %s", w.c.DebugInfo, err.Error())
+	return lat, ErrBackoff
+}
+
+// WriteBatch writes the body of b.
+func (w *SocketWriter) WriteBatch(b *Batch) error {
+	_, err := w.Write(b.Buffer.Bytes())
+	return err
+}
+
+func (w *SocketWriter) write(body []byte) error {
+	if w.conn == nil {
+		conn, err := net.DialTimeout("tcp", w.c.Address, w.c.Timeout)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		if w.c.Read != nil {
+			w.readDone = w.c.Read(conn)
+		}
+	}
+	if w.c.Timeout > 0 {
+		if err := w.conn.SetWriteDeadline(time.Now().Add(w.c.Timeout)); err != nil {
+			return err
+		}
+	}
+	_, err := w.conn.Write(body)
+	return err
+}
+
+// Reconnects returns the number of connections made again after a failed
+// write.
+func (w *SocketWriter) Reconnects() int64 {
+	return w.reconnects
+}
+
+// Bytes returns the number of bytes written.
+func (w *SocketWriter) Bytes() int64 {
+	return w.bytes
+}
+
+// Close closes the connection, if any. When the server is read, it first
+// shuts down the writing side and waits, up to the timeout if any, for the
+// server to close the connection, so that its answers to the last lines are
+// read too.
+func (w *SocketWriter) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	if w.c.Read != nil {
+		if tcpConn, ok := w.conn.(*net.TCPConn); ok && tcpConn.CloseWrite() == nil {
+			var timeout <-chan time.Time // nil, never ready, without a timeout
+			if w.c.Timeout > 0 {
+				timeout = time.After(w.c.Timeout)
+			}
+			select {
+			case <-w.readDone:
+			case <-timeout:
+			}
+		}
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// SocketAddress returns the host:port of a daemon URL given with a scheme,
+// such as "tcp://host:port" or "telnet://host:port", or just as "host:port".
+func SocketAddress(daemonUrl string) (string, error) {
+	if u, err := url.Parse(daemonUrl); err == nil && u.Host != "" {
+		daemonUrl = u.Host
+	}
+	if _, _, err := net.SplitHostPort(daemonUrl); err != nil {
+		return "", fmt.Errorf("invalid address %q: %s", daemonUrl, err.Error())
+	}
+	return daemonUrl, nil
+}
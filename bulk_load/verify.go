@@ -0,0 +1,135 @@
+package bulk_load
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/caict-benchmark/BDC-TS/util/report"
+)
+
+// Verifier is implemented by the Drivers of databases whose stored points
+// can be counted, see Verify.
+type Verifier interface {
+	// Measurement returns the measurement of an item of the Decoder.
+	Measurement(item []byte) []byte
+	// CountPoints returns the number of points of measurement stored by the
+	// daemon at url.
+	CountPoints(url, measurement string) (int64, error)
+}
+
+// TimeRange keeps the oldest and the newest timestamps of the items read,
+// for Verifiers counting the points stored in a time range. It is safe for
+// concurrent use by the scans of the inputs.
+type TimeRange struct {
+	mu       sync.Mutex
+	min, max int64
+	set      bool
+}
+
+// Add extends the range to the timestamp ts.
+func (r *TimeRange) Add(ts int64) {
+	r.mu.Lock()
+	if !r.set || ts < r.min {
+		r.min = ts
+	}
+	if !r.set || ts > r.max {
+		r.max = ts
+	}
+	r.set = true
+	r.mu.Unlock()
+}
+
+// Bounds returns the oldest and the newest timestamps added, and false if
+// none was.
+func (r *TimeRange) Bounds() (min, max int64, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.min, r.max, r.set
+}
+
+// Verify checks after a load that the database stored the points read:
+// partial failures, points overwriting each other, or points dropped by
+// the database would otherwise go unnoticed. The points stored of each
+// measurement are compared with the points read, and their total with the
+// dataset size marker.
+type Verify struct {
+	Enabled bool
+	// Delay is waited before counting, for the database to make the last
+	// points visible.
+	Delay time.Duration
+}
+
+// AddFlags registers the flags of the Verify in fs.
+func (v *Verify) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&v.Enabled, "verify", false, "Whether to count the points stored after the load, and compare them per measurement with the points read and with the dataset size marker.")
+	fs.DurationVar(&v.Delay, "verify-delay", 10*time.Second, "Time to wait after the load before --verify counts the stored points.")
+}
+
+// VerifyResult is the outcome of a Verify.
+type VerifyResult struct {
+	ReadPoints   int64
+	StoredPoints int64
+	// ExpectedPoints is the points of the dataset size marker, -1 if unknown.
+	ExpectedPoints int64
+	// Mismatches is the number of measurements whose stored points differ
+	// from the points read, or could not be counted.
+	Mismatches int
+}
+
+// Run waits for Delay, counts with count the stored points of the
+// measurements of read, which holds the points read of each, and prints
+// the mismatches. expected is the points of the dataset size marker, -1 if
+// unknown or if the load did not read all the input.
+func (v *Verify) Run(read map[string]int64, expected int64, count func(measurement string) (int64, error)) *VerifyResult {
+	if v.Delay > 0 {
+		fmt.Printf("verify: waiting %v before counting the stored points\n", v.Delay)
+		time.Sleep(v.Delay)
+	}
+
+	measurements := make([]string, 0, len(read))
+	for m := range read {
+		measurements = append(measurements, m)
+	}
+	sort.Strings(measurements)
+
+	res := &VerifyResult{ExpectedPoints: expected}
+	for _, m := range measurements {
+		res.ReadPoints += read[m]
+		stored, err := count(m)
+		if err != nil {
+			fmt.Printf("verify: measurement %s: cannot count the stored points: %v\n", m, err)
+			res.Mismatches++
+			continue
+		}
+		res.StoredPoints += stored
+		if stored != read[m] {
+			fmt.Printf("verify: measurement %s: stored %d points, read %d (%+d)\n", m, stored, read[m], stored-read[m])
+			res.Mismatches++
+		}
+	}
+
+	fmt.Printf("verify: stored %d points of %d read in %d measurements, %d mismatching\n", res.StoredPoints, res.ReadPoints, len(measurements), res.Mismatches)
+	if expected >= 0 && res.StoredPoints != expected {
+		fmt.Printf("verify: stored %d points, the dataset has %d (%+d)\n", res.StoredPoints, expected, res.StoredPoints-expected)
+	}
+	return res
+}
+
+// ExtraVals returns the report values of the verification.
+func (r *VerifyResult) ExtraVals() []report.ExtraVal {
+	return []report.ExtraVal{
+		{Name: "stored_points", Value: r.StoredPoints},
+		{Name: "verify_mismatches", Value: r.Mismatches},
+	}
+}
+
+// AddErrors adds the mismatches to the errors of the summary s.
+func (r *VerifyResult) AddErrors(s *report.Summary) {
+	s.Errors["verify_mismatches"] = int64(r.Mismatches)
+	if r.ExpectedPoints >= 0 {
+		s.Errors["verify_missing_points"] = r.ExpectedPoints - r.StoredPoints
+	}
+}
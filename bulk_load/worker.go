@@ -0,0 +1,271 @@
+package bulk_load
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/caict-benchmark/BDC-TS/util/report"
+)
+
+type statsMap map[string]*StatGroup
+
+// workerStats counts what a worker wrote. It is only updated by the worker.
+type workerStats struct {
+	batches  int64
+	items    int64
+	values   int64
+	bytes    int64
+	backoffs int64
+	errors   int64
+}
+
+// processBatches reads batches from batchChan and writes them with w, while
+// tracking stats and latencies on the write. Once a write fails, it ends the load and
+// drains batchChan so that scan can finish.
+func (l *Loader) processBatches(w Writer, worker int, backoffSrc chan bool) {
+	var failed error
+	telemetryWorkerLabel := strconv.Itoa(worker)
+	latencies := l.latencies[worker]
+	ws := &l.workerStats[worker]
+
+	defer l.workersGroup.Done()
+	defer w.Close()
+
+	for batch := range l.batchChan {
+		if failed != nil {
+			l.releaseBatch(batch)
+			continue
+		}
+
+		ts := time.Now().UnixNano()
+
+		// Write the batch: try until backoff is not needed, or the retry
+		// policy gives up.
+		if l.DoLoad {
+			var err error
+			size := int64(batch.Buffer.Len())
+			for attempt := 1; ; attempt++ {
+				start := time.Now()
+				err = w.WriteBatch(&batch)
+				took := time.Since(start)
+				latencies.Record(took)
+				if l.Ramp.Enabled() {
+					l.Ramp.recordWrite(took, err)
+				}
+				ws.batches++
+				if !IsRetryable(err) {
+					backoffSrc <- false
+					break
+				}
+				if l.Retry.Exhausted(attempt) {
+					backoffSrc <- false
+					err = fmt.Errorf("giving up after %d attempts: %v", attempt, err)
+					break
+				}
+				ws.backoffs++
+				backoffSrc <- true
+				l.sendBackoffTelemetry(ts, telemetryWorkerLabel)
+				time.Sleep(l.Retry.Delay(attempt))
+			}
+			if err != nil {
+				ws.errors++
+				failed = fmt.Errorf("Error writing: %s\n", err.Error())
+				l.endPrematurely("Worker error", failed)
+				l.releaseBatch(batch)
+				continue
+			}
+			ws.items += int64(batch.Items)
+			ws.values += int64(batch.Values)
+			ws.bytes += size
+			if l.Ramp.Enabled() {
+				l.Ramp.recordBatch(batch.Items, batch.Values)
+			}
+		}
+
+		values := batch.Values
+		l.releaseBatch(batch)
+
+		// Report sent batch statistic
+		stat := l.statPool.Get().(*Stat)
+		stat.Label = []byte(telemetryWorkerLabel)
+		stat.Value = float64(values)
+		l.statChan <- stat
+	}
+}
+
+// releaseBatch returns the buffer of batch to the pool.
+func (l *Loader) releaseBatch(batch Batch) {
+	batch.Buffer.Reset()
+	l.bufPool.Put(batch.Buffer)
+}
+
+// batchSizeHint returns the size of the next batches: under an ingest rate
+// limit, batches start small and grow while the scan does not have to wait
+// for the limit.
+func (l *Loader) batchSizeHint(itemsPerBatch int) int {
+	if !l.Rate.Enabled() || itemsPerBatch >= l.maxBatchSize {
+		return itemsPerBatch
+	}
+	hint := atomic.LoadInt32(&l.speedUpRequest)
+	if hint > atomic.LoadInt32(&l.activeWorkers)*2 { // we should wait for more requests (and this is just a magic number)
+		atomic.StoreInt32(&l.speedUpRequest, 0)
+		itemsPerBatch += int(float32(l.maxBatchSize) * 0.10)
+		if itemsPerBatch > l.maxBatchSize {
+			itemsPerBatch = l.maxBatchSize
+		}
+		log.Printf("Increased batch size to %d\n", itemsPerBatch)
+	}
+	return itemsPerBatch
+}
+
+// sendBackoffTelemetry reports a backoff of a worker, if telemetry is on.
+func (l *Loader) sendBackoffTelemetry(ts int64, telemetryWorkerLabel string) {
+	if l.telemetryChan == nil {
+		return
+	}
+	p := report.GetPointFromGlobalPool()
+	p.Init("benchmarks_telemetry", ts)
+	for _, tagpair := range l.ReportTags {
+		p.AddTag(tagpair[0], tagpair[1])
+	}
+	p.AddTag("client_type", "load")
+	p.AddTag("worker", telemetryWorkerLabel)
+	p.AddBoolField("backoff", true)
+	l.telemetryChan <- p
+}
+
+// processBackoffMessages sums up the backoffs of a worker reported over src,
+// until it is closed.
+func processBackoffMessages(workerId int, src chan bool) float64 {
+	var totalBackoffSecs float64
+	var start time.Time
+	last := false
+	for this := range src {
+		if this && !last {
+			start = time.Now()
+			last = true
+		} else if !this && last {
+			took := time.Now().Sub(start)
+			log.Printf("[worker %d] backoff took %.02fsec\n", workerId, took.Seconds())
+			totalBackoffSecs += took.Seconds()
+			last = false
+			start = time.Now()
+		}
+	}
+	fmt.Printf("[worker %d] backoffs took a total of %fsec of runtime\n", workerId, totalBackoffSecs)
+	return totalBackoffSecs
+}
+
+func (l *Loader) processStats() {
+	var firstStat time.Time
+
+	l.statMapping = statsMap{
+		"*": &StatGroup{},
+	}
+
+	lastRefresh := time.Time{}
+	i := uint64(0)
+	for stat := range l.statChan {
+		now := time.Now()
+		if lastRefresh.Nanosecond() == 0 {
+			log.Print("First statistic report received")
+			lastRefresh = now
+			firstStat = now
+		}
+
+		l.movingAverageStat.Push(now, stat.Value)
+		l.statMapping["*"].Push(stat.Value)
+
+		l.statPool.Put(stat)
+
+		i++
+
+		if now.Sub(lastRefresh).Seconds() >= 1 {
+			workers := int(atomic.LoadInt32(&l.activeWorkers))
+			l.movingAverageStat.UpdateAvg(now, workers)
+			lastRefresh = now
+			// Report telemetry, if applicable:
+			if l.telemetryChan != nil {
+				p := report.GetPointFromGlobalPool()
+				p.Init("benchmarks_telemetry", now.UnixNano())
+				for _, tagpair := range l.ReportTags {
+					p.AddTag(tagpair[0], tagpair[1])
+				}
+				p.AddTag("client_type", "load")
+				p.AddFloat64Field("ingest_rate_mean", l.statMapping["*"].Sum/now.Sub(firstStat).Seconds())
+				p.AddFloat64Field("ingest_rate_moving_mean", l.movingAverageStat.Rate())
+				p.AddIntField("load_workers", workers)
+				l.intervalLatencies.Reset()
+				for _, r := range l.latencies {
+					r.TakeInterval(l.intervalLatencies)
+				}
+				if l.intervalLatencies.Count() > 0 {
+					l.intervalLatencies.AddFields(p, "write_latency")
+				}
+				l.telemetryChan <- p
+			}
+		}
+
+		// print stats to stderr (if printInterval is greater than zero):
+		if l.PrintInterval > 0 && i > 0 && i%l.PrintInterval == 0 {
+			_, err := fmt.Fprintf(os.Stderr, "%s: after %d batches:\n", time.Now().String(), i)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			l.fprintStats(os.Stderr, firstStat)
+			_, err = fmt.Fprintf(os.Stderr, "\n")
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	// the final stats output goes to stdout:
+	_, err := fmt.Printf("run complete after %d batches:\n", i)
+	if err != nil {
+		log.Fatal(err)
+	}
+	l.fprintStats(os.Stdout, firstStat)
+	l.statGroup.Done()
+}
+
+// fprintStats pretty-prints stats to the given writer.
+func (l *Loader) fprintStats(w io.Writer, firstStat time.Time) {
+	maxKeyLength := 0
+	keys := make([]string, 0, len(l.statMapping))
+	for k := range l.statMapping {
+		if len(k) > maxKeyLength {
+			maxKeyLength = len(k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := l.statMapping[k]
+		paddedKey := fmt.Sprintf("%s", k)
+		for len(paddedKey) < maxKeyLength {
+			paddedKey += " "
+		}
+		_, err := fmt.Fprintf(w, "%s : mean: %8.2f/s, moving mean: %8.2f/s, count: %8d, sum: %f \n", paddedKey, v.Sum/time.Now().Sub(firstStat).Seconds(), l.movingAverageStat.Rate(), v.Count, v.Sum)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "write latency : %s\n", l.totalLatencies())
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// totalLatencies returns the write latencies of all the workers.
+func (l *Loader) totalLatencies() *report.Histogram {
+	return report.TotalLatencies(l.latencies)
+}
@@ -0,0 +1,80 @@
+package bcetsdb
+
+import (
+	"encoding/json"
+	"fmt"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// KairosDBQueryPath is the KairosDB query API, also served by BceTSDB.
+const KairosDBQueryPath = "/api/v1/datapoints/query"
+
+// KairosDBQuery is the body of a KairosDB query request. Metrics are named
+// <measurement>.<field>, as written by the opentsdb and opentsdb-telnet
+// formats.
+type KairosDBQuery struct {
+	StartAbsolute int64            `json:"start_absolute"`
+	EndAbsolute   int64            `json:"end_absolute"`
+	Metrics       []KairosDBMetric `json:"metrics"`
+}
+
+type KairosDBMetric struct {
+	Name        string               `json:"name"`
+	Tags        map[string][]string  `json:"tags,omitempty"`
+	GroupBy     []KairosDBGroupBy    `json:"group_by,omitempty"`
+	Aggregators []KairosDBAggregator `json:"aggregators,omitempty"`
+	Limit       int                  `json:"limit,omitempty"`
+}
+
+type KairosDBGroupBy struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+type KairosDBAggregator struct {
+	Name     string           `json:"name"`
+	Sampling KairosDBSampling `json:"sampling"`
+}
+
+type KairosDBSampling struct {
+	Value int    `json:"value"`
+	Unit  string `json:"unit"`
+}
+
+// aggregator returns an aggregator of name over buckets of one unit.
+func aggregator(name, unit string) []KairosDBAggregator {
+	return []KairosDBAggregator{{Name: name, Sampling: KairosDBSampling{Value: 1, Unit: unit}}}
+}
+
+// BceTSDBCommon holds what the KairosDB query generators share.
+type BceTSDBCommon struct {
+	bulkQuerygen.CommonParams
+}
+
+func newBceTSDBCommon(interval bulkQuerygen.TimeInterval, scaleVar int) *BceTSDBCommon {
+	return &BceTSDBCommon{
+		CommonParams: *bulkQuerygen.NewCommonParams(interval, scaleVar),
+	}
+}
+
+// getHttpQuery populates q with a request running metric over interval.
+func (d *BceTSDBCommon) getHttpQuery(humanLabel string, interval bulkQuerygen.TimeInterval, metric KairosDBMetric, q *bulkQuerygen.HTTPQuery) {
+	body, err := json.Marshal(KairosDBQuery{
+		StartAbsolute: interval.StartUnixNano() / 1e6,
+		// end_absolute is inclusive
+		EndAbsolute: interval.EndUnixNano()/1e6 - 1,
+		Metrics:     []KairosDBMetric{metric},
+	})
+	if err != nil {
+		panic(fmt.Sprintf("logic error in encoding query: %s", err))
+	}
+
+	q.HumanLabel = []byte(humanLabel)
+	q.HumanDescription = []byte(fmt.Sprintf("%s: %s", humanLabel, interval.StartString()))
+	q.Method = []byte("POST")
+	q.Path = []byte(KairosDBQueryPath)
+	q.Body = body
+	q.StartTimestamp = interval.StartUnixNano()
+	q.EndTimestamp = interval.EndUnixNano()
+}
@@ -3,19 +3,20 @@ package bcetsdb
 import "time"
 import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
 
+// BceTSDBDevops8Hosts produces KairosDB queries for the devops 8 hosts case.
 type BceTSDBDevops8Hosts struct {
-    BceTSDBDevops
+	BceTSDBDevops
 }
 
 func NewBceTSDBDevops8Hosts(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
-    underlying := newBceTSDBDevopsCommon(queriesFullRange, scaleVar).(*BceTSDBDevops)
-    return &BceTSDBDevops8Hosts{
-        BceTSDBDevops: *underlying,
-    }
+	underlying := newBceTSDBDevopsCommon(queriesFullRange, scaleVar).(*BceTSDBDevops)
+	return &BceTSDBDevops8Hosts{
+		BceTSDBDevops: *underlying,
+	}
 }
 
 func (d *BceTSDBDevops8Hosts) Dispatch(i int) bulkQuerygen.Query {
-    q := bulkQuerygen.NewHTTPQuery() // from pool
-    d.MaxCPUUsageHourByMinuteEightHosts(q)
-    return q
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsageHourByMinuteEightHosts(q)
+	return q
 }
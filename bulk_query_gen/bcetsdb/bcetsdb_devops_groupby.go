@@ -0,0 +1,22 @@
+package bcetsdb
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// BceTSDBDevopsGroupBy produces KairosDB queries for the devops groupby case.
+type BceTSDBDevopsGroupBy struct {
+	BceTSDBDevops
+}
+
+func NewBceTSDBDevopsGroupBy(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newBceTSDBDevopsCommon(queriesFullRange, scaleVar).(*BceTSDBDevops)
+	return &BceTSDBDevopsGroupBy{
+		BceTSDBDevops: *underlying,
+	}
+}
+
+func (d *BceTSDBDevopsGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanCPUUsageDayByHourAllHostsGroupbyHost(q)
+	return q
+}
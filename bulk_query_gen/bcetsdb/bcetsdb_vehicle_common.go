@@ -1,99 +1,83 @@
 package bcetsdb
 
 import (
-	"bytes"
 	"fmt"
-	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
-	"text/template"
 	"time"
-	"io"
-)
 
-var (
-	vehicleRealTimeQuery *template.Template
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
 )
 
-func init() {
-	vehicleRealTimeQuery = template.Must(template.New("vehicleRealTimeQuery").Parse(rawVehicleRealTimeQuery))
-}
-
+// BceTSDBVehicle produces KairosDB queries for the vehicle use case.
 type BceTSDBVehicle struct {
-	bulkQuerygen.CommonParams
+	BceTSDBCommon
 	bulkQuerygen.TimeWindow
+	queryInterval time.Duration
 }
 
 func NewBceTSDBVehicle(interval bulkQuerygen.TimeInterval, scaleVar int, duration time.Duration) bulkQuerygen.QueryGenerator {
 	return &BceTSDBVehicle{
-		CommonParams: *bulkQuerygen.NewCommonParams(interval, scaleVar),
-		TimeWindow:   bulkQuerygen.TimeWindow{interval.Start, time.Second},
+		BceTSDBCommon: *newBceTSDBCommon(interval, scaleVar),
+		TimeWindow:    bulkQuerygen.TimeWindow{interval.Start, time.Second},
+		queryInterval: duration,
 	}
 }
 
 // Dispatch fulfills the QueryGenerator interface.
 func (d *BceTSDBVehicle) Dispatch(i int) bulkQuerygen.Query {
 	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.RealTimeQueries(q)
 	return q
 }
 
-func (d *BceTSDBVehicle) RealTimeQueries(q bulkQuerygen.Query) {
-	// hard code vin, because I don't know how to change it.
-	d.realTimeQueries(q.(*bulkQuerygen.HTTPQuery), time.Second, "LSVNV2182E2100001")
-}
+// RealTimeQueries populates a Query with a query that reads the raw values of
+// all the vehicles in a window, sliding with --time-window-shift:
+// {"metrics": [{"name": "vehicle.value4", "limit": 30000}], ...}
+func (d *BceTSDBVehicle) RealTimeQueries(qi bulkQuerygen.Query) {
+	interval := bulkQuerygen.VehicleRealTimeWindow(&d.TimeWindow, &d.AllInterval)
 
-func (d *BceTSDBVehicle) realTimeQueries(qi bulkQuerygen.Query, timeRange time.Duration, vin string) {
-	var interval bulkQuerygen.TimeInterval
-	if bulkQuerygen.TimeWindowShift > 0 {
-		interval = d.TimeWindow.SlidingWindow(&d.AllInterval)
-	} else {
-		interval = d.AllInterval.RandWindow(d.Duration)
+	metric := KairosDBMetric{
+		Name:  "vehicle." + bulkQuerygen.VehicleValueField,
+		Limit: bulkQuerygen.VehicleRealTimeLimit,
 	}
 
-	body := new(bytes.Buffer)
-	mustExecuteTemplate(vehicleRealTimeQuery, body, VehicleRealTimeParams{
-		Start: interval.StartUnix(),
-		End:   interval.EndUnix(),
-		Size:  30000,
-	})
-
-	humanLabel := []byte(fmt.Sprintf("Elastic real time query, rand %s by 1m", timeRange))
+	humanLabel := fmt.Sprintf("KairosDB real time query, rand %s", d.Duration)
 	q := qi.(*bulkQuerygen.HTTPQuery)
-	q.HumanLabel = humanLabel
-	q.HumanDescription = []byte(fmt.Sprintf("%s: %s", humanLabel, interval.StartString()))
-	q.Method = []byte("POST")
-
-	q.Path = []byte("/vehicle/_search")
-	q.Body = body.Bytes()
+	d.getHttpQuery(humanLabel, interval, metric, q)
 }
 
-type VehicleRealTimeParams struct {
-	//Vin        string
-	Start, End int64
-	Size       int
-}
+// MeanValueGroupByFleetTag populates a Query with a query that looks like:
+// {"metrics": [{"name": "vehicle.value4", "group_by": [{"name": "tag", "tags": ["$FLEET_TAG"]}], "aggregators": [{"name": "avg", "sampling": {"value": 1, "unit": "minutes"}}]}], ...}
+func (d *BceTSDBVehicle) MeanValueGroupByFleetTag(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	tag, _ := bulkQuerygen.RandVehicleFleetGroupByTag()
+
+	metric := KairosDBMetric{
+		Name:        "vehicle." + bulkQuerygen.VehicleValueField,
+		GroupBy:     []KairosDBGroupBy{{Name: "tag", Tags: []string{tag}}},
+		Aggregators: aggregator("avg", "minutes"),
+	}
 
-const rawVehicleRealTimeQuery = `
-{
-  "query": {
-    "bool": {
-      "must": [
-        {
-          "range": {
-            "timestamp": {
-              "gte": "{{.Start}}",
-              "lte": "{{.End}}"
-            }
-          }
-        }
-      ]
-    }
-  },
-  "size": {{.Size}}
+	humanLabel := fmt.Sprintf("KairosDB mean %s, rand %s by 1m, group by %s", bulkQuerygen.VehicleValueField, d.queryInterval, tag)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, metric, q)
 }
-`
 
-func mustExecuteTemplate(t *template.Template, w io.Writer, params interface{}) {
-	err := t.Execute(w, params)
-	if err != nil {
-		panic(fmt.Sprintf("logic error in executing template: %s", err))
+// MaxValueOneManufacturerOneCity populates a Query with a query that looks like:
+// {"metrics": [{"name": "vehicle.value4", "tags": {"manufacturer": ["$MANUFACTURER"], "city": ["$CITY"]}, "aggregators": [{"name": "max", "sampling": {"value": 1, "unit": "minutes"}}]}], ...}
+func (d *BceTSDBVehicle) MaxValueOneManufacturerOneCity(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	manufacturer, city := bulkQuerygen.RandVehicleFleetSlice()
+
+	metric := KairosDBMetric{
+		Name: "vehicle." + bulkQuerygen.VehicleValueField,
+		Tags: map[string][]string{
+			"manufacturer": {manufacturer},
+			"city":         {city},
+		},
+		Aggregators: aggregator("max", "minutes"),
 	}
-}
\ No newline at end of file
+
+	humanLabel := fmt.Sprintf("KairosDB max %s, 1 manufacturer 1 city, rand %s by 1m", bulkQuerygen.VehicleValueField, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, metric, q)
+}
@@ -0,0 +1,40 @@
+package bcetsdb
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// BceTSDBVehicleFleetGroupBy produces KairosDB queries for the vehicle fleet groupby case.
+type BceTSDBVehicleFleetGroupBy struct {
+	BceTSDBVehicle
+}
+
+func NewBceTSDBVehicleFleetGroupBy(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := NewBceTSDBVehicle(queriesFullRange, scaleVar, queryInterval).(*BceTSDBVehicle)
+	return &BceTSDBVehicleFleetGroupBy{
+		BceTSDBVehicle: *underlying,
+	}
+}
+
+func (d *BceTSDBVehicleFleetGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
+
+// BceTSDBVehicleFleetFilter produces KairosDB queries for the vehicle fleet filter case.
+type BceTSDBVehicleFleetFilter struct {
+	BceTSDBVehicle
+}
+
+func NewBceTSDBVehicleFleetFilter(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := NewBceTSDBVehicle(queriesFullRange, scaleVar, queryInterval).(*BceTSDBVehicle)
+	return &BceTSDBVehicleFleetFilter{
+		BceTSDBVehicle: *underlying,
+	}
+}
+
+func (d *BceTSDBVehicleFleetFilter) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxValueOneManufacturerOneCity(q)
+	return q
+}
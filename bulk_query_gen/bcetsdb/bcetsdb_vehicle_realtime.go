@@ -3,6 +3,7 @@ package bcetsdb
 import "time"
 import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
 
+// BceTSDBVehicleRealTime produces KairosDB queries for the vehicle real time case.
 type BceTSDBVehicleRealTime struct {
 	BceTSDBVehicle
 }
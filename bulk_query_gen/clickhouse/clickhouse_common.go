@@ -0,0 +1,47 @@
+package clickhouse
+
+import (
+	"fmt"
+	"net/url"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// ClickHouseCommon holds what the ClickHouse query generators share. Each
+// measurement is a table with a column per tag, the time and a column per
+// field, as created by bulk_load_clickhouse.
+type ClickHouseCommon struct {
+	bulkQuerygen.CommonParams
+	DatabaseName string
+}
+
+func newClickHouseCommon(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, scaleVar int) *ClickHouseCommon {
+	if _, ok := dbConfig[bulkQuerygen.DatabaseName]; !ok {
+		panic("need ClickHouse database name")
+	}
+
+	return &ClickHouseCommon{
+		CommonParams: *bulkQuerygen.NewCommonParams(interval, scaleVar),
+		DatabaseName: dbConfig[bulkQuerygen.DatabaseName],
+	}
+}
+
+// getHttpQuery populates q with an HTTP interface request running sql.
+func (d *ClickHouseCommon) getHttpQuery(humanLabel string, interval bulkQuerygen.TimeInterval, sql string, q *bulkQuerygen.HTTPQuery) {
+	q.HumanLabel = []byte(humanLabel)
+	q.HumanDescription = []byte(fmt.Sprintf("%s: %s", humanLabel, interval.StartString()))
+
+	v := url.Values{}
+	v.Set("database", d.DatabaseName)
+	q.Method = []byte("POST")
+	q.Path = []byte(fmt.Sprintf("/?%s", v.Encode()))
+	q.Body = []byte(sql)
+	q.StartTimestamp = interval.StartUnixNano()
+	q.EndTimestamp = interval.EndUnixNano()
+}
+
+// timeClause filters on the times of interval, the time columns are in UTC.
+func timeClause(interval bulkQuerygen.TimeInterval) string {
+	const layout = "2006-01-02 15:04:05.000"
+	return fmt.Sprintf("time >= '%s' AND time < '%s'", interval.Start.UTC().Format(layout), interval.End.UTC().Format(layout))
+}
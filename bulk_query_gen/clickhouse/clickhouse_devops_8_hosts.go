@@ -0,0 +1,22 @@
+package clickhouse
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// ClickHouseDevops8Hosts produces ClickHouse SQL queries for the devops 8-hosts case.
+type ClickHouseDevops8Hosts struct {
+	ClickHouseDevops
+}
+
+func NewClickHouseDevops8Hosts(dbConfig bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newClickHouseDevopsCommon(dbConfig, queriesFullRange, queryInterval, scaleVar).(*ClickHouseDevops)
+	return &ClickHouseDevops8Hosts{
+		ClickHouseDevops: *underlying,
+	}
+}
+
+func (d *ClickHouseDevops8Hosts) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsageHourByMinuteEightHosts(q)
+	return q
+}
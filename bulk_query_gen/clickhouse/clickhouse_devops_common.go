@@ -0,0 +1,89 @@
+package clickhouse
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// ClickHouseDevops produces ClickHouse SQL queries for all the devops query types.
+type ClickHouseDevops struct {
+	ClickHouseCommon
+}
+
+// NewClickHouseDevops makes a ClickHouseDevops object ready to generate Queries.
+func newClickHouseDevopsCommon(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	return &ClickHouseDevops{
+		ClickHouseCommon: *newClickHouseCommon(dbConfig, interval, scaleVar),
+	}
+}
+
+// Dispatch fulfills the QueryGenerator interface.
+func (d *ClickHouseDevops) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	bulkQuerygen.DevopsDispatchAll(d, i, q, d.ScaleVar)
+	return q
+}
+
+func (d *ClickHouseDevops) MaxCPUUsageHourByMinuteOneHost(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 1, time.Hour)
+}
+
+func (d *ClickHouseDevops) MaxCPUUsageHourByMinuteTwoHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 2, time.Hour)
+}
+
+func (d *ClickHouseDevops) MaxCPUUsageHourByMinuteFourHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 4, time.Hour)
+}
+
+func (d *ClickHouseDevops) MaxCPUUsageHourByMinuteEightHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 8, time.Hour)
+}
+
+func (d *ClickHouseDevops) MaxCPUUsageHourByMinuteSixteenHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 16, time.Hour)
+}
+
+func (d *ClickHouseDevops) MaxCPUUsageHourByMinuteThirtyTwoHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 32, time.Hour)
+}
+
+func (d *ClickHouseDevops) MaxCPUUsage12HoursByMinuteOneHost(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 1, 12*time.Hour)
+}
+
+// maxCPUUsageHourByMinuteNHosts populates a Query with a query that looks like:
+// SELECT toStartOfMinute(time) AS minute, max(usage_user) FROM cpu WHERE hostname IN ('$HOSTNAME_1',...,'$HOSTNAME_N') AND time >= '$HOUR_START' AND time < '$HOUR_END' GROUP BY minute ORDER BY minute
+func (d *ClickHouseDevops) maxCPUUsageHourByMinuteNHosts(qi bulkQuerygen.Query, nhosts int, timeRange time.Duration) {
+	interval := d.AllInterval.RandWindow(timeRange)
+	nn := rand.Perm(d.ScaleVar)[:nhosts]
+
+	hostnames := []string{}
+	for _, n := range nn {
+		hostnames = append(hostnames, fmt.Sprintf("'host_%d'", n))
+	}
+
+	combinedHostnameClause := strings.Join(hostnames, ",")
+
+	sql := fmt.Sprintf("SELECT toStartOfMinute(time) AS minute, max(usage_user) FROM cpu WHERE hostname IN (%s) AND %s GROUP BY minute ORDER BY minute", combinedHostnameClause, timeClause(interval))
+
+	humanLabel := fmt.Sprintf("ClickHouse max cpu, rand %4d hosts, rand %s by 1m", nhosts, timeRange)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
+
+// MeanCPUUsageDayByHourAllHostsGroupbyHost populates a Query with a query that looks like:
+// SELECT toStartOfHour(time) AS hour, hostname, avg(usage_user) FROM cpu WHERE time >= '$DAY_START' AND time < '$DAY_END' GROUP BY hour, hostname ORDER BY hour, hostname
+func (d *ClickHouseDevops) MeanCPUUsageDayByHourAllHostsGroupbyHost(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(24 * time.Hour)
+
+	sql := fmt.Sprintf("SELECT toStartOfHour(time) AS hour, hostname, avg(usage_user) FROM cpu WHERE %s GROUP BY hour, hostname ORDER BY hour, hostname", timeClause(interval))
+
+	humanLabel := "ClickHouse mean cpu, all hosts, rand 1day by 1hour"
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
@@ -0,0 +1,22 @@
+package clickhouse
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// ClickHouseDevopsGroupBy produces ClickHouse SQL queries for the devops groupby case.
+type ClickHouseDevopsGroupBy struct {
+	ClickHouseDevops
+}
+
+func NewClickHouseDevopsGroupBy(dbConfig bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newClickHouseDevopsCommon(dbConfig, queriesFullRange, queryInterval, scaleVar).(*ClickHouseDevops)
+	return &ClickHouseDevopsGroupBy{
+		ClickHouseDevops: *underlying,
+	}
+}
+
+func (d *ClickHouseDevopsGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanCPUUsageDayByHourAllHostsGroupbyHost(q)
+	return q
+}
@@ -0,0 +1,22 @@
+package clickhouse
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// ClickHouseDevopsSingleHost produces ClickHouse SQL queries for the devops single-host case.
+type ClickHouseDevopsSingleHost struct {
+	ClickHouseDevops
+}
+
+func NewClickHouseDevopsSingleHost(dbConfig bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newClickHouseDevopsCommon(dbConfig, queriesFullRange, queryInterval, scaleVar).(*ClickHouseDevops)
+	return &ClickHouseDevopsSingleHost{
+		ClickHouseDevops: *underlying,
+	}
+}
+
+func (d *ClickHouseDevopsSingleHost) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsageHourByMinuteOneHost(q)
+	return q
+}
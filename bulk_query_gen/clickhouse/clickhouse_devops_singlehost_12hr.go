@@ -0,0 +1,22 @@
+package clickhouse
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// ClickHouseDevopsSingleHost12hr produces ClickHouse SQL queries for the devops single-host case over a 12hr period.
+type ClickHouseDevopsSingleHost12hr struct {
+	ClickHouseDevops
+}
+
+func NewClickHouseDevopsSingleHost12hr(dbConfig bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newClickHouseDevopsCommon(dbConfig, queriesFullRange, queryInterval, scaleVar).(*ClickHouseDevops)
+	return &ClickHouseDevopsSingleHost12hr{
+		ClickHouseDevops: *underlying,
+	}
+}
+
+func (d *ClickHouseDevopsSingleHost12hr) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsage12HoursByMinuteOneHost(q)
+	return q
+}
@@ -0,0 +1,54 @@
+package clickhouse
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// ClickHouseVehicle produces ClickHouse SQL queries for the vehicle use case.
+type ClickHouseVehicle struct {
+	ClickHouseCommon
+	queryInterval time.Duration
+}
+
+func newClickHouseVehicleCommon(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	return &ClickHouseVehicle{
+		ClickHouseCommon: *newClickHouseCommon(dbConfig, interval, scaleVar),
+		queryInterval:    duration,
+	}
+}
+
+// Dispatch fulfills the QueryGenerator interface.
+func (d *ClickHouseVehicle) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
+
+// MeanValueGroupByFleetTag populates a Query with a query that looks like:
+// SELECT toStartOfMinute(time) AS minute, $FLEET_TAG, avg(value4) FROM vehicle WHERE time >= '$START' AND time < '$END' GROUP BY minute, $FLEET_TAG ORDER BY minute, $FLEET_TAG
+func (d *ClickHouseVehicle) MeanValueGroupByFleetTag(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	tag, _ := bulkQuerygen.RandVehicleFleetGroupByTag()
+
+	sql := fmt.Sprintf("SELECT toStartOfMinute(time) AS minute, %s, avg(%s) FROM vehicle WHERE %s GROUP BY minute, %s ORDER BY minute, %s", tag, bulkQuerygen.VehicleValueField, timeClause(interval), tag, tag)
+
+	humanLabel := fmt.Sprintf("ClickHouse mean %s, rand %s by 1m, group by %s", bulkQuerygen.VehicleValueField, d.queryInterval, tag)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
+
+// MaxValueOneManufacturerOneCity populates a Query with a query that looks like:
+// SELECT toStartOfMinute(time) AS minute, max(value4) FROM vehicle WHERE manufacturer = '$MANUFACTURER' AND city = '$CITY' AND time >= '$START' AND time < '$END' GROUP BY minute ORDER BY minute
+func (d *ClickHouseVehicle) MaxValueOneManufacturerOneCity(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	manufacturer, city := bulkQuerygen.RandVehicleFleetSlice()
+
+	sql := fmt.Sprintf("SELECT toStartOfMinute(time) AS minute, max(%s) FROM vehicle WHERE manufacturer = '%s' AND city = '%s' AND %s GROUP BY minute ORDER BY minute", bulkQuerygen.VehicleValueField, manufacturer, city, timeClause(interval))
+
+	humanLabel := fmt.Sprintf("ClickHouse max %s, 1 manufacturer 1 city, rand %s by 1m", bulkQuerygen.VehicleValueField, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
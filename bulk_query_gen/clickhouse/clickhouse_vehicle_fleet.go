@@ -0,0 +1,40 @@
+package clickhouse
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// ClickHouseVehicleFleetGroupBy produces ClickHouse SQL queries for the vehicle fleet groupby case.
+type ClickHouseVehicleFleetGroupBy struct {
+	ClickHouseVehicle
+}
+
+func NewClickHouseVehicleFleetGroupBy(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newClickHouseVehicleCommon(dbConfig, interval, duration, scaleVar).(*ClickHouseVehicle)
+	return &ClickHouseVehicleFleetGroupBy{
+		ClickHouseVehicle: *underlying,
+	}
+}
+
+func (d *ClickHouseVehicleFleetGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
+
+// ClickHouseVehicleFleetFilter produces ClickHouse SQL queries for the vehicle fleet filter case.
+type ClickHouseVehicleFleetFilter struct {
+	ClickHouseVehicle
+}
+
+func NewClickHouseVehicleFleetFilter(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newClickHouseVehicleCommon(dbConfig, interval, duration, scaleVar).(*ClickHouseVehicle)
+	return &ClickHouseVehicleFleetFilter{
+		ClickHouseVehicle: *underlying,
+	}
+}
+
+func (d *ClickHouseVehicleFleetFilter) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxValueOneManufacturerOneCity(q)
+	return q
+}
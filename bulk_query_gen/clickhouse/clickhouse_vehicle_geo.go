@@ -0,0 +1,107 @@
+package clickhouse
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// ClickHouseVehicleGeoBoundingBox produces ClickHouse SQL queries for the vehicle bounding box case.
+type ClickHouseVehicleGeoBoundingBox struct {
+	ClickHouseVehicle
+}
+
+func NewClickHouseVehicleGeoBoundingBox(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newClickHouseVehicleCommon(dbConfig, interval, duration, scaleVar).(*ClickHouseVehicle)
+	return &ClickHouseVehicleGeoBoundingBox{
+		ClickHouseVehicle: *underlying,
+	}
+}
+
+func (d *ClickHouseVehicleGeoBoundingBox) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueInBoundingBox(q)
+	return q
+}
+
+// ClickHouseVehicleGeoRadius produces ClickHouse SQL queries for the vehicle radius case.
+type ClickHouseVehicleGeoRadius struct {
+	ClickHouseVehicle
+}
+
+func NewClickHouseVehicleGeoRadius(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newClickHouseVehicleCommon(dbConfig, interval, duration, scaleVar).(*ClickHouseVehicle)
+	return &ClickHouseVehicleGeoRadius{
+		ClickHouseVehicle: *underlying,
+	}
+}
+
+func (d *ClickHouseVehicleGeoRadius) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueInRadius(q)
+	return q
+}
+
+// ClickHouseVehicleGeoAreaWindow produces ClickHouse SQL queries for the vehicles in area during window case.
+type ClickHouseVehicleGeoAreaWindow struct {
+	ClickHouseVehicle
+}
+
+func NewClickHouseVehicleGeoAreaWindow(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newClickHouseVehicleCommon(dbConfig, interval, duration, scaleVar).(*ClickHouseVehicle)
+	return &ClickHouseVehicleGeoAreaWindow{
+		ClickHouseVehicle: *underlying,
+	}
+}
+
+func (d *ClickHouseVehicleGeoAreaWindow) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.VehiclesInAreaDuringWindow(q)
+	return q
+}
+
+// geoBoxClause filters on the latitude and longitude columns.
+func geoBoxClause(box bulkQuerygen.GeoBox) string {
+	return fmt.Sprintf("latitude BETWEEN %f AND %f AND longitude BETWEEN %f AND %f", box.Bottom, box.Top, box.Left, box.Right)
+}
+
+// MeanValueInBoundingBox populates a Query with a query that looks like:
+// SELECT toStartOfMinute(time) AS minute, avg(value4) FROM vehicle WHERE latitude BETWEEN $SOUTH AND $NORTH AND longitude BETWEEN $WEST AND $EAST AND time >= '$START' AND time < '$END' GROUP BY minute ORDER BY minute
+func (d *ClickHouseVehicle) MeanValueInBoundingBox(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	sql := fmt.Sprintf("SELECT toStartOfMinute(time) AS minute, avg(%s) FROM vehicle WHERE %s AND %s GROUP BY minute ORDER BY minute", bulkQuerygen.VehicleValueField, geoBoxClause(box), timeClause(interval))
+
+	humanLabel := fmt.Sprintf("ClickHouse mean %s, rand %.0fm box, rand %s by 1m", bulkQuerygen.VehicleValueField, 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
+
+// MeanValueInRadius populates a Query with a query that looks like:
+// SELECT toStartOfMinute(time) AS minute, avg(value4) FROM vehicle WHERE greatCircleDistance(longitude, latitude, $LON, $LAT) <= $RADIUS AND time >= '$START' AND time < '$END' GROUP BY minute ORDER BY minute
+func (d *ClickHouseVehicle) MeanValueInRadius(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	center := bulkQuerygen.RandVehicleGeoCenter()
+
+	sql := fmt.Sprintf("SELECT toStartOfMinute(time) AS minute, avg(%s) FROM vehicle WHERE greatCircleDistance(longitude, latitude, %f, %f) <= %.0f AND %s GROUP BY minute ORDER BY minute",
+		bulkQuerygen.VehicleValueField, center.Lon, center.Lat, bulkQuerygen.VehicleGeoQueryRadius, timeClause(interval))
+
+	humanLabel := fmt.Sprintf("ClickHouse mean %s, rand %.0fm radius, rand %s by 1m", bulkQuerygen.VehicleValueField, bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
+
+// VehiclesInAreaDuringWindow populates a Query with a query that looks like:
+// SELECT uniqExact(VIN) FROM vehicle WHERE latitude BETWEEN $SOUTH AND $NORTH AND longitude BETWEEN $WEST AND $EAST AND time >= '$START' AND time < '$END'
+func (d *ClickHouseVehicle) VehiclesInAreaDuringWindow(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	sql := fmt.Sprintf("SELECT uniqExact(VIN) FROM vehicle WHERE %s AND %s", geoBoxClause(box), timeClause(interval))
+
+	humanLabel := fmt.Sprintf("ClickHouse vehicles in rand %.0fm box, rand %s", 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
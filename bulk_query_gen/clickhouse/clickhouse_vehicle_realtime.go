@@ -0,0 +1,41 @@
+package clickhouse
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// ClickHouseVehicleRealTime produces ClickHouse SQL queries for the vehicle real time case.
+type ClickHouseVehicleRealTime struct {
+	ClickHouseVehicle
+	bulkQuerygen.TimeWindow
+}
+
+func NewClickHouseVehicleRealTime(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newClickHouseVehicleCommon(dbConfig, interval, duration, scaleVar).(*ClickHouseVehicle)
+	return &ClickHouseVehicleRealTime{
+		ClickHouseVehicle: *underlying,
+		TimeWindow:        bulkQuerygen.TimeWindow{Start: interval.Start, Duration: time.Second},
+	}
+}
+
+func (d *ClickHouseVehicleRealTime) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.RealTimeQueries(q)
+	return q
+}
+
+// RealTimeQueries populates a Query with a query that reads the raw values of
+// all the vehicles in a window, sliding with --time-window-shift:
+// SELECT time, VIN, value4 FROM vehicle WHERE time >= '$START' AND time < '$END' LIMIT 30000
+func (d *ClickHouseVehicleRealTime) RealTimeQueries(qi bulkQuerygen.Query) {
+	interval := bulkQuerygen.VehicleRealTimeWindow(&d.TimeWindow, &d.AllInterval)
+
+	sql := fmt.Sprintf("SELECT time, VIN, %s FROM vehicle WHERE %s LIMIT %d", bulkQuerygen.VehicleValueField, timeClause(interval), bulkQuerygen.VehicleRealTimeLimit)
+
+	humanLabel := fmt.Sprintf("ClickHouse real time query, rand %s", d.Duration)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
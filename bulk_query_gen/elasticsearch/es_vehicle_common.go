@@ -20,13 +20,15 @@ func init() {
 type ElasticSearchVehicle struct {
 	bulkQuerygen.CommonParams
 	bulkQuerygen.TimeWindow
+	queryInterval time.Duration
 }
 
 // NewElasticSearchDevops makes an ElasticSearchDevops object ready to generate Queries.
 func NewElasticSearchVehicle(interval bulkQuerygen.TimeInterval, scaleVar int, duration time.Duration) bulkQuerygen.QueryGenerator {
 	return &ElasticSearchVehicle{
-		CommonParams: *bulkQuerygen.NewCommonParams(interval, scaleVar),
-		TimeWindow:   bulkQuerygen.TimeWindow{interval.Start, time.Second},
+		CommonParams:  *bulkQuerygen.NewCommonParams(interval, scaleVar),
+		TimeWindow:    bulkQuerygen.TimeWindow{interval.Start, time.Second},
+		queryInterval: duration,
 	}
 }
 
@@ -39,7 +41,7 @@ func (d *ElasticSearchVehicle) Dispatch(i int) bulkQuerygen.Query {
 
 func (d *ElasticSearchVehicle) RealTimeQueries(q bulkQuerygen.Query) {
 	// hard code vin, because I don't know how to change it.
-	d.realTimeQueries(q.(*bulkQuerygen.HTTPQuery), time.Second, "LSVNV2182E2100001")
+	d.realTimeQueries(q.(*bulkQuerygen.HTTPQuery), time.Second, "LSVNV2187E2100001")
 }
 
 func (d *ElasticSearchVehicle) realTimeQueries(qi bulkQuerygen.Query, timeRange time.Duration, vin string) {
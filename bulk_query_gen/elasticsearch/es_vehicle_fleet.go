@@ -0,0 +1,193 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"fmt"
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+	"text/template"
+	"time"
+)
+
+var (
+	vehicleFleetGroupByQuery, vehicleFleetFilterQuery *template.Template
+)
+
+func init() {
+	vehicleFleetGroupByQuery = template.Must(template.New("vehicleFleetGroupByQuery").Parse(rawVehicleFleetGroupByQuery))
+	vehicleFleetFilterQuery = template.Must(template.New("vehicleFleetFilterQuery").Parse(rawVehicleFleetFilterQuery))
+}
+
+// ElasticSearchVehicleFleetGroupBy produces ES-specific queries for the vehicle fleet groupby case.
+type ElasticSearchVehicleFleetGroupBy struct {
+	ElasticSearchVehicle
+}
+
+func NewElasticSearchVehicleFleetGroupBy(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := NewElasticSearchVehicle(queriesFullRange, scaleVar, queryInterval).(*ElasticSearchVehicle)
+	return &ElasticSearchVehicleFleetGroupBy{
+		ElasticSearchVehicle: *underlying,
+	}
+}
+
+func (d *ElasticSearchVehicleFleetGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
+
+// ElasticSearchVehicleFleetFilter produces ES-specific queries for the vehicle fleet filter case.
+type ElasticSearchVehicleFleetFilter struct {
+	ElasticSearchVehicle
+}
+
+func NewElasticSearchVehicleFleetFilter(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := NewElasticSearchVehicle(queriesFullRange, scaleVar, queryInterval).(*ElasticSearchVehicle)
+	return &ElasticSearchVehicleFleetFilter{
+		ElasticSearchVehicle: *underlying,
+	}
+}
+
+func (d *ElasticSearchVehicleFleetFilter) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxValueOneManufacturerOneCity(q)
+	return q
+}
+
+// MeanValueGroupByFleetTag populates a Query for getting the mean value per
+// minute of every value of a randomly chosen fleet tag.
+func (d *ElasticSearchVehicle) MeanValueGroupByFleetTag(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	tag, cardinality := bulkQuerygen.RandVehicleFleetGroupByTag()
+
+	body := new(bytes.Buffer)
+	mustExecuteTemplate(vehicleFleetGroupByQuery, body, VehicleFleetQueryParams{
+		Start:    interval.StartString(),
+		End:      interval.EndString(),
+		Bucket:   "1m",
+		Field:    bulkQuerygen.VehicleValueField,
+		Tag:      tag,
+		TagCount: cardinality,
+	})
+
+	humanLabel := []byte(fmt.Sprintf("Elastic mean %s, rand %s by 1m, group by %s", bulkQuerygen.VehicleValueField, d.queryInterval, tag))
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	q.HumanLabel = humanLabel
+	q.HumanDescription = []byte(fmt.Sprintf("%s: %s", humanLabel, interval.StartString()))
+	q.Method = []byte("POST")
+
+	q.Path = []byte("/vehicle/_search")
+	q.Body = body.Bytes()
+}
+
+// MaxValueOneManufacturerOneCity populates a Query for getting the maximum
+// value per minute of the vehicles of one manufacturer in one city.
+func (d *ElasticSearchVehicle) MaxValueOneManufacturerOneCity(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	manufacturer, city := bulkQuerygen.RandVehicleFleetSlice()
+
+	body := new(bytes.Buffer)
+	mustExecuteTemplate(vehicleFleetFilterQuery, body, VehicleFleetQueryParams{
+		Start:        interval.StartString(),
+		End:          interval.EndString(),
+		Bucket:       "1m",
+		Field:        bulkQuerygen.VehicleValueField,
+		Manufacturer: manufacturer,
+		City:         city,
+	})
+
+	humanLabel := []byte(fmt.Sprintf("Elastic max %s, 1 manufacturer 1 city, rand %s by 1m", bulkQuerygen.VehicleValueField, d.queryInterval))
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	q.HumanLabel = humanLabel
+	q.HumanDescription = []byte(fmt.Sprintf("%s: %s", humanLabel, interval.StartString()))
+	q.Method = []byte("POST")
+
+	q.Path = []byte("/vehicle/_search")
+	q.Body = body.Bytes()
+}
+
+type VehicleFleetQueryParams struct {
+	Start, End, Bucket, Field string
+	Tag                       string
+	TagCount                  int
+	Manufacturer, City        string
+}
+
+const rawVehicleFleetGroupByQuery = `
+{
+  "size": 0,
+  "query": {
+    "bool": {
+      "filter": {
+        "range": {
+          "timestamp": {
+            "gte": "{{.Start}}",
+            "lt": "{{.End}}"
+          }
+        }
+      }
+    }
+  },
+  "aggs": {
+    "by_tag": {
+      "terms": {
+        "size": {{.TagCount}},
+        "field": "{{.Tag}}"
+      },
+      "aggs": {
+        "result2": {
+          "date_histogram": {
+            "field": "timestamp",
+            "interval": "{{.Bucket}}",
+            "format": "yyyy-MM-dd-HH:mm"
+          },
+          "aggs": {
+            "avg_of_field": {
+              "avg": {
+                "field": "{{.Field}}"
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+const rawVehicleFleetFilterQuery = `
+{
+  "size": 0,
+  "query": {
+    "bool": {
+      "filter": [
+        {
+          "range": {
+            "timestamp": {
+              "gte": "{{.Start}}",
+              "lt": "{{.End}}"
+            }
+          }
+        },
+        { "term": { "manufacturer": "{{.Manufacturer}}" } },
+        { "term": { "city": "{{.City}}" } }
+      ]
+    }
+  },
+  "aggs": {
+    "result2": {
+      "date_histogram": {
+        "field": "timestamp",
+        "interval": "{{.Bucket}}",
+        "format": "yyyy-MM-dd-HH:mm"
+      },
+      "aggs": {
+        "max_of_field": {
+          "max": {
+            "field": "{{.Field}}"
+          }
+        }
+      }
+    }
+  }
+}
+`
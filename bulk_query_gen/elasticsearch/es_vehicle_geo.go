@@ -0,0 +1,275 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"fmt"
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+	"text/template"
+	"time"
+)
+
+var (
+	vehicleGeoBoundingBoxQuery, vehicleGeoRadiusQuery, vehicleGeoAreaWindowQuery *template.Template
+)
+
+func init() {
+	vehicleGeoBoundingBoxQuery = template.Must(template.New("vehicleGeoBoundingBoxQuery").Parse(rawVehicleGeoBoundingBoxQuery))
+	vehicleGeoRadiusQuery = template.Must(template.New("vehicleGeoRadiusQuery").Parse(rawVehicleGeoRadiusQuery))
+	vehicleGeoAreaWindowQuery = template.Must(template.New("vehicleGeoAreaWindowQuery").Parse(rawVehicleGeoAreaWindowQuery))
+}
+
+// ElasticSearchVehicleGeoBoundingBox produces ES-specific queries for the vehicle bounding box case.
+type ElasticSearchVehicleGeoBoundingBox struct {
+	ElasticSearchVehicle
+}
+
+func NewElasticSearchVehicleGeoBoundingBox(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := NewElasticSearchVehicle(queriesFullRange, scaleVar, queryInterval).(*ElasticSearchVehicle)
+	return &ElasticSearchVehicleGeoBoundingBox{
+		ElasticSearchVehicle: *underlying,
+	}
+}
+
+func (d *ElasticSearchVehicleGeoBoundingBox) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueInBoundingBox(q)
+	return q
+}
+
+// ElasticSearchVehicleGeoRadius produces ES-specific queries for the vehicle radius case.
+type ElasticSearchVehicleGeoRadius struct {
+	ElasticSearchVehicle
+}
+
+func NewElasticSearchVehicleGeoRadius(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := NewElasticSearchVehicle(queriesFullRange, scaleVar, queryInterval).(*ElasticSearchVehicle)
+	return &ElasticSearchVehicleGeoRadius{
+		ElasticSearchVehicle: *underlying,
+	}
+}
+
+func (d *ElasticSearchVehicleGeoRadius) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueInRadius(q)
+	return q
+}
+
+// ElasticSearchVehicleGeoAreaWindow produces ES-specific queries for the vehicles in area during window case.
+type ElasticSearchVehicleGeoAreaWindow struct {
+	ElasticSearchVehicle
+}
+
+func NewElasticSearchVehicleGeoAreaWindow(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := NewElasticSearchVehicle(queriesFullRange, scaleVar, queryInterval).(*ElasticSearchVehicle)
+	return &ElasticSearchVehicleGeoAreaWindow{
+		ElasticSearchVehicle: *underlying,
+	}
+}
+
+func (d *ElasticSearchVehicleGeoAreaWindow) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.VehiclesInAreaDuringWindow(q)
+	return q
+}
+
+// MeanValueInBoundingBox populates a Query for getting the mean value per
+// minute of the vehicles inside a random bounding box.
+func (d *ElasticSearchVehicle) MeanValueInBoundingBox(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	body := new(bytes.Buffer)
+	mustExecuteTemplate(vehicleGeoBoundingBoxQuery, body, VehicleGeoQueryParams{
+		Start:  interval.StartString(),
+		End:    interval.EndString(),
+		Bucket: "1m",
+		Field:  bulkQuerygen.VehicleValueField,
+		Box:    box,
+	})
+
+	humanLabel := []byte(fmt.Sprintf("Elastic mean %s, rand %.0fm box, rand %s by 1m", bulkQuerygen.VehicleValueField, 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval))
+	d.fillVehicleQuery(qi, humanLabel, interval, body)
+}
+
+// MeanValueInRadius populates a Query for getting the mean value per minute
+// of the vehicles within a random circle.
+func (d *ElasticSearchVehicle) MeanValueInRadius(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	center := bulkQuerygen.RandVehicleGeoCenter()
+
+	body := new(bytes.Buffer)
+	mustExecuteTemplate(vehicleGeoRadiusQuery, body, VehicleGeoQueryParams{
+		Start:  interval.StartString(),
+		End:    interval.EndString(),
+		Bucket: "1m",
+		Field:  bulkQuerygen.VehicleValueField,
+		Lat:    center.Lat,
+		Lon:    center.Lon,
+		Radius: fmt.Sprintf("%.0fm", bulkQuerygen.VehicleGeoQueryRadius),
+	})
+
+	humanLabel := []byte(fmt.Sprintf("Elastic mean %s, rand %.0fm radius, rand %s by 1m", bulkQuerygen.VehicleValueField, bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval))
+	d.fillVehicleQuery(qi, humanLabel, interval, body)
+}
+
+// VehiclesInAreaDuringWindow populates a Query for getting the vehicles that
+// were inside a random bounding box during a random time window.
+func (d *ElasticSearchVehicle) VehiclesInAreaDuringWindow(qi bulkQuerygen.Query) {
+	if d.ScaleVar > 10000 {
+		panic("scaleVar > 10000 implies size > 10000, which is not supported on elasticsearch. see https://www.elastic.co/guide/en/elasticsearch/reference/current/search-request-from-size.html")
+	}
+
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	body := new(bytes.Buffer)
+	mustExecuteTemplate(vehicleGeoAreaWindowQuery, body, VehicleGeoQueryParams{
+		Start:    interval.StartString(),
+		End:      interval.EndString(),
+		Box:      box,
+		VinCount: d.ScaleVar,
+	})
+
+	humanLabel := []byte(fmt.Sprintf("Elastic vehicles in rand %.0fm box, rand %s", 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval))
+	d.fillVehicleQuery(qi, humanLabel, interval, body)
+}
+
+func (d *ElasticSearchVehicle) fillVehicleQuery(qi bulkQuerygen.Query, humanLabel []byte, interval bulkQuerygen.TimeInterval, body *bytes.Buffer) {
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	q.HumanLabel = humanLabel
+	q.HumanDescription = []byte(fmt.Sprintf("%s: %s", humanLabel, interval.StartString()))
+	q.Method = []byte("POST")
+
+	q.Path = []byte("/vehicle/_search")
+	q.Body = body.Bytes()
+}
+
+type VehicleGeoQueryParams struct {
+	Start, End, Bucket, Field string
+	Box                       bulkQuerygen.GeoBox
+	Lat, Lon                  float64
+	Radius                    string
+	VinCount                  int
+}
+
+const rawVehicleGeoBoundingBoxQuery = `
+{
+  "size": 0,
+  "query": {
+    "bool": {
+      "filter": [
+        {
+          "range": {
+            "timestamp": {
+              "gte": "{{.Start}}",
+              "lt": "{{.End}}"
+            }
+          }
+        },
+        {
+          "geo_bounding_box": {
+            "location": {
+              "top_left": { "lat": {{.Box.Top}}, "lon": {{.Box.Left}} },
+              "bottom_right": { "lat": {{.Box.Bottom}}, "lon": {{.Box.Right}} }
+            }
+          }
+        }
+      ]
+    }
+  },
+  "aggs": {
+    "result2": {
+      "date_histogram": {
+        "field": "timestamp",
+        "interval": "{{.Bucket}}",
+        "format": "yyyy-MM-dd-HH:mm"
+      },
+      "aggs": {
+        "avg_of_field": {
+          "avg": {
+            "field": "{{.Field}}"
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+const rawVehicleGeoRadiusQuery = `
+{
+  "size": 0,
+  "query": {
+    "bool": {
+      "filter": [
+        {
+          "range": {
+            "timestamp": {
+              "gte": "{{.Start}}",
+              "lt": "{{.End}}"
+            }
+          }
+        },
+        {
+          "geo_distance": {
+            "distance": "{{.Radius}}",
+            "location": { "lat": {{.Lat}}, "lon": {{.Lon}} }
+          }
+        }
+      ]
+    }
+  },
+  "aggs": {
+    "result2": {
+      "date_histogram": {
+        "field": "timestamp",
+        "interval": "{{.Bucket}}",
+        "format": "yyyy-MM-dd-HH:mm"
+      },
+      "aggs": {
+        "avg_of_field": {
+          "avg": {
+            "field": "{{.Field}}"
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+const rawVehicleGeoAreaWindowQuery = `
+{
+  "size": 0,
+  "query": {
+    "bool": {
+      "filter": [
+        {
+          "range": {
+            "timestamp": {
+              "gte": "{{.Start}}",
+              "lt": "{{.End}}"
+            }
+          }
+        },
+        {
+          "geo_bounding_box": {
+            "location": {
+              "top_left": { "lat": {{.Box.Top}}, "lon": {{.Box.Left}} },
+              "bottom_right": { "lat": {{.Box.Bottom}}, "lon": {{.Box.Right}} }
+            }
+          }
+        }
+      ]
+    }
+  },
+  "aggs": {
+    "by_vin": {
+      "terms": {
+        "size": {{.VinCount}},
+        "field": "VIN"
+      }
+    }
+  }
+}
+`
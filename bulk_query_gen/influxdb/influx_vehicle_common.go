@@ -0,0 +1,87 @@
+package influxdb
+
+import (
+	"fmt"
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+	"time"
+)
+
+// InfluxVehicle produces Influx-specific queries for the vehicle use case.
+type InfluxVehicle struct {
+	InfluxCommon
+	queryInterval time.Duration
+}
+
+func newInfluxVehicleCommon(lang Language, dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	if _, ok := dbConfig[bulkQuerygen.DatabaseName]; !ok {
+		panic("need influx database name")
+	}
+
+	return &InfluxVehicle{
+		InfluxCommon:  *newInfluxCommon(lang, dbConfig[bulkQuerygen.DatabaseName], interval, scaleVar),
+		queryInterval: duration,
+	}
+}
+
+// Dispatch fulfills the QueryGenerator interface.
+func (d *InfluxVehicle) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
+
+// MeanValueGroupByFleetTag populates a Query with a query that looks like:
+// SELECT mean(value4) from vehicle where time >= '$START' and time < '$END' group by time(1m),$FLEET_TAG
+func (d *InfluxVehicle) MeanValueGroupByFleetTag(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	tag, _ := bulkQuerygen.RandVehicleFleetGroupByTag()
+
+	var query string
+	if d.language == InfluxQL {
+		query = fmt.Sprintf("SELECT mean(%s) from vehicle where time >= '%s' and time < '%s' group by time(1m),%s", bulkQuerygen.VehicleValueField, interval.StartString(), interval.EndString(), tag)
+	} else {
+		query = fmt.Sprintf(`from(db:"%s") `+
+			`|> range(start:%s, stop:%s) `+
+			`|> filter(fn:(r) => r._measurement == "vehicle" and r._field == "%s") `+
+			`|> keep(columns:["_start", "_stop", "%s", "_value", "_time"]) `+
+			`|> window(every:1m) `+
+			`|> mean() `+
+			`|> group(by:["%s"]) `+
+			`|> keep(columns:["_start", "%s", "_value"]) `+
+			`|> yield()`,
+			d.DatabaseName,
+			interval.StartString(), interval.EndString(),
+			bulkQuerygen.VehicleValueField, tag, tag, tag)
+	}
+
+	humanLabel := fmt.Sprintf("InfluxDB (%s) mean %s, rand %s by 1m, group by %s", d.language.String(), bulkQuerygen.VehicleValueField, d.queryInterval, tag)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval.StartString(), query, q)
+}
+
+// MaxValueOneManufacturerOneCity populates a Query with a query that looks like:
+// SELECT max(value4) from vehicle where manufacturer = '$MANUFACTURER' and city = '$CITY' and time >= '$START' and time < '$END' group by time(1m)
+func (d *InfluxVehicle) MaxValueOneManufacturerOneCity(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	manufacturer, city := bulkQuerygen.RandVehicleFleetSlice()
+
+	var query string
+	if d.language == InfluxQL {
+		query = fmt.Sprintf("SELECT max(%s) from vehicle where manufacturer = '%s' and city = '%s' and time >= '%s' and time < '%s' group by time(1m)", bulkQuerygen.VehicleValueField, manufacturer, city, interval.StartString(), interval.EndString())
+	} else {
+		query = fmt.Sprintf(`from(db:"%s") `+
+			`|> range(start:%s, stop:%s) `+
+			`|> filter(fn:(r) => r._measurement == "vehicle" and r._field == "%s" and r.manufacturer == "%s" and r.city == "%s") `+
+			`|> keep(columns:["_start", "_stop", "_time", "_value"]) `+
+			`|> window(period:1m) `+
+			`|> max() `+
+			`|> yield()`,
+			d.DatabaseName,
+			interval.StartString(), interval.EndString(),
+			bulkQuerygen.VehicleValueField, manufacturer, city)
+	}
+
+	humanLabel := fmt.Sprintf("InfluxDB (%s) max %s, 1 manufacturer 1 city, rand %s by 1m", d.language.String(), bulkQuerygen.VehicleValueField, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval.StartString(), query, q)
+}
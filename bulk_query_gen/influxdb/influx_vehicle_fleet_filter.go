@@ -0,0 +1,29 @@
+package influxdb
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// InfluxVehicleFleetFilter produces Influx-specific queries for the vehicle fleet filter case.
+type InfluxVehicleFleetFilter struct {
+	InfluxVehicle
+}
+
+func NewInfluxQLVehicleFleetFilter(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newInfluxVehicleCommon(InfluxQL, dbConfig, interval, duration, scaleVar).(*InfluxVehicle)
+	return &InfluxVehicleFleetFilter{
+		InfluxVehicle: *underlying,
+	}
+}
+
+func NewFluxVehicleFleetFilter(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newInfluxVehicleCommon(Flux, dbConfig, interval, duration, scaleVar).(*InfluxVehicle)
+	return &InfluxVehicleFleetFilter{
+		InfluxVehicle: *underlying,
+	}
+}
+
+func (d *InfluxVehicleFleetFilter) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxValueOneManufacturerOneCity(q)
+	return q
+}
@@ -0,0 +1,29 @@
+package influxdb
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// InfluxVehicleFleetGroupBy produces Influx-specific queries for the vehicle fleet groupby case.
+type InfluxVehicleFleetGroupBy struct {
+	InfluxVehicle
+}
+
+func NewInfluxQLVehicleFleetGroupBy(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newInfluxVehicleCommon(InfluxQL, dbConfig, interval, duration, scaleVar).(*InfluxVehicle)
+	return &InfluxVehicleFleetGroupBy{
+		InfluxVehicle: *underlying,
+	}
+}
+
+func NewFluxVehicleFleetGroupBy(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newInfluxVehicleCommon(Flux, dbConfig, interval, duration, scaleVar).(*InfluxVehicle)
+	return &InfluxVehicleFleetGroupBy{
+		InfluxVehicle: *underlying,
+	}
+}
+
+func (d *InfluxVehicleFleetGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
@@ -0,0 +1,81 @@
+package influxdb
+
+import (
+	"fmt"
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+	"time"
+)
+
+// InfluxVehicleGeoBoundingBox produces Influx-specific queries for the vehicle bounding box case.
+type InfluxVehicleGeoBoundingBox struct {
+	InfluxVehicle
+}
+
+func NewInfluxQLVehicleGeoBoundingBox(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newInfluxVehicleCommon(InfluxQL, dbConfig, interval, duration, scaleVar).(*InfluxVehicle)
+	return &InfluxVehicleGeoBoundingBox{
+		InfluxVehicle: *underlying,
+	}
+}
+
+func (d *InfluxVehicleGeoBoundingBox) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueInBoundingBox(q)
+	return q
+}
+
+// InfluxVehicleGeoAreaWindow produces Influx-specific queries for the vehicles in area during window case.
+type InfluxVehicleGeoAreaWindow struct {
+	InfluxVehicle
+}
+
+func NewInfluxQLVehicleGeoAreaWindow(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newInfluxVehicleCommon(InfluxQL, dbConfig, interval, duration, scaleVar).(*InfluxVehicle)
+	return &InfluxVehicleGeoAreaWindow{
+		InfluxVehicle: *underlying,
+	}
+}
+
+func (d *InfluxVehicleGeoAreaWindow) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.VehiclesInAreaDuringWindow(q)
+	return q
+}
+
+// geoBoxClause filters on the latitude and longitude fields, InfluxDB has no
+// geo index.
+func geoBoxClause(box bulkQuerygen.GeoBox) string {
+	return fmt.Sprintf("latitude >= %f and latitude <= %f and longitude >= %f and longitude <= %f", box.Bottom, box.Top, box.Left, box.Right)
+}
+
+// MeanValueInBoundingBox populates a Query with a query that looks like:
+// SELECT mean(value4) from vehicle where latitude >= $SOUTH and latitude <= $NORTH and longitude >= $WEST and longitude <= $EAST and time >= '$START' and time < '$END' group by time(1m)
+func (d *InfluxVehicle) MeanValueInBoundingBox(qi bulkQuerygen.Query) {
+	if d.language != InfluxQL {
+		panic("geo queries are only supported in InfluxQL")
+	}
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	query := fmt.Sprintf("SELECT mean(%s) from vehicle where %s and time >= '%s' and time < '%s' group by time(1m)", bulkQuerygen.VehicleValueField, geoBoxClause(box), interval.StartString(), interval.EndString())
+
+	humanLabel := fmt.Sprintf("InfluxDB (%s) mean %s, rand %.0fm box, rand %s by 1m", d.language.String(), bulkQuerygen.VehicleValueField, 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval.StartString(), query, q)
+}
+
+// VehiclesInAreaDuringWindow populates a Query with a query that looks like:
+// SELECT count(latitude) from vehicle where latitude >= $SOUTH and latitude <= $NORTH and longitude >= $WEST and longitude <= $EAST and time >= '$START' and time < '$END' group by VIN
+func (d *InfluxVehicle) VehiclesInAreaDuringWindow(qi bulkQuerygen.Query) {
+	if d.language != InfluxQL {
+		panic("geo queries are only supported in InfluxQL")
+	}
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	query := fmt.Sprintf("SELECT count(latitude) from vehicle where %s and time >= '%s' and time < '%s' group by VIN", geoBoxClause(box), interval.StartString(), interval.EndString())
+
+	humanLabel := fmt.Sprintf("InfluxDB (%s) vehicles in rand %.0fm box, rand %s", d.language.String(), 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval.StartString(), query, q)
+}
@@ -0,0 +1,69 @@
+package iotdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	bulkDataGenCommon "github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// IoTDBCommon holds what the IoTDB query generators share. Devices are
+// root.<measurement>.<VIN or host> with the fields as aligned timeseries, as
+// written by the iotdb format of bulk_data_gen: the other tags are TEXT
+// timeseries of the device.
+type IoTDBCommon struct {
+	bulkQuerygen.CommonParams
+}
+
+func newIoTDBCommon(interval bulkQuerygen.TimeInterval, scaleVar int) *IoTDBCommon {
+	return &IoTDBCommon{
+		CommonParams: *bulkQuerygen.NewCommonParams(interval, scaleVar),
+	}
+}
+
+// getHttpQuery populates q with a REST API request running sql.
+func (d *IoTDBCommon) getHttpQuery(humanLabel string, interval bulkQuerygen.TimeInterval, sql string, q *bulkQuerygen.HTTPQuery) {
+	q.HumanLabel = []byte(humanLabel)
+	q.HumanDescription = []byte(fmt.Sprintf("%s: %s", humanLabel, interval.StartString()))
+
+	body, err := json.Marshal(map[string]string{"sql": sql})
+	if err != nil {
+		panic(err)
+	}
+	q.Method = []byte("POST")
+	q.Path = []byte("/rest/v2/query")
+	q.Body = body
+	q.StartTimestamp = interval.StartUnixNano()
+	q.EndTimestamp = interval.EndUnixNano()
+}
+
+// groupByTime returns the GROUP BY clause of the buckets of interval, in
+// milliseconds like the stored timestamps.
+func groupByTime(interval bulkQuerygen.TimeInterval, bucket string) string {
+	return fmt.Sprintf("GROUP BY ([%d, %d), %s)", interval.StartUnixNano()/1e6, interval.EndUnixNano()/1e6, bucket)
+}
+
+// timeClause filters on the timestamps of interval.
+func timeClause(interval bulkQuerygen.TimeInterval) string {
+	return fmt.Sprintf("time >= %d AND time < %d", interval.StartUnixNano()/1e6, interval.EndUnixNano()/1e6)
+}
+
+// quote returns s as a string literal.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// path joins nodes into a path, quoting them like the serializer.
+func path(nodes ...string) string {
+	quoted := make([]string, len(nodes))
+	for i, node := range nodes {
+		if node == "*" || node == "**" {
+			quoted[i] = node
+		} else {
+			quoted[i] = string(bulkDataGenCommon.AppendIoTDBPathNode(nil, []byte(node)))
+		}
+	}
+	return strings.Join(quoted, ".")
+}
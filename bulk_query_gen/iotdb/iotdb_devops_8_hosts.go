@@ -0,0 +1,22 @@
+package iotdb
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// IoTDBDevops8Hosts produces IoTDB SQL queries for the devops 8-hosts case.
+type IoTDBDevops8Hosts struct {
+	IoTDBDevops
+}
+
+func NewIoTDBDevops8Hosts(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newIoTDBDevopsCommon(queriesFullRange, queryInterval, scaleVar).(*IoTDBDevops)
+	return &IoTDBDevops8Hosts{
+		IoTDBDevops: *underlying,
+	}
+}
+
+func (d *IoTDBDevops8Hosts) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsageHourByMinuteEightHosts(q)
+	return q
+}
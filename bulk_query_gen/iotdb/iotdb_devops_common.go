@@ -0,0 +1,91 @@
+package iotdb
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// IoTDBDevops produces IoTDB SQL queries for all the devops query types.
+type IoTDBDevops struct {
+	IoTDBCommon
+}
+
+// NewIoTDBDevops makes an IoTDBDevops object ready to generate Queries.
+func newIoTDBDevopsCommon(interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	return &IoTDBDevops{
+		IoTDBCommon: *newIoTDBCommon(interval, scaleVar),
+	}
+}
+
+// Dispatch fulfills the QueryGenerator interface.
+func (d *IoTDBDevops) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	bulkQuerygen.DevopsDispatchAll(d, i, q, d.ScaleVar)
+	return q
+}
+
+func (d *IoTDBDevops) MaxCPUUsageHourByMinuteOneHost(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 1, time.Hour)
+}
+
+func (d *IoTDBDevops) MaxCPUUsageHourByMinuteTwoHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 2, time.Hour)
+}
+
+func (d *IoTDBDevops) MaxCPUUsageHourByMinuteFourHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 4, time.Hour)
+}
+
+func (d *IoTDBDevops) MaxCPUUsageHourByMinuteEightHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 8, time.Hour)
+}
+
+func (d *IoTDBDevops) MaxCPUUsageHourByMinuteSixteenHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 16, time.Hour)
+}
+
+func (d *IoTDBDevops) MaxCPUUsageHourByMinuteThirtyTwoHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 32, time.Hour)
+}
+
+func (d *IoTDBDevops) MaxCPUUsage12HoursByMinuteOneHost(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 1, 12*time.Hour)
+}
+
+// maxCPUUsageHourByMinuteNHosts populates a Query with a query that looks like:
+// SELECT max_value(usage_user) FROM root.cpu.$HOSTNAME_1.**, ..., root.cpu.$HOSTNAME_N.** GROUP BY ([$HOUR_START, $HOUR_END), 1m), LEVEL = 1
+func (d *IoTDBDevops) maxCPUUsageHourByMinuteNHosts(qi bulkQuerygen.Query, nhosts int, timeRange time.Duration) {
+	interval := d.AllInterval.RandWindow(timeRange)
+	nn := rand.Perm(d.ScaleVar)[:nhosts]
+
+	paths := []string{}
+	for _, n := range nn {
+		paths = append(paths, path("root", "cpu", fmt.Sprintf("host_%d", n), "**"))
+	}
+
+	combinedHostnameClause := strings.Join(paths, ", ")
+
+	// LEVEL = 1 aggregates the series of all the hosts
+	sql := fmt.Sprintf("SELECT max_value(usage_user) FROM %s %s, LEVEL = 1", combinedHostnameClause, groupByTime(interval, "1m"))
+
+	humanLabel := fmt.Sprintf("IoTDB max cpu, rand %4d hosts, rand %s by 1m", nhosts, timeRange)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
+
+// MeanCPUUsageDayByHourAllHostsGroupbyHost populates a Query with a query that looks like:
+// SELECT avg(usage_user) FROM root.cpu.** GROUP BY ([$DAY_START, $DAY_END), 1h), LEVEL = 2
+func (d *IoTDBDevops) MeanCPUUsageDayByHourAllHostsGroupbyHost(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(24 * time.Hour)
+
+	// LEVEL = 2 is the hostname
+	sql := fmt.Sprintf("SELECT avg(usage_user) FROM %s %s, LEVEL = 2", path("root", "cpu", "**"), groupByTime(interval, "1h"))
+
+	humanLabel := "IoTDB mean cpu, all hosts, rand 1day by 1hour"
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
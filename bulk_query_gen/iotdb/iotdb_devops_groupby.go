@@ -0,0 +1,22 @@
+package iotdb
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// IoTDBDevopsGroupBy produces IoTDB SQL queries for the devops groupby case.
+type IoTDBDevopsGroupBy struct {
+	IoTDBDevops
+}
+
+func NewIoTDBDevopsGroupBy(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newIoTDBDevopsCommon(queriesFullRange, queryInterval, scaleVar).(*IoTDBDevops)
+	return &IoTDBDevopsGroupBy{
+		IoTDBDevops: *underlying,
+	}
+}
+
+func (d *IoTDBDevopsGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanCPUUsageDayByHourAllHostsGroupbyHost(q)
+	return q
+}
@@ -0,0 +1,22 @@
+package iotdb
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// IoTDBDevopsSingleHost produces IoTDB SQL queries for the devops single-host case.
+type IoTDBDevopsSingleHost struct {
+	IoTDBDevops
+}
+
+func NewIoTDBDevopsSingleHost(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newIoTDBDevopsCommon(queriesFullRange, queryInterval, scaleVar).(*IoTDBDevops)
+	return &IoTDBDevopsSingleHost{
+		IoTDBDevops: *underlying,
+	}
+}
+
+func (d *IoTDBDevopsSingleHost) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsageHourByMinuteOneHost(q)
+	return q
+}
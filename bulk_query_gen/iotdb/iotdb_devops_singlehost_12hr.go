@@ -0,0 +1,22 @@
+package iotdb
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// IoTDBDevopsSingleHost12hr produces IoTDB SQL queries for the devops single-host case over a 12hr period.
+type IoTDBDevopsSingleHost12hr struct {
+	IoTDBDevops
+}
+
+func NewIoTDBDevopsSingleHost12hr(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newIoTDBDevopsCommon(queriesFullRange, queryInterval, scaleVar).(*IoTDBDevops)
+	return &IoTDBDevopsSingleHost12hr{
+		IoTDBDevops: *underlying,
+	}
+}
+
+func (d *IoTDBDevopsSingleHost12hr) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsage12HoursByMinuteOneHost(q)
+	return q
+}
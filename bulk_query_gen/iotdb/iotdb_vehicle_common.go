@@ -0,0 +1,59 @@
+package iotdb
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// IoTDBVehicle produces IoTDB SQL queries for the vehicle use case. Vehicles
+// are root.vehicle.<VIN>, the fleet tags of data generated with --fleet-tags
+// being TEXT timeseries of the vehicle.
+type IoTDBVehicle struct {
+	IoTDBCommon
+	queryInterval time.Duration
+}
+
+func newIoTDBVehicleCommon(interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	return &IoTDBVehicle{
+		IoTDBCommon:   *newIoTDBCommon(interval, scaleVar),
+		queryInterval: duration,
+	}
+}
+
+// Dispatch fulfills the QueryGenerator interface.
+func (d *IoTDBVehicle) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
+
+// MeanValueGroupByFleetTag populates a Query with a query that looks like:
+// SELECT avg(value4), last_value($FLEET_TAG) FROM root.vehicle.* GROUP BY ([$START, $END), 1m) ALIGN BY DEVICE
+//
+// GROUP BY LEVEL only groups by path levels, so the averages are per vehicle
+// with its fleet tag, left to the client to group.
+func (d *IoTDBVehicle) MeanValueGroupByFleetTag(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	tag, _ := bulkQuerygen.RandVehicleFleetGroupByTag()
+
+	sql := fmt.Sprintf("SELECT avg(%s), last_value(%s) FROM %s %s ALIGN BY DEVICE", bulkQuerygen.VehicleValueField, path(tag), path("root", "vehicle", "*"), groupByTime(interval, "1m"))
+
+	humanLabel := fmt.Sprintf("IoTDB mean %s, rand %s by 1m, group by %s", bulkQuerygen.VehicleValueField, d.queryInterval, tag)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
+
+// MaxValueOneManufacturerOneCity populates a Query with a query that looks like:
+// SELECT max_value(value4) FROM root.vehicle.* WHERE manufacturer = '$MANUFACTURER' AND city = '$CITY' GROUP BY ([$START, $END), 1m) ALIGN BY DEVICE
+func (d *IoTDBVehicle) MaxValueOneManufacturerOneCity(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	manufacturer, city := bulkQuerygen.RandVehicleFleetSlice()
+
+	sql := fmt.Sprintf("SELECT max_value(%s) FROM %s WHERE manufacturer = %s AND city = %s %s ALIGN BY DEVICE", bulkQuerygen.VehicleValueField, path("root", "vehicle", "*"), quote(manufacturer), quote(city), groupByTime(interval, "1m"))
+
+	humanLabel := fmt.Sprintf("IoTDB max %s, 1 manufacturer 1 city, rand %s by 1m", bulkQuerygen.VehicleValueField, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
@@ -0,0 +1,40 @@
+package iotdb
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// IoTDBVehicleFleetGroupBy produces IoTDB SQL queries for the vehicle fleet groupby case.
+type IoTDBVehicleFleetGroupBy struct {
+	IoTDBVehicle
+}
+
+func NewIoTDBVehicleFleetGroupBy(_ bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newIoTDBVehicleCommon(interval, duration, scaleVar).(*IoTDBVehicle)
+	return &IoTDBVehicleFleetGroupBy{
+		IoTDBVehicle: *underlying,
+	}
+}
+
+func (d *IoTDBVehicleFleetGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
+
+// IoTDBVehicleFleetFilter produces IoTDB SQL queries for the vehicle fleet filter case.
+type IoTDBVehicleFleetFilter struct {
+	IoTDBVehicle
+}
+
+func NewIoTDBVehicleFleetFilter(_ bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newIoTDBVehicleCommon(interval, duration, scaleVar).(*IoTDBVehicle)
+	return &IoTDBVehicleFleetFilter{
+		IoTDBVehicle: *underlying,
+	}
+}
+
+func (d *IoTDBVehicleFleetFilter) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxValueOneManufacturerOneCity(q)
+	return q
+}
@@ -0,0 +1,78 @@
+package iotdb
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// IoTDBVehicleGeoBoundingBox produces IoTDB SQL queries for the vehicle bounding box case.
+type IoTDBVehicleGeoBoundingBox struct {
+	IoTDBVehicle
+}
+
+func NewIoTDBVehicleGeoBoundingBox(_ bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newIoTDBVehicleCommon(interval, duration, scaleVar).(*IoTDBVehicle)
+	return &IoTDBVehicleGeoBoundingBox{
+		IoTDBVehicle: *underlying,
+	}
+}
+
+func (d *IoTDBVehicleGeoBoundingBox) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueInBoundingBox(q)
+	return q
+}
+
+// IoTDBVehicleGeoAreaWindow produces IoTDB SQL queries for the vehicles in area during window case.
+type IoTDBVehicleGeoAreaWindow struct {
+	IoTDBVehicle
+}
+
+func NewIoTDBVehicleGeoAreaWindow(_ bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newIoTDBVehicleCommon(interval, duration, scaleVar).(*IoTDBVehicle)
+	return &IoTDBVehicleGeoAreaWindow{
+		IoTDBVehicle: *underlying,
+	}
+}
+
+func (d *IoTDBVehicleGeoAreaWindow) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.VehiclesInAreaDuringWindow(q)
+	return q
+}
+
+// geoBoxClause filters on the latitude and longitude timeseries, IoTDB has no
+// geo index.
+func geoBoxClause(box bulkQuerygen.GeoBox) string {
+	return fmt.Sprintf("latitude >= %f AND latitude <= %f AND longitude >= %f AND longitude <= %f", box.Bottom, box.Top, box.Left, box.Right)
+}
+
+// MeanValueInBoundingBox populates a Query with a query that looks like:
+// SELECT avg(value4) FROM root.vehicle.** WHERE latitude >= $SOUTH AND latitude <= $NORTH AND longitude >= $WEST AND longitude <= $EAST GROUP BY ([$START, $END), 1m) ALIGN BY DEVICE
+//
+// The value filter is evaluated per device, so the means are per vehicle.
+func (d *IoTDBVehicle) MeanValueInBoundingBox(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	sql := fmt.Sprintf("SELECT avg(%s) FROM %s WHERE %s %s ALIGN BY DEVICE", bulkQuerygen.VehicleValueField, path("root", "vehicle", "**"), geoBoxClause(box), groupByTime(interval, "1m"))
+
+	humanLabel := fmt.Sprintf("IoTDB mean %s, rand %.0fm box, rand %s by 1m", bulkQuerygen.VehicleValueField, 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
+
+// VehiclesInAreaDuringWindow populates a Query with a query that looks like:
+// SELECT count(latitude) FROM root.vehicle.** WHERE latitude >= $SOUTH AND latitude <= $NORTH AND longitude >= $WEST AND longitude <= $EAST AND time >= $START AND time < $END ALIGN BY DEVICE
+func (d *IoTDBVehicle) VehiclesInAreaDuringWindow(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	sql := fmt.Sprintf("SELECT count(latitude) FROM %s WHERE %s AND %s ALIGN BY DEVICE", path("root", "vehicle", "**"), geoBoxClause(box), timeClause(interval))
+
+	humanLabel := fmt.Sprintf("IoTDB vehicles in rand %.0fm box, rand %s", 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
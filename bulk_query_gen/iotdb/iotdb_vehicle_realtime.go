@@ -0,0 +1,41 @@
+package iotdb
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// IoTDBVehicleRealTime produces IoTDB SQL queries for the vehicle real time case.
+type IoTDBVehicleRealTime struct {
+	IoTDBVehicle
+	bulkQuerygen.TimeWindow
+}
+
+func NewIoTDBVehicleRealTime(_ bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newIoTDBVehicleCommon(interval, duration, scaleVar).(*IoTDBVehicle)
+	return &IoTDBVehicleRealTime{
+		IoTDBVehicle: *underlying,
+		TimeWindow:   bulkQuerygen.TimeWindow{Start: interval.Start, Duration: time.Second},
+	}
+}
+
+func (d *IoTDBVehicleRealTime) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.RealTimeQueries(q)
+	return q
+}
+
+// RealTimeQueries populates a Query with a query that reads the raw values of
+// all the vehicles in a window, sliding with --time-window-shift:
+// SELECT value4 FROM root.vehicle.** WHERE time >= $START AND time < $END LIMIT 30000 ALIGN BY DEVICE
+func (d *IoTDBVehicleRealTime) RealTimeQueries(qi bulkQuerygen.Query) {
+	interval := bulkQuerygen.VehicleRealTimeWindow(&d.TimeWindow, &d.AllInterval)
+
+	sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s LIMIT %d ALIGN BY DEVICE", bulkQuerygen.VehicleValueField, path("root", "vehicle", "**"), timeClause(interval), bulkQuerygen.VehicleRealTimeLimit)
+
+	humanLabel := fmt.Sprintf("IoTDB real time query, rand %s", d.Duration)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
@@ -0,0 +1,72 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// PrometheusCommon holds what the PromQL query generators share. Metrics are
+// named <measurement>_<field> and labelled with the tags, as written by the
+// prometheus-remote-write format of bulk_data_gen.
+type PrometheusCommon struct {
+	bulkQuerygen.CommonParams
+}
+
+func newPrometheusCommon(interval bulkQuerygen.TimeInterval, scaleVar int) *PrometheusCommon {
+	return &PrometheusCommon{
+		CommonParams: *bulkQuerygen.NewCommonParams(interval, scaleVar),
+	}
+}
+
+// getHttpQuery populates q with a /api/v1/query_range request evaluating
+// query every step over interval. Range selectors of query are expected to
+// span step: the first evaluation is one step after the interval start, so
+// that each evaluation covers the step before it, like a group by time(step)
+// bucket.
+func (d *PrometheusCommon) getHttpQuery(humanLabel string, interval bulkQuerygen.TimeInterval, step time.Duration, query string, q *bulkQuerygen.HTTPQuery) {
+	q.HumanLabel = []byte(humanLabel)
+	q.HumanDescription = []byte(fmt.Sprintf("%s: %s", humanLabel, interval.StartString()))
+
+	start := interval.Start.Add(step)
+	if start.After(interval.End) {
+		start = interval.End
+	}
+
+	v := url.Values{}
+	v.Set("query", query)
+	v.Set("start", strconv.FormatInt(start.Unix(), 10))
+	v.Set("end", strconv.FormatInt(interval.EndUnix(), 10))
+	v.Set("step", promDuration(step))
+	q.Method = []byte("GET")
+	q.Path = []byte(fmt.Sprintf("/api/v1/query_range?%s", v.Encode()))
+	q.Body = nil
+	q.StartTimestamp = interval.StartUnixNano()
+	q.EndTimestamp = interval.EndUnixNano()
+}
+
+// getInstantQuery populates q with a /api/v1/query request evaluating query
+// at the end of interval, for range selectors spanning interval which return
+// the raw samples.
+func (d *PrometheusCommon) getInstantQuery(humanLabel string, interval bulkQuerygen.TimeInterval, query string, q *bulkQuerygen.HTTPQuery) {
+	q.HumanLabel = []byte(humanLabel)
+	q.HumanDescription = []byte(fmt.Sprintf("%s: %s", humanLabel, interval.StartString()))
+
+	v := url.Values{}
+	v.Set("query", query)
+	v.Set("time", strconv.FormatInt(interval.EndUnix(), 10))
+	q.Method = []byte("GET")
+	q.Path = []byte(fmt.Sprintf("/api/v1/query?%s", v.Encode()))
+	q.Body = nil
+	q.StartTimestamp = interval.StartUnixNano()
+	q.EndTimestamp = interval.EndUnixNano()
+}
+
+// promDuration formats d in whole seconds, which PromQL and the HTTP API
+// both accept.
+func promDuration(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d.Seconds()))
+}
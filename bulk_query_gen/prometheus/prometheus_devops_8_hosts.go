@@ -0,0 +1,22 @@
+package prometheus
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// PrometheusDevops8Hosts produces PromQL queries for the devops 8-hosts case.
+type PrometheusDevops8Hosts struct {
+	PrometheusDevops
+}
+
+func NewPrometheusDevops8Hosts(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newPrometheusDevopsCommon(queriesFullRange, queryInterval, scaleVar).(*PrometheusDevops)
+	return &PrometheusDevops8Hosts{
+		PrometheusDevops: *underlying,
+	}
+}
+
+func (d *PrometheusDevops8Hosts) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsageHourByMinuteEightHosts(q)
+	return q
+}
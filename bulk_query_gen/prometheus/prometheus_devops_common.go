@@ -0,0 +1,91 @@
+package prometheus
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// PrometheusDevops produces PromQL queries for all the devops query types.
+type PrometheusDevops struct {
+	PrometheusCommon
+}
+
+// NewPrometheusDevops makes a PrometheusDevops object ready to generate Queries.
+func newPrometheusDevopsCommon(interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	return &PrometheusDevops{
+		PrometheusCommon: *newPrometheusCommon(interval, scaleVar),
+	}
+}
+
+// Dispatch fulfills the QueryGenerator interface.
+func (d *PrometheusDevops) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	bulkQuerygen.DevopsDispatchAll(d, i, q, d.ScaleVar)
+	return q
+}
+
+func (d *PrometheusDevops) MaxCPUUsageHourByMinuteOneHost(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 1, time.Hour)
+}
+
+func (d *PrometheusDevops) MaxCPUUsageHourByMinuteTwoHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 2, time.Hour)
+}
+
+func (d *PrometheusDevops) MaxCPUUsageHourByMinuteFourHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 4, time.Hour)
+}
+
+func (d *PrometheusDevops) MaxCPUUsageHourByMinuteEightHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 8, time.Hour)
+}
+
+func (d *PrometheusDevops) MaxCPUUsageHourByMinuteSixteenHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 16, time.Hour)
+}
+
+func (d *PrometheusDevops) MaxCPUUsageHourByMinuteThirtyTwoHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 32, time.Hour)
+}
+
+func (d *PrometheusDevops) MaxCPUUsage12HoursByMinuteOneHost(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 1, 12*time.Hour)
+}
+
+// maxCPUUsageHourByMinuteNHosts populates a Query with a query that looks like:
+// max(max_over_time(cpu_usage_user{hostname=~"$HOSTNAME_1|...|$HOSTNAME_N"}[1m]))
+// evaluated every minute from $HOUR_START to $HOUR_END
+func (d *PrometheusDevops) maxCPUUsageHourByMinuteNHosts(qi bulkQuerygen.Query, nhosts int, timeRange time.Duration) {
+	interval := d.AllInterval.RandWindow(timeRange)
+	nn := rand.Perm(d.ScaleVar)[:nhosts]
+
+	hostnames := []string{}
+	for _, n := range nn {
+		hostnames = append(hostnames, fmt.Sprintf("host_%d", n))
+	}
+
+	combinedHostnameClause := strings.Join(hostnames, "|")
+
+	query := fmt.Sprintf(`max(max_over_time(cpu_usage_user{hostname=~"%s"}[1m]))`, combinedHostnameClause)
+
+	humanLabel := fmt.Sprintf("Prometheus max cpu, rand %4d hosts, rand %s by 1m", nhosts, timeRange)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, time.Minute, query, q)
+}
+
+// MeanCPUUsageDayByHourAllHostsGroupbyHost populates a Query with a query that looks like:
+// avg by (hostname) (avg_over_time(cpu_usage_user[1h]))
+// evaluated every hour from $DAY_START to $DAY_END
+func (d *PrometheusDevops) MeanCPUUsageDayByHourAllHostsGroupbyHost(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(24 * time.Hour)
+
+	query := "avg by (hostname) (avg_over_time(cpu_usage_user[1h]))"
+
+	humanLabel := "Prometheus mean cpu, all hosts, rand 1day by 1hour"
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, time.Hour, query, q)
+}
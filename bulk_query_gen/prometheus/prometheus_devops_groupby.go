@@ -0,0 +1,22 @@
+package prometheus
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// PrometheusDevopsGroupBy produces PromQL queries for the devops groupby case.
+type PrometheusDevopsGroupBy struct {
+	PrometheusDevops
+}
+
+func NewPrometheusDevopsGroupBy(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newPrometheusDevopsCommon(queriesFullRange, queryInterval, scaleVar).(*PrometheusDevops)
+	return &PrometheusDevopsGroupBy{
+		PrometheusDevops: *underlying,
+	}
+}
+
+func (d *PrometheusDevopsGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanCPUUsageDayByHourAllHostsGroupbyHost(q)
+	return q
+}
@@ -0,0 +1,22 @@
+package prometheus
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// PrometheusDevopsSingleHost produces PromQL queries for the devops single-host case.
+type PrometheusDevopsSingleHost struct {
+	PrometheusDevops
+}
+
+func NewPrometheusDevopsSingleHost(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newPrometheusDevopsCommon(queriesFullRange, queryInterval, scaleVar).(*PrometheusDevops)
+	return &PrometheusDevopsSingleHost{
+		PrometheusDevops: *underlying,
+	}
+}
+
+func (d *PrometheusDevopsSingleHost) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsageHourByMinuteOneHost(q)
+	return q
+}
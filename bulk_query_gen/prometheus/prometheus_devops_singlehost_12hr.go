@@ -0,0 +1,22 @@
+package prometheus
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// PrometheusDevopsSingleHost12hr produces PromQL queries for the devops single-host case over a 12hr period.
+type PrometheusDevopsSingleHost12hr struct {
+	PrometheusDevops
+}
+
+func NewPrometheusDevopsSingleHost12hr(_ bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newPrometheusDevopsCommon(queriesFullRange, queryInterval, scaleVar).(*PrometheusDevops)
+	return &PrometheusDevopsSingleHost12hr{
+		PrometheusDevops: *underlying,
+	}
+}
+
+func (d *PrometheusDevopsSingleHost12hr) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsage12HoursByMinuteOneHost(q)
+	return q
+}
@@ -0,0 +1,59 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// PrometheusVehicle produces PromQL queries for the vehicle use case.
+type PrometheusVehicle struct {
+	PrometheusCommon
+	queryInterval time.Duration
+}
+
+func newPrometheusVehicleCommon(interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	return &PrometheusVehicle{
+		PrometheusCommon: *newPrometheusCommon(interval, scaleVar),
+		queryInterval:    duration,
+	}
+}
+
+// Dispatch fulfills the QueryGenerator interface.
+func (d *PrometheusVehicle) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
+
+// vehicleMetric is the metric holding the aggregated vehicle field.
+const vehicleMetric = "vehicle_" + bulkQuerygen.VehicleValueField
+
+// MeanValueGroupByFleetTag populates a Query with a query that looks like:
+// avg by ($FLEET_TAG) (avg_over_time(vehicle_value4[1m]))
+// evaluated every minute from $START to $END
+func (d *PrometheusVehicle) MeanValueGroupByFleetTag(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	tag, _ := bulkQuerygen.RandVehicleFleetGroupByTag()
+
+	query := fmt.Sprintf("avg by (%s) (avg_over_time(%s[1m]))", tag, vehicleMetric)
+
+	humanLabel := fmt.Sprintf("Prometheus mean %s, rand %s by 1m, group by %s", bulkQuerygen.VehicleValueField, d.queryInterval, tag)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, time.Minute, query, q)
+}
+
+// MaxValueOneManufacturerOneCity populates a Query with a query that looks like:
+// max(max_over_time(vehicle_value4{manufacturer="$MANUFACTURER",city="$CITY"}[1m]))
+// evaluated every minute from $START to $END
+func (d *PrometheusVehicle) MaxValueOneManufacturerOneCity(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	manufacturer, city := bulkQuerygen.RandVehicleFleetSlice()
+
+	query := fmt.Sprintf(`max(max_over_time(%s{manufacturer="%s",city="%s"}[1m]))`, vehicleMetric, manufacturer, city)
+
+	humanLabel := fmt.Sprintf("Prometheus max %s, 1 manufacturer 1 city, rand %s by 1m", bulkQuerygen.VehicleValueField, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, time.Minute, query, q)
+}
@@ -0,0 +1,40 @@
+package prometheus
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// PrometheusVehicleFleetGroupBy produces PromQL queries for the vehicle fleet groupby case.
+type PrometheusVehicleFleetGroupBy struct {
+	PrometheusVehicle
+}
+
+func NewPrometheusVehicleFleetGroupBy(_ bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newPrometheusVehicleCommon(interval, duration, scaleVar).(*PrometheusVehicle)
+	return &PrometheusVehicleFleetGroupBy{
+		PrometheusVehicle: *underlying,
+	}
+}
+
+func (d *PrometheusVehicleFleetGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
+
+// PrometheusVehicleFleetFilter produces PromQL queries for the vehicle fleet filter case.
+type PrometheusVehicleFleetFilter struct {
+	PrometheusVehicle
+}
+
+func NewPrometheusVehicleFleetFilter(_ bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newPrometheusVehicleCommon(interval, duration, scaleVar).(*PrometheusVehicle)
+	return &PrometheusVehicleFleetFilter{
+		PrometheusVehicle: *underlying,
+	}
+}
+
+func (d *PrometheusVehicleFleetFilter) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxValueOneManufacturerOneCity(q)
+	return q
+}
@@ -0,0 +1,82 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// PrometheusVehicleGeoBoundingBox produces PromQL queries for the vehicle bounding box case.
+type PrometheusVehicleGeoBoundingBox struct {
+	PrometheusVehicle
+}
+
+func NewPrometheusVehicleGeoBoundingBox(_ bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newPrometheusVehicleCommon(interval, duration, scaleVar).(*PrometheusVehicle)
+	return &PrometheusVehicleGeoBoundingBox{
+		PrometheusVehicle: *underlying,
+	}
+}
+
+func (d *PrometheusVehicleGeoBoundingBox) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueInBoundingBox(q)
+	return q
+}
+
+// PrometheusVehicleGeoAreaWindow produces PromQL queries for the vehicles in area during window case.
+type PrometheusVehicleGeoAreaWindow struct {
+	PrometheusVehicle
+}
+
+func NewPrometheusVehicleGeoAreaWindow(_ bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newPrometheusVehicleCommon(interval, duration, scaleVar).(*PrometheusVehicle)
+	return &PrometheusVehicleGeoAreaWindow{
+		PrometheusVehicle: *underlying,
+	}
+}
+
+func (d *PrometheusVehicleGeoAreaWindow) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.VehiclesInAreaDuringWindow(q)
+	return q
+}
+
+// inBoxSelector keeps the series of the per-vehicle expression expr whose
+// mean position over rangeSelector is in box. Prometheus has no geo type:
+// positions are the vehicle_latitude and vehicle_longitude metrics.
+func inBoxSelector(expr string, box bulkQuerygen.GeoBox, rangeSelector string) string {
+	return fmt.Sprintf("%s and on(VIN) (avg_over_time(vehicle_latitude[%s]) >= %f <= %f) and on(VIN) (avg_over_time(vehicle_longitude[%s]) >= %f <= %f)",
+		expr, rangeSelector, box.Bottom, box.Top, rangeSelector, box.Left, box.Right)
+}
+
+// MeanValueInBoundingBox populates a Query with a query that looks like:
+// avg(avg_over_time(vehicle_value4[1m]) and on(VIN) (avg_over_time(vehicle_latitude[1m]) >= $SOUTH <= $NORTH) and on(VIN) (...))
+// evaluated every minute from $START to $END
+func (d *PrometheusVehicle) MeanValueInBoundingBox(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	query := fmt.Sprintf("avg(%s)", inBoxSelector(fmt.Sprintf("avg_over_time(%s[1m])", vehicleMetric), box, "1m"))
+
+	humanLabel := fmt.Sprintf("Prometheus mean %s, rand %.0fm box, rand %s by 1m", bulkQuerygen.VehicleValueField, 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, time.Minute, query, q)
+}
+
+// VehiclesInAreaDuringWindow populates a Query with a query that looks like:
+// count(max_over_time((vehicle_latitude >= $SOUTH <= $NORTH and on(VIN) (vehicle_longitude >= $WEST <= $EAST))[$WINDOW:1m]))
+// evaluated once at $END
+func (d *PrometheusVehicle) VehiclesInAreaDuringWindow(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	inBox := fmt.Sprintf("vehicle_latitude >= %f <= %f and on(VIN) (vehicle_longitude >= %f <= %f)", box.Bottom, box.Top, box.Left, box.Right)
+	query := fmt.Sprintf("count(max_over_time((%s)[%s:1m]))", inBox, promDuration(d.queryInterval))
+
+	humanLabel := fmt.Sprintf("Prometheus vehicles in rand %.0fm box, rand %s", 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	// a single evaluation covering the whole window
+	d.getHttpQuery(humanLabel, interval, interval.Duration(), query, q)
+}
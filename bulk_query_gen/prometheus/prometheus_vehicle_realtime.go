@@ -0,0 +1,41 @@
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// PrometheusVehicleRealTime produces PromQL queries for the vehicle real time case.
+type PrometheusVehicleRealTime struct {
+	PrometheusVehicle
+	bulkQuerygen.TimeWindow
+}
+
+func NewPrometheusVehicleRealTime(_ bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newPrometheusVehicleCommon(interval, duration, scaleVar).(*PrometheusVehicle)
+	return &PrometheusVehicleRealTime{
+		PrometheusVehicle: *underlying,
+		TimeWindow:        bulkQuerygen.TimeWindow{Start: interval.Start, Duration: time.Second},
+	}
+}
+
+func (d *PrometheusVehicleRealTime) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.RealTimeQueries(q)
+	return q
+}
+
+// RealTimeQueries populates a Query with a query that reads the raw values of
+// all the vehicles in a window, sliding with --time-window-shift:
+// vehicle_value4[1s] evaluated at $END
+func (d *PrometheusVehicleRealTime) RealTimeQueries(qi bulkQuerygen.Query) {
+	interval := bulkQuerygen.VehicleRealTimeWindow(&d.TimeWindow, &d.AllInterval)
+
+	query := fmt.Sprintf("%s[%s]", vehicleMetric, promDuration(d.Duration))
+
+	humanLabel := fmt.Sprintf("Prometheus real time query, rand %s", d.Duration)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getInstantQuery(humanLabel, interval, query, q)
+}
@@ -0,0 +1,53 @@
+package tdengine
+
+import (
+	"fmt"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// TDengineCommon holds what the TDengine query generators share. Measurements
+// are super tables, with the tags as TAGS and the fields as columns, as
+// written by the tdengine format of bulk_data_gen.
+//
+// Queries use the TDengine 3.x SQL dialect (PARTITION BY) and are sent to
+// the REST API.
+type TDengineCommon struct {
+	bulkQuerygen.CommonParams
+	DatabaseName string
+}
+
+func newTDengineCommon(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, scaleVar int) *TDengineCommon {
+	if _, ok := dbConfig[bulkQuerygen.DatabaseName]; !ok {
+		panic("need TDengine database name")
+	}
+
+	return &TDengineCommon{
+		CommonParams: *bulkQuerygen.NewCommonParams(interval, scaleVar),
+		DatabaseName: dbConfig[bulkQuerygen.DatabaseName],
+	}
+}
+
+// getHttpQuery populates q with a REST API request running sql.
+func (d *TDengineCommon) getHttpQuery(humanLabel string, interval bulkQuerygen.TimeInterval, sql string, q *bulkQuerygen.HTTPQuery) {
+	q.HumanLabel = []byte(humanLabel)
+	q.HumanDescription = []byte(fmt.Sprintf("%s: %s", humanLabel, interval.StartString()))
+
+	q.Method = []byte("POST")
+	q.Path = []byte(fmt.Sprintf("/rest/sql/%s", d.DatabaseName))
+	q.Body = []byte(sql)
+	q.StartTimestamp = interval.StartUnixNano()
+	q.EndTimestamp = interval.EndUnixNano()
+}
+
+// timeClause filters on the timestamps of interval, in milliseconds like the
+// database precision.
+func timeClause(interval bulkQuerygen.TimeInterval) string {
+	return fmt.Sprintf("ts >= %d AND ts < %d", interval.StartUnixNano()/1e6, interval.EndUnixNano()/1e6)
+}
+
+// column quotes the name of a tag or field: bulk_load_tdengine creates them
+// quoted, which keeps their case (VIN), and some are keywords.
+func column(name string) string {
+	return "`" + name + "`"
+}
@@ -0,0 +1,22 @@
+package tdengine
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// TDengineDevops8Hosts produces TDengine SQL queries for the devops 8-hosts case.
+type TDengineDevops8Hosts struct {
+	TDengineDevops
+}
+
+func NewTDengineDevops8Hosts(dbConfig bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newTDengineDevopsCommon(dbConfig, queriesFullRange, queryInterval, scaleVar).(*TDengineDevops)
+	return &TDengineDevops8Hosts{
+		TDengineDevops: *underlying,
+	}
+}
+
+func (d *TDengineDevops8Hosts) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsageHourByMinuteEightHosts(q)
+	return q
+}
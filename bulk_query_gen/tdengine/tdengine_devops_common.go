@@ -0,0 +1,89 @@
+package tdengine
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// TDengineDevops produces TDengine SQL queries for all the devops query types.
+type TDengineDevops struct {
+	TDengineCommon
+}
+
+// NewTDengineDevops makes a TDengineDevops object ready to generate Queries.
+func newTDengineDevopsCommon(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	return &TDengineDevops{
+		TDengineCommon: *newTDengineCommon(dbConfig, interval, scaleVar),
+	}
+}
+
+// Dispatch fulfills the QueryGenerator interface.
+func (d *TDengineDevops) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	bulkQuerygen.DevopsDispatchAll(d, i, q, d.ScaleVar)
+	return q
+}
+
+func (d *TDengineDevops) MaxCPUUsageHourByMinuteOneHost(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 1, time.Hour)
+}
+
+func (d *TDengineDevops) MaxCPUUsageHourByMinuteTwoHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 2, time.Hour)
+}
+
+func (d *TDengineDevops) MaxCPUUsageHourByMinuteFourHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 4, time.Hour)
+}
+
+func (d *TDengineDevops) MaxCPUUsageHourByMinuteEightHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 8, time.Hour)
+}
+
+func (d *TDengineDevops) MaxCPUUsageHourByMinuteSixteenHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 16, time.Hour)
+}
+
+func (d *TDengineDevops) MaxCPUUsageHourByMinuteThirtyTwoHosts(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 32, time.Hour)
+}
+
+func (d *TDengineDevops) MaxCPUUsage12HoursByMinuteOneHost(q bulkQuerygen.Query) {
+	d.maxCPUUsageHourByMinuteNHosts(q.(*bulkQuerygen.HTTPQuery), 1, 12*time.Hour)
+}
+
+// maxCPUUsageHourByMinuteNHosts populates a Query with a query that looks like:
+// SELECT _wstart, max(`usage_user`) FROM cpu WHERE `hostname` IN ('$HOSTNAME_1',...,'$HOSTNAME_N') AND ts >= $HOUR_START AND ts < $HOUR_END INTERVAL(1m)
+func (d *TDengineDevops) maxCPUUsageHourByMinuteNHosts(qi bulkQuerygen.Query, nhosts int, timeRange time.Duration) {
+	interval := d.AllInterval.RandWindow(timeRange)
+	nn := rand.Perm(d.ScaleVar)[:nhosts]
+
+	hostnames := []string{}
+	for _, n := range nn {
+		hostnames = append(hostnames, fmt.Sprintf("'host_%d'", n))
+	}
+
+	combinedHostnameClause := strings.Join(hostnames, ",")
+
+	sql := fmt.Sprintf("SELECT _wstart, max(%s) FROM cpu WHERE %s IN (%s) AND %s INTERVAL(1m)", column("usage_user"), column("hostname"), combinedHostnameClause, timeClause(interval))
+
+	humanLabel := fmt.Sprintf("TDengine max cpu, rand %4d hosts, rand %s by 1m", nhosts, timeRange)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
+
+// MeanCPUUsageDayByHourAllHostsGroupbyHost populates a Query with a query that looks like:
+// SELECT _wstart, `hostname`, avg(`usage_user`) FROM cpu WHERE ts >= $DAY_START AND ts < $DAY_END PARTITION BY `hostname` INTERVAL(1h)
+func (d *TDengineDevops) MeanCPUUsageDayByHourAllHostsGroupbyHost(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(24 * time.Hour)
+
+	sql := fmt.Sprintf("SELECT _wstart, %s, avg(%s) FROM cpu WHERE %s PARTITION BY %s INTERVAL(1h)", column("hostname"), column("usage_user"), timeClause(interval), column("hostname"))
+
+	humanLabel := "TDengine mean cpu, all hosts, rand 1day by 1hour"
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
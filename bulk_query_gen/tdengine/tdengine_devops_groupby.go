@@ -0,0 +1,22 @@
+package tdengine
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// TDengineDevopsGroupBy produces TDengine SQL queries for the devops groupby case.
+type TDengineDevopsGroupBy struct {
+	TDengineDevops
+}
+
+func NewTDengineDevopsGroupBy(dbConfig bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newTDengineDevopsCommon(dbConfig, queriesFullRange, queryInterval, scaleVar).(*TDengineDevops)
+	return &TDengineDevopsGroupBy{
+		TDengineDevops: *underlying,
+	}
+}
+
+func (d *TDengineDevopsGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanCPUUsageDayByHourAllHostsGroupbyHost(q)
+	return q
+}
@@ -0,0 +1,22 @@
+package tdengine
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// TDengineDevopsSingleHost produces TDengine SQL queries for the devops single-host case.
+type TDengineDevopsSingleHost struct {
+	TDengineDevops
+}
+
+func NewTDengineDevopsSingleHost(dbConfig bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newTDengineDevopsCommon(dbConfig, queriesFullRange, queryInterval, scaleVar).(*TDengineDevops)
+	return &TDengineDevopsSingleHost{
+		TDengineDevops: *underlying,
+	}
+}
+
+func (d *TDengineDevopsSingleHost) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsageHourByMinuteOneHost(q)
+	return q
+}
@@ -0,0 +1,22 @@
+package tdengine
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// TDengineDevopsSingleHost12hr produces TDengine SQL queries for the devops single-host case over a 12hr period.
+type TDengineDevopsSingleHost12hr struct {
+	TDengineDevops
+}
+
+func NewTDengineDevopsSingleHost12hr(dbConfig bulkQuerygen.DatabaseConfig, queriesFullRange bulkQuerygen.TimeInterval, queryInterval time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newTDengineDevopsCommon(dbConfig, queriesFullRange, queryInterval, scaleVar).(*TDengineDevops)
+	return &TDengineDevopsSingleHost12hr{
+		TDengineDevops: *underlying,
+	}
+}
+
+func (d *TDengineDevopsSingleHost12hr) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxCPUUsage12HoursByMinuteOneHost(q)
+	return q
+}
@@ -0,0 +1,54 @@
+package tdengine
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// TDengineVehicle produces TDengine SQL queries for the vehicle use case.
+type TDengineVehicle struct {
+	TDengineCommon
+	queryInterval time.Duration
+}
+
+func newTDengineVehicleCommon(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	return &TDengineVehicle{
+		TDengineCommon: *newTDengineCommon(dbConfig, interval, scaleVar),
+		queryInterval:  duration,
+	}
+}
+
+// Dispatch fulfills the QueryGenerator interface.
+func (d *TDengineVehicle) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
+
+// MeanValueGroupByFleetTag populates a Query with a query that looks like:
+// SELECT _wstart, `$FLEET_TAG`, avg(`value4`) FROM vehicle WHERE ts >= $START AND ts < $END PARTITION BY `$FLEET_TAG` INTERVAL(1m)
+func (d *TDengineVehicle) MeanValueGroupByFleetTag(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	tag, _ := bulkQuerygen.RandVehicleFleetGroupByTag()
+
+	sql := fmt.Sprintf("SELECT _wstart, %s, avg(%s) FROM vehicle WHERE %s PARTITION BY %s INTERVAL(1m)", column(tag), column(bulkQuerygen.VehicleValueField), timeClause(interval), column(tag))
+
+	humanLabel := fmt.Sprintf("TDengine mean %s, rand %s by 1m, group by %s", bulkQuerygen.VehicleValueField, d.queryInterval, tag)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
+
+// MaxValueOneManufacturerOneCity populates a Query with a query that looks like:
+// SELECT _wstart, max(`value4`) FROM vehicle WHERE `manufacturer` = '$MANUFACTURER' AND `city` = '$CITY' AND ts >= $START AND ts < $END INTERVAL(1m)
+func (d *TDengineVehicle) MaxValueOneManufacturerOneCity(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	manufacturer, city := bulkQuerygen.RandVehicleFleetSlice()
+
+	sql := fmt.Sprintf("SELECT _wstart, max(%s) FROM vehicle WHERE %s = '%s' AND %s = '%s' AND %s INTERVAL(1m)", column(bulkQuerygen.VehicleValueField), column("manufacturer"), manufacturer, column("city"), city, timeClause(interval))
+
+	humanLabel := fmt.Sprintf("TDengine max %s, 1 manufacturer 1 city, rand %s by 1m", bulkQuerygen.VehicleValueField, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
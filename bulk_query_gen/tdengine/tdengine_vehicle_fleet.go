@@ -0,0 +1,40 @@
+package tdengine
+
+import "time"
+import bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+
+// TDengineVehicleFleetGroupBy produces TDengine SQL queries for the vehicle fleet groupby case.
+type TDengineVehicleFleetGroupBy struct {
+	TDengineVehicle
+}
+
+func NewTDengineVehicleFleetGroupBy(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newTDengineVehicleCommon(dbConfig, interval, duration, scaleVar).(*TDengineVehicle)
+	return &TDengineVehicleFleetGroupBy{
+		TDengineVehicle: *underlying,
+	}
+}
+
+func (d *TDengineVehicleFleetGroupBy) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueGroupByFleetTag(q)
+	return q
+}
+
+// TDengineVehicleFleetFilter produces TDengine SQL queries for the vehicle fleet filter case.
+type TDengineVehicleFleetFilter struct {
+	TDengineVehicle
+}
+
+func NewTDengineVehicleFleetFilter(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newTDengineVehicleCommon(dbConfig, interval, duration, scaleVar).(*TDengineVehicle)
+	return &TDengineVehicleFleetFilter{
+		TDengineVehicle: *underlying,
+	}
+}
+
+func (d *TDengineVehicleFleetFilter) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MaxValueOneManufacturerOneCity(q)
+	return q
+}
@@ -0,0 +1,77 @@
+package tdengine
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// TDengineVehicleGeoBoundingBox produces TDengine SQL queries for the vehicle bounding box case.
+type TDengineVehicleGeoBoundingBox struct {
+	TDengineVehicle
+}
+
+func NewTDengineVehicleGeoBoundingBox(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newTDengineVehicleCommon(dbConfig, interval, duration, scaleVar).(*TDengineVehicle)
+	return &TDengineVehicleGeoBoundingBox{
+		TDengineVehicle: *underlying,
+	}
+}
+
+func (d *TDengineVehicleGeoBoundingBox) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.MeanValueInBoundingBox(q)
+	return q
+}
+
+// TDengineVehicleGeoAreaWindow produces TDengine SQL queries for the vehicles in area during window case.
+type TDengineVehicleGeoAreaWindow struct {
+	TDengineVehicle
+}
+
+func NewTDengineVehicleGeoAreaWindow(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newTDengineVehicleCommon(dbConfig, interval, duration, scaleVar).(*TDengineVehicle)
+	return &TDengineVehicleGeoAreaWindow{
+		TDengineVehicle: *underlying,
+	}
+}
+
+func (d *TDengineVehicleGeoAreaWindow) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.VehiclesInAreaDuringWindow(q)
+	return q
+}
+
+// geoBoxClause filters on the latitude and longitude columns, TDengine has no
+// geo index.
+func geoBoxClause(box bulkQuerygen.GeoBox) string {
+	return fmt.Sprintf("%s >= %f AND %s <= %f AND %s >= %f AND %s <= %f",
+		column("latitude"), box.Bottom, column("latitude"), box.Top, column("longitude"), box.Left, column("longitude"), box.Right)
+}
+
+// MeanValueInBoundingBox populates a Query with a query that looks like:
+// SELECT _wstart, avg(`value4`) FROM vehicle WHERE `latitude` >= $SOUTH AND `latitude` <= $NORTH AND `longitude` >= $WEST AND `longitude` <= $EAST AND ts >= $START AND ts < $END INTERVAL(1m)
+func (d *TDengineVehicle) MeanValueInBoundingBox(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	sql := fmt.Sprintf("SELECT _wstart, avg(%s) FROM vehicle WHERE %s AND %s INTERVAL(1m)", column(bulkQuerygen.VehicleValueField), geoBoxClause(box), timeClause(interval))
+
+	humanLabel := fmt.Sprintf("TDengine mean %s, rand %.0fm box, rand %s by 1m", bulkQuerygen.VehicleValueField, 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
+
+// VehiclesInAreaDuringWindow populates a Query with a query that looks like:
+// SELECT `VIN`, count(`latitude`) FROM vehicle WHERE `latitude` >= $SOUTH AND `latitude` <= $NORTH AND `longitude` >= $WEST AND `longitude` <= $EAST AND ts >= $START AND ts < $END PARTITION BY `VIN`
+func (d *TDengineVehicle) VehiclesInAreaDuringWindow(qi bulkQuerygen.Query) {
+	interval := d.AllInterval.RandWindow(d.queryInterval)
+	box := bulkQuerygen.GeoBoxAround(bulkQuerygen.RandVehicleGeoCenter(), bulkQuerygen.VehicleGeoQueryRadius)
+
+	sql := fmt.Sprintf("SELECT %s, count(%s) FROM vehicle WHERE %s AND %s PARTITION BY %s", column("VIN"), column("latitude"), geoBoxClause(box), timeClause(interval), column("VIN"))
+
+	humanLabel := fmt.Sprintf("TDengine vehicles in rand %.0fm box, rand %s", 2*bulkQuerygen.VehicleGeoQueryRadius, d.queryInterval)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
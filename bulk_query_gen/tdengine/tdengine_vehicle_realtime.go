@@ -0,0 +1,41 @@
+package tdengine
+
+import (
+	"fmt"
+	"time"
+
+	bulkQuerygen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+)
+
+// TDengineVehicleRealTime produces TDengine SQL queries for the vehicle real time case.
+type TDengineVehicleRealTime struct {
+	TDengineVehicle
+	bulkQuerygen.TimeWindow
+}
+
+func NewTDengineVehicleRealTime(dbConfig bulkQuerygen.DatabaseConfig, interval bulkQuerygen.TimeInterval, duration time.Duration, scaleVar int) bulkQuerygen.QueryGenerator {
+	underlying := newTDengineVehicleCommon(dbConfig, interval, duration, scaleVar).(*TDengineVehicle)
+	return &TDengineVehicleRealTime{
+		TDengineVehicle: *underlying,
+		TimeWindow:      bulkQuerygen.TimeWindow{Start: interval.Start, Duration: time.Second},
+	}
+}
+
+func (d *TDengineVehicleRealTime) Dispatch(i int) bulkQuerygen.Query {
+	q := bulkQuerygen.NewHTTPQuery() // from pool
+	d.RealTimeQueries(q)
+	return q
+}
+
+// RealTimeQueries populates a Query with a query that reads the raw values of
+// all the vehicles in a window, sliding with --time-window-shift:
+// SELECT ts, `VIN`, `value4` FROM vehicle WHERE ts >= $START AND ts < $END LIMIT 30000
+func (d *TDengineVehicleRealTime) RealTimeQueries(qi bulkQuerygen.Query) {
+	interval := bulkQuerygen.VehicleRealTimeWindow(&d.TimeWindow, &d.AllInterval)
+
+	sql := fmt.Sprintf("SELECT ts, %s, %s FROM vehicle WHERE %s LIMIT %d", column("VIN"), column(bulkQuerygen.VehicleValueField), timeClause(interval), bulkQuerygen.VehicleRealTimeLimit)
+
+	humanLabel := fmt.Sprintf("TDengine real time query, rand %s", d.Duration)
+	q := qi.(*bulkQuerygen.HTTPQuery)
+	d.getHttpQuery(humanLabel, interval, sql, q)
+}
@@ -0,0 +1,87 @@
+package bulk_query_gen
+
+import (
+	"math/rand"
+	"strconv"
+
+	bulkDataGenCommon "github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	bulkDataGenVehicle "github.com/caict-benchmark/BDC-TS/bulk_data_gen/vehicle"
+)
+
+// VehicleFleet is the fleet tag cardinality used for data generation. It
+// must match the bulk_data_gen --fleet-* flags so that filters hit data.
+var VehicleFleet = bulkDataGenVehicle.DefaultFleetConfig
+
+// VehicleValueField is the field aggregated by the vehicle fleet queries.
+const VehicleValueField = "value4"
+
+// VehicleRealTimeLimit caps the points returned by a vehicle real time query.
+const VehicleRealTimeLimit = 30000
+
+// VehicleRealTimeWindow picks the window of a vehicle real time query: tw
+// slides over all with --time-window-shift, otherwise it is a random window
+// of the duration of tw.
+func VehicleRealTimeWindow(tw *TimeWindow, all *TimeInterval) TimeInterval {
+	if TimeWindowShift > 0 {
+		return tw.SlidingWindow(all)
+	}
+	return all.RandWindow(tw.Duration)
+}
+
+// RandVehicleFleetGroupByTag picks a fleet tag to group by, together with the
+// number of distinct values it can have.
+func RandVehicleFleetGroupByTag() (tag string, cardinality int) {
+	fleet := &VehicleFleet
+	tags := bulkDataGenVehicle.FleetTagKeys
+	i := rand.Intn(len(tags))
+	switch string(tags[i]) {
+	case "manufacturer":
+		cardinality = fleet.Manufacturers
+	case "model":
+		cardinality = fleet.Manufacturers * fleet.Models
+	case "province", "city":
+		cardinality = fleet.Cities
+	case "fuel_type":
+		cardinality = len(bulkDataGenVehicle.FuelTypeChoices)
+	case "model_year":
+		cardinality = bulkDataGenVehicle.ModelYearMax - bulkDataGenVehicle.ModelYearMin + 1
+	default:
+		panic("logic error: unknown fleet tag " + strconv.Quote(string(tags[i])))
+	}
+	return string(tags[i]), cardinality
+}
+
+// RandVehicleFleetSlice picks a manufacturer and a city to filter on.
+func RandVehicleFleetSlice() (manufacturer, city string) {
+	fleet := &VehicleFleet
+	return string(fleet.RandManufacturer().Name), string(fleet.RandCity().Name)
+}
+
+// VehicleGeoQueryRadius is the radius, or half the side of the bounding box,
+// of the vehicle geo queries, in meters.
+const VehicleGeoQueryRadius = 2000.0
+
+// GeoBox is a latitude/longitude bounding box.
+type GeoBox struct {
+	Top, Left, Bottom, Right float64
+}
+
+// RandVehicleGeoCenter picks a position inside the road network of one of the
+// configured cities, where vehicles generated with --gps drive.
+func RandVehicleGeoCenter() bulkDataGenCommon.GeoPoint {
+	city := VehicleFleet.RandCity()
+	r := bulkDataGenVehicle.RoadGraphRadius
+	return bulkDataGenVehicle.Offset(city.Center, (rand.Float64()*2-1)*r/2, (rand.Float64()*2-1)*r/2)
+}
+
+// GeoBoxAround returns the bounding box of the given half side around center.
+func GeoBoxAround(center bulkDataGenCommon.GeoPoint, halfSide float64) GeoBox {
+	topLeft := bulkDataGenVehicle.Offset(center, halfSide, -halfSide)
+	bottomRight := bulkDataGenVehicle.Offset(center, -halfSide, halfSide)
+	return GeoBox{
+		Top:    topLeft.Lat,
+		Left:   topLeft.Lon,
+		Bottom: bottomRight.Lat,
+		Right:  bottomRight.Lon,
+	}
+}
@@ -26,14 +26,15 @@ import (
 	"time"
 
 	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
-	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/dashboard"
+	_ "github.com/caict-benchmark/BDC-TS/bulk_data_gen/dashboard"
 	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/devops"
-	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/iot"
+	_ "github.com/caict-benchmark/BDC-TS/bulk_data_gen/iot"
 	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/vehicle"
 )
 
-// Output data format choices:
-var formatChoices = []string{"influx-bulk", "es-bulk", "es-bulk6x", "cassandra", "mongo", "opentsdb", "bcetsdb", "bcetsdb-bulk", "timescaledb-sql", "timescaledb-copyFrom", "graphite-line", "graphite-pickle", "alitsdb-http", "alitsdb"}
+// Use cases and formats are registered by the packages above, see
+// common.RegisterSimulator and common.RegisterSerializer. Link in other
+// packages to get more of them.
 
 // Program option vars:
 var (
@@ -61,14 +62,25 @@ var (
 
 	cpuProfile string
 
-	startVinIndex int
+	fleetTags   bool
+	fleetConfig vehicle.FleetConfig
+
+	schemaChangesStr string
+	schemaEvolution  *common.SchemaEvolution
+
+	flushPoints int64
+	batchPoints int64
 )
 
 // Parse args:
 func init() {
-	flag.StringVar(&format, "format", formatChoices[0], fmt.Sprintf("Format to emit. (choices: %s)", strings.Join(formatChoices, ", ")))
+	flag.StringVar(&format, "format", "influx-bulk", fmt.Sprintf("Format to emit. (choices: %s)", strings.Join(common.SerializerNames(), ", ")))
+
+	flag.Int64Var(&flushPoints, "flush-points", 100000, "Number of points after which the file formats (csv, parquet) flush. Their columns are those of the points before the first flush, and for parquet this is the row group size.")
 
-	flag.StringVar(&useCase, "use-case", common.UseCaseChoices[0], fmt.Sprintf("Use case to model. (choices: %s)", strings.Join(common.UseCaseChoices, ", ")))
+	flag.Int64Var(&batchPoints, "batch-points", 0, "Number of points framed together by the binary formats supporting it (alitsdb, timescaledb-copyFrom, mongo), 0 to frame each point on its own.")
+
+	flag.StringVar(&useCase, "use-case", common.UseCaseDevOps, fmt.Sprintf("Use case to model. (choices: %s)", strings.Join(common.SimulatorNames(), ", ")))
 	flag.Int64Var(&scaleVar, "scale-var", 20000, "Scaling variable specific to the use case.")
 	flag.Int64Var(&scaleVarOffset, "scale-var-offset", 0, "Scaling variable offset specific to the use case.")
 	flag.DurationVar(&samplingInterval, "sampling-interval", vehicle.EpochDuration, "Simulated sampling interval.")
@@ -84,7 +96,15 @@ func init() {
 
 	flag.StringVar(&cpuProfile, "cpu-profile", "", "Write CPU profile to `file`")
 
-	flag.IntVar(&startVinIndex, "start-vin-index", 100000, "which first vin do you want to generate")
+	flag.IntVar(&vehicle.Options.StartVinIndex, "start-vin-index", vehicle.Options.StartVinIndex, "which first vin do you want to generate")
+
+	flag.BoolVar(&fleetTags, "fleet-tags", false, "Add static fleet tags (manufacturer, model, province, city, fuel_type, model_year) to vehicles.")
+	flag.IntVar(&fleetConfig.Manufacturers, "fleet-manufacturers", vehicle.DefaultFleetConfig.Manufacturers, "Number of distinct vehicle manufacturers (with --fleet-tags).")
+	flag.IntVar(&fleetConfig.Models, "fleet-models", vehicle.DefaultFleetConfig.Models, "Number of distinct models per manufacturer (with --fleet-tags).")
+	flag.IntVar(&fleetConfig.Cities, "fleet-cities", vehicle.DefaultFleetConfig.Cities, "Number of distinct cities (with --fleet-tags).")
+	flag.BoolVar(&vehicle.Options.GPS, "gps", false, "Add GPS trajectories (latitude and longitude fields) to vehicles.")
+
+	flag.StringVar(&schemaChangesStr, "schema-changes", "", "Comma-separated schema changes applied during the run, each op:measurement.field[:type]@time[/fraction] with op one of add, drop, retype and type one of float, int, string. Example: add:cpu.usage_extra:float@2018-01-01T06:00:00Z/0.5")
 
 	flag.Parse()
 
@@ -93,7 +113,7 @@ func init() {
 	}
 
 	validFormat := false
-	for _, s := range formatChoices {
+	for _, s := range common.SerializerNames() {
 		if s == format {
 			validFormat = true
 			break
@@ -103,6 +123,18 @@ func init() {
 		log.Fatal("invalid format specifier")
 	}
 
+	if useCase == common.UseCaseVehicle {
+		if vehicle.Options.StartVinIndex < 0 || int64(vehicle.Options.StartVinIndex)+scaleVar-1 > vehicle.VinSerialMax {
+			log.Fatalf("VIN serials must fit in [0, %d]", vehicle.VinSerialMax)
+		}
+		if fleetTags {
+			if err := fleetConfig.Validate(); err != nil {
+				log.Fatal(err)
+			}
+			vehicle.Options.Fleet = &fleetConfig
+		}
+	}
+
 	// the default seed is the current timestamp:
 	if seed == 0 {
 		seed = int64(time.Now().Nanosecond())
@@ -122,6 +154,25 @@ func init() {
 	}
 	timestampEnd = timestampEnd.UTC()
 
+	if schemaChangesStr != "" {
+		changes, err := common.ParseSchemaChanges(schemaChangesStr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		schemaEvolution = common.NewSchemaEvolution(changes)
+		for i := range changes {
+			log.Printf("Schema change %s\n", &changes[i])
+		}
+	}
+
+	if flushPoints <= 0 {
+		log.Fatal("Invalid flush points")
+	}
+
+	if batchPoints < 0 {
+		log.Fatal("Invalid batch points")
+	}
+
 	if samplingInterval <= 0 {
 		log.Fatal("Invalid sampling interval")
 	}
@@ -146,82 +197,35 @@ func main() {
 	out := bufio.NewWriterSize(os.Stdout, 4<<20)
 	defer out.Flush()
 
-	var sim common.Simulator
+	sim, err := common.NewSimulator(useCase, &common.SimulatorConfig{
+		Start: timestampStart,
+		End:   timestampEnd,
 
-	switch useCase {
-	case common.UseCaseChoices[0]:
-		cfg := &devops.DevopsSimulatorConfig{
-			Start: timestampStart,
-			End:   timestampEnd,
-
-			HostCount:  scaleVar,
-			HostOffset: scaleVarOffset,
-		}
-		sim = cfg.ToSimulator()
-	case common.UseCaseChoices[2]:
-		cfg := &dashboard.DashboardSimulatorConfig{
-			Start: timestampStart,
-			End:   timestampEnd,
-
-			HostCount:  scaleVar,
-			HostOffset: scaleVarOffset,
-		}
-		sim = cfg.ToSimulator()
-	case common.UseCaseChoices[1]:
-		cfg := &iot.IotSimulatorConfig{
-			Start: timestampStart,
-			End:   timestampEnd,
-
-			SmartHomeCount:  scaleVar,
-			SmartHomeOffset: scaleVarOffset,
-		}
-		sim = cfg.ToSimulator()
-	case common.UseCaseChoices[3]:
-		cfg := &vehicle.VehicleSimulatorConfig{
-			Start: timestampStart,
-			End:   timestampEnd,
+		ScaleVar:       scaleVar,
+		ScaleVarOffset: scaleVarOffset,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 
-			VehicleCount:  scaleVar,
-			VehicleOffset: scaleVarOffset,
+	serializer, err := common.NewSerializer(format)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-			StartVinIndex: startVinIndex,
+	fileSerializer, isFileSerializer := serializer.(common.FileSerializer)
+	if isFileSerializer {
+		if err := fileSerializer.Open(out); err != nil {
+			log.Fatal(err)
 		}
-		sim = cfg.ToSimulator()
-	default:
-		panic("unreachable")
 	}
-
-	var serializer common.Serializer
-	switch format {
-	case "influx-bulk":
-		serializer = common.NewSerializerInflux()
-	case "es-bulk":
-		serializer = common.NewSerializerElastic("5x")
-	case "es-bulk6x":
-		serializer = common.NewSerializerElastic("6x")
-	case "cassandra":
-		serializer = common.NewSerializerCassandra()
-	case "bcetsdb":
-		serializer = common.NewSerializerBceTSDB()
-	case "bcetsdb-bulk":
-		serializer = common.NewSerializerBceTSDBBulk()
-	case "mongo":
-		serializer = common.NewSerializerMongo()
-	case "opentsdb":
-		serializer = common.NewSerializerOpenTSDB()
-	case "timescaledb-sql":
-		serializer = common.NewSerializerTimescaleSql()
-	case "timescaledb-copyFrom":
-		serializer = common.NewSerializerTimescaleBin()
-	case "graphite-line":
-		serializer = common.NewSerializerGraphiteLine()
-	case "alitsdb-http":
-		serializer = common.NewSerializerAliTSDBHttp()
-	case "alitsdb":
-		serializer = common.NewSerializerAliTSDB()
-	default:
-		panic("unreachable")
+	batchSerializer, isBatchSerializer := serializer.(common.BatchSerializer)
+	if batchPoints > 0 && !isBatchSerializer {
+		log.Printf("Format %s frames each point on its own, ignoring batch points\n", format)
 	}
+	isBatchSerializer = isBatchSerializer && batchPoints > 0
+	inBatch := false
+	var written int64
 
 	var currentInterleavedGroup uint = 0
 
@@ -229,9 +233,15 @@ func main() {
 	n := int64(0)
 	last := time.Now()
 	log.Printf("%d points\n", sim.Total())
+	// the point is serialized before the next one is generated, so one
+	// point is enough:
+	point := common.MakeUsablePoint()
 	for !sim.Finished() {
-		point := common.MakeUsablePoint()
+		point.Reset()
 		sim.Next(point)
+		if schemaEvolution != nil {
+			schemaEvolution.Apply(point)
+		}
 		n++
 
 		if n % 10000 == 0 {
@@ -246,10 +256,28 @@ func main() {
 		// in the default case this is always true
 		if currentInterleavedGroup == interleavedGenerationGroupID {
 			//println("printing")
+			if isBatchSerializer && !inBatch {
+				if err := batchSerializer.BeginBatch(out); err != nil {
+					log.Fatal(err)
+				}
+				inBatch = true
+			}
 			err := serializer.SerializePoint(out, point)
 			if err != nil {
 				log.Fatal(err)
 			}
+			written++
+			if isBatchSerializer && written%batchPoints == 0 {
+				if err := batchSerializer.EndBatch(out); err != nil {
+					log.Fatal(err)
+				}
+				inBatch = false
+			}
+			if isFileSerializer && written%flushPoints == 0 {
+				if err := fileSerializer.Flush(out); err != nil {
+					log.Fatal(err)
+				}
+			}
 
 		}
 
@@ -263,10 +291,25 @@ func main() {
 	if n != sim.SeenPoints() {
 		panic(fmt.Sprintf("Logic error, written %d points, generated %d points", n, sim.SeenPoints()))
 	}
-	serializer.SerializeSize(out, sim.SeenPoints(), sim.SeenValues())
-	err := out.Flush()
+	values := sim.SeenValues()
+	if schemaEvolution != nil {
+		values += schemaEvolution.ValuesDelta()
+	}
+	if inBatch {
+		if err := batchSerializer.EndBatch(out); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if isFileSerializer {
+		if err := fileSerializer.Close(out); err != nil {
+			log.Fatal(err)
+		}
+	}
+	serializer.SerializeSize(out, sim.SeenPoints(), values)
+	err = out.Flush()
 	dur := time.Now().Sub(t)
-	log.Printf("Written %d points, %d values, took %0f seconds\n", n, sim.SeenValues(), dur.Seconds())
+	log.Printf("Written %d points, %d values, took %0f seconds (%.0f points/sec, %.0f values/sec)\n",
+		n, values, dur.Seconds(), float64(n)/dur.Seconds(), float64(values)/dur.Seconds())
 	if err != nil {
 		log.Fatal(err.Error())
 	}
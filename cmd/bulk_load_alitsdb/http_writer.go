@@ -3,57 +3,41 @@ package main
 import (
 	"bytes"
 	"fmt"
-	alitsdb_serialization "github.com/caict-benchmark/BDC-TS/alitsdb_serializaition"
-	"log"
-	"sync"
 	"time"
 
-	"github.com/valyala/fasthttp"
-)
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
 
-var (
-	BackoffError      error  = fmt.Errorf("backpressure is needed")
-	backoffMagicWords []byte = []byte("engine: cache maximum memory size exceeded")
+	"github.com/valyala/fasthttp"
 )
 
-// LineProtocolWriter is the interface used to write OpenTSDB bulk data.
-type LineProtocolWriter interface {
-	// WriteLineProtocol writes the given byte slice containing bulk data
-	// to an implementation-specific remote server.
-	// Returns the latency, in nanoseconds, of executing the write against the remote server and applicable errors.
-	// Implementers must return errors returned by the underlying transport but are free to return
-	// other, context-specific errors.
-
-	//WriteLineProtocol([]byte) (latencyNs int64, err error)
-	ProcessBatches(doLoad bool, bufPool *sync.Pool, wg *sync.WaitGroup, backoff time.Duration, backingOffChan chan bool)
-	PutPoint(point *alitsdb_serialization.MputRequest)
-	Close()
-}
-
-// HTTPWriterConfig is the configuration used to create an HTTPWriter.
+// WriterConfig is the configuration used to create an HTTPWriter or a
+// RpcWriter.
 type WriterConfig struct {
-	// URL of the host, in form "http://example.com:8086"
-	Host string
-	Port int
+	// Address of the daemon, in form "example.com:8242"
+	Url string
+
+	// Retry decides which failed writes are written again, and when.
+	Retry *bulk_load.RetryPolicy
 }
 
-// HTTPWriter is a Writer that writes to an OpenTSDB HTTP server.
+// HTTPWriter is a Writer that writes to the /api/mput endpoint of AliTSDB.
 type HTTPWriter struct {
 	client fasthttp.Client
 
-	c   WriterConfig
-	url []byte
+	c          WriterConfig
+	url        []byte
+	compressed bytes.Buffer
 }
 
-// NewHTTPWriter returns a new HTTPWriter from the supplied HTTPWriterConfig.
-func NewHTTPWriter(c WriterConfig) LineProtocolWriter {
+// NewHTTPWriter returns a new HTTPWriter from the supplied WriterConfig.
+func NewHTTPWriter(c WriterConfig) *HTTPWriter {
 	return &HTTPWriter{
 		client: fasthttp.Client{
 			Name: "bulk_load_alitsdb",
 		},
 
 		c:   c,
-		url: []byte(fmt.Sprintf("http://%s:%d/api/mput", c.Host, c.Port)),
+		url: []byte(fmt.Sprintf("http://%s/api/mput", c.Url)),
 	}
 }
 
@@ -62,15 +46,19 @@ var (
 	applicationJsonHeader = []byte("application/json")
 )
 
-func (w *HTTPWriter) PutPoint(point *alitsdb_serialization.MputRequest) {
+// WriteBatch writes the JSON array of points of b, gzipped.
+func (w *HTTPWriter) WriteBatch(b *bulk_load.Batch) error {
+	w.compressed.Reset()
+	fasthttp.WriteGzip(&w.compressed, b.Buffer.Bytes())
+	_, err := w.WriteLineProtocol(w.compressed.Bytes())
+	return err
 }
 
-func (w *HTTPWriter) Close() {
-
+func (w *HTTPWriter) Close() error {
+	return nil
 }
 
-
-// WriteLineProtocol writes the given byte slice to the HTTP server described in the Writer's HTTPWriterConfig.
+// WriteLineProtocol writes the given byte slice to the HTTP server described in the Writer's WriterConfig.
 // It returns the latency in nanoseconds and any error received while sending the data over HTTP,
 // or it returns a new error if the HTTP response isn't as expected.
 func (w *HTTPWriter) WriteLineProtocol(body []byte) (int64, error) {
@@ -87,12 +75,9 @@ func (w *HTTPWriter) WriteLineProtocol(body []byte) (int64, error) {
 	lat := time.Since(start).Nanoseconds()
 	if err == nil {
 		sc := resp.StatusCode()
-		//if sc == 500 && backpressurePred(resp.Body()) {
-		//	err = BackoffError
 		if sc != fasthttp.StatusNoContent && sc != fasthttp.StatusOK {
-			if sc == 500 && backpressurePred(resp.Body()) {
-				err = BackoffError
-			} else {
+			err = w.c.Retry.Classify(sc, resp.Body())
+			if err == nil {
 				err = fmt.Errorf("Invalid write response (status %d): %s", sc, resp.Body())
 			}
 		}
@@ -103,35 +88,3 @@ func (w *HTTPWriter) WriteLineProtocol(body []byte) (int64, error) {
 
 	return lat, err
 }
-
-// ProcessBatches read the data from input stream and write by batch
-func (w *HTTPWriter) ProcessBatches(doLoad bool, bufPool *sync.Pool, wg *sync.WaitGroup, backoff time.Duration, backingOffChan chan bool) {
-	for batch := range batchChan {
-		// Write the batch: try until backoff is not needed.
-		if doLoad {
-			var err error
-			for {
-				_, err = w.WriteLineProtocol(batch.Bytes())
-				if err == BackoffError {
-					backingOffChan <- true
-					time.Sleep(backoff)
-				} else {
-					backingOffChan <- false
-					break
-				}
-			}
-			if err != nil {
-				log.Fatalf("Error writing: %s\n", err.Error())
-			}
-		}
-
-		// Return the batch buffer to the pool.
-		batch.Reset()
-		bufPool.Put(batch)
-	}
-	wg.Done()
-}
-
-func backpressurePred(body []byte) bool {
-	return bytes.Contains(body, backoffMagicWords)
-}
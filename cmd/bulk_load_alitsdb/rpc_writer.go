@@ -2,18 +2,17 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"encoding/binary"
 	"log"
-	"math/rand"
+	"strconv"
 	"strings"
-	"sync"
-	"sync/atomic"
 	"time"
 
 	alitsdb_serialization "github.com/caict-benchmark/BDC-TS/alitsdb_serializaition"
 	cmap "github.com/orcaman/concurrent-map"
 
 	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
 
 	"google.golang.org/grpc"
 )
@@ -25,106 +24,145 @@ var (
 	fieldNameCache = cmap.New()
 )
 
+// RpcWriter is a Writer that writes with the Mput RPC of AliTSDB. A request
+// of the input is written as it is to the daemon picked by the last byte of
+// the series key of its first point, so that the points of a series, which
+// are requests of their own without --batch-points, always go to the same
+// daemon.
 type RpcWriter struct {
-	c          WriterConfig
-	url        string
-	pointsChan chan *alitsdb_serialization.MputRequest
-	processes  int32
+	c WriterConfig
+	// a client per daemon, connected on first use
+	clients []*Client
+	// the requests of a batch, per daemon and field names
+	requests map[string]*alitsdb_serialization.MputRequest
+	order    []string
+	req      alitsdb_serialization.MputRequest
 }
 
 var logcount = 0
 
-// WriteLineProtocol returns the latency in nanoseconds and any error received while sending the data over RPC,
-// or it returns a new error if the RPC response isn't as expected.
+// WriteLineProtocol returns the latency in nanoseconds and any error received while sending the data over RPC.
+// A failed mput is retried by the worker on a new connection, as a RetryableError.
 func (w *RpcWriter) WriteLineProtocol(client *Client, req *alitsdb_serialization.MputRequest) (latencyNs int64, err error) {
-
-	if doLoad {
-		retries := 0
-
-		for {
-			last := time.Now()
-			//TODO: send the write request
-			ctx, cel := context.WithTimeout(context.Background(), time.Second*20)
-			defer cel()
-			resp, err := client.client.Mput(ctx, req)
-			now := time.Now()
-			dur := now.Sub(last).Milliseconds()
-			if dur > 4000 {
-				logcount++
-				if logcount%1000 == 0 {
-					log.Printf("Timeout request mput points(%d): %dms %s\n", len(req.Points), dur, client.url)
-				}
-			}
-
-			if err == nil {
-				if !resp.Ret {
-					log.Println("[WARN] mput request succeeded but retval is false")
-				}
-				// request succeeded so no need to retry
-				break
-			} else {
-				log.Printf("Error request mput interface(%d: %d): %s tries: %d %s\n", len(req.Fnames),
-					len(req.Points), client.url, retries, err.Error())
-				retries++
-
-				s1 := rand.NewSource(time.Now().UnixNano())
-				r1 := rand.New(s1)
-
-				// wait a while
-				time.Sleep(time.Duration(r1.Intn(10)) * time.Second)
-				// then start to retry
-				client.close()
-				if client.init() != nil {
-					/* init failed */
-					log.Println("[WARN] MultiFieldsPutServiceClient initialization failed")
-				}
-			}
+	last := time.Now()
+	ctx, cel := context.WithTimeout(context.Background(), time.Second*20)
+	defer cel()
+	resp, err := client.client.Mput(ctx, req)
+	now := time.Now()
+	dur := now.Sub(last).Milliseconds()
+	if dur > 4000 {
+		logcount++
+		if logcount%1000 == 0 {
+			log.Printf("Timeout request mput points(%d): %dms %s\n", len(req.Points), dur, client.url)
 		}
-
-		// it means all attempts failed when the retries decreased to zero
-		//if retries == 0 {
-		//	log.Fatalf("[Fatal]Error caused all retry attempts failed")
-		//}
 	}
 
-	return 0, err
+	if err != nil {
+		log.Printf("Error request mput interface(%d: %d): %s %s\n", len(req.Fnames),
+			len(req.Points), client.url, err.Error())
+		client.close()
+		if client.init() != nil {
+			/* init failed */
+			log.Println("[WARN] MultiFieldsPutServiceClient initialization failed")
+		}
+		return 0, &bulk_load.RetryableError{Body: err.Error()}
+	}
+	if !resp.Ret {
+		log.Println("[WARN] mput request succeeded but retval is false")
+	}
+	return now.Sub(last).Nanoseconds(), nil
 }
 
-// NewRPCWriter returns a new RPCWriter from the supplied WriterConfig.
-func NewRPCWriter(c WriterConfig) LineProtocolWriter {
+// NewRPCWriter returns a new RPCWriter from the supplied WriterConfig. It
+// checks that the daemon at c.Url can be connected.
+func NewRPCWriter(c WriterConfig) *RpcWriter {
 	writer := &RpcWriter{
-		c:   c,
-		url: fmt.Sprintf("%s:%d", c.Host, c.Port),
+		c:        c,
+		clients:  make([]*Client, len(loader.DaemonUrls)),
+		requests: map[string]*alitsdb_serialization.MputRequest{},
 	}
 
-	client := newClient(writer.url)
-	err := client.init()
-	if err != nil {
-		log.Fatalf("Error connecting: %s\n", err.Error())
+	if loader.DoLoad {
+		client := newClient(c.Url)
+		err := client.init()
+		if err != nil {
+			log.Fatalf("Error connecting: %s\n", err.Error())
+		}
+		client.close()
 	}
 
-	client.close()
+	return writer
+}
 
-	writer.pointsChan = make(chan *alitsdb_serialization.MputRequest, batchSize*100)
+// WriteBatch writes the requests of b, those of the same daemon and field
+// names joined into one.
+func (w *RpcWriter) WriteBatch(b *bulk_load.Batch) error {
+	for _, key := range w.order {
+		w.requests[key].Points = w.requests[key].Points[:0]
+	}
+	w.order = w.order[:0]
+
+	body := b.Buffer.Bytes()
+	for len(body) >= 8 {
+		size := binary.LittleEndian.Uint64(body)
+		body = body[8:]
+		w.req.Reset()
+		if err := w.req.Unmarshal(body[:size]); err != nil {
+			return err
+		}
+		body = body[size:]
 
-	return writer
+		if len(w.req.Points) == 0 {
+			continue
+		}
+		daemon := 0
+		if seriesKey := w.req.Points[0].Serieskey; len(seriesKey) > 0 {
+			daemon = int(seriesKey[len(seriesKey)-1]) % len(w.clients)
+		}
+		key := strconv.Itoa(daemon) + "\x00" + strings.Join(w.req.Fnames, "\x00")
+		req, ok := w.requests[key]
+		if !ok {
+			req = &alitsdb_serialization.MputRequest{Fnames: w.req.Fnames}
+			w.requests[key] = req
+		}
+		if len(req.Points) == 0 {
+			w.order = append(w.order, key)
+		}
+		req.Points = append(req.Points, w.req.Points...)
+	}
+
+	for _, key := range w.order {
+		daemon, _ := strconv.Atoi(key[:strings.IndexByte(key, 0)])
+		client, err := w.client(daemon)
+		if err != nil {
+			return &bulk_load.RetryableError{Body: err.Error()}
+		}
+		if _, err := w.WriteLineProtocol(client, w.requests[key]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (w *RpcWriter) Close() {
-	for i := 0; i < int(w.processes); i++ {
-		w.pointsChan <- nil
+// client returns the client of the daemon, connecting it on first use.
+func (w *RpcWriter) client(daemon int) (*Client, error) {
+	if w.clients[daemon] == nil {
+		client := newClient(loader.DaemonUrls[daemon])
+		if err := client.init(); err != nil {
+			return nil, err
+		}
+		w.clients[daemon] = client
 	}
-	//close(w.pointsChan)
+	return w.clients[daemon], nil
 }
 
-func (w *RpcWriter) PutPoint(point *alitsdb_serialization.MputRequest) {
-	if debug {
-		size := len(w.pointsChan)
-		if size <= batchSize && size > 0 {
-			fmt.Printf("size: %d\n", size)
+func (w *RpcWriter) Close() error {
+	for _, client := range w.clients {
+		if client != nil {
+			client.close()
 		}
 	}
-	w.pointsChan <- point
+	return nil
 }
 
 type Client struct {
@@ -160,84 +198,6 @@ func (c *Client) init() error {
 	}
 }
 
-var requestPool = sync.Pool{
-	New: func() interface{} {
-		return new(alitsdb_serialization.MputRequest)
-	},
-}
-
-var done_count = 0
-
-// ProcessBatches read the data from input stream and write by batch
-func (w *RpcWriter) ProcessBatches(doLoad bool, bufPool *sync.Pool, wg *sync.WaitGroup, backoff time.Duration, backingOffChan chan bool) {
-	atomic.AddInt32(&w.processes, 1)
-	client := newClient(w.url)
-	if client.init() != nil {
-		return
-	}
-
-	buff := make([]*alitsdb_serialization.MputRequest, 0, batchSize)
-	var n int
-	var exit = false
-
-	for {
-		var basePoint *alitsdb_serialization.MputRequest
-		select {
-		case basePoint = <-w.pointsChan:
-			if basePoint != nil {
-				buff = append(buff, basePoint)
-				n++
-				done_count++
-			} else {
-				lastWriteTime = time.Now()
-				exit = true
-			}
-		}
-
-		if n > 0 && (n >= batchSize || exit) {
-			var err error
-			for {
-				startTimeOnce.Do(func() {
-					firstWriteTime = time.Now()
-				})
-
-				req := requestPool.Get().(*alitsdb_serialization.MputRequest)
-				//req := new(alitsdb_serialization.MputRequest)
-				req.Points = make([]*alitsdb_serialization.MputPoint, len(buff))
-				for i, p := range buff {
-					req.Points[i] = p.Points[0]
-					req.Fnames = p.Fnames
-				}
-				_, err = w.WriteLineProtocol(client, req)
-				req.Reset()
-				requestPool.Put(req)
-
-				for _, p := range buff {
-					tasksGroup.Done()
-					p.Reset()
-					pointPool.Put(p)
-				}
-
-				backingOffChan <- false
-				break
-			}
-			if err != nil {
-				log.Fatalf("Error writing: %s\n", err.Error())
-			}
-
-			n = 0
-			buff = nil
-			buff = make([]*alitsdb_serialization.MputRequest, 0, batchSize)
-		}
-
-		if exit {
-			break
-		}
-	}
-
-	wg.Done()
-}
-
 func getMetric(mp *alitsdb_serialization.MultifieldPoint) string {
 	firstDeli := strings.IndexByte(mp.GetSerieskey(), byte(common.SerieskeyDelimeter))
 	var metric string
@@ -5,12 +5,13 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+	"github.com/klauspost/compress/gzip"
 	"github.com/valyala/fasthttp"
 )
 
 var (
-	BackoffError error = fmt.Errorf("backpressure is needed")
-	backoffMagicWords []byte = []byte("engine: cache maximum memory size exceeded")
+	BackoffError error = bulk_load.ErrBackoff
 )
 
 // LineProtocolWriter is the interface used to write Bcetsdb data.
@@ -27,18 +28,23 @@ type LineProtocolWriter interface {
 type HTTPWriterConfig struct {
 	// URL of the host, in form "http://example.com:8011"
 	Host string
+
+	// Retry classifies the retryable error responses.
+	Retry *bulk_load.RetryPolicy
 }
 
 // HTTPWriter is a Writer that writes to an bcetsdb HTTP server.
 type HTTPWriter struct {
 	client fasthttp.Client
 
-	c   HTTPWriterConfig
-	url []byte
+	c          HTTPWriterConfig
+	url        []byte
+	compressed bytes.Buffer
+	zw         *gzip.Writer
 }
 
 // NewHTTPWriter returns a new HTTPWriter from the supplied HTTPWriterConfig.
-func NewHTTPWriter(c HTTPWriterConfig) LineProtocolWriter {
+func NewHTTPWriter(c HTTPWriterConfig) *HTTPWriter {
 	return &HTTPWriter{
 		client: fasthttp.Client{
 			Name: "bulk_load_bcetsdb",
@@ -71,10 +77,11 @@ func (w *HTTPWriter) WriteLineProtocol(body []byte) (int64, error) {
 	lat := time.Since(start).Nanoseconds()
 	if err == nil {
 		sc := resp.StatusCode()
-		//if sc == 500 && backpressurePred(resp.Body()) {
-		//	err = BackoffError
-		if (sc != fasthttp.StatusNoContent && sc != fasthttp.StatusOK) {
-			err = fmt.Errorf("Invalid write response (status %d): %s", sc, resp.Body())
+		if sc != fasthttp.StatusNoContent && sc != fasthttp.StatusOK {
+			err = w.c.Retry.Classify(sc, resp.Body())
+			if err == nil {
+				err = fmt.Errorf("Invalid write response (status %d): %s", sc, resp.Body())
+			}
 		}
 	}
 
@@ -84,6 +91,19 @@ func (w *HTTPWriter) WriteLineProtocol(body []byte) (int64, error) {
 	return lat, err
 }
 
-func backpressurePred(body []byte) bool {
-	return bytes.Contains(body, backoffMagicWords)
+// WriteBatch gzips the write request of b and writes it.
+func (w *HTTPWriter) WriteBatch(b *bulk_load.Batch) error {
+	if w.zw == nil {
+		w.zw = gzip.NewWriter(&w.compressed)
+	}
+	w.compressed.Reset()
+	w.zw.Reset(&w.compressed)
+	w.zw.Write(b.Buffer.Bytes())
+	w.zw.Close()
+	_, err := w.WriteLineProtocol(w.compressed.Bytes())
+	return err
+}
+
+func (w *HTTPWriter) Close() error {
+	return nil
 }
@@ -1,91 +1,58 @@
-// bulk_load_cassandra loads a Cassandra daemon with data from stdin.
+// bulk_load_cassandra loads a Cassandra daemon with data from stdin or files.
 //
 // The caller is responsible for assuring that the database is empty before
 // bulk load.
 package main
 
 import (
-	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"os"
-	"sync"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/gocql/gocql"
 	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
 	"github.com/caict-benchmark/BDC-TS/util/report"
-	"strconv"
-	"strings"
+	"github.com/gocql/gocql"
 )
 
 // Program option vars:
 var (
-	daemonUrl      string
-	workers        int
-	batchSize      int
-	doLoad         bool
-	writeTimeout   time.Duration
-	reportDatabase string
-	reportHost     string
-	reportUser     string
-	reportPassword string
-	reportTagsCSV  string
-	compressor     string
-	useCase        string
+	loader = &bulk_load.Loader{
+		Config: bulk_load.Config{
+			Urls:      "localhost:9042",
+			BatchSize: 100,
+		},
+		DBType: "Cassandra",
+	}
+	writeTimeout  time.Duration
+	compressor    string
+	useCase       string
+	schemaMigrate bool
 )
 
 // Global vars
 var (
-	batchChan      chan *gocql.Batch
-	inputDone      chan struct{}
-	workersGroup   sync.WaitGroup
-	reportTags     [][2]string
-	reportHostname string
+	session  *gocql.Session
+	migrator *schemaMigrator
 )
 
 // Parse args:
 func init() {
-	flag.StringVar(&daemonUrl, "url", "localhost:9042", "Cassandra URL.")
-
-	flag.IntVar(&batchSize, "batch-size", 100, "Batch size (input items).")
-	flag.IntVar(&workers, "workers", 1, "Number of parallel requests to make.")
+	loader.AddFlags(flag.CommandLine)
+	flag.StringVar(&loader.Urls, "url", loader.Urls, "Deprecated, use -urls.")
 	flag.DurationVar(&writeTimeout, "write-timeout", 60*time.Second, "Write timeout.")
 	flag.StringVar(&compressor, "compressor", "LZ4Compressor", "Table compressor: DeflateCompressor, LZ4Compressor or SnappyCompressor ")
 	flag.StringVar(&useCase, "use-case", common.UseCaseChoices[0], "Use case to set specific load behavior. Options: "+strings.Join(common.UseCaseChoices, ","))
-
-	flag.BoolVar(&doLoad, "do-load", true, "Whether to write data. Set this flag to false to check input read speed.")
-
-	flag.StringVar(&reportDatabase, "report-database", "database_benchmarks", "Database name where to store result metrics")
-	flag.StringVar(&reportHost, "report-host", "", "Host to send result metrics")
-	flag.StringVar(&reportUser, "report-user", "", "User for host to send result metrics")
-	flag.StringVar(&reportPassword, "report-password", "", "User password for Host to send result metrics")
-	flag.StringVar(&reportTagsCSV, "report-tags", "", "Comma separated k:v tags to send  alongside result metrics")
+	flag.BoolVar(&schemaMigrate, "schema-migrate", false, "Whether to add table columns when the input carries new fields or changed field types (see bulk_data_gen --schema-changes).")
 
 	flag.Parse()
 
-	if reportHost != "" {
-		fmt.Printf("results report destination: %v\n", reportHost)
-		fmt.Printf("results report database: %v\n", reportDatabase)
-
-		var err error
-		reportHostname, err = os.Hostname()
-		if err != nil {
-			log.Fatalf("os.Hostname() error: %s", err.Error())
-		}
-		fmt.Printf("hostname for results report: %v\n", reportHostname)
-
-		if reportTagsCSV != "" {
-			pairs := strings.Split(reportTagsCSV, ",")
-			for _, pair := range pairs {
-				fields := strings.SplitN(pair, ":", 2)
-				tagpair := [2]string{fields[0], fields[1]}
-				reportTags = append(reportTags, tagpair)
-			}
-		}
-		fmt.Printf("results report tags: %v\n", reportTags)
-	}
+	loader.Init()
 }
 
 func main() {
@@ -94,19 +61,15 @@ func main() {
 		common.UseCaseIot:    createTablesCQLIot,
 	}
 
-	if doLoad {
+	if loader.DoLoad {
 		log.Println("Creating keyspace")
 		if tablesCql, ok := ucTablesMap[useCase]; ok {
-			createKeyspace(daemonUrl, tablesCql)
+			createKeyspace(loader.DaemonUrls, tablesCql)
 		} else {
 			log.Fatalf("Unsupport use-case: %s\n", useCase)
 		}
-	}
-
-	var session *gocql.Session
 
-	if doLoad {
-		cluster := gocql.NewCluster(daemonUrl)
+		cluster := gocql.NewCluster(loader.DaemonUrls...)
 		cluster.Keyspace = "measurements"
 		cluster.Timeout = writeTimeout
 		cluster.Consistency = gocql.One
@@ -117,131 +80,91 @@ func main() {
 			log.Fatal(err)
 		}
 		defer session.Close()
-	}
 
-	batchChan = make(chan *gocql.Batch, workers)
-	inputDone = make(chan struct{})
-	log.Println("Starting workers")
-	for i := 0; i < workers; i++ {
-		workersGroup.Add(1)
-		go processBatches(session)
+		if schemaMigrate {
+			migrator, err = newSchemaMigrator(session)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
 	}
 
-	start := time.Now()
-	itemsRead, bytesRead, valuesRead := scan(session, batchSize)
-
-	<-inputDone
-	close(batchChan)
-	workersGroup.Wait()
-	end := time.Now()
-	took := end.Sub(start)
-
-	itemsRate := float64(itemsRead) / float64(took.Seconds())
-	bytesRate := float64(bytesRead) / float64(took.Seconds())
-	valuesRate := float64(valuesRead) / float64(took.Seconds())
-
-	fmt.Printf("loaded %d items in %fsec with %d workers (mean point rate %.2f/s, mean value rate %.2f/s, %.2fMB/sec from stdin)\n", itemsRead, took.Seconds(), workers, itemsRate, valuesRate, bytesRate/(1<<20))
-
-	if reportHost != "" {
-		//append db specific tags to custom tags
-		reportTags = append(reportTags, [2]string{"write_timeout", strconv.Itoa(int(writeTimeout))})
+	res := loader.Run(driver{})
 
-		reportParams := &report.LoadReportParams{
-			ReportParams: report.ReportParams{
-				DBType:             "Cassandra",
-				ReportDatabaseName: reportDatabase,
-				ReportHost:         reportHost,
-				ReportUser:         reportUser,
-				ReportPassword:     reportPassword,
-				ReportTags:         reportTags,
-				Hostname:           reportHostname,
-				DestinationUrl:     daemonUrl,
-				Workers:            workers,
-				ItemLimit:          -1,
-			},
-			IsGzip:    false,
-			BatchSize: batchSize,
-		}
-		err := report.ReportLoadResult(reportParams, itemsRead, valuesRate, bytesRate, took)
-
-		if err != nil {
-			log.Fatal(err)
-		}
+	var extraVals []report.ExtraVal
+	if migrator != nil {
+		fmt.Printf("performed %d schema migrations\n", migrator.Migrations())
+		extraVals = append(extraVals, report.ExtraVal{Name: "schema_migrations", Value: migrator.Migrations()})
 	}
+	loader.Finish(res, false, [][2]string{{"write_timeout", strconv.Itoa(int(writeTimeout))}}, extraVals...)
 }
 
-// scan reads lines from stdin. It expects input in the Cassandra CQL format.
-func scan(session *gocql.Session, itemsPerBatch int) (int64, int64, int64) {
-	var batch *gocql.Batch
-	if doLoad {
-		batch = session.NewBatch(gocql.LoggedBatch)
-	}
-
-	var n int
-	var err error
-	var itemsRead, bytesRead int64
-	var totalPoints, totalValues int64
+// driver batches the CQL INSERT statements of the input, one per line, into
+// logged batches. The gocql session, which balances the queries over the
+// urls, is shared by the workers.
+type driver struct{}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		line := scanner.Text()
-		totalPoints, totalValues, err = common.CheckTotalValues(line)
-		if totalPoints > 0 || totalValues > 0 {
-			continue
-		}
-		if err != nil {
-			log.Fatal(err)
-		}
-		itemsRead++
-		bytesRead += int64(len(scanner.Bytes()))
+func (driver) NewDecoder(r io.Reader) bulk_load.Decoder {
+	return &decoder{bulk_load.NewLineDecoder(r, 0, countValues)}
+}
 
-		if !doLoad {
-			continue
-		}
+func (driver) NewEncoder() bulk_load.Encoder {
+	return bulk_load.NewlineEncoder
+}
 
-		batch.Query(string(scanner.Bytes()))
+func (driver) NewWriter(worker int, url string) bulk_load.Writer {
+	return cqlWriter{}
+}
 
-		n++
-		if n >= itemsPerBatch {
-			batchChan <- batch
-			batch = session.NewBatch(gocql.LoggedBatch)
-			n = 0
-		}
-	}
+// decoder migrates the schema for the statements read, see schemaMigrator:
+// migrations run here, before any batch holding the new columns is queued.
+type decoder struct {
+	*bulk_load.LineDecoder
+}
 
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("Error reading input: %s", err.Error())
+func (d *decoder) Decode() ([]byte, int, error) {
+	line, values, err := d.LineDecoder.Decode()
+	if err != nil || migrator == nil {
+		return line, values, err
 	}
-
-	// Finished reading input, make sure last batch goes out.
-	if n > 0 {
-		batchChan <- batch
+	migrated, err := migrator.migrate(string(line))
+	if err != nil {
+		return nil, 0, err
 	}
+	return []byte(migrated), values, nil
+}
 
-	// Closing inputDone signals to the application that we've read everything and can now shut down.
-	close(inputDone)
-	//cassandra's schema stores each value separately, point is represented in series_id
-	if itemsRead != totalPoints {
-		log.Fatalf("Incorrent number of read items: %d, expected: %d:", itemsRead, totalPoints)
+// countValues returns the number of field values inserted by a statement of
+// the cassandra serializer: the values but the time and the quoted tags.
+func countValues(line []byte) int {
+	i := bytes.Index(line, []byte(") VALUES ("))
+	if i < 0 {
+		return 0
 	}
-
-	return itemsRead, bytesRead, totalValues
+	values := -1
+	eachLiteral(string(line[i+len(") VALUES ("):]), func(literal string) {
+		if !strings.HasPrefix(literal, "'") {
+			values++
+		}
+	})
+	return values
 }
 
-// processBatches reads byte buffers from batchChan and writes them to the target server, while tracking stats on the write.
-func processBatches(session *gocql.Session) {
-	for batch := range batchChan {
-		if !doLoad {
-			continue
-		}
+// cqlWriter executes the statements of a batch as a logged batch.
+type cqlWriter struct{}
 
-		// Write the batch.
-		err := session.ExecuteBatch(batch)
-		if err != nil {
-			log.Fatalf("Error writing: %s\n", err.Error())
+func (cqlWriter) WriteBatch(b *bulk_load.Batch) error {
+	batch := session.NewBatch(gocql.LoggedBatch)
+	for _, line := range bytes.Split(b.Buffer.Bytes(), []byte("\n")) {
+		if len(line) > 0 {
+			batch.Query(string(line))
 		}
 	}
-	workersGroup.Done()
+	return session.ExecuteBatch(batch)
+}
+
+func (cqlWriter) Close() error {
+	return nil
 }
 
 var tableOptionsFmt = "with compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': 1, 'compaction_window_unit': 'DAYS'} and compression = {'class':'%s'}"
@@ -274,8 +197,8 @@ var createTablesCQLIot = []string{
 	"CREATE TABLE measurements.window_state_room (time bigint,room_id TEXT,sensor_id TEXT,window_id TEXT,home_id TEXT, state double,battery_voltage double, primary key(home_id, time)) %s;",
 }
 
-func createKeyspace(daemonUrl string, tableSchema []string) {
-	cluster := gocql.NewCluster(daemonUrl)
+func createKeyspace(daemonUrls []string, tableSchema []string) {
+	cluster := gocql.NewCluster(daemonUrls...)
 	cluster.Consistency = gocql.Quorum
 	cluster.ProtoVersion = 4
 	cluster.Timeout = writeTimeout
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/gocql/gocql"
+)
+
+// Data generated with --schema-changes may carry columns the tables do not
+// have, or values that do not fit a column type. With --schema-migrate the
+// loader adds missing columns before writing such rows. Cassandra cannot
+// change the type of a column, so a value that does not fit goes to a
+// sibling column named after its type instead, e.g. usage_user_blob.
+
+// Column types, as reported by system_schema.columns.
+const (
+	columnBigint = "bigint"
+	columnDouble = "double"
+	columnText   = "text"
+	columnBlob   = "blob"
+)
+
+// schemaMigrator tracks the columns of the tables of the measurements
+// keyspace. It is shared by the scans of the inputs.
+type schemaMigrator struct {
+	mu         sync.Mutex
+	session    *gocql.Session
+	tables     map[string]map[string]string // table -> column -> type
+	migrations int64
+}
+
+func newSchemaMigrator(session *gocql.Session) (*schemaMigrator, error) {
+	m := &schemaMigrator{
+		session: session,
+		tables:  map[string]map[string]string{},
+	}
+	iter := session.Query("SELECT table_name, column_name, type FROM system_schema.columns WHERE keyspace_name = 'measurements'").Iter()
+	var table, column, columnType string
+	for iter.Scan(&table, &column, &columnType) {
+		if m.tables[table] == nil {
+			m.tables[table] = map[string]string{}
+		}
+		m.tables[table][column] = columnType
+	}
+	return m, iter.Close()
+}
+
+// Migrations returns the number of ALTER TABLE statements executed.
+func (m *schemaMigrator) Migrations() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.migrations
+}
+
+// migrate alters the table of an INSERT statement written by the cassandra
+// serializer when needed, and returns the statement to run, which differs
+// from line when values were moved to sibling columns.
+func (m *schemaMigrator) migrate(line string) (string, error) {
+	table, columns, values, err := parseInsert(line)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tableColumns, ok := m.tables[table]
+	if !ok {
+		// leave it for the write to fail on
+		return line, nil
+	}
+
+	renamed := false
+	for i, column := range columns {
+		want := literalColumnType(values[i])
+		have, ok := tableColumns[column]
+		if ok && fits(have, want) {
+			continue
+		}
+		if ok {
+			column = column + "_" + want
+			columns[i] = column
+			renamed = true
+			if _, ok := tableColumns[column]; ok {
+				continue
+			}
+		}
+
+		cql := fmt.Sprintf("ALTER TABLE measurements.%s ADD %s %s", table, column, want)
+		if err := m.session.Query(cql).Exec(); err != nil {
+			return "", fmt.Errorf("schema migration %q: %v", cql, err)
+		}
+		log.Printf("schema migration: %s\n", cql)
+		tableColumns[column] = want
+		m.migrations++
+	}
+
+	if !renamed {
+		return line, nil
+	}
+	return fmt.Sprintf("INSERT INTO measurements.%s (%s) VALUES (%s);", table, strings.Join(columns, ","), strings.Join(values, ",")), nil
+}
+
+// fits reports whether a literal of type want can be stored in a column of
+// type have. Integer literals are accepted for double columns.
+func fits(have, want string) bool {
+	return have == want || have == columnDouble && want == columnBigint
+}
+
+// parseInsert splits an INSERT statement into its lower-cased table name,
+// its lower-cased columns and its value literals.
+func parseInsert(line string) (table string, columns, values []string, err error) {
+	const prefix = "INSERT INTO measurements."
+	if !strings.HasPrefix(line, prefix) {
+		return "", nil, nil, fmt.Errorf("not an INSERT statement: %s", line)
+	}
+	rest := line[len(prefix):]
+	open := strings.Index(rest, " (")
+	closing := strings.Index(rest, ") VALUES (")
+	if open < 0 || closing < open {
+		return "", nil, nil, fmt.Errorf("malformed INSERT statement: %s", line)
+	}
+	// unquoted CQL identifiers are case-insensitive
+	table = strings.ToLower(rest[:open])
+	columns = strings.Split(strings.ToLower(rest[open+2:closing]), ",")
+
+	values = make([]string, 0, len(columns))
+	eachLiteral(rest[closing+len(") VALUES ("):], func(literal string) {
+		values = append(values, literal)
+	})
+	if len(values) != len(columns) {
+		return "", nil, nil, fmt.Errorf("%d columns but %d values: %s", len(columns), len(values), line)
+	}
+	return table, columns, values, nil
+}
+
+// eachLiteral calls fn with each value literal of the VALUES list of an
+// INSERT statement, given from after its opening parenthesis.
+func eachLiteral(list string, fn func(literal string)) {
+	literals := strings.TrimSuffix(strings.TrimSpace(list), ");")
+	inQuotes := false
+	depth := 0
+	start := 0
+	for i := 0; i <= len(literals); i++ {
+		if i < len(literals) {
+			switch c := literals[i]; {
+			case c == '\'':
+				inQuotes = !inQuotes
+			case inQuotes:
+			case c == '(':
+				depth++
+			case c == ')':
+				depth--
+			}
+			if inQuotes || depth > 0 || literals[i] != ',' {
+				continue
+			}
+		}
+		fn(literals[start:i])
+		start = i + 1
+	}
+}
+
+func literalColumnType(literal string) string {
+	switch {
+	case strings.HasPrefix(literal, "textasblob("):
+		return columnBlob
+	case strings.HasPrefix(literal, "'"):
+		return columnText
+	case strings.ContainsAny(literal, ".eE"):
+		return columnDouble
+	default:
+		return columnBigint
+	}
+}
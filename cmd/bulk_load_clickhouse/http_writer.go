@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+	"github.com/klauspost/compress/gzip"
+	"github.com/valyala/fasthttp"
+)
+
+const DefaultIdleConnectionTimeout = 90 * time.Second
+
+var (
+	BackoffError       error  = bulk_load.ErrBackoff
+	backoffMagicWords0 []byte = []byte("TOO_MANY_PARTS")
+	backoffMagicWords1 []byte = []byte("TOO_MANY_SIMULTANEOUS_QUERIES")
+)
+
+// HTTPWriterConfig is the configuration used to create an HTTPWriter.
+type HTTPWriterConfig struct {
+	// URL of the host, in form "http://example.com:8123"
+	Host string
+
+	// Credentials of the HTTP interface.
+	User     string
+	Password string
+
+	// Debug label for more informative errors.
+	DebugInfo string
+}
+
+// HTTPWriter is a Writer that writes to the ClickHouse HTTP interface.
+type HTTPWriter struct {
+	client fasthttp.Client
+
+	c HTTPWriterConfig
+
+	// pending holds the row groups of the current batch not written yet.
+	pending    []*rowGroup
+	groups     map[int]*rowGroup
+	compressed bytes.Buffer
+	zw         *gzip.Writer
+}
+
+// rowGroup holds TabSeparated rows of the columns of a header.
+type rowGroup struct {
+	Schema *tableSchema
+	Buffer bytes.Buffer
+}
+
+// NewHTTPWriter returns a new HTTPWriter from the supplied HTTPWriterConfig.
+func NewHTTPWriter(c HTTPWriterConfig) *HTTPWriter {
+	return &HTTPWriter{
+		client: fasthttp.Client{
+			Name:                "bulk_load_clickhouse",
+			MaxIdleConnDuration: DefaultIdleConnectionTimeout,
+		},
+
+		c:      c,
+		groups: map[int]*rowGroup{},
+	}
+}
+
+var (
+	post      = []byte("POST")
+	textPlain = []byte("text/plain")
+)
+
+// Exec runs the given statement.
+func (w *HTTPWriter) Exec(sql string) error {
+	_, err := w.do(w.c.Host+"/", []byte(sql), false, nil)
+	return err
+}
+
+// Query runs the given query, and returns its result.
+func (w *HTTPWriter) Query(sql string) ([]byte, error) {
+	var result bytes.Buffer
+	_, err := w.do(w.c.Host+"/", []byte(sql), false, &result)
+	return result.Bytes(), err
+}
+
+// WriteRows runs the given INSERT query, the body holding its rows.
+// It returns the latency in nanoseconds and any error received while sending the data over HTTP,
+// or it returns a new error if the HTTP response isn't as expected.
+func (w *HTTPWriter) WriteRows(query string, body []byte, isGzip bool) (int64, error) {
+	return w.do(w.c.Host+"/?query="+url.QueryEscape(query), body, isGzip, nil)
+}
+
+// do posts body to uri, and copies the response body to result, unless nil.
+func (w *HTTPWriter) do(uri string, body []byte, isGzip bool, result *bytes.Buffer) (int64, error) {
+	req := fasthttp.AcquireRequest()
+	req.Header.SetContentTypeBytes(textPlain)
+	req.Header.SetMethodBytes(post)
+	req.Header.SetRequestURI(uri)
+	req.Header.Set("X-ClickHouse-User", w.c.User)
+	if w.c.Password != "" {
+		req.Header.Set("X-ClickHouse-Key", w.c.Password)
+	}
+	if isGzip {
+		req.Header.Add("Content-Encoding", "gzip")
+	}
+	req.SetBody(body)
+
+	resp := fasthttp.AcquireResponse()
+	start := time.Now()
+	err := w.client.Do(req, resp)
+	lat := time.Since(start).Nanoseconds()
+	if err == nil {
+		sc := resp.StatusCode()
+		if sc == fasthttp.StatusServiceUnavailable ||
+			bytes.Contains(resp.Body(), backoffMagicWords0) ||
+			bytes.Contains(resp.Body(), backoffMagicWords1) {
+			err = BackoffError
+		} else if sc != fasthttp.StatusOK {
+			err = fmt.Errorf("[DebugInfo: %s] Invalid response (status %d): %s", w.c.DebugInfo, sc, resp.Body())
+		} else if result != nil {
+			result.Write(resp.Body())
+		}
+	}
+
+	fasthttp.ReleaseResponse(resp)
+	fasthttp.ReleaseRequest(req)
+
+	return lat, err
+}
+
+// WriteBatch groups the rows of b by header, and inserts each group. After a
+// backoff, the groups already written are not written again.
+func (w *HTTPWriter) WriteBatch(b *bulk_load.Batch) error {
+	if len(w.pending) == 0 {
+		if err := w.group(b.Buffer.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	for len(w.pending) > 0 {
+		g := w.pending[0]
+		body := g.Buffer.Bytes()
+		if useGzip {
+			if w.zw == nil {
+				w.zw = gzip.NewWriter(&w.compressed)
+			}
+			w.compressed.Reset()
+			w.zw.Reset(&w.compressed)
+			w.zw.Write(body)
+			w.zw.Close()
+			body = w.compressed.Bytes()
+		}
+		if _, err := w.WriteRows(g.Schema.insertQuery, body, useGzip); err != nil {
+			if err != BackoffError {
+				w.pending = nil
+			}
+			return err
+		}
+		g.Buffer.Reset()
+		w.pending = w.pending[1:]
+	}
+	return nil
+}
+
+// group splits rows, each prefixed by the index of its header, into the
+// pending row groups.
+func (w *HTTPWriter) group(rows []byte) error {
+	for k := range w.groups {
+		delete(w.groups, k)
+	}
+	for len(rows) > 0 {
+		row := rows
+		if i := bytes.IndexByte(rows, '\n'); i >= 0 {
+			row, rows = rows[:i], rows[i+1:]
+		} else {
+			rows = nil
+		}
+		i := bytes.IndexByte(row, '\t')
+		if i < 0 {
+			return fmt.Errorf("[DebugInfo: %s] malformed row: %s", w.c.DebugInfo, row)
+		}
+		id, err := strconv.Atoi(string(row[:i]))
+		if err != nil {
+			return fmt.Errorf("[DebugInfo: %s] malformed row: %s", w.c.DebugInfo, row)
+		}
+		g := w.groups[id]
+		if g == nil {
+			schemasMu.RLock()
+			g = &rowGroup{Schema: schemas[id]}
+			schemasMu.RUnlock()
+			w.groups[id] = g
+			w.pending = append(w.pending, g)
+		}
+		g.Buffer.Write(row[i+1:])
+		g.Buffer.WriteByte('\n')
+	}
+	return nil
+}
+
+func (w *HTTPWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,246 @@
+// bulk_load_clickhouse loads a ClickHouse server with data from stdin or files,
+// through the HTTP interface.
+//
+// The input is the clickhouse format of bulk_data_gen: TabSeparated rows
+// prefixed by their table, and header lines describing the columns of the
+// tables. Each table is created from its first header as a MergeTree ordered
+// by the tags and the time. The rows of a batch are inserted with one
+// gzip compressed INSERT ... FORMAT TabSeparated per table.
+//
+// The caller is responsible for assuring that the database is empty before
+// bulk load.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+)
+
+// Program option vars:
+var (
+	loader = &bulk_load.Loader{
+		Config: bulk_load.Config{
+			Urls:      "http://localhost:8123",
+			BatchSize: 5000,
+		},
+		DBType:     "ClickHouse",
+		Verifiable: true,
+	}
+	dbName        string
+	user          string
+	password      string
+	doDbCreate    bool
+	schemaMigrate bool
+	useGzip       bool
+)
+
+// Global vars
+var (
+	schemaWriter *HTTPWriter
+	migrations   int
+
+	// schemas holds the headers of the input, rows refer to their header by
+	// index.
+	schemas   []*tableSchema
+	schemasMu sync.RWMutex
+
+	// tables are the columns of the tables created, the decoders of the
+	// input files share them.
+	tables   = map[string]tableColumns{}
+	tablesMu sync.Mutex
+)
+
+// Parse args:
+func init() {
+	loader.AddFlags(flag.CommandLine)
+	flag.StringVar(&dbName, "db", "benchmark_db", "Database name.")
+	flag.StringVar(&user, "user", "default", "ClickHouse user.")
+	flag.StringVar(&password, "password", "", "ClickHouse password.")
+	flag.BoolVar(&doDbCreate, "do-db-create", true, "Whether to create the database. Set this flag to false to write data to an existing database.")
+	flag.BoolVar(&schemaMigrate, "schema-migrate", false, "Whether to add or change table columns when the input carries new fields or changed field types (see bulk_data_gen --schema-changes).")
+	flag.BoolVar(&useGzip, "gzip", true, "Whether to gzip encode requests.")
+
+	flag.Parse()
+
+	loader.Init()
+}
+
+func main() {
+	daemonUrl := loader.DaemonUrls[0]
+	if loader.DoLoad {
+		schemaWriter = NewHTTPWriter(HTTPWriterConfig{
+			DebugInfo: fmt.Sprintf("schema, dest url: %s", daemonUrl),
+			Host:      daemonUrl,
+			User:      user,
+			Password:  password,
+		})
+		if doDbCreate {
+			createDatabase(daemonUrl)
+		}
+	}
+
+	res := loader.Run(driver{})
+	if schemaMigrate {
+		fmt.Printf("performed %d schema migrations\n", migrations)
+	}
+	loader.Finish(res, useGzip, nil)
+}
+
+// driver batches the rows of the input, 1 item = 1 row. The writers group
+// the rows of a batch by header, and insert each group with one INSERT.
+type driver struct{}
+
+func (driver) NewDecoder(r io.Reader) bulk_load.Decoder {
+	return &decoder{
+		lines:   bulk_load.NewLineDecoder(r, 4*1024*1024, nil),
+		current: map[string]int{},
+	}
+}
+
+func (driver) NewEncoder() bulk_load.Encoder {
+	return bulk_load.NewlineEncoder
+}
+
+func (driver) NewWriter(worker int, url string) bulk_load.Writer {
+	return NewHTTPWriter(HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("worker #%d, dest url: %s", worker, url),
+		Host:      url,
+		User:      user,
+		Password:  password,
+	})
+}
+
+// Measurement returns the table of a row.
+func (driver) Measurement(row []byte) []byte {
+	i := bytes.IndexByte(row, '\t')
+	id, err := strconv.Atoi(string(row[:i]))
+	if err != nil {
+		log.Fatalf("Error reading input, malformed row: %s", row)
+	}
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	return []byte(schemas[id].table)
+}
+
+// CountPoints counts the rows of table.
+func (driver) CountPoints(daemonUrl, table string) (int64, error) {
+	w := NewHTTPWriter(HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("verify, dest url: %s", daemonUrl),
+		Host:      daemonUrl,
+		User:      user,
+		Password:  password,
+	})
+	result, err := w.Query(fmt.Sprintf("SELECT count() FROM %s.%s", quoteIdentifier(dbName), quoteIdentifier(table)))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(bytes.TrimSpace(result)), 10, 64)
+}
+
+// decoder reads the rows of the input. Header lines create or migrate their
+// table. The table of a row is replaced by the index of its header in
+// schemas. The dataset size marker is read by lines, which skips it.
+type decoder struct {
+	lines   *bulk_load.LineDecoder
+	current map[string]int
+	item    []byte
+}
+
+func (d *decoder) Decode() ([]byte, int, error) {
+	for {
+		line, _, err := d.lines.Decode()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if len(line) > 0 && line[0] == '#' {
+			s, err := parseHeader(string(line[1:]))
+			if err != nil {
+				return nil, 0, err
+			}
+			_, known := d.current[s.table]
+			ensureTable(s, known)
+			schemasMu.Lock()
+			d.current[s.table] = len(schemas)
+			schemas = append(schemas, s)
+			schemasMu.Unlock()
+			continue
+		}
+
+		i := bytes.IndexByte(line, '\t')
+		if i < 0 {
+			return nil, 0, fmt.Errorf("malformed row: %s", line)
+		}
+		id, ok := d.current[string(line[:i])]
+		if !ok {
+			return nil, 0, fmt.Errorf("row before the header of its table: %s", line)
+		}
+		d.item = strconv.AppendInt(d.item[:0], int64(id), 10)
+		d.item = append(d.item, line[i:]...)
+		schemasMu.RLock()
+		fields := schemas[id].fields
+		schemasMu.RUnlock()
+		return d.item, fields, nil
+	}
+}
+
+func (d *decoder) DatasetSize() (int64, int64) {
+	return d.lines.DatasetSize()
+}
+
+// ensureTable creates the table of a header, or migrates it when the header
+// changes the columns of a table known to the decoder, which read an earlier
+// header of it. The decoders of the input files change the tables one at a
+// time.
+func ensureTable(s *tableSchema, known bool) {
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+	t, ok := tables[s.table]
+	if !ok {
+		if loader.DoLoad {
+			if err := schemaWriter.Exec(createTableSql(s)); err != nil {
+				log.Fatalf("Error creating table %s: %s", s.table, err.Error())
+			}
+		}
+		tables[s.table] = newTableColumns(s)
+		return
+	}
+	if !known {
+		return
+	}
+
+	sqls := t.migrationSql(s)
+	if len(sqls) > 0 && !schemaMigrate {
+		log.Fatalf("The columns of table %s changed, run with -schema-migrate to alter it: %s", s.table, sqls[0])
+	}
+	for _, sql := range sqls {
+		if loader.DoLoad {
+			if err := schemaWriter.Exec(sql); err != nil {
+				log.Fatalf("Error migrating table %s: %s", s.table, err.Error())
+			}
+		}
+		log.Printf("migrated: %s\n", sql)
+		migrations++
+	}
+}
+
+// createDatabase creates the database.
+func createDatabase(daemonUrl string) {
+	w := NewHTTPWriter(HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("create database, dest url: %s", daemonUrl),
+		Host:      daemonUrl,
+		User:      user,
+		Password:  password,
+	})
+	sql := fmt.Sprintf("CREATE DATABASE %s", quoteIdentifier(dbName))
+	if err := w.Exec(sql); err != nil {
+		log.Fatalf("Error creating database %s: %s\nIf you know what you are doing, drop it with:\ncurl -d 'DROP DATABASE %s' %s/\nor run with -do-db-create=false\n", dbName, err.Error(), dbName, daemonUrl)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// column is a column of a table and its ClickHouse type.
+type column struct {
+	name string
+	typ  string
+}
+
+// tableSchema is a header line of the clickhouse format of bulk_data_gen:
+// #<table>	<tag> LowCardinality(String)	...	time DateTime64(3, 'UTC')	<field> <type>	...
+type tableSchema struct {
+	table   string
+	columns []column
+	tags    []string
+	fields  int
+
+	// insertQuery inserts rows of these columns.
+	insertQuery string
+}
+
+// parseHeader parses a header line, without its leading #.
+func parseHeader(line string) (*tableSchema, error) {
+	parts := strings.Split(line, "\t")
+	s := &tableSchema{table: parts[0]}
+	seenTime := false
+	for _, part := range parts[1:] {
+		i := strings.IndexByte(part, ' ')
+		if i <= 0 {
+			return nil, fmt.Errorf("bad column %q in header: %s", part, line)
+		}
+		c := column{name: part[:i], typ: part[i+1:]}
+		s.columns = append(s.columns, c)
+		switch {
+		case c.name == "time":
+			seenTime = true
+		case seenTime:
+			s.fields++
+		default:
+			s.tags = append(s.tags, c.name)
+		}
+	}
+	if s.table == "" || !seenTime {
+		return nil, fmt.Errorf("bad header: %s", line)
+	}
+
+	names := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		names[i] = quoteIdentifier(c.name)
+	}
+	s.insertQuery = fmt.Sprintf("INSERT INTO %s.%s (%s) FORMAT TabSeparated", quoteIdentifier(dbName), quoteIdentifier(s.table), strings.Join(names, ","))
+	return s, nil
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "\\`", -1) + "`"
+}
+
+// createTableSql returns the statement creating the table of s, a MergeTree
+// ordered by the tags and the time.
+func createTableSql(s *tableSchema) string {
+	columns := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		columns[i] = quoteIdentifier(c.name) + " " + c.typ
+	}
+	orderBy := make([]string, 0, len(s.tags)+1)
+	for _, tag := range s.tags {
+		orderBy = append(orderBy, quoteIdentifier(tag))
+	}
+	orderBy = append(orderBy, "time")
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s (%s) ENGINE = MergeTree ORDER BY (%s)",
+		quoteIdentifier(dbName), quoteIdentifier(s.table), strings.Join(columns, ", "), strings.Join(orderBy, ", "))
+}
+
+// tableColumns are the types of the columns of a created table, by name.
+type tableColumns map[string]string
+
+func newTableColumns(s *tableSchema) tableColumns {
+	t := tableColumns{}
+	for _, c := range s.columns {
+		t[c.name] = c.typ
+	}
+	return t
+}
+
+// migrationSql returns the statements adding the columns of s to the table t:
+// added fields become columns and retyped fields change type. Dropped fields
+// are kept, ClickHouse fills them with default values. t is updated.
+func (t tableColumns) migrationSql(s *tableSchema) []string {
+	var sqls []string
+	for _, c := range s.columns {
+		typ, ok := t[c.name]
+		switch {
+		case !ok:
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS %s %s", quoteIdentifier(dbName), quoteIdentifier(s.table), quoteIdentifier(c.name), c.typ))
+		case typ != c.typ:
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s.%s MODIFY COLUMN %s %s", quoteIdentifier(dbName), quoteIdentifier(s.table), quoteIdentifier(c.name), c.typ))
+		}
+		t[c.name] = c.typ
+	}
+	return sqls
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/caict-benchmark/BDC-TS/util/report"
+)
+
+// The _bulk API answers 200 even when some of the items fail, with the
+// top-level errors field set and the outcome of each item in items, in the
+// order of the request. Rejected items (429, es_rejected_execution_exception)
+// are sent again, and only them; items failing for their mapping (400, such
+// as mapper_parsing_exception) or for another reason are counted and dropped.
+
+// bulkResponse is the part of a _bulk response the loader looks at.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	// Items maps the action of each item, like index or create, to its
+	// result.
+	Items []map[string]bulkItemResult `json:"items"`
+}
+
+type bulkItemResult struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// bulkResult sums up the items of a _bulk response which are not indexed.
+type bulkResult struct {
+	// Rejected holds the positions in the request of the rejected items.
+	Rejected        []int
+	MappingFailures int
+	OtherFailures   int
+	// FirstError describes the first failed item.
+	FirstError string
+}
+
+// Failures returns the number of items dropped for good.
+func (r *bulkResult) Failures() int {
+	return r.MappingFailures + r.OtherFailures
+}
+
+// parseBulkResponse reads the per-item results of body, the response to a
+// _bulk request, if its errors field is set. It returns nil when all the
+// items were indexed.
+func parseBulkResponse(body []byte) (*bulkResult, error) {
+	var resp bulkResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("bad bulk response: %v: %s", err, body)
+	}
+	if !resp.Errors {
+		return nil, nil
+	}
+	res := &bulkResult{}
+	for i, item := range resp.Items {
+		for action, r := range item {
+			if r.Status >= 200 && r.Status < 300 {
+				continue
+			}
+			errType := ""
+			if r.Error != nil {
+				errType = r.Error.Type
+				if res.FirstError == "" {
+					res.FirstError = fmt.Sprintf("%s item %d (status %d): %s: %s", action, i, r.Status, r.Error.Type, r.Error.Reason)
+				}
+			}
+			switch {
+			case r.Status == 429 || errType == "es_rejected_execution_exception":
+				res.Rejected = append(res.Rejected, i)
+			case r.Status == 400:
+				res.MappingFailures++
+			default:
+				res.OtherFailures++
+			}
+		}
+	}
+	return res, nil
+}
+
+// bulkItems counts the items of a _bulk request body, an action line and a
+// document line each.
+func bulkItems(body []byte) int {
+	return bytes.Count(body, []byte{'\n'}) / 2
+}
+
+// nextBulkItem returns the length of the first item of body, with the
+// newline ending its document line.
+func nextBulkItem(body []byte) int {
+	n := 0
+	for lines := 0; lines < 2 && n < len(body); lines++ {
+		i := bytes.IndexByte(body[n:], '\n')
+		if i < 0 {
+			return len(body)
+		}
+		n += i + 1
+	}
+	return n
+}
+
+// selectBulkItems returns the request body holding only the items of body
+// at the positions positions, in increasing order.
+func selectBulkItems(body []byte, positions []int) []byte {
+	selected := make([]byte, 0, len(body)/(bulkItems(body)+1)*(len(positions)+1))
+	item, p := 0, 0
+	for len(body) > 0 && p < len(positions) {
+		end := nextBulkItem(body)
+		if item == positions[p] {
+			selected = append(selected, body[:end]...)
+			p++
+		}
+		body = body[end:]
+		item++
+	}
+	return selected
+}
+
+// bulkItemStats counts the outcome of the items written by all the workers.
+type bulkItemStats struct {
+	indexed         int64
+	rejections      int64
+	mappingFailures int64
+	otherFailures   int64
+}
+
+func (s *bulkItemStats) add(items int, res *bulkResult) {
+	if res == nil {
+		atomic.AddInt64(&s.indexed, int64(items))
+		return
+	}
+	atomic.AddInt64(&s.indexed, int64(items-len(res.Rejected)-res.Failures()))
+	atomic.AddInt64(&s.rejections, int64(len(res.Rejected)))
+	atomic.AddInt64(&s.mappingFailures, int64(res.MappingFailures))
+	atomic.AddInt64(&s.otherFailures, int64(res.OtherFailures))
+}
+
+func (s *bulkItemStats) String() string {
+	return fmt.Sprintf("indexed %d documents, %d rejected items resent, %d mapping failures, %d other item failures", s.indexed, s.rejections, s.mappingFailures, s.otherFailures)
+}
+
+// ExtraVals returns the item counts as report values.
+func (s *bulkItemStats) ExtraVals() []report.ExtraVal {
+	return []report.ExtraVal{
+		{Name: "indexed_documents", Value: s.indexed},
+		{Name: "rejected_items", Value: s.rejections},
+		{Name: "mapping_failures", Value: s.mappingFailures},
+		{Name: "item_failures", Value: s.otherFailures},
+	}
+}
@@ -3,11 +3,11 @@ package main
 // This file modified from mountainflux by Mark Rushakoff.
 
 import (
-	"encoding/json"
 	"fmt"
 	//"net/url"
 	"time"
 
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
 	"github.com/valyala/fasthttp"
 )
 
@@ -18,6 +18,9 @@ type HTTPWriterConfig struct {
 
 	// Name of the target database into which points will be written.
 	//	Database string
+
+	// Retry classifies the retryable error responses.
+	Retry *bulk_load.RetryPolicy
 }
 
 // HTTPWriter is a Writer that writes to an InfluxDB HTTP server.
@@ -51,9 +54,10 @@ var (
 )
 
 // WriteLineProtocol writes the given byte slice to the HTTP server described in the Writer's HTTPWriterConfig.
-// It returns the latency in nanoseconds and any error received while sending the data over HTTP,
-// or it returns a new error if the HTTP response isn't as expected.
-func (w *HTTPWriter) WriteLineProtocol(body []byte, isGzip bool, authorization string) (int64, error) {
+// It returns the latency in nanoseconds, the items of the request which were
+// not indexed, nil if all were, and any error received while sending the data
+// over HTTP, or it returns a new error if the HTTP response isn't as expected.
+func (w *HTTPWriter) WriteLineProtocol(body []byte, isGzip bool, authorization string) (int64, *bulkResult, error) {
 	req := fasthttp.AcquireRequest()
 	req.Header.SetContentTypeBytes(applicationNdJson)
 	req.Header.SetMethodBytes(post)
@@ -73,27 +77,20 @@ func (w *HTTPWriter) WriteLineProtocol(body []byte, isGzip bool, authorization s
 	if err == nil {
 		sc := resp.StatusCode()
 		if sc != 200 {
-			err = fmt.Errorf("Invalid write response (status %d): %s", sc, resp.Body())
+			err = w.c.Retry.Classify(sc, resp.Body())
+			if err == nil {
+				err = fmt.Errorf("Invalid write response (status %d): %s", sc, resp.Body())
+			}
 		}
 	}
 
-	// anonymous type to get the 'errors' field from the response:
-	errorFlag := struct {
-		Errors bool `json:"errors"`
-	}{}
-
-	if err == nil {
-		err = json.Unmarshal(resp.Body(), &errorFlag)
-	}
-
+	var res *bulkResult
 	if err == nil {
-		if errorFlag.Errors {
-			err = fmt.Errorf("Write response set the errors field to true (status 200): %s", resp.Body())
-		}
+		res, err = parseBulkResponse(resp.Body())
 	}
 
 	fasthttp.ReleaseResponse(resp)
 	fasthttp.ReleaseRequest(req)
 
-	return lat, err
+	return lat, res, err
 }
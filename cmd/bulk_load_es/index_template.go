@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// ElasticSearch 7 removed mapping types, and 7.8 added composable index
+// templates (_index_template), which can create data streams. OpenSearch
+// answers like ElasticSearch 7. Data streams take the documents of
+// bulk_data_gen --format=es-bulk-ds, written with create actions and an
+// @timestamp field; the timestamp field the queries use is mapped as an
+// alias of it. With --ilm-policy the backing indices roll over by an ILM
+// policy, and with --time-series (ElasticSearch 8.8 or later) the data
+// streams are time series data streams whose dimensions are the tags.
+// The priority of the templates is above the one of the built-in templates
+// of ElasticSearch, like metrics-*-*, which is 100.
+
+// templateParams fills the index templates.
+type templateParams struct {
+	NumberOfReplicas uint
+	NumberOfShards   uint
+	// IndexPatterns is the JSON array of the index patterns of a composable
+	// template.
+	IndexPatterns string
+	DataStream    bool
+	TimeSeries    bool
+	ILMPolicy     string
+}
+
+var defaultTemplate7x = []byte(`
+{
+  "index_patterns": {{.IndexPatterns}},
+  "priority": 200,{{if .DataStream}}
+  "data_stream": {},{{end}}
+  "template": {
+    "settings": {
+      "index": {
+        "refresh_interval": "5s",
+        "number_of_replicas": {{.NumberOfReplicas}},
+        "number_of_shards": {{.NumberOfShards}}{{if .TimeSeries}},
+        "mode": "time_series"{{end}}{{if .ILMPolicy}},
+        "lifecycle": { "name": "{{.ILMPolicy}}" }{{end}}
+      }
+    },
+    "mappings": {
+      {{- if .TimeSeries}}
+      "dynamic_templates": [
+        {
+          "tags_are_dimensions": {
+            "match_mapping_type": "string",
+            "mapping": { "type": "keyword", "time_series_dimension": true }
+          }
+        }
+      ],{{else}}
+      "_source":         { "enabled": true },{{end}}
+      "properties": {
+        {{- if .DataStream}}
+        "@timestamp":   { "type": "date" },
+        "timestamp":    { "type": "alias", "path": "@timestamp" },{{else}}
+        "timestamp":    { "type": "date", "doc_values": true },{{end}}
+        "location":     { "type": "geo_point" }
+      }
+    }
+  }
+}
+`)
+
+var aggregationTemplate7x = []byte(`
+{
+  "index_patterns": {{.IndexPatterns}},
+  "priority": 200,{{if .DataStream}}
+  "data_stream": {},{{end}}
+  "template": {
+    "settings": {
+      "index": {
+        "refresh_interval": "5s",
+        "number_of_replicas": {{.NumberOfReplicas}},
+        "number_of_shards": {{.NumberOfShards}}{{if .TimeSeries}},
+        "mode": "time_series"{{end}}{{if .ILMPolicy}},
+        "lifecycle": { "name": "{{.ILMPolicy}}" }{{end}}
+      }
+    },
+    "mappings": {
+      "dynamic_templates": [
+        {
+          "all_string_fields_can_be_used_for_filtering": {
+            "match": "*",
+            "match_mapping_type": "string",
+            "mapping": {
+              "type": "keyword",
+              "doc_values": true{{if .TimeSeries}},
+              "time_series_dimension": true{{end}}
+            }
+          }
+        },
+        {
+          "all_nonstring_fields_are_just_stored_in_column_index": {
+            "match": "*",
+            "match_mapping_type": "*",
+            "mapping": {
+              "doc_values": true,
+              "index": false
+            }
+          }
+        }
+      ],{{if not .TimeSeries}}
+      "_source": { "enabled": false },{{end}}
+      "properties": {
+        {{- if .DataStream}}
+        "@timestamp": {
+          "type": "date",
+          "doc_values": true,
+          "index": true
+        },
+        "timestamp": {
+          "type": "alias",
+          "path": "@timestamp"
+        },{{else}}
+        "timestamp": {
+          "type": "date",
+          "doc_values": true,
+          "index": true
+        },{{end}}
+        "location": {
+          "type": "geo_point"
+        }
+      }
+    }
+  }
+}
+`)
+
+// templateVersion returns the key of the index templates for the major
+// version of the server: 5, 6, or 7 for ElasticSearch 7 and later.
+func templateVersion(majorVer string) string {
+	switch majorVer {
+	case "5", "6":
+		return majorVer
+	default:
+		return "7"
+	}
+}
+
+// indexPatternsJSON returns the comma-separated patterns as a JSON array.
+func indexPatternsJSON(csv string) (string, error) {
+	var patterns []string
+	for _, p := range strings.Split(csv, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	if len(patterns) == 0 {
+		return "", fmt.Errorf("missing index patterns")
+	}
+	buf, err := json.Marshal(patterns)
+	return string(buf), err
+}
+
+// executeTemplate fills the text/template of an index template.
+func executeTemplate(indexTemplateBodyTemplate []byte, params templateParams) ([]byte, error) {
+	t := template.Must(template.New("index_template").Parse(string(indexTemplateBodyTemplate)))
+	var body bytes.Buffer
+	if err := t.Execute(&body, params); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+// ilmPolicyBody returns the ILM policy rolling over the backing indices on
+// the comma-separated k:v rollover conditions, like max_age:1d, and
+// deleting them deleteAfter after the rollover, if set.
+func ilmPolicyBody(rolloverCSV, deleteAfter string) ([]byte, error) {
+	rollover := map[string]string{}
+	for _, pair := range strings.Split(rolloverCSV, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		fields := strings.SplitN(pair, ":", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid rollover condition %q, expected k:v", pair)
+		}
+		rollover[fields[0]] = fields[1]
+	}
+	phases := map[string]interface{}{
+		"hot": map[string]interface{}{
+			"actions": map[string]interface{}{"rollover": rollover},
+		},
+	}
+	if deleteAfter != "" {
+		phases["delete"] = map[string]interface{}{
+			"min_age": deleteAfter,
+			"actions": map[string]interface{}{"delete": map[string]interface{}{}},
+		}
+	}
+	return json.Marshal(map[string]interface{}{
+		"policy": map[string]interface{}{"phases": phases},
+	})
+}
+
+// createILMPolicy creates or replaces the ILM policy name.
+func createILMPolicy(daemonUrl, name string, body []byte) error {
+	status, resp, err := putJSON(daemonUrl+"/_ilm/policy/"+name, body)
+	if err != nil {
+		return err
+	}
+	if status != 200 {
+		return fmt.Errorf("bad ILM policy create: %s", resp)
+	}
+	log.Printf("created ILM policy %s: %s\n", name, body)
+	return nil
+}
+
+// createComposableTemplate creates the composable index template name.
+func createComposableTemplate(daemonUrl, name string, body []byte) error {
+	status, resp, err := putJSON(daemonUrl+"/_index_template/"+name, body)
+	if err != nil {
+		return err
+	}
+	if status != 200 {
+		return fmt.Errorf("bad index template create: %s", resp)
+	}
+	return nil
+}
+
+func putJSON(u string, body []byte) (int, []byte, error) {
+	return doJSON("PUT", u, body)
+}
+
+// doJSON sends a request with the JSON body, and returns the status and the
+// body of the response.
+func doJSON(method, u string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Add("Authorization", authorization)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, respBody, err
+}
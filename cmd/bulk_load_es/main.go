@@ -1,4 +1,4 @@
-// bulk_load_es loads an ElasticSearch daemon with data from stdin.
+// bulk_load_es loads an ElasticSearch daemon with data from stdin or files.
 //
 // The caller is responsible for assuring that the database is empty before
 // bulk load.
@@ -8,63 +8,71 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
 	"strings"
 	"sync"
-	"text/template"
 	"time"
 
+	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
 	"github.com/caict-benchmark/BDC-TS/util/report"
 	"github.com/valyala/fasthttp"
-	"strconv"
 )
 
 // Program option vars:
 var (
-	csvDaemonUrls      string
-	daemonUrls         []string
-	refreshEachBatch   bool
-	workers            int
-	batchSize          int
-	itemLimit          int64
-	indexTemplateName  string
-	useGzip            bool
-	doLoad             bool
-	doDBCreate         bool
-	numberOfReplicas   uint
-	numberOfShards     uint
+	loader = &bulk_load.Loader{
+		Config: bulk_load.Config{
+			Urls:      "http://localhost:9200",
+			BatchSize: 5000,
+		},
+		DBType:     "ElasticSearch",
+		Verifiable: true,
+	}
+	refreshEachBatch  bool
+	indexTemplateName string
+	useGzip           bool
+	doDBCreate        bool
+	numberOfReplicas  uint
+	numberOfShards    uint
+	authorization     string
+	schemaMigrate     bool
+	indexPatternsCSV  string
+	dataStream        bool
+	timeSeries        bool
+	ilmPolicy         string
+	ilmRolloverCSV    string
+	ilmDeleteAfter    string
+
+	// deprecated telemetry flags, mapped onto the report flags of the Loader
 	telemetryHost      string
-	telemetryStderr    bool
-	telemetryBatchSize uint64
-	telemetryTagsCSV   string
 	telemetryBasicAuth string
-	reportDatabase     string
-	reportHost         string
-	reportUser         string
-	reportPassword     string
-	reportTagsCSV      string
-	authorization      string
+	telemetryTagsCSV   string
 )
 
 // Global vars
 var (
-	bufPool             sync.Pool
-	batchChan           chan *bytes.Buffer
-	inputDone           chan struct{}
-	workersGroup        sync.WaitGroup
-	telemetryChanPoints chan *report.Point
-	telemetryChanDone   chan struct{}
-	telemetryHostname   string
-	telemetryTags       [][2]string
-	reportTags          [][2]string
-	reportHostname      string
+	migrator         *esSchemaMigrator
+	itemStats        bulkItemStats
+	firstItemFailure sync.Once
+	openSearch       bool
+)
+
+// timeSeriesLookBack and timeSeriesLookAhead are the defaults of the
+// index.look_back_time and index.look_ahead_time settings: a time series data
+// stream only accepts documents with a @timestamp within them of the current
+// time.
+const (
+	timeSeriesLookBack  = 2 * time.Hour
+	timeSeriesLookAhead = 30 * time.Minute
 )
 
 // Args parsing vars
@@ -73,10 +81,12 @@ var (
 		"default": {
 			"5": defaultTemplate,
 			"6": defaultTemplate6x,
+			"7": defaultTemplate7x,
 		},
 		"aggregation": {
 			"5": aggregationTemplate,
 			"6": aggregationTemplate6x,
+			"7": aggregationTemplate7x,
 		},
 	}
 )
@@ -96,7 +106,8 @@ var defaultTemplate = []byte(`
       "_all":            { "enabled": false },
       "_source":         { "enabled": true },
       "properties": {
-        "timestamp":    { "type": "date", "doc_values": true }
+        "timestamp":    { "type": "date", "doc_values": true },
+        "location":     { "type": "geo_point" }
       }
     }
   }
@@ -145,6 +156,9 @@ var aggregationTemplate = []byte(`
           "type": "date",
           "doc_values": true,
           "index": "not_analyzed"
+        },
+        "location": {
+          "type": "geo_point"
         }
       }
     }
@@ -171,7 +185,8 @@ var defaultTemplate6x = []byte(`
       "_all":            { "enabled": false },
       "_source":         { "enabled": true },
       "properties": {
-        "timestamp":    { "type": "date", "doc_values": true }
+        "timestamp":    { "type": "date", "doc_values": true },
+        "location":     { "type": "geo_point" }
       }
     }
   }
@@ -222,6 +237,9 @@ var aggregationTemplate6x = []byte(`
           "type": "date",
           "doc_values": true,
           "index": true
+        },
+        "location": {
+          "type": "geo_point"
         }
       }
     }
@@ -231,104 +249,70 @@ var aggregationTemplate6x = []byte(`
 
 // Parse args:
 func init() {
-	flag.StringVar(&csvDaemonUrls, "urls", "http://localhost:9200", "ElasticSearch URLs, comma-separated. Will be used in a round-robin fashion.")
+	loader.AddFlags(flag.CommandLine)
+	flag.StringVar(&telemetryHost, "telemetry-host", "", "Deprecated, use -report-host with -report-telemetry.")
+	flag.StringVar(&telemetryBasicAuth, "telemetry-basic-auth", "", "Deprecated, use -report-user and -report-password.")
+	flag.StringVar(&telemetryTagsCSV, "telemetry-tags", "", "Deprecated, use -report-tags.")
 	flag.BoolVar(&refreshEachBatch, "refresh", true, "Whether each batch is immediately indexed.")
-	flag.Int64Var(&itemLimit, "item-limit", -1, "Number of items to read from stdin before quitting. (2 lines of input = 1 item)")
-
-	flag.IntVar(&batchSize, "batch-size", 5000, "Batch size (input items).")
-	flag.IntVar(&workers, "workers", 1, "Number of parallel requests to make.")
 
 	flag.StringVar(&indexTemplateName, "index-template", "default", "ElasticSearch index template to use (choices: default, aggregation).")
 
+	flag.StringVar(&indexPatternsCSV, "index-patterns", "*", "Comma separated index patterns of the composable index template (ElasticSearch 7 and later).")
+	flag.BoolVar(&dataStream, "data-stream", false, "Whether the index template creates data streams (ElasticSearch 7.9 and later, input from bulk_data_gen --format=es-bulk-ds).")
+	flag.BoolVar(&timeSeries, "time-series", false, "Whether the data streams are time series data streams, with the tags as dimensions (ElasticSearch 8.8 and later, needs --data-stream). Such a data stream only accepts documents with a @timestamp from 2h before to 30m after the current time, so the input must be generated with a bulk_data_gen --timestamp-start close to the start of the load; a document out of this window stops the load.")
+	flag.StringVar(&ilmPolicy, "ilm-policy", "", "ILM policy of the data streams (needs --data-stream, not supported by OpenSearch).")
+	flag.StringVar(&ilmRolloverCSV, "ilm-rollover", "max_age:1d,max_primary_shard_size:50gb", "Comma separated k:v rollover conditions of the ILM policy created as --ilm-policy. Empty to use an existing policy.")
+	flag.StringVar(&ilmDeleteAfter, "ilm-delete-after", "", "Age after the rollover at which the ILM policy deletes the backing indices, like 7d (optional).")
+
 	flag.BoolVar(&useGzip, "gzip", true, "Whether to gzip encode requests (default true).")
 
-	flag.BoolVar(&doLoad, "do-load", true, "Whether to write data. Set this flag to false to check input read speed.")
 	flag.BoolVar(&doDBCreate, "do-db-create", true, "Whether to create the database.")
+	flag.BoolVar(&schemaMigrate, "schema-migrate", false, "Whether to update mappings when the input carries new fields or changed field types (see bulk_data_gen --schema-changes).")
 
 	flag.UintVar(&numberOfReplicas, "number-of-replicas", 0, "Number of ES replicas (note: replicas == replication_factor - 1). Zero replicas means RF of 1.")
 	flag.UintVar(&numberOfShards, "number-of-shards", 1, "Number of ES shards. Typically you will set this to the number of nodes in the cluster.")
 
-	flag.StringVar(&telemetryHost, "telemetry-host", "", "InfluxDB host to write telegraf telemetry to (optional).")
-	flag.BoolVar(&telemetryStderr, "telemetry-stderr", false, "Whether to write telemetry also to stderr.")
-	flag.Uint64Var(&telemetryBatchSize, "telemetry-batch-size", 100, "Telemetry batch size (lines).")
-	flag.StringVar(&telemetryBasicAuth, "telemetry-basic-auth", "", "basic auth (username:password) for telemetry.")
-	flag.StringVar(&telemetryTagsCSV, "telemetry-tags", "", "Tag(s) for telemetry. Format: key0:val0,key1:val1,...")
-
-	flag.StringVar(&reportDatabase, "report-database", "database_benchmarks", "Database name where to store result metrics")
-	flag.StringVar(&reportHost, "report-host", "", "Host to send result metrics")
-	flag.StringVar(&reportUser, "report-user", "", "User for host to send result metrics")
-	flag.StringVar(&reportPassword, "report-password", "", "User password for Host to send result metrics")
-	flag.StringVar(&reportTagsCSV, "report-tags", "", "Comma separated k:v tags to send  alongside result metrics")
-
 	flag.StringVar(&authorization, "header-authorization", "", "authorization in header k:v tags to send  alongside result metrics")
 
 	flag.Parse()
-
-	daemonUrls = strings.Split(csvDaemonUrls, ",")
-	if len(daemonUrls) == 0 {
-		log.Fatal("missing 'urls' flag")
-	}
-	fmt.Printf("daemon URLs: %v\n", daemonUrls)
-
 	if telemetryHost != "" {
-		fmt.Printf("telemetry destination: %v\n", telemetryHost)
-		if telemetryBatchSize == 0 {
-			panic("invalid telemetryBatchSize")
-		}
-		var err error
-		telemetryHostname, err = os.Hostname()
-		if err != nil {
-			log.Fatalf("os.Hostname() error: %s", err.Error())
-		}
-		fmt.Printf("src addr for telemetry: %v\n", telemetryHostname)
-
-		if telemetryTagsCSV != "" {
-			pairs := strings.Split(telemetryTagsCSV, ",")
-			for _, pair := range pairs {
-				fields := strings.SplitN(pair, ":", 2)
-				tagpair := [2]string{fields[0], fields[1]}
-				telemetryTags = append(telemetryTags, tagpair)
-			}
-		}
-		fmt.Printf("telemetry tags: %v\n", telemetryTags)
+		loader.ReportHost = telemetryHost
+		loader.ReportTelemetry = true
 	}
-
-	if reportHost != "" {
-		fmt.Printf("results report destination: %v\n", reportHost)
-		fmt.Printf("results report database: %v\n", reportDatabase)
-
-		var err error
-		reportHostname, err = os.Hostname()
-		if err != nil {
-			log.Fatalf("os.Hostname() error: %s", err.Error())
+	if telemetryBasicAuth != "" {
+		fields := strings.SplitN(telemetryBasicAuth, ":", 2)
+		if len(fields) != 2 {
+			log.Fatal("-telemetry-basic-auth must be username:password")
 		}
-		fmt.Printf("hostname for results report: %v\n", reportHostname)
-
-		if reportTagsCSV != "" {
-			pairs := strings.Split(reportTagsCSV, ",")
-			for _, pair := range pairs {
-				fields := strings.SplitN(pair, ":", 2)
-				tagpair := [2]string{fields[0], fields[1]}
-				reportTags = append(reportTags, tagpair)
-			}
-		}
-		fmt.Printf("results report tags: %v\n", reportTags)
+		loader.ReportUser, loader.ReportPassword = fields[0], fields[1]
+	}
+	if telemetryTagsCSV != "" {
+		loader.ReportTagsCSV = telemetryTagsCSV
 	}
 
+	loader.Init()
+
 	if _, ok := indexTemplateChoices[indexTemplateName]; !ok {
 		log.Fatalf("invalid index template type")
 	}
+	if timeSeries && !dataStream {
+		log.Fatal("--time-series needs --data-stream")
+	}
+	if ilmPolicy != "" && !dataStream {
+		log.Fatal("--ilm-policy needs --data-stream")
+	}
 }
 
 func main() {
-	if doLoad {
-		v, err := checkServer(daemonUrls[0])
+	daemonUrl := loader.DaemonUrls[0]
+	if loader.DoLoad {
+		v, err := checkServer(daemonUrl)
 		if err != nil {
 			log.Fatal(err)
 		}
 		if doDBCreate {
 			// check that there are no pre-existing index templates:
-			existingIndexTemplates, err := listIndexTemplates(daemonUrls[0])
+			existingIndexTemplates, err := listIndexTemplates(daemonUrl)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -338,7 +322,7 @@ func main() {
 			}
 
 			// check that there are no pre-existing indices:
-			existingIndices, err := listIndices(daemonUrls[0])
+			existingIndices, err := listIndices(daemonUrl)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -348,234 +332,296 @@ func main() {
 			}
 
 			// create the index template:
-			indexTemplate := indexTemplateChoices[indexTemplateName]
-			err = createESTemplate(daemonUrls[0], "measurements_template", indexTemplate[v], numberOfReplicas, numberOfShards)
-			if err != nil {
+			if err := createIndexTemplate(daemonUrl, v); err != nil {
 				log.Fatal(err)
 			}
 		}
+		if schemaMigrate {
+			migrator = newESSchemaMigrator(daemonUrl, dataStream)
+		}
 	}
-	bufPool = sync.Pool{
-		New: func() interface{} {
-			return bytes.NewBuffer(make([]byte, 0, 4*1024*1024))
-		},
+
+	loader.Summarize = func(s *report.Summary) {
+		if !loader.DoLoad {
+			return
+		}
+		// Count the documents Elasticsearch indexed, not the ones read:
+		s.Totals.Items = itemStats.indexed
+		if secs := s.Totals.DurationSecs; secs > 0 {
+			s.Rates.ItemsPerSec = float64(itemStats.indexed) / secs
+		}
+		s.Errors["rejections"] = itemStats.rejections
+		s.Errors["mapping_failures"] = itemStats.mappingFailures
+		s.Errors["item_failures"] = itemStats.otherFailures
 	}
 
-	batchChan = make(chan *bytes.Buffer, workers)
-	inputDone = make(chan struct{})
+	res := loader.Run(driver{})
 
-	if telemetryHost != "" {
-		telemetryCollector := report.NewCollector(telemetryHost, "telegraf", reportUser, reportPassword)
-		telemetryChanPoints, telemetryChanDone = report.TelemetryRunAsync(telemetryCollector, telemetryBatchSize, telemetryStderr, 0)
+	var extraVals []report.ExtraVal
+	if migrator != nil {
+		fmt.Printf("performed %d schema migrations\n", migrator.Migrations())
+		extraVals = append(extraVals, report.ExtraVal{Name: "schema_migrations", Value: migrator.Migrations()})
+	}
+	if loader.DoLoad {
+		fmt.Println(itemStats.String())
+		extraVals = append(extraVals, itemStats.ExtraVals()...)
 	}
 
-	for i := 0; i < workers; i++ {
-		daemonUrl := daemonUrls[i%len(daemonUrls)]
-		workersGroup.Add(1)
-		cfg := HTTPWriterConfig{
-			Host: daemonUrl,
-		}
-		go processBatches(NewHTTPWriter(cfg, refreshEachBatch), telemetryChanPoints, fmt.Sprintf("%d", i))
+	//append db specific tags to custom tags
+	tags := [][2]string{
+		{"replicas", strconv.Itoa(int(numberOfReplicas))},
+		{"shards", strconv.Itoa(int(numberOfShards))},
+		{"index-template", indexTemplateName},
 	}
+	loader.Finish(res, useGzip, tags, extraVals...)
+}
 
-	start := time.Now()
-	itemsRead, bytesRead, valuesRead := scan(batchSize)
+// driver reads the input in the ElasticSearch bulk format, and writes it
+// with the _bulk API. The items rejected by Elasticsearch are written again,
+// alone.
+type driver struct{}
 
-	<-inputDone
-	close(batchChan)
-	workersGroup.Wait()
-	end := time.Now()
-	took := end.Sub(start)
-	itemsRate := float64(itemsRead) / float64(took.Seconds())
-	bytesRate := float64(bytesRead) / float64(took.Seconds())
-	valuesRate := float64(valuesRead) / float64(took.Seconds())
+func (driver) NewDecoder(r io.Reader) bulk_load.Decoder {
+	return &decoder{
+		scanner:     bufio.NewScanner(bufio.NewReaderSize(r, 4*1024*1024)),
+		totalPoints: -1,
+		totalValues: -1,
+	}
+}
 
-	if telemetryHost != "" {
-		close(telemetryChanPoints)
-		<-telemetryChanDone
-	}
-
-	fmt.Printf("loaded %d items in %fsec with %d workers (mean point rate %f items/sec, mean value rate %f/s, %.2fMB/sec from stdin)\n", itemsRead, took.Seconds(), workers, itemsRate, valuesRate, bytesRate/(1<<20))
-
-	if reportHost != "" {
-		//append db specific tags to custom tags
-		reportTags = append(reportTags, [2]string{"replicas", strconv.Itoa(int(numberOfReplicas))})
-		reportTags = append(reportTags, [2]string{"shards", strconv.Itoa(int(numberOfShards))})
-		reportTags = append(reportTags, [2]string{"index-template", indexTemplateName})
-
-		reportParams := &report.LoadReportParams{
-			ReportParams: report.ReportParams{
-				DBType:             "ElasticSearch",
-				ReportDatabaseName: reportDatabase,
-				ReportHost:         reportHost,
-				ReportUser:         reportUser,
-				ReportPassword:     reportPassword,
-				ReportTags:         reportTags,
-				Hostname:           reportHostname,
-				DestinationUrl:     csvDaemonUrls,
-				Workers:            workers,
-				ItemLimit:          int(itemLimit),
-			},
-			IsGzip:    useGzip,
-			BatchSize: batchSize,
-		}
-		err := report.ReportLoadResult(reportParams, itemsRead, valuesRate, bytesRate, took)
+func (driver) NewEncoder() bulk_load.Encoder {
+	return bulk_load.NewlineEncoder
+}
 
-		if err != nil {
-			log.Fatal(err)
-		}
+func (driver) NewWriter(worker int, url string) bulk_load.Writer {
+	cfg := HTTPWriterConfig{
+		Host:  url,
+		Retry: &loader.Retry,
 	}
+	return &bulkWriter{w: NewHTTPWriter(cfg, refreshEachBatch)}
 }
 
-// scan reads items from stdin. It expects input in the ElasticSearch bulk
-// format: two line pairs, the first line being an 'action' and the second line
-// being the payload. (2 lines = 1 item)
-func scan(itemsPerBatch int) (int64, int64, int64) {
-	buf := bufPool.Get().(*bytes.Buffer)
-
-	var linesRead int64
-	var err error
-	var itemsRead, bytesRead int64
-	var totalPoints, totalValues int64
+// Measurement returns the index of an item, named by its action line.
+func (driver) Measurement(item []byte) []byte {
+	return actionIndex(item)
+}
 
-	var itemsThisBatch int
-	scanner := bufio.NewScanner(os.Stdin)
+func (driver) CountPoints(url, index string) (int64, error) {
+	return countDocuments(url, index)
+}
 
-	for scanner.Scan() {
+// errOddLines is returned when the input ends with an action line without
+// its document.
+var errOddLines = errors.New("the number of lines read was not a multiple of 2, which indicates a bad bulk format for Elastic")
+
+// decoder reads items of two lines, the first line being an 'action' and
+// the second line being the payload. (2 lines = 1 item)
+type decoder struct {
+	scanner     *bufio.Scanner
+	action      []byte
+	item        []byte
+	totalPoints int64
+	totalValues int64
+}
 
-		totalPoints, totalValues, err = common.CheckTotalValues(scanner.Text())
-		if totalPoints > 0 || totalValues > 0 {
+func (d *decoder) Decode() ([]byte, int, error) {
+	haveAction := false
+	for d.scanner.Scan() {
+		line := d.scanner.Bytes()
+		if bytes.HasPrefix(line, []byte(common.DatasetSizeMarker)) {
+			points, values, err := common.CheckTotalValues(string(line))
+			if err != nil {
+				return nil, 0, err
+			}
+			d.totalPoints, d.totalValues = points, values
 			continue
 		}
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		linesRead++
-
-		buf.Write(scanner.Bytes())
-		buf.Write([]byte("\n"))
-
-		//n++
-		if linesRead%2 == 0 {
-			itemsRead++
-			itemsThisBatch++
+		if !haveAction {
+			d.action = append(d.action[:0], line...)
+			haveAction = true
+			continue
 		}
 
-		hitLimit := itemLimit >= 0 && itemsRead >= itemLimit
-
-		if itemsThisBatch == itemsPerBatch || hitLimit {
-			bytesRead += int64(buf.Len())
-			batchChan <- buf
-			buf = bufPool.Get().(*bytes.Buffer)
-			itemsThisBatch = 0
+		if timeSeries {
+			if err := checkTimeSeriesTimestamp(line); err != nil {
+				return nil, 0, err
+			}
 		}
 
-		if hitLimit {
-			break
+		// the mapping is updated before the document is queued:
+		if migrator != nil {
+			var err error
+			line, err = migrator.migrate(d.action, line)
+			if err != nil {
+				return nil, 0, err
+			}
 		}
+		d.item = append(append(append(d.item[:0], d.action...), '\n'), line...)
+		return d.item, docValues(line), nil
 	}
-
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("Error reading input: %s", err.Error())
+	if err := d.scanner.Err(); err != nil {
+		return nil, 0, err
 	}
-
-	// Finished reading input, make sure last batch goes out.
-	if itemsThisBatch > 0 {
-		batchChan <- buf
+	if haveAction {
+		return nil, 0, errOddLines
 	}
+	return nil, 0, io.EOF
+}
 
-	// Closing inputDone signals to the application that we've read everything and can now shut down.
-	close(inputDone)
+func (d *decoder) DatasetSize() (int64, int64) {
+	return d.totalPoints, d.totalValues
+}
 
-	// The ES bulk format uses 2 lines per item:
-	if linesRead%2 != 0 {
-		log.Fatalf("the number of lines read was not a multiple of 2, which indicates a bad bulk format for Elastic")
+var timestampKey = []byte(`"@timestamp": `)
+
+// checkTimeSeriesTimestamp returns an error if the @timestamp of doc, in
+// milliseconds, is out of the window accepted by a time series data stream.
+// Elasticsearch would otherwise fail such a document on its own, counted as a
+// mapping failure, and go on with the load.
+func checkTimeSeriesTimestamp(doc []byte) error {
+	i := bytes.LastIndex(doc, timestampKey)
+	if i < 0 {
+		return nil
 	}
-	if itemsRead != totalPoints { // totalPoints is unknown (0) when exiting prematurely due to time limit
-		log.Fatalf("Incorrent number of read points: %d, expected: %d:", itemsRead, totalPoints)
+	digits := doc[i+len(timestampKey):]
+	end := 0
+	for end < len(digits) && digits[end] >= '0' && digits[end] <= '9' {
+		end++
 	}
-
-	return itemsRead, bytesRead, totalValues
+	ms, err := strconv.ParseInt(string(digits[:end]), 10, 64)
+	if err != nil {
+		return fmt.Errorf("bad @timestamp in document %s", doc)
+	}
+	ts := time.Unix(0, ms*int64(time.Millisecond))
+	now := time.Now()
+	if ts.Before(now.Add(-timeSeriesLookBack)) || ts.After(now.Add(timeSeriesLookAhead)) {
+		return fmt.Errorf("@timestamp %s is out of the window of a time series data stream, %v before to %v after the current time: generate the input with a bulk_data_gen --timestamp-start close to the start of the load, or load it without --time-series", ts.UTC().Format(time.RFC3339), timeSeriesLookBack, timeSeriesLookAhead)
+	}
+	return nil
 }
 
-// processBatches reads byte buffers from batchChan and writes them to the target server, while tracking stats on the write.
-func processBatches(w *HTTPWriter, telemetrySink chan *report.Point, telemetryWorkerLabel string) {
-	var batchesSeen int64
-	for batch := range batchChan {
-		batchesSeen++
-		if !doLoad {
+// docValues counts the values of a bulk document, the numbers and booleans
+// of its fields. The timestamp is not a value.
+func docValues(doc []byte) int {
+	values := 0
+	for i := 0; i+1 < len(doc); i++ {
+		if doc[i] != ':' {
 			continue
 		}
-
-		var err error
-		var bodySize int
-
-		// Write the batch.
-		if useGzip {
-			compressedBatch := bufPool.Get().(*bytes.Buffer)
-			fasthttp.WriteGzip(compressedBatch, batch.Bytes())
-			bodySize = len(compressedBatch.Bytes())
-			_, err = w.WriteLineProtocol(compressedBatch.Bytes(), true, authorization)
-			// Return the compressed batch buffer to the pool.
-			compressedBatch.Reset()
-			bufPool.Put(compressedBatch)
-		} else {
-			bodySize = len(batch.Bytes())
-			_, err = w.WriteLineProtocol(batch.Bytes(), false, authorization)
-		}
-
-		if err != nil {
-			log.Fatalf("Error writing: %s\n", err.Error())
+		j := i + 1
+		for j < len(doc) && doc[j] == ' ' {
+			j++
 		}
-
-		// Return the batch buffer to the pool.
-		batch.Reset()
-		bufPool.Put(batch)
-
-		// Report telemetry, if applicable:
-		if telemetrySink != nil {
-			p := report.GetPointFromGlobalPool()
-			p.Init("benchmark_write", time.Now().UnixNano())
-			p.AddTag("src_addr", telemetryHostname)
-			p.AddTag("dst_addr", w.c.Host)
-			p.AddTag("worker_id", telemetryWorkerLabel)
-			p.AddInt64Field("worker_req_num", batchesSeen)
-			p.AddBoolField("gzip", useGzip)
-			p.AddInt64Field("body_bytes", int64(bodySize))
-			telemetrySink <- p
+		if j < len(doc) && doc[j] != '"' && doc[j] != '{' && doc[j] != '[' {
+			values++
 		}
 	}
-	workersGroup.Done()
+	if values > 0 && bytes.Contains(doc, []byte(`timestamp"`)) {
+		values--
+	}
+	return values
 }
 
-// createESTemplate uses a Go text/template to create an ElasticSearch index
-// template. (This terminological conflict is mostly unavoidable).
-func createESTemplate(daemonUrl, indexTemplateName string, indexTemplateBodyTemplate []byte, numberOfReplicas, numberOfShards uint) error {
-	// set up URL:
-	u, err := url.Parse(daemonUrl)
+// bulkWriter writes batches with the _bulk API. When Elasticsearch rejects
+// items of a batch, the body of the batch is cut down to them, and the
+// batch is written again.
+type bulkWriter struct {
+	w          *HTTPWriter
+	compressed bytes.Buffer
+}
+
+func (w *bulkWriter) WriteBatch(b *bulk_load.Batch) error {
+	raw := b.Buffer.Bytes()
+	body := raw
+	if useGzip {
+		w.compressed.Reset()
+		fasthttp.WriteGzip(&w.compressed, raw)
+		body = w.compressed.Bytes()
+	}
+	_, res, err := w.w.WriteLineProtocol(body, useGzip, authorization)
 	if err != nil {
 		return err
 	}
-	u.Path = fmt.Sprintf("_template/%s", indexTemplateName)
+	itemStats.add(bulkItems(raw), res)
+	if res == nil {
+		return nil
+	}
+	if res.Failures() > 0 {
+		firstItemFailure.Do(func() {
+			log.Printf("Elasticsearch failed to index items, first one: %s\n", res.FirstError)
+		})
+	}
+	if len(res.Rejected) == 0 {
+		return nil
+	}
+	selected := selectBulkItems(raw, res.Rejected)
+	b.Buffer.Reset()
+	b.Buffer.Write(selected)
+	return &bulk_load.RetryableError{Status: 429, Body: fmt.Sprintf("%d items rejected: %s", len(res.Rejected), res.FirstError)}
+}
 
-	// parse and execute the text/template:
-	t := template.Must(template.New("index_template").Parse(string(indexTemplateBodyTemplate)))
-	var body bytes.Buffer
-	params := struct {
-		NumberOfReplicas uint
-		NumberOfShards   uint
-	}{
+func (w *bulkWriter) Close() error {
+	return nil
+}
+
+// createIndexTemplate creates the index template for the major version of
+// the server, and the ILM policy of the data streams, if any.
+func createIndexTemplate(daemonUrl, majorVer string) error {
+	v := templateVersion(majorVer)
+	if v != "7" && dataStream {
+		return fmt.Errorf("data streams need ElasticSearch 7.9 or later, server version is %s", majorVer)
+	}
+	if major, _ := strconv.Atoi(majorVer); timeSeries && (openSearch || major < 8) {
+		return fmt.Errorf("time series data streams need ElasticSearch 8.8 or later")
+	}
+	params := templateParams{
 		NumberOfReplicas: numberOfReplicas,
 		NumberOfShards:   numberOfShards,
+		DataStream:       dataStream,
+		TimeSeries:       timeSeries,
+		ILMPolicy:        ilmPolicy,
+	}
+	var err error
+	if params.IndexPatterns, err = indexPatternsJSON(indexPatternsCSV); err != nil {
+		return err
 	}
-	err = t.Execute(&body, params)
+	body, err := executeTemplate(indexTemplateChoices[indexTemplateName][v], params)
 	if err != nil {
 		return err
 	}
+	if v != "7" {
+		return createESTemplate(daemonUrl, "measurements_template", body)
+	}
+
+	if ilmPolicy != "" {
+		if openSearch {
+			return fmt.Errorf("ILM policies are not supported by OpenSearch")
+		}
+		if ilmRolloverCSV != "" {
+			policy, err := ilmPolicyBody(ilmRolloverCSV, ilmDeleteAfter)
+			if err != nil {
+				return err
+			}
+			if err := createILMPolicy(daemonUrl, ilmPolicy, policy); err != nil {
+				return err
+			}
+		}
+	}
+	return createComposableTemplate(daemonUrl, "measurements_template", body)
+}
+
+// createESTemplate creates a legacy ElasticSearch index template, filled by
+// createIndexTemplate. (This terminological conflict is mostly unavoidable).
+func createESTemplate(daemonUrl, indexTemplateName string, body []byte) error {
+	// set up URL:
+	u, err := url.Parse(daemonUrl)
+	if err != nil {
+		return err
+	}
+	u.Path = fmt.Sprintf("_template/%s", indexTemplateName)
 
 	// do the HTTP PUT request with the body data:
-	req, err := http.NewRequest("PUT", u.String(), bytes.NewReader(body.Bytes()))
+	req, err := http.NewRequest("PUT", u.String(), bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -704,6 +750,12 @@ func checkServer(daemonUrl string) (string, error) {
 				majorVer = nums[0]
 			}
 		}
+		// OpenSearch is typeless like ElasticSearch 7, whatever its version:
+		if d, ok := vo["distribution"]; ok && d == "opensearch" {
+			fmt.Println("OpenSearch detected, loading like ElasticSearch 7")
+			openSearch = true
+			majorVer = "7"
+		}
 	}
 
 	return majorVer, nil
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Data generated with --schema-changes may carry new fields, or fields whose
+// values change type. With --schema-migrate the loader maps every numeric
+// field as a double before writing it, so that integer and float values of a
+// field fit the same mapping. ElasticSearch cannot change the type of a
+// mapped field, so a value of another kind goes to a sibling field named
+// after its kind instead, e.g. value7_string. With --data-stream the
+// indices named by the actions are data streams, whose mapping updates
+// apply to their backing indices.
+
+const (
+	fieldKindNumber  = "number"
+	fieldKindString  = "string"
+	fieldKindBoolean = "boolean"
+)
+
+// esSchemaMigrator tracks the kind of the fields of each index. It is shared
+// by the scans of the inputs.
+type esSchemaMigrator struct {
+	mu         sync.Mutex
+	daemonUrl  string
+	dataStream bool
+	indices    map[string]map[string]string // index -> field -> kind
+	migrations int64
+}
+
+func newESSchemaMigrator(daemonUrl string, dataStream bool) *esSchemaMigrator {
+	return &esSchemaMigrator{
+		daemonUrl:  daemonUrl,
+		dataStream: dataStream,
+		indices:    map[string]map[string]string{},
+	}
+}
+
+// Migrations returns the number of mapping updates made.
+func (m *esSchemaMigrator) Migrations() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.migrations
+}
+
+// migrate updates the mapping of the index named by a bulk action line when
+// its document brings new numeric fields, and returns the document to
+// write, which differs from doc when values were moved to sibling fields.
+func (m *esSchemaMigrator) migrate(action, doc []byte) ([]byte, error) {
+	type target struct {
+		Index string `json:"_index"`
+		Type  string `json:"_type"`
+	}
+	var a struct {
+		Index  *target `json:"index"`
+		Create *target `json:"create"`
+	}
+	if err := json.Unmarshal(action, &a); err != nil {
+		return nil, fmt.Errorf("bad bulk action %s: %v", action, err)
+	}
+	// data streams are written with create actions:
+	if a.Index == nil {
+		a.Index = a.Create
+	}
+	if a.Index == nil {
+		return nil, fmt.Errorf("bad bulk action %s: neither index nor create", action)
+	}
+
+	fields := map[string]interface{}{}
+	d := json.NewDecoder(bytes.NewReader(doc))
+	d.UseNumber()
+	if err := d.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("bad bulk document %s: %v", doc, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kinds, ok := m.indices[a.Index.Index]
+	if !ok {
+		if err := m.createIndex(a.Index.Index); err != nil {
+			return nil, err
+		}
+		kinds = map[string]string{}
+		m.indices[a.Index.Index] = kinds
+	}
+
+	var newNumbers []string
+	renamed := false
+	for name, value := range fields {
+		if name == "timestamp" || name == "@timestamp" {
+			continue
+		}
+		kind := fieldKind(value)
+		if kind == "" {
+			continue
+		}
+		have, ok := kinds[name]
+		if ok && have == kind {
+			continue
+		}
+		if ok {
+			delete(fields, name)
+			name = name + "_" + kind
+			fields[name] = value
+			renamed = true
+			if _, ok := kinds[name]; ok {
+				continue
+			}
+		}
+		kinds[name] = kind
+		if kind == fieldKindNumber {
+			newNumbers = append(newNumbers, name)
+		}
+	}
+
+	if len(newNumbers) > 0 {
+		if err := m.mapDoubles(a.Index.Index, a.Index.Type, newNumbers); err != nil {
+			return nil, err
+		}
+	}
+
+	if !renamed {
+		return doc, nil
+	}
+	return json.Marshal(fields)
+}
+
+func fieldKind(v interface{}) string {
+	switch v.(type) {
+	case json.Number:
+		return fieldKindNumber
+	case string:
+		return fieldKindString
+	case bool:
+		return fieldKindBoolean
+	default:
+		// objects such as locations keep their template mapping
+		return ""
+	}
+}
+
+// createIndex creates an index, or a data stream, ahead of its first
+// document, so that its mapping can be updated. The index template still
+// applies.
+func (m *esSchemaMigrator) createIndex(index string) error {
+	path := "/" + index
+	if m.dataStream {
+		path = "/_data_stream/" + index
+	}
+	status, body, err := m.put(path, nil)
+	if err != nil {
+		return err
+	}
+	if status != 200 && !strings.Contains(string(body), "already_exists") {
+		return fmt.Errorf("bad index create: %s", body)
+	}
+	return nil
+}
+
+func (m *esSchemaMigrator) mapDoubles(index, typeName string, fields []string) error {
+	properties := map[string]interface{}{}
+	for _, f := range fields {
+		properties[f] = map[string]interface{}{"type": "double", "doc_values": true}
+	}
+	body, err := json.Marshal(map[string]interface{}{"properties": properties})
+	if err != nil {
+		return err
+	}
+
+	path := "/" + index + "/_mapping"
+	if typeName != "" {
+		path += "/" + typeName
+	}
+	status, resp, err := m.put(path, body)
+	if err != nil {
+		return err
+	}
+	if status != 200 {
+		return fmt.Errorf("bad mapping update of %s: %s", index, resp)
+	}
+	log.Printf("schema migration: mapped %s of %s as double\n", strings.Join(fields, ", "), index)
+	m.migrations++
+	return nil
+}
+
+func (m *esSchemaMigrator) put(path string, body []byte) (int, []byte, error) {
+	return putJSON(m.daemonUrl+path, body)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// With --verify the documents read of each index are compared with the
+// documents of the index, counted with _count once the index is refreshed.
+
+var indexKey = []byte(`"_index"`)
+
+// actionIndex returns the index of a bulk action line, nil if it has none.
+func actionIndex(action []byte) []byte {
+	i := bytes.Index(action, indexKey)
+	if i < 0 {
+		return nil
+	}
+	rest := action[i+len(indexKey):]
+	j := bytes.IndexByte(rest, '"')
+	if j < 0 {
+		return nil
+	}
+	rest = rest[j+1:]
+	k := bytes.IndexByte(rest, '"')
+	if k < 0 {
+		return nil
+	}
+	return rest[:k]
+}
+
+// countDocuments refreshes index, or data stream, and counts its documents.
+func countDocuments(daemonUrl, index string) (int64, error) {
+	status, body, err := doJSON("POST", daemonUrl+"/"+index+"/_refresh", nil)
+	if err != nil {
+		return 0, err
+	}
+	if status == 404 {
+		// nothing was indexed
+		return 0, nil
+	}
+	if status != 200 {
+		return 0, fmt.Errorf("bad refresh: %s", body)
+	}
+
+	status, body, err = doJSON("GET", daemonUrl+"/"+index+"/_count", nil)
+	if err != nil {
+		return 0, err
+	}
+	if status != 200 {
+		return 0, fmt.Errorf("bad count: %s", body)
+	}
+	var count struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.Unmarshal(body, &count); err != nil {
+		return 0, err
+	}
+	return count.Count, nil
+}
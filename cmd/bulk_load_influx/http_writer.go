@@ -9,13 +9,14 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
 	"github.com/valyala/fasthttp"
 )
 
 const DefaultIdleConnectionTimeout = 90 * time.Second
 
 var (
-	BackoffError        error  = fmt.Errorf("backpressure is needed")
+	BackoffError        error  = bulk_load.ErrBackoff
 	backoffMagicWords0  []byte = []byte("engine: cache maximum memory size exceeded")
 	backoffMagicWords1  []byte = []byte("write failed: hinted handoff queue not empty")
 	backoffMagicWords2a []byte = []byte("write failed: read message type: read tcp")
@@ -33,9 +34,6 @@ type HTTPWriterConfig struct {
 	// Name of the target database into which points will be written.
 	Database string
 
-	BackingOffChan chan bool
-	BackingOffDone chan struct{}
-
 	// Debug label for more informative errors.
 	DebugInfo string
 }
@@ -44,8 +42,9 @@ type HTTPWriterConfig struct {
 type HTTPWriter struct {
 	client fasthttp.Client
 
-	c   HTTPWriterConfig
-	url []byte
+	c          HTTPWriterConfig
+	url        []byte
+	compressed bytes.Buffer
 }
 
 // NewHTTPWriter returns a new HTTPWriter from the supplied HTTPWriterConfig.
@@ -99,6 +98,23 @@ func (w *HTTPWriter) WriteLineProtocol(body []byte, isGzip bool) (int64, error)
 	return lat, err
 }
 
+// WriteBatch writes the lines of b, gzip compressed if -gzip is set.
+func (w *HTTPWriter) WriteBatch(b *bulk_load.Batch) error {
+	if !useGzip {
+		_, err := w.WriteLineProtocol(b.Buffer.Bytes(), false)
+		return err
+	}
+	w.compressed.Reset()
+	fasthttp.WriteGzip(&w.compressed, b.Buffer.Bytes())
+	_, err := w.WriteLineProtocol(w.compressed.Bytes(), true)
+	return err
+}
+
+// Close is a no-op, the client closes idle connections itself.
+func (w *HTTPWriter) Close() error {
+	return nil
+}
+
 func backpressurePred(body []byte) bool {
 	if bytes.Contains(body, backoffMagicWords0) {
 		return true
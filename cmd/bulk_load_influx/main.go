@@ -1,108 +1,56 @@
-// bulk_load_influx loads an InfluxDB daemon with data from stdin.
-// bulk_load_influx loads an InfluxDB daemon with data from stdin.
+// bulk_load_influx loads an InfluxDB daemon with data from stdin or files.
 //
 // The caller is responsible for assuring that the database is empty before
 // bulk load.
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"github.com/caict-benchmark/BDC-TS/bulk_load"
 	"io"
 	"io/ioutil"
 	"log"
-	"math"
-	"net"
 	"net/http"
-	"net/rpc"
 	"net/url"
-	"os"
 	"runtime"
-	"runtime/pprof"
-	"sort"
+	"strconv"
 	"strings"
-	"sync"
-	"sync/atomic"
 	"time"
 
-	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
 	"github.com/caict-benchmark/BDC-TS/util/report"
-	"github.com/pkg/profile"
-	"github.com/valyala/fasthttp"
-	"strconv"
 )
 
 // TODO VH: This should be calculated from available simulation data
 const ValuesPerMeasurement = 9.63636 // dashboard use-case, original value was: 11.2222
 
-// TODO AP: Maybe useless
-const RateControlGranularity = 1000 // 1000 ms = 1s
-const RateControlMinBatchSize = 100
-
 // Program option vars:
 var (
-	csvDaemonUrls          string
-	daemonUrls             []string
-	dbName                 string
-	replicationFactor      int
-	workers                int
-	itemLimit              int64
-	batchSize              int
-	ingestRateLimit        int
-	backoff                time.Duration
-	timeLimit              time.Duration
-	progressInterval       time.Duration
-	doLoad                 bool
-	doDBCreate             bool
-	useGzip                bool
-	doAbortOnExist         bool
-	memprofile             bool
-	cpuProfileFile         string
-	consistency            string
-	telemetryStderr        bool
-	telemetryBatchSize     uint64
-	reportDatabase         string
-	reportHost             string
-	reportUser             string
-	reportPassword         string
-	reportTagsCSV          string
-	reportTelemetry        bool
-	notificationListenPort int
-	clientIndex            int
-	printInterval          uint64
-	trendSamples           int
-	movingAverageInterval  time.Duration
+	loader = &bulk_load.Loader{
+		Config: bulk_load.Config{
+			Urls:      "http://localhost:8086",
+			BatchSize: 5000,
+		},
+		DBType:        "InfluxDB",
+		ValuesPerItem: ValuesPerMeasurement,
+		Verifiable:    true,
+	}
+	dbName            string
+	replicationFactor int
+	doDBCreate        bool
+	useGzip           bool
+	doAbortOnExist    bool
+	consistency       string
+	protocol          string
+	tcpTimeout        time.Duration
+	tcpMaxReconnects  int
 )
 
 // Global vars
 var (
-	bufPool               sync.Pool
-	batchChan             chan batch
-	inputDone             chan struct{}
-	workersGroup          sync.WaitGroup
-	backingOffChans       []chan bool
-	backingOffDones       []chan struct{}
-	telemetryChanPoints   chan *report.Point
-	telemetryChanDone     chan struct{}
-	syncChanDone          chan int
-	progressIntervalItems uint64
-	reportTags            [][2]string
-	reportHostname        string
-	ingestionRateGran     float64
-	endedPrematurely      bool
-	prematureEndReason    string
-	maxBatchSize          int
-	speedUpRequest        int32
-	statMapping           statsMap
-	statPool              sync.Pool
-	statChan              chan *Stat
-	statGroup             sync.WaitGroup
-	movingAverageStat     *TimedStatGroup
-	scanFinished          bool
+	tcpWriters []*bulk_load.SocketWriter
 )
 
 var consistencyChoices = map[string]struct{}{
@@ -112,118 +60,53 @@ var consistencyChoices = map[string]struct{}{
 	"all":    {},
 }
 
-type statsMap map[string]*StatGroup
-
-type batch struct {
-	Buffer *bytes.Buffer
-	Items  int
+var protocolChoices = map[string]struct{}{
+	"http": {},
+	"tcp":  {},
 }
 
 // Parse args:
 func init() {
-	flag.StringVar(&csvDaemonUrls, "urls", "http://localhost:8086", "InfluxDB URLs, comma-separated. Will be used in a round-robin fashion.")
+	loader.AddFlags(flag.CommandLine)
 	flag.StringVar(&dbName, "db", "benchmark_db", "Database name.")
 	flag.IntVar(&replicationFactor, "replication-factor", 1, "Cluster replication factor (only applies to clustered databases).")
 	flag.StringVar(&consistency, "consistency", "one", "Write consistency. Must be one of: any, one, quorum, all.")
-	flag.IntVar(&batchSize, "batch-size", 5000, "Batch size (1 line of input = 1 item).")
-	flag.IntVar(&workers, "workers", 1, "Number of parallel requests to make.")
-	flag.IntVar(&ingestRateLimit, "ingest-rate-limit", -1, "Ingest rate limit in values/s (-1 = no limit).")
-	flag.Int64Var(&itemLimit, "item-limit", -1, "Number of items to read from stdin before quitting. (1 item per 1 line of input.)")
-	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when server indicates backpressure is needed.")
-	flag.DurationVar(&timeLimit, "time-limit", -1, "Maximum duration to run (-1 is the default: no limit).")
-	flag.DurationVar(&progressInterval, "progress-interval", -1, "Duration between printing progress messages.")
+	flag.StringVar(&protocol, "protocol", "http", "Write protocol. Must be one of: http (the /write endpoint), tcp (line protocol over persistent TCP connections, as QuestDB accepts it, urls are then in form tcp://host:port).")
+	flag.DurationVar(&tcpTimeout, "tcp-timeout", 30*time.Second, "Timeout of connecting and of writing a batch, for -protocol=tcp.")
+	flag.IntVar(&tcpMaxReconnects, "tcp-max-reconnects", 10, "Number of consecutive failed writes, each followed by a reconnect, before a worker gives up, for -protocol=tcp.")
 	flag.BoolVar(&useGzip, "gzip", true, "Whether to gzip encode requests (default true).")
-	flag.BoolVar(&doLoad, "do-load", true, "Whether to write data. Set this flag to false to check input read speed.")
 	flag.BoolVar(&doDBCreate, "do-db-create", true, "Whether to create the database.")
 	flag.BoolVar(&doAbortOnExist, "do-abort-on-exist", true, "Whether to abort if the destination database already exists.")
-	flag.BoolVar(&memprofile, "memprofile", false, "Whether to write a memprofile (file automatically determined).")
-	flag.BoolVar(&telemetryStderr, "telemetry-stderr", false, "Whether to write telemetry also to stderr.")
-	flag.Uint64Var(&telemetryBatchSize, "telemetry-batch-size", 1, "Telemetry batch size (lines).")
-	flag.StringVar(&reportDatabase, "report-database", "database_benchmarks", "Database name where to store result metrics")
-	flag.StringVar(&reportHost, "report-host", "", "Host to send result metrics")
-	flag.StringVar(&reportUser, "report-user", "", "User for host to send result metrics")
-	flag.StringVar(&reportPassword, "report-password", "", "User password for Host to send result metrics")
-	flag.StringVar(&reportTagsCSV, "report-tags", "", "Comma separated k:v tags to send  alongside result metrics")
-	flag.BoolVar(&reportTelemetry, "report-telemetry", false, "Turn on/off reporting telemetry")
-	flag.IntVar(&notificationListenPort, "notification-port", -1, "Listen port for remote notification messages. Used to remotely finish benchmark. -1 to disable feature")
-	flag.StringVar(&cpuProfileFile, "cpu-profile", "", "Write cpu profile to `file`")
-	flag.IntVar(&clientIndex, "client-index", 0, "Index of a client host running this tool. Used to distribute load")
-	flag.Uint64Var(&printInterval, "print-interval", 1000, "Print timing stats to stderr after this many batches (0 to disable)")
-	flag.DurationVar(&movingAverageInterval, "moving-average-interval", time.Second*30, "Interval of measuring mean write rate on which moving average is calculated.")
 
 	flag.Parse()
 
 	if _, ok := consistencyChoices[consistency]; !ok {
 		log.Fatalf("invalid consistency settings")
 	}
-
-	daemonUrls = strings.Split(csvDaemonUrls, ",")
-	if len(daemonUrls) == 0 {
-		log.Fatal("missing 'urls' flag")
+	if _, ok := protocolChoices[protocol]; !ok {
+		log.Fatalf("invalid protocol: %s", protocol)
 	}
-	fmt.Printf("daemon URLs: %v\n", daemonUrls)
 
-	if workers < 1 {
-		log.Fatalf("invalid number of workers: %d\n", workers)
-	}
+	loader.Init()
 
-	if reportHost != "" {
-		fmt.Printf("results report destination: %v\n", reportHost)
-		fmt.Printf("results report database: %v\n", reportDatabase)
-
-		var err error
-		reportHostname, err = os.Hostname()
-		if err != nil {
-			log.Fatalf("os.Hostname() error: %s", err.Error())
-		}
-		fmt.Printf("hostname for results report: %v\n", reportHostname)
-
-		if reportTagsCSV != "" {
-			pairs := strings.Split(reportTagsCSV, ",")
-			for _, pair := range pairs {
-				fields := strings.SplitN(pair, ":", 2)
-				tagpair := [2]string{fields[0], fields[1]}
-				reportTags = append(reportTags, tagpair)
+	if protocol == "tcp" {
+		for i, daemonUrl := range loader.DaemonUrls {
+			address, err := bulk_load.SocketAddress(daemonUrl)
+			if err != nil {
+				log.Fatal(err)
 			}
+			loader.DaemonUrls[i] = address
 		}
-		fmt.Printf("results report tags: %v\n", reportTags)
-	}
-
-	if ingestRateLimit > 0 {
-		ingestionRateGran = (float64(ingestRateLimit) / float64(workers)) / (float64(1000) / float64(RateControlGranularity))
-		log.Printf("Using worker ingestion rate %v values/%v ms", ingestionRateGran, RateControlGranularity)
-		recommendedBatchSize := int((ingestionRateGran / ValuesPerMeasurement) * 0.20)
-		log.Printf("Calculated batch size hint: %v (allowed min: %v max: %v)", recommendedBatchSize, RateControlMinBatchSize, batchSize)
-		if recommendedBatchSize < RateControlMinBatchSize {
-			recommendedBatchSize = RateControlMinBatchSize
-		} else if recommendedBatchSize > batchSize {
-			recommendedBatchSize = batchSize
+		if useGzip {
+			log.Printf("gzip is not supported over tcp, sending plain line protocol")
+			useGzip = false
 		}
-		maxBatchSize = batchSize
-		if recommendedBatchSize != batchSize {
-			log.Printf("Adjusting batchSize from %v to %v (%v values in 1 batch)", batchSize, recommendedBatchSize, float32(recommendedBatchSize)*ValuesPerMeasurement)
-			batchSize = recommendedBatchSize
+		// there is no HTTP API to list or create databases with
+		doDBCreate = false
+		if loader.Verify.Enabled {
+			log.Fatalf("--verify needs the HTTP API, it is not supported with -protocol=tcp")
 		}
-	} else {
-		log.Printf("Ingestion rate control is off")
-	}
-
-	if trendSamples <= 0 {
-		trendSamples = int(movingAverageInterval.Seconds())
-	}
-}
-
-func notifyHandler(arg int) (int, error) {
-	var e error
-	if arg == 0 {
-		fmt.Println("Received external finish request")
-		endedPrematurely = true
-		prematureEndReason = "External notification"
-		syncChanDone <- 1
-	} else {
-		e = fmt.Errorf("unknown notification code: %d", arg)
 	}
-	return 0, e
 }
 
 func printInfo() {
@@ -231,30 +114,20 @@ func printInfo() {
 	fmt.Printf("  Current GOMAXPROCS: %d\n", runtime.GOMAXPROCS(-1))
 	fmt.Printf("  Num CPUs: %d\n", runtime.NumCPU())
 }
+
 func main() {
-	exitCode := 0
 	printInfo()
-	if memprofile {
-		p := profile.Start(profile.MemProfile)
-		defer p.Stop()
-	}
-	if cpuProfileFile != "" {
-		f, err := os.Create(cpuProfileFile)
-		if err != nil {
-			log.Fatal("could not create CPU profile: ", err)
-		}
-		if err := pprof.StartCPUProfile(f); err != nil {
-			log.Fatal("could not start CPU profile: ", err)
-		}
-		defer pprof.StopCPUProfile()
-	}
 	// check that there are no pre-existing databases
 	// this also test db connection
-	existingDatabases, err := listDatabases(daemonUrls[0])
-	if err != nil {
-		log.Fatal(err)
+	var existingDatabases []string
+	var err error
+	if protocol == "http" {
+		existingDatabases, err = listDatabases(loader.DaemonUrls[0])
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
-	if doLoad && doDBCreate {
+	if loader.DoLoad && doDBCreate {
 
 		if len(existingDatabases) > 0 {
 			if doAbortOnExist {
@@ -265,7 +138,7 @@ func main() {
 		}
 
 		if len(existingDatabases) == 0 {
-			err = createDb(daemonUrls[0], dbName, replicationFactor)
+			err = createDb(loader.DaemonUrls[0], dbName, replicationFactor)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -273,436 +146,162 @@ func main() {
 		}
 	}
 
-	bufPool = sync.Pool{
-		New: func() interface{} {
-			return bytes.NewBuffer(make([]byte, 0, 4*1024*1024))
-		},
-	}
+	res := loader.Run(driver{})
 
-	statPool = sync.Pool{
-		New: func() interface{} {
-			return &Stat{}
-		},
+	var tcpReconnects int64
+	for i, w := range tcpWriters {
+		fmt.Printf("[worker %d] wrote %.2fMB over tcp (%.2fMB/sec), reconnected %d times\n", i, float64(w.Bytes())/(1<<20), float64(w.Bytes())/(1<<20)/res.Took.Seconds(), w.Reconnects())
+		tcpReconnects += w.Reconnects()
 	}
 
-	movingAverageStat = NewTimedStatGroup(movingAverageInterval, trendSamples)
-
-	batchChan = make(chan batch, workers)
-	inputDone = make(chan struct{})
-	syncChanDone = make(chan int)
-
-	backingOffChans = make([]chan bool, workers)
-	backingOffDones = make([]chan struct{}, workers)
-	backingOffSecs := make([]float64, workers)
-
-	if reportHost != "" && reportTelemetry {
-		telemetryCollector := report.NewCollector(reportHost, reportDatabase, reportUser, reportPassword)
-		err = telemetryCollector.CreateDatabase()
-		if err != nil {
-			log.Fatalf("Error creating telemetry db: %v\n", err)
-		}
-		telemetryChanPoints, telemetryChanDone = report.TelemetryRunAsync(telemetryCollector, telemetryBatchSize, telemetryStderr, 0)
+	//append db specific tags to custom tags
+	tags := [][2]string{
+		{"back_off", strconv.Itoa(int(loader.Retry.Backoff.Seconds()))},
+		{"consistency", consistency},
+		{"protocol", protocol},
 	}
-
-	if notificationListenPort > 0 {
-		notif := new(bulk_load.NotifyReceiver)
-		rpc.Register(notif)
-		rpc.HandleHTTP()
-		bulk_load.RegisterHandler(notifyHandler)
-		l, e := net.Listen("tcp", fmt.Sprintf(":%d", notificationListenPort))
-		if e != nil {
-			log.Fatal("listen error:", e)
-		}
-		log.Println("Listening for incoming notification")
-		go http.Serve(l, nil)
+	extraVals := make([]report.ExtraVal, 0, 1)
+	if tcpReconnects > 0 {
+		extraVals = append(extraVals, report.ExtraVal{Name: "tcp_reconnects", Value: tcpReconnects})
 	}
+	loader.Finish(res, useGzip, tags, extraVals...)
+}
 
-	statChan = make(chan *Stat, workers)
-	statGroup.Add(1)
-	go processStats(telemetryChanPoints)
-	var once sync.Once
-
-	for i := 0; i < workers; i++ {
-		daemonUrl := daemonUrls[(i+clientIndex)%len(daemonUrls)]
-		backingOffChans[i] = make(chan bool, 100)
-		backingOffDones[i] = make(chan struct{})
-		workersGroup.Add(1)
-		cfg := HTTPWriterConfig{
-			DebugInfo:      fmt.Sprintf("worker #%d, dest url: %s", i, daemonUrl),
-			Host:           daemonUrl,
-			Database:       dbName,
-			BackingOffChan: backingOffChans[i],
-			BackingOffDone: backingOffDones[i],
-		}
-		go func(w int) {
-			err := processBatches(NewHTTPWriter(cfg, consistency), backingOffChans[w], telemetryChanPoints, fmt.Sprintf("%d", w))
-			if err != nil {
-				fmt.Println(err.Error())
-				once.Do(func() {
-					endedPrematurely = true
-					prematureEndReason = "Worker error"
-					if !scanFinished {
-						go func() {
-							for range batchChan {
-								//read out remaining batches
-							}
-						}()
-						syncChanDone <- 1
-					}
-					exitCode = 1
-				})
-			}
-		}(i)
-		go func(w int) {
-			backingOffSecs[w] = processBackoffMessages(w, backingOffChans[w], backingOffDones[w])
-		}(i)
-	}
-	fmt.Printf("Started load with %d workers\n", workers)
-
-	if progressInterval >= 0 {
-		go func() {
-			start := time.Now()
-			for end := range time.NewTicker(progressInterval).C {
-				n := atomic.SwapUint64(&progressIntervalItems, 0)
-
-				//absoluteMillis := end.Add(-progressInterval).UnixNano() / 1e6
-				absoluteMillis := start.UTC().UnixNano() / 1e6
-				fmt.Printf("[interval_progress_items] %dms, %d\n", absoluteMillis, n)
-				start = end
-			}
-		}()
-	}
-
-	start := time.Now()
-	itemsRead, bytesRead, valuesRead := scan(batchSize, syncChanDone)
-
-	<-inputDone
-	close(batchChan)
-	close(syncChanDone)
-
-	workersGroup.Wait()
+// driver batches the lines of the input, 1 item = 1 line, as they are.
+type driver struct{}
 
-	close(statChan)
-	statGroup.Wait()
+func (driver) NewDecoder(r io.Reader) bulk_load.Decoder {
+	return bulk_load.NewLineDecoder(r, 0, countFields)
+}
 
-	for i := range backingOffChans {
-		close(backingOffChans[i])
-		<-backingOffDones[i]
-	}
+func (driver) NewEncoder() bulk_load.Encoder {
+	return bulk_load.NewlineEncoder
+}
 
-	end := time.Now()
-	took := end.Sub(start)
+func (driver) NewWriter(worker int, url string) bulk_load.Writer {
+	if protocol == "tcp" {
+		w := bulk_load.NewSocketWriter(bulk_load.SocketWriterConfig{
+			DebugInfo:     fmt.Sprintf("worker #%d, dest address: %s", worker, url),
+			Address:       url,
+			Timeout:       tcpTimeout,
+			MaxReconnects: tcpMaxReconnects,
+		})
+		tcpWriters = append(tcpWriters, w)
+		return w
+	}
+	return NewHTTPWriter(HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("worker #%d, dest url: %s", worker, url),
+		Host:      url,
+		Database:  dbName,
+	}, consistency)
+}
 
-	totalBackOffSecs := float64(0)
-	for i := 0; i < workers; i++ {
-		totalBackOffSecs += backingOffSecs[i]
+// Measurement returns the measurement of a line protocol line, up to the
+// first unescaped comma or space.
+func (driver) Measurement(line []byte) []byte {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case ',', ' ':
+			return line[:i]
+		}
 	}
+	return line
+}
 
-	itemsRate := float64(itemsRead) / float64(took.Seconds())
-	bytesRate := float64(bytesRead) / float64(took.Seconds())
-	valuesRate := float64(valuesRead) / float64(took.Seconds())
-
-	if reportHost != "" && reportTelemetry {
-		close(telemetryChanPoints)
-		<-telemetryChanDone
-	}
-	if endedPrematurely {
-		fmt.Printf("load finished prematurely: %s\n", prematureEndReason)
+// CountPoints counts the points of measurement with SELECT count(*), which
+// counts the values of each field: the points are as many as the values of
+// the field they all have.
+func (driver) CountPoints(daemonUrl, measurement string) (int64, error) {
+	u, err := url.Parse(daemonUrl)
+	if err != nil {
+		return 0, err
 	}
+	u.Path = "query"
+	v := u.Query()
+	v.Set("db", dbName)
+	v.Set("q", fmt.Sprintf(`SELECT count(*) FROM "%s"`, strings.Replace(measurement, `"`, `\"`, -1)))
+	u.RawQuery = v.Encode()
 
-	fmt.Printf("loaded %d items in %fsec with %d workers (mean point rate %f/sec, mean value rate %f/s, %.2fMB/sec from stdin)\n", itemsRead, took.Seconds(), workers, itemsRate, valuesRate, bytesRate/(1<<20))
-
-	if reportHost != "" {
-		//append db specific tags to custom tags
-		reportTags = append(reportTags, [2]string{"back_off", strconv.Itoa(int(backoff.Seconds()))})
-		reportTags = append(reportTags, [2]string{"consistency", consistency})
-		if endedPrematurely {
-			reportTags = append(reportTags, [2]string{"premature_end_reason", report.Escape(prematureEndReason)})
-		}
-		if timeLimit.Seconds() > 0 {
-			reportTags = append(reportTags, [2]string{"time_limit", timeLimit.String()})
-		}
-		extraVals := make([]report.ExtraVal, 0, 1)
-		if ingestRateLimit > 0 {
-			extraVals = append(extraVals, report.ExtraVal{Name: "ingest_rate_limit_values", Value: ingestRateLimit})
-		}
-		if totalBackOffSecs > 0 {
-			extraVals = append(extraVals, report.ExtraVal{Name: "total_backoff_secs", Value: totalBackOffSecs})
-		}
-
-		reportParams := &report.LoadReportParams{
-			ReportParams: report.ReportParams{
-				DBType:             "InfluxDB",
-				ReportDatabaseName: reportDatabase,
-				ReportHost:         reportHost,
-				ReportUser:         reportUser,
-				ReportPassword:     reportPassword,
-				ReportTags:         reportTags,
-				Hostname:           reportHostname,
-				DestinationUrl:     csvDaemonUrls,
-				Workers:            workers,
-				ItemLimit:          int(itemLimit),
-			},
-			IsGzip:    useGzip,
-			BatchSize: batchSize,
-		}
-		err = report.ReportLoadResult(reportParams, itemsRead, valuesRate, bytesRate, took, extraVals...)
-
-		if err != nil {
-			log.Fatal(err)
-		}
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return 0, err
 	}
-	if exitCode != 0 {
-		os.Exit(exitCode)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
 	}
-
-}
-
-// scan reads one item at a time from stdin. 1 item = 1 line.
-// When the requested number of items per batch is met, send a batch over batchChan for the workers to write.
-func scan(itemsPerBatch int, doneCh chan int) (int64, int64, int64) {
-	scanFinished = false
-	buf := bufPool.Get().(*bytes.Buffer)
-
-	var n int
-	var itemsRead, bytesRead int64
-	var totalPoints, totalValues int64
-
-	newline := []byte("\n")
-	var deadline time.Time
-	if timeLimit > 0 {
-		deadline = time.Now().Add(timeLimit)
+	if resp.StatusCode != 200 {
+		return 0, fmt.Errorf("bad count query (status %d): %s", resp.StatusCode, body)
 	}
 
-	var batchItemCount uint64
-
-	scanner := bufio.NewScanner(bufio.NewReaderSize(os.Stdin, 4*1024*1024))
-outer:
-	for scanner.Scan() {
-		if itemsRead == itemLimit {
-			break
-		}
-
-		line := scanner.Text()
-		if strings.HasPrefix(line, common.DatasetSizeMarker) {
-			parts := common.DatasetSizeMarkerRE.FindAllStringSubmatch(line, -1)
-			if parts == nil || len(parts[0]) != 3 {
-				log.Fatalf("Incorrent number of matched groups: %#v", parts)
-			}
-			if i, err := strconv.Atoi(parts[0][1]); err == nil {
-				totalPoints = int64(i)
-			} else {
-				log.Fatal(err)
-			}
-			if i, err := strconv.Atoi(parts[0][2]); err == nil {
-				totalValues = int64(i)
-			} else {
-				log.Fatal(err)
-			}
-			continue
-		}
-		itemsRead++
-		batchItemCount++
-
-		buf.Write(scanner.Bytes())
-		buf.Write(newline)
-
-		n++
-		if n >= itemsPerBatch {
-			atomic.AddUint64(&progressIntervalItems, batchItemCount)
-			batchItemCount = 0
-
-			bytesRead += int64(buf.Len())
-			batchChan <- batch{buf, n}
-			buf = bufPool.Get().(*bytes.Buffer)
-			n = 0
-
-			if timeLimit > 0 && time.Now().After(deadline) {
-				endedPrematurely = true
-				prematureEndReason = "Timeout elapsed"
-				break outer
-			}
-
-			if ingestRateLimit > 0 {
-				if itemsPerBatch < maxBatchSize {
-					hint := atomic.LoadInt32(&speedUpRequest)
-					if hint > int32(workers*2) { // we should wait for more requests (and this is just a magic number)
-						atomic.StoreInt32(&speedUpRequest, 0)
-						itemsPerBatch += int(float32(maxBatchSize) * 0.10)
-						if itemsPerBatch > maxBatchSize {
-							itemsPerBatch = maxBatchSize
-						}
-						log.Printf("Increased batch size to %d\n", itemsPerBatch)
-					}
-				}
+	// {"results":[{"series":[{"columns":["time","count_f1","count_f2"],"values":[["1970-01-01T00:00:00Z",10,10]]}]}]}
+	var result struct {
+		Results []struct {
+			Error  string
+			Series []struct {
+				Values [][]interface{}
 			}
 		}
-		select {
-		case <-doneCh:
-			break outer
-		default:
-		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("Error reading input: %s", err.Error())
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&result); err != nil {
+		return 0, err
 	}
-
-	// Finished reading input, make sure last batch goes out.
-	if n > 0 {
-		batchChan <- batch{buf, n}
+	if len(result.Results) == 0 {
+		return 0, fmt.Errorf("bad count response: %s", body)
 	}
-
-	// Closing inputDone signals to the application that we've read everything and can now shut down.
-	close(inputDone)
-
-	if itemsRead != totalPoints { // totalPoints is unknown (0) when exiting prematurely due to time limit
-		if !endedPrematurely {
-			log.Fatalf("Incorrent number of read points: %d, expected: %d:", itemsRead, totalPoints)
-		} else {
-			totalValues = int64(float64(itemsRead) * ValuesPerMeasurement) // needed for statistics summary
-		}
+	if result.Results[0].Error != "" {
+		return 0, fmt.Errorf("count query failed: %s", result.Results[0].Error)
 	}
-	scanFinished = true
-	return itemsRead, bytesRead, totalValues
-}
-
-// processBatches reads byte buffers from batchChan and writes them to the target server, while tracking stats on the write.
-func processBatches(w *HTTPWriter, backoffSrc chan bool, telemetrySink chan *report.Point, telemetryWorkerLabel string) error {
-	var batchesSeen int64
-
-	// Ingestion rate control vars
-	var gvCount float64
-	var gvStart time.Time
-
-	defer workersGroup.Done()
-
-	for batch := range batchChan {
-		batchesSeen++
-
-		//var bodySize int
-		ts := time.Now().UnixNano()
-
-		if ingestRateLimit > 0 && gvStart.Nanosecond() == 0 {
-			gvStart = time.Now()
-		}
-
-		// Write the batch: try until backoff is not needed.
-		if doLoad {
-			var err error
-			sleepTime := backoff
-			for {
-				if useGzip {
-					compressedBatch := bufPool.Get().(*bytes.Buffer)
-					fasthttp.WriteGzip(compressedBatch, batch.Buffer.Bytes())
-					//bodySize = len(compressedBatch.Bytes())
-					_, err = w.WriteLineProtocol(compressedBatch.Bytes(), true)
-					// Return the compressed batch buffer to the pool.
-					compressedBatch.Reset()
-					bufPool.Put(compressedBatch)
-				} else {
-					//bodySize = len(batch.Bytes())
-					_, err = w.WriteLineProtocol(batch.Buffer.Bytes(), false)
+	var points int64
+	for _, s := range result.Results[0].Series {
+		for _, row := range s.Values {
+			for _, c := range row[1:] {
+				n, ok := c.(json.Number)
+				if !ok {
+					continue
 				}
-
-				if err == BackoffError {
-					backoffSrc <- true
-					// Report telemetry, if applicable:
-					if telemetrySink != nil {
-						p := report.GetPointFromGlobalPool()
-						p.Init("benchmarks_telemetry", ts)
-						for _, tagpair := range reportTags {
-							p.AddTag(tagpair[0], tagpair[1])
-						}
-						p.AddTag("client_type", "load")
-						p.AddTag("worker", telemetryWorkerLabel)
-						p.AddBoolField("backoff", true)
-						telemetrySink <- p
-					}
-					time.Sleep(sleepTime)
-					sleepTime += backoff        // sleep longer if backpressure comes again
-					if sleepTime > 10*backoff { // but not longer than 10x default backoff time
-						log.Printf("[worker %s] sleeping on backoff response way too long (10x %v)", telemetryWorkerLabel, backoff)
-						sleepTime = 10 * backoff
-					}
-				} else {
-					backoffSrc <- false
-					break
+				count, err := n.Int64()
+				if err != nil {
+					return 0, err
 				}
-			}
-			if err != nil {
-				return fmt.Errorf("Error writing: %s\n", err.Error())
-			}
-		}
-
-		// lagMillis intentionally includes backoff time,
-		// and incidentally includes compression time:
-		lagMillis := float64(time.Now().UnixNano()-ts) / 1e6
-
-		// Return the batch buffer to the pool.
-		batch.Buffer.Reset()
-		bufPool.Put(batch.Buffer)
-
-		// Normally report after each batch
-		reportStat := true
-		valuesWritten := float64(batch.Items) * ValuesPerMeasurement
-
-		// Apply ingest rate control if set
-		if ingestRateLimit > 0 {
-			gvCount += valuesWritten
-			if gvCount >= ingestionRateGran {
-				now := time.Now()
-				elapsed := now.Sub(gvStart)
-				overdelay := (gvCount - ingestionRateGran) / (ingestionRateGran / float64(RateControlGranularity))
-				remainingMs := RateControlGranularity - (elapsed.Nanoseconds() / 1e6) + int64(overdelay)
-				valuesWritten = gvCount
-				lagMillis = float64(elapsed.Nanoseconds() / 1e6)
-				if remainingMs > 0 {
-					time.Sleep(time.Duration(remainingMs) * time.Millisecond)
-					gvStart = time.Now()
-					realDelay := gvStart.Sub(now).Nanoseconds() / 1e6 // 'now' was before sleep
-					lagMillis += float64(realDelay)
-				} else {
-					gvStart = now
-					atomic.AddInt32(&speedUpRequest, 1)
+				if count > points {
+					points = count
 				}
-				gvCount = 0
-			} else {
-				reportStat = false
 			}
 		}
-
-		// Report sent batch statistic
-		if reportStat {
-			stat := statPool.Get().(*Stat)
-			stat.Label = []byte(telemetryWorkerLabel)
-			stat.Value = valuesWritten
-			statChan <- stat
-		}
 	}
-
-	return nil
+	return points, nil
 }
 
-func processBackoffMessages(workerId int, src chan bool, dst chan struct{}) float64 {
-	var totalBackoffSecs float64
-	var start time.Time
-	last := false
-	for this := range src {
-		if this && !last {
-			start = time.Now()
-			last = true
-		} else if !this && last {
-			took := time.Now().Sub(start)
-			log.Printf("[worker %d] backoff took %.02fsec\n", workerId, took.Seconds())
-			totalBackoffSecs += took.Seconds()
-			last = false
-			start = time.Now()
+// countFields returns the number of fields of a line protocol line: the
+// commas of the field set, which follows the first unescaped space, plus one.
+// Commas and spaces are ignored in escapes and in string field values.
+func countFields(line []byte) int {
+	section := 0
+	fields := 1
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\':
+			i++
+		case c == '"' && section == 1:
+			quoted = !quoted
+		case quoted:
+		case c == ' ':
+			section++
+			if section > 1 {
+				return fields
+			}
+		case c == ',' && section == 1:
+			fields++
 		}
 	}
-	fmt.Printf("[worker %d] backoffs took a total of %fsec of runtime\n", workerId, totalBackoffSecs)
-	dst <- struct{}{}
-	return totalBackoffSecs
+	return fields
 }
 
 func createDb(daemonUrl, dbname string, replicationFactor int) error {
@@ -778,95 +377,3 @@ func listDatabases(daemonUrl string) ([]string, error) {
 	}
 	return ret, nil
 }
-
-var firstStat time.Time
-
-func processStats(telemetrySink chan *report.Point) {
-
-	statMapping = statsMap{
-		"*": &StatGroup{},
-	}
-
-	lastRefresh := time.Time{}
-	i := uint64(0)
-	for stat := range statChan {
-		now := time.Now()
-		if lastRefresh.Nanosecond() == 0 {
-			log.Print("First statistic report received")
-			lastRefresh = now
-			firstStat = now
-		}
-
-		movingAverageStat.Push(now, stat.Value)
-		statMapping["*"].Push(stat.Value)
-
-		statPool.Put(stat)
-
-		i++
-
-		if now.Sub(lastRefresh).Seconds() >= 1 {
-			movingAverageStat.UpdateAvg(now, workers)
-			lastRefresh = now
-			// Report telemetry, if applicable:
-			if telemetrySink != nil {
-				p := report.GetPointFromGlobalPool()
-				p.Init("benchmarks_telemetry", now.UnixNano())
-				for _, tagpair := range reportTags {
-					p.AddTag(tagpair[0], tagpair[1])
-				}
-				p.AddTag("client_type", "load")
-				p.AddFloat64Field("ingest_rate_mean", statMapping["*"].Sum/now.Sub(firstStat).Seconds()) /*statMapping["*"].Mean*/
-				p.AddFloat64Field("ingest_rate_moving_mean", movingAverageStat.Rate())
-				p.AddIntField("load_workers", workers)
-				telemetrySink <- p
-			}
-		}
-
-		// print stats to stderr (if printInterval is greater than zero):
-		if printInterval > 0 && i > 0 && i%printInterval == 0 {
-			_, err := fmt.Fprintf(os.Stderr, "%s: after %d batches:\n", time.Now().String(), i)
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			fprintStats(os.Stderr, statMapping)
-			_, err = fmt.Fprintf(os.Stderr, "\n")
-			if err != nil {
-				log.Fatal(err)
-			}
-		}
-
-	}
-
-	// the final stats output goes to stdout:
-	_, err := fmt.Printf("run complete after %d batches:\n", i)
-	if err != nil {
-		log.Fatal(err)
-	}
-	fprintStats(os.Stdout, statMapping)
-	statGroup.Done()
-}
-
-// fprintStats pretty-prints stats to the given writer.
-func fprintStats(w io.Writer, statGroups statsMap) {
-	maxKeyLength := 0
-	keys := make([]string, 0, len(statGroups))
-	for k := range statGroups {
-		if len(k) > maxKeyLength {
-			maxKeyLength = len(k)
-		}
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-	for _, k := range keys {
-		v := statGroups[k]
-		paddedKey := fmt.Sprintf("%s", k)
-		for len(paddedKey) < maxKeyLength {
-			paddedKey += " "
-		}
-		_, err := fmt.Fprintf(w, "%s : min: %8.2f/s, mean: %8.2f/s, moving mean: %8.2f/s, moving median: %8.2f/s, max: %7.2f/s, count: %8d, sum: %f \n", paddedKey, math.NaN(), v.Sum/time.Now().Sub(firstStat).Seconds(), movingAverageStat.Rate(), math.NaN(), math.NaN(), v.Count, v.Sum)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
-}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+	"github.com/valyala/fasthttp"
+)
+
+const DefaultIdleConnectionTimeout = 90 * time.Second
+
+var BackoffError = bulk_load.ErrBackoff
+
+const (
+	// codes of the IoTDB TSStatusCode enum
+	successCode       = 200
+	writeRejectedCode = 606
+)
+
+// HTTPWriterConfig is the configuration used to create an HTTPWriter.
+type HTTPWriterConfig struct {
+	// URL of the host, in form "http://example.com:18080"
+	Host string
+
+	// Credentials of the REST API.
+	User     string
+	Password string
+
+	// Debug label for more informative errors.
+	DebugInfo string
+}
+
+// HTTPWriter is a Writer that writes to the IoTDB REST API.
+type HTTPWriter struct {
+	client fasthttp.Client
+
+	c             HTTPWriterConfig
+	url           []byte
+	authorization string
+
+	// pending holds the tablets of the current batch not written yet.
+	pending []*tablet
+}
+
+// NewHTTPWriter returns a new HTTPWriter from the supplied HTTPWriterConfig.
+func NewHTTPWriter(c HTTPWriterConfig) *HTTPWriter {
+	return &HTTPWriter{
+		client: fasthttp.Client{
+			Name:                "bulk_load_iotdb",
+			MaxIdleConnDuration: DefaultIdleConnectionTimeout,
+		},
+
+		c:             c,
+		url:           []byte(c.Host + "/rest/v2/insertTablet"),
+		authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte(c.User+":"+c.Password)),
+	}
+}
+
+var (
+	post            = []byte("POST")
+	applicationJSON = []byte("application/json")
+)
+
+// statusResponse is the body of the REST API responses to writes.
+type statusResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// WriteTablet writes the given insertTablet request body to the HTTP server
+// described in the Writer's HTTPWriterConfig.
+// It returns the latency in nanoseconds and any error received while sending the data over HTTP,
+// or it returns a new error if the HTTP response isn't as expected.
+func (w *HTTPWriter) WriteTablet(body []byte) (int64, error) {
+	req := fasthttp.AcquireRequest()
+	req.Header.SetContentTypeBytes(applicationJSON)
+	req.Header.SetMethodBytes(post)
+	req.Header.SetRequestURIBytes(w.url)
+	req.Header.Add("Authorization", w.authorization)
+	req.SetBody(body)
+
+	resp := fasthttp.AcquireResponse()
+	start := time.Now()
+	err := w.client.Do(req, resp)
+	lat := time.Since(start).Nanoseconds()
+	if err == nil {
+		err = w.checkResponse(resp)
+	}
+
+	fasthttp.ReleaseResponse(resp)
+	fasthttp.ReleaseRequest(req)
+
+	return lat, err
+}
+
+func (w *HTTPWriter) checkResponse(resp *fasthttp.Response) error {
+	sc := resp.StatusCode()
+	if sc == fasthttp.StatusServiceUnavailable {
+		return BackoffError
+	}
+
+	var r statusResponse
+	if err := json.Unmarshal(resp.Body(), &r); err != nil {
+		return fmt.Errorf("[DebugInfo: %s] Invalid write response (status %d): %s", w.c.DebugInfo, sc, resp.Body())
+	}
+	if r.Code == writeRejectedCode {
+		// the memtables are full, flushing is behind
+		return BackoffError
+	}
+	if sc != fasthttp.StatusOK || r.Code != successCode {
+		return fmt.Errorf("[DebugInfo: %s] Write failed (status %d, code %d): %s", w.c.DebugInfo, sc, r.Code, r.Message)
+	}
+	return nil
+}
+
+// WriteBatch groups the records of b into tablets, and writes them. After a
+// backoff, the tablets already written are not written again.
+func (w *HTTPWriter) WriteBatch(b *bulk_load.Batch) error {
+	if len(w.pending) == 0 {
+		ts := newTablets()
+		for _, line := range bytes.Split(bytes.TrimSuffix(b.Buffer.Bytes(), []byte{'\n'}), []byte{'\n'}) {
+			var r record
+			if err := json.Unmarshal(line, &r); err != nil {
+				return err
+			}
+			if err := r.addAttributes(); err != nil {
+				return err
+			}
+			ts.add(&r)
+		}
+		w.pending = ts.list
+	}
+
+	for len(w.pending) > 0 {
+		t := w.pending[0]
+		body, err := json.Marshal(t)
+		if err != nil {
+			w.pending = nil
+			return fmt.Errorf("cannot encode tablet of %s: %s", t.Device, err.Error())
+		}
+		if _, err := w.WriteTablet(body); err != nil {
+			if err != BackoffError {
+				w.pending = nil
+			}
+			return err
+		}
+		w.pending = w.pending[1:]
+	}
+	return nil
+}
+
+func (w *HTTPWriter) Close() error {
+	return nil
+}
+
+// queryResponse is the body of the REST API responses to queries, the
+// values stored by column.
+type queryResponse struct {
+	ColumnNames []string            `json:"column_names"`
+	Values      [][]json.RawMessage `json:"values"`
+}
+
+// Query runs the query sql with the REST API.
+func (w *HTTPWriter) Query(sql string) (*queryResponse, error) {
+	body, err := json.Marshal(map[string]string{"sql": sql})
+	if err != nil {
+		return nil, err
+	}
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.Header.SetContentTypeBytes(applicationJSON)
+	req.Header.SetMethodBytes(post)
+	req.Header.SetRequestURI(w.c.Host + "/rest/v2/query")
+	req.Header.Add("Authorization", w.authorization)
+	req.SetBody(body)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	if err := w.client.Do(req, resp); err != nil {
+		return nil, err
+	}
+	if sc := resp.StatusCode(); sc != fasthttp.StatusOK {
+		return nil, fmt.Errorf("[DebugInfo: %s] Query failed (status %d): %s", w.c.DebugInfo, sc, resp.Body())
+	}
+	var r queryResponse
+	if err := json.Unmarshal(resp.Body(), &r); err != nil {
+		return nil, fmt.Errorf("[DebugInfo: %s] Invalid query response: %s", w.c.DebugInfo, resp.Body())
+	}
+	return &r, nil
+}
@@ -0,0 +1,153 @@
+// bulk_load_iotdb loads an Apache IoTDB server with data from stdin or files,
+// through the insertTablet REST API.
+//
+// The input is the iotdb format of bulk_data_gen: one JSON record per line,
+// holding the values of the aligned timeseries of a device at one timestamp.
+// The attributes of the device, its tags but the first, are written as TEXT
+// timeseries. The records of a batch are grouped by device into tablets. Databases and
+// timeseries are created by IoTDB on the first insert (enable_auto_create_schema),
+// the database of a device is root.<measurement>.
+//
+// The caller is responsible for assuring that the database is empty before
+// bulk load.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+)
+
+// Program option vars:
+var (
+	loader = &bulk_load.Loader{
+		Config: bulk_load.Config{
+			Urls:      "http://localhost:18080",
+			BatchSize: 5000,
+		},
+		DBType:     "IoTDB",
+		Verifiable: true,
+	}
+	user     string
+	password string
+)
+
+// Parse args:
+func init() {
+	loader.AddFlags(flag.CommandLine)
+	flag.StringVar(&user, "user", "root", "IoTDB user.")
+	flag.StringVar(&password, "password", "root", "IoTDB password.")
+
+	flag.Parse()
+
+	loader.Init()
+}
+
+func main() {
+	res := loader.Run(driver{})
+	loader.Finish(res, false, nil)
+}
+
+// driver batches the lines of the input, 1 item = 1 line = 1 record. The
+// writers group the records of a batch by device into tablets, and send one
+// insertTablet request per tablet.
+type driver struct{}
+
+func (driver) NewDecoder(r io.Reader) bulk_load.Decoder {
+	return &decoder{bulk_load.NewLineDecoder(r, 4*1024*1024, nil)}
+}
+
+func (driver) NewEncoder() bulk_load.Encoder {
+	return bulk_load.NewlineEncoder
+}
+
+func (driver) NewWriter(worker int, url string) bulk_load.Writer {
+	return NewHTTPWriter(HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("worker #%d, dest url: %s", worker, url),
+		Host:      url,
+		User:      user,
+		Password:  password,
+	})
+}
+
+var devicePrefix = []byte(`"device":"root.`)
+
+// Measurement returns the measurement of a record, its database being
+// root.<measurement>.
+func (driver) Measurement(item []byte) []byte {
+	i := bytes.Index(item, devicePrefix)
+	if i < 0 {
+		return nil
+	}
+	m := item[i+len(devicePrefix):]
+	if end := bytes.IndexAny(m, `."`); end >= 0 {
+		m = m[:end]
+	}
+	return m
+}
+
+// CountPoints counts the records of the devices of root.<measurement>: the
+// timestamps of a device, taken as the largest count of its timeseries.
+func (driver) CountPoints(daemonUrl, measurement string) (int64, error) {
+	w := NewHTTPWriter(HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("verify, dest url: %s", daemonUrl),
+		Host:      daemonUrl,
+		User:      user,
+		Password:  password,
+	})
+	r, err := w.Query("SELECT COUNT(*) FROM root." + measurement + ".** ALIGN BY DEVICE")
+	if err != nil {
+		return 0, err
+	}
+	var devices []int64
+	for i, column := range r.Values {
+		if i < len(r.ColumnNames) && r.ColumnNames[i] == "Device" {
+			continue
+		}
+		for row, value := range column {
+			for len(devices) <= row {
+				devices = append(devices, 0)
+			}
+			n, err := strconv.ParseInt(string(value), 10, 64)
+			if err != nil {
+				if string(value) == "null" {
+					continue
+				}
+				return 0, fmt.Errorf("bad count of %s: %s", measurement, value)
+			}
+			if n > devices[row] {
+				devices[row] = n
+			}
+		}
+	}
+	var count int64
+	for _, n := range devices {
+		count += n
+	}
+	return count, nil
+}
+
+// decoder checks the records of the input, and counts their values.
+type decoder struct {
+	*bulk_load.LineDecoder
+}
+
+func (d *decoder) Decode() ([]byte, int, error) {
+	line, _, err := d.LineDecoder.Decode()
+	if err != nil {
+		return nil, 0, err
+	}
+	var r record
+	if err := json.Unmarshal(line, &r); err != nil {
+		return nil, 0, fmt.Errorf("%s: %s", err.Error(), line)
+	}
+	if len(r.Values) != len(r.Measurements) || len(r.DataTypes) != len(r.Measurements) {
+		return nil, 0, fmt.Errorf("mismatched measurements and values: %s", line)
+	}
+	return line, len(r.Values), nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// record is a line of the iotdb format of bulk_data_gen: the values of the
+// aligned timeseries of a device at one timestamp, and the attributes of the
+// device.
+type record struct {
+	Device       string            `json:"device"`
+	Timestamp    int64             `json:"timestamp"`
+	Measurements []string          `json:"measurements"`
+	DataTypes    []string          `json:"data_types"`
+	Values       []json.RawMessage `json:"values"`
+	Attributes   map[string]string `json:"attributes"`
+}
+
+// addAttributes appends the attributes of r to its values as TEXT
+// timeseries, sorted by name so that the records of a device share their
+// measurements.
+func (r *record) addAttributes() error {
+	names := make([]string, 0, len(r.Attributes))
+	for name := range r.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := json.Marshal(r.Attributes[name])
+		if err != nil {
+			return err
+		}
+		r.Measurements = append(r.Measurements, name)
+		r.DataTypes = append(r.DataTypes, "TEXT")
+		r.Values = append(r.Values, value)
+	}
+	r.Attributes = nil
+	return nil
+}
+
+// tablet is the body of an insertTablet request: records of one device with
+// the same measurements, with the values stored by measurement.
+type tablet struct {
+	Timestamps   []int64             `json:"timestamps"`
+	Measurements []string            `json:"measurements"`
+	DataTypes    []string            `json:"data_types"`
+	Values       [][]json.RawMessage `json:"values"`
+	IsAligned    bool                `json:"is_aligned"`
+	Device       string              `json:"device"`
+}
+
+func newTablet(r *record) *tablet {
+	return &tablet{
+		Measurements: r.Measurements,
+		DataTypes:    r.DataTypes,
+		Values:       make([][]json.RawMessage, len(r.Measurements)),
+		IsAligned:    true,
+		Device:       r.Device,
+	}
+}
+
+// accepts tells whether r can be appended to t: schema changes start a new
+// tablet.
+func (t *tablet) accepts(r *record) bool {
+	return sameStrings(t.Measurements, r.Measurements) && sameStrings(t.DataTypes, r.DataTypes)
+}
+
+func (t *tablet) add(r *record) {
+	t.Timestamps = append(t.Timestamps, r.Timestamp)
+	for i, v := range r.Values {
+		t.Values[i] = append(t.Values[i], v)
+	}
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// tablets groups the records of a batch by device, keeping the devices in
+// the order they were first seen.
+type tablets struct {
+	byDevice map[string]*tablet
+	list     []*tablet
+}
+
+func newTablets() *tablets {
+	return &tablets{byDevice: map[string]*tablet{}}
+}
+
+func (ts *tablets) add(r *record) {
+	t, ok := ts.byDevice[r.Device]
+	if !ok || !t.accepts(r) {
+		t = newTablet(r)
+		ts.byDevice[r.Device] = t
+		ts.list = append(ts.list, t)
+	}
+	t.add(r)
+}
@@ -5,12 +5,13 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+	"github.com/klauspost/compress/gzip"
 	"github.com/valyala/fasthttp"
 )
 
 var (
-	BackoffError error = fmt.Errorf("backpressure is needed")
-	backoffMagicWords []byte = []byte("engine: cache maximum memory size exceeded")
+	BackoffError error = bulk_load.ErrBackoff
 )
 
 // LineProtocolWriter is the interface used to write OpenTSDB bulk data.
@@ -21,24 +22,32 @@ type LineProtocolWriter interface {
 	// Implementers must return errors returned by the underlying transport but are free to return
 	// other, context-specific errors.
 	WriteLineProtocol([]byte) (latencyNs int64, err error)
+
+	// Close releases the resources of the writer, such as its connection.
+	Close() error
 }
 
 // HTTPWriterConfig is the configuration used to create an HTTPWriter.
 type HTTPWriterConfig struct {
 	// URL of the host, in form "http://example.com:8086"
 	Host string
+
+	// Retry classifies the retryable error responses.
+	Retry *bulk_load.RetryPolicy
 }
 
 // HTTPWriter is a Writer that writes to an OpenTSDB HTTP server.
 type HTTPWriter struct {
 	client fasthttp.Client
 
-	c   HTTPWriterConfig
-	url []byte
+	c          HTTPWriterConfig
+	url        []byte
+	compressed bytes.Buffer
+	zw         *gzip.Writer
 }
 
 // NewHTTPWriter returns a new HTTPWriter from the supplied HTTPWriterConfig.
-func NewHTTPWriter(c HTTPWriterConfig) LineProtocolWriter {
+func NewHTTPWriter(c HTTPWriterConfig) *HTTPWriter {
 	return &HTTPWriter{
 		client: fasthttp.Client{
 			Name: "bulk_load_opentsdb",
@@ -71,10 +80,11 @@ func (w *HTTPWriter) WriteLineProtocol(body []byte) (int64, error) {
 	lat := time.Since(start).Nanoseconds()
 	if err == nil {
 		sc := resp.StatusCode()
-		//if sc == 500 && backpressurePred(resp.Body()) {
-		//	err = BackoffError
-		if (sc != fasthttp.StatusNoContent && sc != fasthttp.StatusOK) {
-			err = fmt.Errorf("Invalid write response (status %d): %s", sc, resp.Body())
+		if sc != fasthttp.StatusNoContent && sc != fasthttp.StatusOK {
+			err = w.c.Retry.Classify(sc, resp.Body())
+			if err == nil {
+				err = fmt.Errorf("Invalid write response (status %d): %s", sc, resp.Body())
+			}
 		}
 	}
 
@@ -84,6 +94,20 @@ func (w *HTTPWriter) WriteLineProtocol(body []byte) (int64, error) {
 	return lat, err
 }
 
-func backpressurePred(body []byte) bool {
-	return bytes.Contains(body, backoffMagicWords)
+// WriteBatch gzips the JSON array of b and writes it.
+func (w *HTTPWriter) WriteBatch(b *bulk_load.Batch) error {
+	if w.zw == nil {
+		w.zw = gzip.NewWriter(&w.compressed)
+	}
+	w.compressed.Reset()
+	w.zw.Reset(&w.compressed)
+	w.zw.Write(b.Buffer.Bytes())
+	w.zw.Close()
+	_, err := w.WriteLineProtocol(w.compressed.Bytes())
+	return err
+}
+
+// Close is a no-op, the client closes idle connections itself.
+func (w *HTTPWriter) Close() error {
+	return nil
 }
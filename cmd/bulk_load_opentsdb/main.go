@@ -1,105 +1,87 @@
-// bulk_load_opentsdb loads an OpenTSDB daemon with data from stdin.
+// bulk_load_opentsdb loads an OpenTSDB daemon with data from stdin or files.
 //
 // The caller is responsible for assuring that the database is empty before
 // bulk load.
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"os"
-	"strings"
-	"sync"
 	"time"
 
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
 	"github.com/caict-benchmark/BDC-TS/util/report"
-	"github.com/klauspost/compress/gzip"
-	"github.com/pkg/profile"
+	"github.com/valyala/fasthttp"
 )
 
 // Program option vars:
 var (
-	csvDaemonUrls  string
-	daemonUrls     []string
-	workers        int
-	batchSize      int
-	backoff        time.Duration
-	doLoad         bool
-	memprofile     bool
-	reportDatabase string
-	reportHost     string
-	reportUser     string
-	reportPassword string
-	reportTagsCSV  string
+	loader = &bulk_load.Loader{
+		Config: bulk_load.Config{
+			Urls:      "http://localhost:8086",
+			BatchSize: 5000,
+		},
+		DBType:     "OpenTSDB",
+		Verifiable: true,
+	}
+	protocol      string
+	timeout       time.Duration
+	maxReconnects int
+	queryAPI      string
+	queryUrl      string
 )
 
 // Global vars
 var (
-	bufPool        sync.Pool
-	batchChan      chan *bytes.Buffer
-	inputDone      chan struct{}
-	workersGroup   sync.WaitGroup
-	backingOffChan chan bool
-	backingOffDone chan struct{}
-	reportTags     [][2]string
-	reportHostname string
+	telnetWriters []*bulk_load.SocketWriter
+	telnetErrs    = &telnetErrors{}
+	// verifyRange holds the timestamps of the data points read, for --verify.
+	verifyRange bulk_load.TimeRange
 )
 
 // Parse args:
 func init() {
-	flag.StringVar(&csvDaemonUrls, "urls", "http://localhost:8086", "OpenTSDB URLs, comma-separated. Will be used in a round-robin fashion.")
-	flag.IntVar(&batchSize, "batch-size", 5000, "Batch size (input lines).")
-	flag.IntVar(&workers, "workers", 1, "Number of parallel requests to make.")
-	//flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when server indicates backpressure is needed.")
-	flag.BoolVar(&doLoad, "do-load", true, "Whether to write data. Set this flag to false to check input read speed.")
-	flag.BoolVar(&memprofile, "memprofile", false, "Whether to write a memprofile (file automatically determined).")
-	flag.StringVar(&reportDatabase, "report-database", "database_benchmarks", "Database name where to store result metrics")
-	flag.StringVar(&reportHost, "report-host", "", "Host to send result metrics")
-	flag.StringVar(&reportUser, "report-user", "", "User for host to send result metrics")
-	flag.StringVar(&reportPassword, "report-password", "", "User password for Host to send result metrics")
-	flag.StringVar(&reportTagsCSV, "report-tags", "", "Comma separated k:v tags to send  alongside result metrics")
+	loader.AddFlags(flag.CommandLine)
+	flag.StringVar(&protocol, "protocol", "http", "Write protocol. Must be one of: http (the /api/put endpoint, input in the opentsdb format), telnet (put commands over persistent TCP connections, input in the opentsdb-telnet format, urls are then in form telnet://host:port).")
+	flag.DurationVar(&timeout, "telnet-timeout", 30*time.Second, "Timeout of connecting and of writing a batch, for -protocol=telnet.")
+	flag.IntVar(&maxReconnects, "telnet-max-reconnects", 10, "Number of consecutive failed writes, each followed by a reconnect, before a worker gives up, for -protocol=telnet.")
+	flag.StringVar(&queryAPI, "query-api", "opentsdb", "Query API --verify counts the stored data points with. Must be one of: opentsdb (/api/query), kairosdb (/api/v1/datapoints/query).")
+	flag.StringVar(&queryUrl, "query-url", "", "URL of the query API for --verify, in form http://host:port. Defaults to the first of urls, with http:// for -protocol=telnet.")
 	flag.Parse()
 
-	daemonUrls = strings.Split(csvDaemonUrls, ",")
-	if len(daemonUrls) == 0 {
-		log.Fatal("missing 'urls' flag")
-	}
-	fmt.Printf("daemon URLs: %v\n", daemonUrls)
-
-	if reportHost != "" {
-		fmt.Printf("results report destination: %v\n", reportHost)
-		fmt.Printf("results report database: %v\n", reportDatabase)
+	loader.Init()
 
-		var err error
-		reportHostname, err = os.Hostname()
-		if err != nil {
-			log.Fatalf("os.Hostname() error: %s", err.Error())
+	switch protocol {
+	case "http":
+	case "telnet":
+		for i, daemonUrl := range loader.DaemonUrls {
+			address, err := bulk_load.SocketAddress(daemonUrl)
+			if err != nil {
+				log.Fatal(err)
+			}
+			loader.DaemonUrls[i] = address
 		}
-		fmt.Printf("hostname for results report: %v\n", reportHostname)
+	default:
+		log.Fatalf("invalid protocol: %s", protocol)
+	}
 
-		if reportTagsCSV != "" {
-			pairs := strings.Split(reportTagsCSV, ",")
-			for _, pair := range pairs {
-				fields := strings.SplitN(pair, ":", 2)
-				tagpair := [2]string{fields[0], fields[1]}
-				reportTags = append(reportTags, tagpair)
-			}
+	if queryAPI != "opentsdb" && queryAPI != "kairosdb" {
+		log.Fatalf("invalid query API: %s", queryAPI)
+	}
+	if queryUrl == "" {
+		queryUrl = loader.DaemonUrls[0]
+		if protocol == "telnet" {
+			queryUrl = "http://" + queryUrl
 		}
-		fmt.Printf("results report tags: %v\n", reportTags)
 	}
 }
 
 func main() {
-	if memprofile {
-		p := profile.Start(profile.MemProfile)
-		defer p.Stop()
-	}
-	if doLoad {
+	if loader.DoLoad {
 		// check that there are no pre-existing databases:
-		existingDatabases, err := listDatabases(daemonUrls[0])
+		existingDatabases, err := listDatabases(loader.DaemonUrls[0])
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -109,179 +91,87 @@ func main() {
 		}
 	}
 
-	bufPool = sync.Pool{
-		New: func() interface{} {
-			return bytes.NewBuffer(make([]byte, 0, 4*1024*1024))
-		},
-	}
+	res := loader.Run(driver{})
 
-	batchChan = make(chan *bytes.Buffer, workers)
-	inputDone = make(chan struct{})
-
-	backingOffChan = make(chan bool, 100)
-	backingOffDone = make(chan struct{})
-
-	for i := 0; i < workers; i++ {
-		daemonUrl := daemonUrls[i%len(daemonUrls)]
-		workersGroup.Add(1)
-		cfg := HTTPWriterConfig{
-			Host: daemonUrl,
-		}
-		go processBatches(NewHTTPWriter(cfg))
+	var telnetReconnects int64
+	for _, w := range telnetWriters {
+		telnetReconnects += w.Reconnects()
+	}
+	telnetErrors := telnetErrs.Errors()
+	if protocol == "telnet" {
+		fmt.Printf("telnet: %d error responses, %d reconnects\n", telnetErrors, telnetReconnects)
 	}
 
-	go processBackoffMessages()
-
-	start := time.Now()
-	itemsRead := scan(batchSize)
-
-	<-inputDone
-	close(batchChan)
-
-	workersGroup.Wait()
-
-	close(backingOffChan)
-	<-backingOffDone
-
-	end := time.Now()
-	took := end.Sub(start)
-	rate := float64(itemsRead) / float64(took.Seconds())
-
-	fmt.Printf("loaded %d items in %fsec with %d workers (mean values rate %f/sec)\n", itemsRead, took.Seconds(), workers, rate)
-
-	if reportHost != "" {
-		reportParams := &report.LoadReportParams{
-			ReportParams: report.ReportParams{
-				DBType:             "OpenTSDB",
-				ReportDatabaseName: reportDatabase,
-				ReportHost:         reportHost,
-				ReportUser:         reportUser,
-				ReportPassword:     reportPassword,
-				ReportTags:         reportTags,
-				Hostname:           reportHostname,
-				DestinationUrl:     daemonUrls[0],
-				Workers:            workers,
-				ItemLimit:          -1,
-			},
-			IsGzip:    true,
-			BatchSize: batchSize,
-		}
-		err := report.ReportLoadResult(reportParams, itemsRead, rate, -1, took)
-
-		if err != nil {
-			log.Fatal(err)
-		}
+	extraVals := make([]report.ExtraVal, 0, 2)
+	if telnetErrors > 0 {
+		extraVals = append(extraVals, report.ExtraVal{Name: "telnet_errors", Value: telnetErrors})
 	}
+	if telnetReconnects > 0 {
+		extraVals = append(extraVals, report.ExtraVal{Name: "telnet_reconnects", Value: telnetReconnects})
+	}
+	loader.Finish(res, protocol == "http", [][2]string{{"protocol", protocol}}, extraVals...)
 }
 
-// scan reads one line at a time from stdin.
-// When the requested number of lines per batch is met, send a batch over batchChan for the workers to write.
-func scan(linesPerBatch int) int64 {
-	buf := bufPool.Get().(*bytes.Buffer)
-	zw := gzip.NewWriter(buf)
-
-	var n int
-	var itemsRead int64
-
-	openbracket := []byte("[")
-	closebracket := []byte("]")
-	commaspace := []byte(", ")
-	newline := []byte("\n")
+// driver joins the lines of the input, 1 item = 1 data point, into a JSON
+// array for the http protocol, or sends them as they are, one put command per
+// line, for the telnet protocol.
+type driver struct{}
 
-	zw.Write(openbracket)
-	zw.Write(newline)
-
-	scanner := bufio.NewScanner(bufio.NewReaderSize(os.Stdin, 4*1024*1024))
-	for scanner.Scan() {
-		itemsRead++
-		if n > 0 {
-			zw.Write(commaspace)
-			zw.Write(newline)
-		}
-
-		zw.Write(scanner.Bytes())
-
-		n++
-		if n >= linesPerBatch {
-			zw.Write(newline)
-			zw.Write(closebracket)
-			zw.Close()
-
-			batchChan <- buf
+func (driver) NewDecoder(r io.Reader) bulk_load.Decoder {
+	return bulk_load.NewLineDecoder(r, 0, nil)
+}
 
-			buf = bufPool.Get().(*bytes.Buffer)
-			zw = gzip.NewWriter(buf)
-			zw.Write(openbracket)
-			zw.Write(newline)
-			n = 0
-		}
+func (driver) NewEncoder() bulk_load.Encoder {
+	if protocol == "telnet" {
+		return bulk_load.NewlineEncoder
 	}
-
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("Error reading input: %s", err.Error())
+	return &bulk_load.LineEncoder{
+		Prefix:    []byte("[\n"),
+		Separator: []byte(", \n"),
+		Suffix:    []byte("\n]"),
 	}
+}
 
-	// Finished reading input, make sure last batch goes out.
-	if n > 0 {
-		zw.Write(newline)
-		zw.Write(closebracket)
-		zw.Close()
-		batchChan <- buf
+func (driver) NewWriter(worker int, url string) bulk_load.Writer {
+	if protocol == "telnet" {
+		w := bulk_load.NewSocketWriter(bulk_load.SocketWriterConfig{
+			DebugInfo:     fmt.Sprintf("worker #%d, dest address: %s", worker, url),
+			Address:       url,
+			Timeout:       timeout,
+			MaxReconnects: maxReconnects,
+			Read:          telnetErrs.read,
+		})
+		telnetWriters = append(telnetWriters, w)
+		return w
 	}
-
-	// Closing inputDone signals to the application that we've read everything and can now shut down.
-	close(inputDone)
-
-	return itemsRead
+	return NewHTTPWriter(HTTPWriterConfig{
+		Host:  url,
+		Retry: &loader.Retry,
+	})
 }
 
-// processBatches reads byte buffers from batchChan and writes them to the target server, while tracking stats on the write.
-func processBatches(w LineProtocolWriter) {
-	for batch := range batchChan {
-		// Write the batch: try until backoff is not needed.
-		if doLoad {
-			var err error
-			for {
-				_, err = w.WriteLineProtocol(batch.Bytes())
-				if err == BackoffError {
-					backingOffChan <- true
-					time.Sleep(backoff)
-				} else {
-					backingOffChan <- false
-					break
-				}
-			}
-			if err != nil {
-				log.Fatalf("Error writing: %s\n", err.Error())
-			}
-		}
-		//fmt.Println(string(batch.Bytes()))
-
-		// Return the batch buffer to the pool.
-		batch.Reset()
-		bufPool.Put(batch)
+// Measurement returns the metric of a data point.
+func (driver) Measurement(item []byte) []byte {
+	metric, timestamp, err := parsePoint(item)
+	if err != nil {
+		return nil
 	}
-	workersGroup.Done()
+	verifyRange.Add(timestamp)
+	return metric
 }
 
-func processBackoffMessages() {
-	var totalBackoffSecs float64
-	var start time.Time
-	last := false
-	for this := range backingOffChan {
-		if this && !last {
-			start = time.Now()
-			last = true
-		} else if !this && last {
-			took := time.Now().Sub(start)
-			fmt.Printf("backoff took %.02fsec\n", took.Seconds())
-			totalBackoffSecs += took.Seconds()
-			last = false
-			start = time.Now()
-		}
+// CountPoints counts the data points of metric over the time range of the
+// data points read, with the -query-api at -query-url.
+func (driver) CountPoints(_, metric string) (int64, error) {
+	start, end, ok := verifyRange.Bounds()
+	if !ok {
+		return 0, nil
+	}
+	client := &fasthttp.Client{Name: "bulk_load_opentsdb"}
+	if queryAPI == "kairosdb" {
+		return countKairosDB(client, queryUrl, metric, start, end)
 	}
-	fmt.Printf("backoffs took a total of %fsec of runtime\n", totalBackoffSecs)
-	backingOffDone <- struct{}{}
+	return countOpenTSDB(client, queryUrl, metric, start, end)
 }
 
 // TODO(rw): listDatabases lists the existing data in OpenTSDB.
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	metricKey    = []byte(`"metric":"`)
+	timestampKey = []byte(`"timestamp":`)
+)
+
+// parsePoint returns the metric and the timestamp in milliseconds of a line
+// of the input, a JSON data point for the http protocol, or a put command
+// for the telnet protocol.
+func parsePoint(line []byte) ([]byte, int64, error) {
+	var metric, timestamp []byte
+	if protocol == "telnet" {
+		fields := bytes.Fields(line)
+		if len(fields) < 4 || string(fields[0]) != "put" {
+			return nil, 0, fmt.Errorf("malformed put command: %s", line)
+		}
+		metric, timestamp = fields[1], fields[2]
+	} else {
+		i := bytes.Index(line, metricKey)
+		j := bytes.Index(line, timestampKey)
+		if i < 0 || j < 0 {
+			return nil, 0, fmt.Errorf("malformed data point: %s", line)
+		}
+		metric = line[i+len(metricKey):]
+		end := bytes.IndexByte(metric, '"')
+		if end < 0 {
+			return nil, 0, fmt.Errorf("malformed data point: %s", line)
+		}
+		metric = metric[:end]
+		timestamp = line[j+len(timestampKey):]
+		if end := bytes.IndexAny(timestamp, ",}"); end >= 0 {
+			timestamp = timestamp[:end]
+		}
+	}
+	ts, err := strconv.ParseInt(string(bytes.TrimSpace(timestamp)), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("malformed timestamp: %s", line)
+	}
+	// OpenTSDB takes timestamps in seconds too:
+	if ts < 1e12 {
+		ts *= 1000
+	}
+	return metric, ts, nil
+}
+
+// countOpenTSDB counts the data points of metric from start to end, in
+// milliseconds, with the /api/query endpoint of OpenTSDB, summing the
+// all-time count of each series.
+func countOpenTSDB(client *fasthttp.Client, host, metric string, start, end int64) (int64, error) {
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(start, 10))
+	params.Set("end", strconv.FormatInt(end, 10))
+	params.Set("m", "none:0all-count:"+metric)
+	sc, body, err := client.Get(nil, host+"/api/query?"+params.Encode())
+	if err != nil {
+		return 0, err
+	}
+	var results []struct {
+		Dps map[string]float64 `json:"dps"`
+	}
+	if err := json.Unmarshal(body, &results); err != nil || sc != fasthttp.StatusOK {
+		return 0, fmt.Errorf("Invalid query response (status %d): %s", sc, body)
+	}
+	var count float64
+	for _, r := range results {
+		for _, v := range r.Dps {
+			count += v
+		}
+	}
+	return int64(count), nil
+}
+
+// countKairosDB counts the data points of metric from start to end, in
+// milliseconds, with the /api/v1/datapoints/query endpoint of KairosDB.
+func countKairosDB(client *fasthttp.Client, host, metric string, start, end int64) (int64, error) {
+	type sampling struct {
+		Value int    `json:"value"`
+		Unit  string `json:"unit"`
+	}
+	type aggregator struct {
+		Name     string   `json:"name"`
+		Sampling sampling `json:"sampling"`
+	}
+	type metricQuery struct {
+		Name        string       `json:"name"`
+		Aggregators []aggregator `json:"aggregators"`
+	}
+	query, err := json.Marshal(struct {
+		StartAbsolute int64         `json:"start_absolute"`
+		EndAbsolute   int64         `json:"end_absolute"`
+		Metrics       []metricQuery `json:"metrics"`
+	}{start, end, []metricQuery{{metric, []aggregator{{"count", sampling{100, "years"}}}}}})
+	if err != nil {
+		return 0, err
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.Header.SetContentTypeBytes(applicationJsonHeader)
+	req.Header.SetMethodBytes(post)
+	req.Header.SetRequestURI(host + "/api/v1/datapoints/query")
+	req.SetBody(query)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	if err := client.Do(req, resp); err != nil {
+		return 0, err
+	}
+
+	var r struct {
+		Queries []struct {
+			Results []struct {
+				Values [][2]float64 `json:"values"`
+			} `json:"results"`
+		} `json:"queries"`
+	}
+	if err := json.Unmarshal(resp.Body(), &r); err != nil || resp.StatusCode() != fasthttp.StatusOK {
+		return 0, fmt.Errorf("Invalid query response (status %d): %s", resp.StatusCode(), resp.Body())
+	}
+	var count float64
+	for _, q := range r.Queries {
+		for _, res := range q.Results {
+			for _, v := range res.Values {
+				count += v[1]
+			}
+		}
+	}
+	return int64(count), nil
+}
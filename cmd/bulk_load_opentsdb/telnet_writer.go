@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"sync/atomic"
+)
+
+// telnetErrors reads the answers of the OpenTSDB telnet interface, which
+// answers put commands only when they fail: the lines it sends back are
+// counted as errors. The connections of all the workers share it.
+type telnetErrors struct {
+	count int64
+}
+
+// read counts the lines OpenTSDB sends back on conn, and logs the first of
+// them, until conn is closed. It fulfills bulk_load.SocketWriterConfig.Read.
+func (e *telnetErrors) read(conn net.Conn) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			if atomic.AddInt64(&e.count, 1) <= 10 {
+				log.Printf("error from %s: %s", conn.RemoteAddr(), scanner.Text())
+			}
+		}
+	}()
+	return done
+}
+
+// Errors returns the number of error lines received so far.
+func (e *telnetErrors) Errors() int64 {
+	return atomic.LoadInt64(&e.count)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+	"github.com/golang/snappy"
+	"github.com/valyala/fasthttp"
+)
+
+const DefaultIdleConnectionTimeout = 90 * time.Second
+
+var BackoffError = bulk_load.ErrBackoff
+
+// HTTPWriterConfig is the configuration used to create an HTTPWriter.
+type HTTPWriterConfig struct {
+	// URL of the host, in form "http://example.com:8428"
+	Host string
+
+	// Path of the remote write endpoint, e.g. "/api/v1/write"
+	Path string
+
+	// Debug label for more informative errors.
+	DebugInfo string
+}
+
+// HTTPWriter is a Writer that writes to a Prometheus remote write receiver.
+type HTTPWriter struct {
+	client fasthttp.Client
+
+	c          HTTPWriterConfig
+	url        []byte
+	compressed []byte
+}
+
+// NewHTTPWriter returns a new HTTPWriter from the supplied HTTPWriterConfig.
+func NewHTTPWriter(c HTTPWriterConfig) *HTTPWriter {
+	return &HTTPWriter{
+		client: fasthttp.Client{
+			Name:                "bulk_load_prometheus",
+			MaxIdleConnDuration: DefaultIdleConnectionTimeout,
+		},
+
+		c:   c,
+		url: []byte(c.Host + c.Path),
+	}
+}
+
+var (
+	post                  = []byte("POST")
+	applicationProtobuf   = []byte("application/x-protobuf")
+	remoteWriteVersion    = "0.1.0"
+	remoteWriteVersionKey = "X-Prometheus-Remote-Write-Version"
+)
+
+// WriteRemote writes the given snappy compressed WriteRequest to the HTTP
+// server described in the Writer's HTTPWriterConfig.
+// It returns the latency in nanoseconds and any error received while sending the data over HTTP,
+// or it returns a new error if the HTTP response isn't as expected.
+func (w *HTTPWriter) WriteRemote(body []byte) (int64, error) {
+	req := fasthttp.AcquireRequest()
+	req.Header.SetContentTypeBytes(applicationProtobuf)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set(remoteWriteVersionKey, remoteWriteVersion)
+	req.Header.SetMethodBytes(post)
+	req.Header.SetRequestURIBytes(w.url)
+	req.SetBody(body)
+
+	resp := fasthttp.AcquireResponse()
+	start := time.Now()
+	err := w.client.Do(req, resp)
+	lat := time.Since(start).Nanoseconds()
+	if err == nil {
+		sc := resp.StatusCode()
+		// receivers ask to retry later with 429, or 503 while overloaded
+		if sc == fasthttp.StatusTooManyRequests || sc == fasthttp.StatusServiceUnavailable {
+			err = BackoffError
+		} else if sc/100 != 2 {
+			err = fmt.Errorf("[DebugInfo: %s] Invalid write response (status %d): %s", w.c.DebugInfo, sc, resp.Body())
+		}
+	}
+
+	fasthttp.ReleaseResponse(resp)
+	fasthttp.ReleaseRequest(req)
+
+	return lat, err
+}
+
+// WriteBatch snappy compresses the WriteRequest of b and writes it.
+func (w *HTTPWriter) WriteBatch(b *bulk_load.Batch) error {
+	w.compressed = snappy.Encode(w.compressed[:cap(w.compressed)], b.Buffer.Bytes())
+	_, err := w.WriteRemote(w.compressed)
+	return err
+}
+
+func (w *HTTPWriter) Close() error {
+	return nil
+}
+
+// queryResponse is the body of the responses of the query API to instant
+// queries.
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Value [2]interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// QueryCount runs the instant query at the time in seconds, and returns the
+// value of its single result, 0 if there is none.
+func (w *HTTPWriter) QueryCount(query string, time int64) (int64, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", strconv.FormatInt(time, 10))
+	sc, body, err := w.client.Get(nil, w.c.Host+w.c.Path+"?"+params.Encode())
+	if err != nil {
+		return 0, err
+	}
+	var r queryResponse
+	if err := json.Unmarshal(body, &r); err != nil || sc != fasthttp.StatusOK || r.Status != "success" {
+		return 0, fmt.Errorf("[DebugInfo: %s] Invalid query response (status %d): %s", w.c.DebugInfo, sc, body)
+	}
+	if len(r.Data.Result) == 0 {
+		return 0, nil
+	}
+	value, _ := r.Data.Result[0].Value[1].(string)
+	count, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("[DebugInfo: %s] bad count: %s", w.c.DebugInfo, body)
+	}
+	return int64(count), nil
+}
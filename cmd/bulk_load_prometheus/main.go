@@ -0,0 +1,237 @@
+// bulk_load_prometheus loads a Prometheus remote write receiver (such as
+// VictoriaMetrics, Thanos receive, Cortex or Mimir) with data from stdin or
+// files.
+//
+// The input is the prometheus-remote-write format of bulk_data_gen: snappy
+// compressed WriteRequest protobufs, each prefixed by its length.
+//
+// The caller is responsible for assuring that the database is empty before
+// bulk load.
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+	"github.com/golang/snappy"
+)
+
+// Program option vars:
+var (
+	loader = &bulk_load.Loader{
+		Config: bulk_load.Config{
+			Urls:      "http://localhost:8428",
+			BatchSize: 5000,
+		},
+		DBType:     "Prometheus",
+		Verifiable: true,
+	}
+	writePath string
+	queryPath string
+)
+
+// Global vars
+var (
+	// verifyRange holds the timestamps of the samples read, for --verify.
+	verifyRange bulk_load.TimeRange
+)
+
+// Register args, parsed in main so that the tests can run:
+func init() {
+	loader.AddFlags(flag.CommandLine)
+	flag.StringVar(&writePath, "path", "/api/v1/write", "Path of the remote write endpoint (VictoriaMetrics: /api/v1/write, Thanos receive: /api/v1/receive, Cortex and Mimir: /api/v1/push).")
+	flag.StringVar(&queryPath, "query-path", "/api/v1/query", "Path of the instant query endpoint --verify counts the stored samples with (VictoriaMetrics and Prometheus: /api/v1/query, Mimir: /prometheus/api/v1/query).")
+}
+
+func main() {
+	flag.Parse()
+
+	loader.Init()
+
+	res := loader.Run(driver{})
+	loader.Finish(res, false, nil)
+}
+
+// driver batches the WriteRequests of the input by concatenating them
+// uncompressed, which forms a single WriteRequest of all their series.
+type driver struct{}
+
+func (driver) NewDecoder(r io.Reader) bulk_load.Decoder {
+	return &decoder{frames: bulk_load.NewFrameDecoder(r, nil)}
+}
+
+func (driver) NewEncoder() bulk_load.Encoder {
+	return &bulk_load.LineEncoder{}
+}
+
+func (driver) NewWriter(worker int, url string) bulk_load.Writer {
+	return NewHTTPWriter(HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("worker #%d, dest url: %s", worker, url),
+		Host:      url,
+		Path:      writePath,
+	})
+}
+
+// Measurement returns the name of the first series of a WriteRequest, that
+// of the first field of the point, <measurement>_<field>. The points of a
+// measurement are counted as the samples of these series.
+func (driver) Measurement(item []byte) []byte {
+	name, timestamp, err := firstSeries(item)
+	if err != nil {
+		return nil
+	}
+	verifyRange.Add(timestamp)
+	return name
+}
+
+// CountPoints counts the samples of the series named name, over the time
+// range of the samples read.
+func (driver) CountPoints(daemonUrl, name string) (int64, error) {
+	min, max, ok := verifyRange.Bounds()
+	if !ok {
+		return 0, nil
+	}
+	w := NewHTTPWriter(HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("verify, dest url: %s", daemonUrl),
+		Host:      daemonUrl,
+		Path:      queryPath,
+	})
+	// the range of the query ends at its time, inclusive, and starts before
+	// the oldest sample:
+	end := max/1000 + 1
+	query := fmt.Sprintf("sum(count_over_time(%s[%ds]))", name, end-min/1000+1)
+	return w.QueryCount(query, end)
+}
+
+// decoder returns the uncompressed WriteRequests of the input, 1 item = 1
+// length-prefixed WriteRequest.
+type decoder struct {
+	frames  *bulk_load.FrameDecoder
+	decoded []byte
+}
+
+func (d *decoder) Decode() ([]byte, int, error) {
+	item, _, err := d.frames.Decode()
+	if err != nil {
+		return nil, 0, err
+	}
+	d.decoded, err = snappy.Decode(d.decoded[:cap(d.decoded)], item)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot decode item: %s", err.Error())
+	}
+	series, err := countSeries(d.decoded)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot decode item: %s", err.Error())
+	}
+	return d.decoded, series, nil
+}
+
+// firstSeries returns the value of the __name__ label and the timestamp of
+// the first sample of the first time series of an encoded WriteRequest.
+func firstSeries(msg []byte) ([]byte, int64, error) {
+	const timeseriesField, labelsField, samplesField = 1, 1, 2
+	const nameField, valueField, timestampField = 1, 2, 2
+
+	field, series, _, err := nextField(msg)
+	if err != nil || field != timeseriesField {
+		return nil, 0, fmt.Errorf("malformed WriteRequest")
+	}
+	var name []byte
+	var timestamp int64
+	for len(series) > 0 {
+		var value []byte
+		if field, value, series, err = nextField(series); err != nil {
+			return nil, 0, err
+		}
+		switch field {
+		case labelsField:
+			var labelName, labelValue []byte
+			for len(value) > 0 {
+				var f int
+				var v []byte
+				if f, v, value, err = nextField(value); err != nil {
+					return nil, 0, err
+				}
+				if f == nameField {
+					labelName = v
+				} else if f == valueField {
+					labelValue = v
+				}
+			}
+			if string(labelName) == "__name__" {
+				name = labelValue
+			}
+		case samplesField:
+			for len(value) > 0 {
+				key, n := binary.Uvarint(value)
+				if n <= 0 {
+					return nil, 0, fmt.Errorf("malformed WriteRequest")
+				}
+				value = value[n:]
+				switch key & 7 {
+				case wireFixed64:
+					if len(value) < 8 {
+						return nil, 0, fmt.Errorf("malformed WriteRequest")
+					}
+					value = value[8:]
+				case wireVarint:
+					v, n := binary.Uvarint(value)
+					if n <= 0 {
+						return nil, 0, fmt.Errorf("malformed WriteRequest")
+					}
+					value = value[n:]
+					if key>>3 == timestampField {
+						timestamp = int64(v)
+					}
+				default:
+					return nil, 0, fmt.Errorf("malformed WriteRequest")
+				}
+			}
+			return name, timestamp, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("malformed WriteRequest: no sample")
+}
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// nextField splits the length-delimited field at the start of msg off it.
+func nextField(msg []byte) (field int, value, rest []byte, err error) {
+	key, n := binary.Uvarint(msg)
+	if n <= 0 || key&7 != wireBytes {
+		return 0, nil, nil, fmt.Errorf("malformed WriteRequest")
+	}
+	msg = msg[n:]
+	length, n := binary.Uvarint(msg)
+	if n <= 0 || uint64(len(msg)-n) < length {
+		return 0, nil, nil, fmt.Errorf("malformed WriteRequest")
+	}
+	return int(key >> 3), msg[n : n+int(length)], msg[n+int(length):], nil
+}
+
+// countSeries returns the number of time series of an encoded WriteRequest.
+// The serializer writes one sample per series.
+func countSeries(msg []byte) (int, error) {
+	const timeseriesField = 1
+
+	count := 0
+	for len(msg) > 0 {
+		field, _, rest, err := nextField(msg)
+		if err != nil {
+			return 0, err
+		}
+		msg = rest
+		if field == timeseriesField {
+			count++
+		}
+	}
+	return count, nil
+}
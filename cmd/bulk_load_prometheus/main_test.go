@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+	"github.com/golang/snappy"
+)
+
+// appendBytesField appends a length-delimited protobuf field.
+func appendBytesField(buf []byte, field int, value []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field<<3|2))
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+// writeRequest encodes a WriteRequest of series series of the metric name,
+// each with one sample, like the prometheus-remote-write serializer.
+func writeRequest(name string, series int) []byte {
+	var msg []byte
+	for i := 0; i < series; i++ {
+		var ts, label, sample []byte
+		label = appendBytesField(label, 1, []byte("__name__"))
+		label = appendBytesField(label, 2, []byte(name))
+		ts = appendBytesField(ts, 1, label)
+		label = appendBytesField(label[:0], 1, []byte("id"))
+		label = appendBytesField(label, 2, []byte(strconv.Itoa(i)))
+		ts = appendBytesField(ts, 1, label)
+		sample = append(sample, 1<<3|1, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f) // value 1.0
+		sample = append(sample, 2<<3|0, 1)                            // timestamp 1
+		ts = appendBytesField(ts, 2, sample)
+		msg = appendBytesField(msg, 1, ts)
+	}
+	return msg
+}
+
+// input frames the snappy compressed requests like bulk_data_gen.
+func input(requests ...[]byte) []byte {
+	var in []byte
+	for _, r := range requests {
+		item := snappy.Encode(nil, r)
+		in = binary.LittleEndian.AppendUint64(in, uint64(len(item)))
+		in = append(in, item...)
+	}
+	return in
+}
+
+func TestCountSeries(t *testing.T) {
+	for _, n := range []int{0, 1, 7} {
+		got, err := countSeries(writeRequest("cpu_usage_user", n))
+		if err != nil || got != n {
+			t.Errorf("countSeries of %d series: %d, %v", n, got, err)
+		}
+	}
+	msg := writeRequest("cpu_usage_user", 2)
+	if _, err := countSeries(msg[:len(msg)-1]); err == nil {
+		t.Errorf("countSeries of a truncated request: no error")
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	var query, time string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, time = r.FormValue("query"), r.FormValue("time")
+		if r.URL.Path != "/api/v1/query" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1,"42"]}]}}`))
+	}))
+	defer srv.Close()
+	queryPath = "/api/v1/query"
+
+	d := driver{}
+	if got := string(d.Measurement(writeRequest("cpu_usage_user", 3))); got != "cpu_usage_user" {
+		t.Errorf("Measurement: %q", got)
+	}
+	if got := d.Measurement(writeRequest("cpu_usage_user", 0)); got != nil {
+		t.Errorf("Measurement of an empty request: %q", got)
+	}
+	count, err := d.CountPoints(srv.URL, "cpu_usage_user")
+	if err != nil || count != 42 {
+		t.Fatalf("CountPoints: %d, %v", count, err)
+	}
+	if query != "sum(count_over_time(cpu_usage_user[2s]))" || time != "1" {
+		t.Errorf("query %q at %s", query, time)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	requests := [][]byte{writeRequest("cpu_usage_user", 3), writeRequest("mem_used", 5)}
+	dec := driver{}.NewDecoder(bytes.NewReader(input(requests...)))
+	for i, want := range requests {
+		item, series, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("item %d: %v", i, err)
+		}
+		if string(item) != string(want) {
+			t.Errorf("item %d is not the uncompressed request", i)
+		}
+		if wantSeries, _ := countSeries(want); series != wantSeries {
+			t.Errorf("item %d: %d series, want %d", i, series, wantSeries)
+		}
+	}
+	if _, _, err := dec.Decode(); err == nil {
+		t.Errorf("no error after the last item")
+	}
+}
+
+// receiver is a remote write receiver answering the requests with statuses,
+// then with 204.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests int
+	series   int
+	err      string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests++
+	if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get(remoteWriteVersionKey) != remoteWriteVersion {
+		rc.err = "missing remote write headers"
+	}
+	if len(rc.statuses) > 0 {
+		status := rc.statuses[0]
+		rc.statuses = rc.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	msg, err := snappy.Decode(nil, body)
+	if err != nil {
+		rc.err = err.Error()
+	}
+	series, err := countSeries(msg)
+	if err != nil {
+		rc.err = err.Error()
+	}
+	rc.series += series
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// received returns the requests and series received, and the first problem
+// of a request.
+func (rc *receiver) received() (int, int, string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.requests, rc.series, rc.err
+}
+
+func TestHTTPWriter(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusBadRequest}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	w := driver{}.NewWriter(0, srv.URL)
+	batch := func() *bulk_load.Batch {
+		return &bulk_load.Batch{Buffer: bytes.NewBuffer(writeRequest("cpu_usage_user", 4)), Items: 1, Values: 4}
+	}
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		if err := w.WriteBatch(batch()); err != bulk_load.ErrBackoff {
+			t.Errorf("status %d: %v, want a backoff", status, err)
+		}
+	}
+	if err := w.WriteBatch(batch()); err == nil || bulk_load.IsRetryable(err) {
+		t.Errorf("status 400: %v, want an error that is not retryable", err)
+	}
+	if err := w.WriteBatch(batch()); err != nil {
+		t.Errorf("status 204: %v", err)
+	}
+	_, received, problem := rc.received()
+	if problem != "" {
+		t.Error(problem)
+	}
+	if received != 4 {
+		t.Errorf("received %d series, want 4", received)
+	}
+}
+
+// TestLoad loads requests to a receiver which first asks to back off, and
+// checks that the re-batched requests carry all the series.
+func TestLoad(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusTooManyRequests}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	var requests [][]byte
+	series := 0
+	for i := 1; i <= 10; i++ {
+		requests = append(requests, writeRequest("cpu_usage_user", i))
+		series += i
+	}
+	file := filepath.Join(t.TempDir(), "input")
+	if err := ioutil.WriteFile(file, input(requests...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := &bulk_load.Loader{Config: bulk_load.Config{BatchSize: 4}, DBType: "Prometheus"}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	l.AddFlags(fs)
+	if err := fs.Parse([]string{"-urls", srv.URL, "-input", file, "-backoff", "1ms", "-print-interval", "0"}); err != nil {
+		t.Fatal(err)
+	}
+	l.Init()
+	res := l.Run(driver{})
+
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if res.ItemsRead != int64(len(requests)) || res.ValuesRead != int64(series) {
+		t.Errorf("read %d items, %d values, want %d, %d", res.ItemsRead, res.ValuesRead, len(requests), series)
+	}
+	if res.Backoffs != 1 {
+		t.Errorf("%d backoffs, want 1", res.Backoffs)
+	}
+	requestsReceived, received, problem := rc.received()
+	if problem != "" {
+		t.Error(problem)
+	}
+	// 3 batches of up to 4 requests, one of them written twice:
+	if requestsReceived != 4 || received != series {
+		t.Errorf("received %d requests of %d series, want 4 of %d", requestsReceived, received, series)
+	}
+}
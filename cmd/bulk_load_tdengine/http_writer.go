@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+	"github.com/valyala/fasthttp"
+)
+
+const DefaultIdleConnectionTimeout = 90 * time.Second
+
+var (
+	BackoffError       error  = bulk_load.ErrBackoff
+	backoffMagicWords0 []byte = []byte("Out of memory")
+	backoffMagicWords1 []byte = []byte("memory is full")
+)
+
+// HTTPWriterConfig is the configuration used to create an HTTPWriter.
+type HTTPWriterConfig struct {
+	// URL of the host, in form "http://example.com:6041"
+	Host string
+
+	// Name of the default database of the statements, may be empty.
+	Database string
+
+	// Credentials of the REST API.
+	User     string
+	Password string
+
+	// Debug label for more informative errors.
+	DebugInfo string
+}
+
+// HTTPWriter is a Writer that runs SQL statements through the TDengine REST
+// API.
+type HTTPWriter struct {
+	client fasthttp.Client
+
+	c             HTTPWriterConfig
+	url           []byte
+	authorization string
+}
+
+// NewHTTPWriter returns a new HTTPWriter from the supplied HTTPWriterConfig.
+func NewHTTPWriter(c HTTPWriterConfig) *HTTPWriter {
+	u := c.Host + "/rest/sql"
+	if c.Database != "" {
+		u += "/" + c.Database
+	}
+	return &HTTPWriter{
+		client: fasthttp.Client{
+			Name:                "bulk_load_tdengine",
+			MaxIdleConnDuration: DefaultIdleConnectionTimeout,
+		},
+
+		c:             c,
+		url:           []byte(u),
+		authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte(c.User+":"+c.Password)),
+	}
+}
+
+var (
+	post      = []byte("POST")
+	textPlain = []byte("text/plain")
+)
+
+// sqlResponse holds the status fields of a REST API response: TDengine 2.x
+// sets status to "succ" or "error", TDengine 3.x sets a non zero code on
+// error. Both describe the error in desc.
+type sqlResponse struct {
+	Status string `json:"status"`
+	Code   int    `json:"code"`
+	Desc   string `json:"desc"`
+	// Data holds the rows of the result of a query.
+	Data [][]interface{} `json:"data"`
+}
+
+// ExecSQL runs the given SQL statement on the HTTP server described in the
+// Writer's HTTPWriterConfig.
+// It returns the latency in nanoseconds and any error received while sending the data over HTTP,
+// or it returns a new error if the HTTP response isn't as expected.
+func (w *HTTPWriter) ExecSQL(body []byte) (int64, error) {
+	return w.exec(body, &sqlResponse{})
+}
+
+// QuerySQL runs the given SQL query, and returns the rows of its result.
+func (w *HTTPWriter) QuerySQL(body []byte) ([][]interface{}, error) {
+	var r sqlResponse
+	_, err := w.exec(body, &r)
+	return r.Data, err
+}
+
+// exec runs the given SQL statement, and decodes the response in r.
+func (w *HTTPWriter) exec(body []byte, r *sqlResponse) (int64, error) {
+	req := fasthttp.AcquireRequest()
+	req.Header.SetContentTypeBytes(textPlain)
+	req.Header.SetMethodBytes(post)
+	req.Header.SetRequestURIBytes(w.url)
+	req.Header.Add("Authorization", w.authorization)
+	req.SetBody(body)
+
+	resp := fasthttp.AcquireResponse()
+	start := time.Now()
+	err := w.client.Do(req, resp)
+	lat := time.Since(start).Nanoseconds()
+	if err == nil {
+		err = w.checkResponse(resp, r)
+	}
+
+	fasthttp.ReleaseResponse(resp)
+	fasthttp.ReleaseRequest(req)
+
+	return lat, err
+}
+
+func (w *HTTPWriter) checkResponse(resp *fasthttp.Response, r *sqlResponse) error {
+	sc := resp.StatusCode()
+	body := resp.Body()
+	if sc == fasthttp.StatusServiceUnavailable ||
+		bytes.Contains(body, backoffMagicWords0) ||
+		bytes.Contains(body, backoffMagicWords1) {
+		return BackoffError
+	}
+
+	if err := json.Unmarshal(body, r); err != nil {
+		return fmt.Errorf("[DebugInfo: %s] Invalid response (status %d): %s", w.c.DebugInfo, sc, body)
+	}
+	if sc != fasthttp.StatusOK || r.Status == "error" || r.Code != 0 {
+		return fmt.Errorf("[DebugInfo: %s] SQL failed (status %d, code %d): %s", w.c.DebugInfo, sc, r.Code, r.Desc)
+	}
+	return nil
+}
+
+// WriteBatch runs the INSERT INTO statement of b.
+func (w *HTTPWriter) WriteBatch(b *bulk_load.Batch) error {
+	_, err := w.ExecSQL(b.Buffer.Bytes())
+	return err
+}
+
+func (w *HTTPWriter) Close() error {
+	return nil
+}
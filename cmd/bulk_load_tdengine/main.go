@@ -0,0 +1,226 @@
+// bulk_load_tdengine loads a TDengine server with data from stdin or files,
+// through the REST API of taosAdapter (or of taosd for TDengine 2.x).
+//
+// The input is the tdengine format of bulk_data_gen: one multi-table insert
+// clause per line. Lines are joined into INSERT INTO statements, and the
+// super table of each measurement is created from its first line. Schema
+// changes are not migrated.
+//
+// The caller is responsible for assuring that the database is empty before
+// bulk load.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+)
+
+// Program option vars:
+var (
+	loader = &bulk_load.Loader{
+		Config: bulk_load.Config{
+			Urls:      "http://localhost:6041",
+			BatchSize: 5000,
+		},
+		DBType:     "TDengine",
+		Verifiable: true,
+	}
+	dbName       string
+	user         string
+	password     string
+	doDbCreate   bool
+	binaryLength int
+	maxSqlLength int
+)
+
+// Global vars
+var (
+	// superTables are the super tables created, the decoders of the input
+	// files share them. Lines of existing super tables only take the read
+	// lock.
+	superTables   = map[string]bool{}
+	superTablesMu sync.RWMutex
+	schemaWriter  *HTTPWriter
+)
+
+// Register args, parsed in main so that the tests can run:
+func init() {
+	loader.AddFlags(flag.CommandLine)
+	flag.StringVar(&dbName, "db", "benchmark_db", "Database name.")
+	flag.StringVar(&user, "user", "root", "TDengine user.")
+	flag.StringVar(&password, "password", "taosdata", "TDengine password.")
+	flag.BoolVar(&doDbCreate, "do-db-create", true, "Whether to create the database. Set this flag to false to write data to an existing database.")
+	flag.IntVar(&binaryLength, "binary-length", 256, "Length of the BINARY columns and tags created for strings.")
+	flag.IntVar(&maxSqlLength, "max-sql-length", 1024*1024, "Maximum length in bytes of an INSERT statement, a batch is sent early rather than exceed it (TDengine maxSQLLength).")
+}
+
+func main() {
+	flag.Parse()
+	loader.Init()
+
+	daemonUrl := loader.DaemonUrls[0]
+	if loader.DoLoad {
+		schemaWriter = NewHTTPWriter(HTTPWriterConfig{
+			DebugInfo: fmt.Sprintf("schema, dest url: %s", daemonUrl),
+			Host:      daemonUrl,
+			Database:  dbName,
+			User:      user,
+			Password:  password,
+		})
+		if doDbCreate {
+			createDatabase(daemonUrl)
+		}
+	}
+
+	res := loader.Run(driver{})
+	loader.Finish(res, false, nil)
+}
+
+// driver joins the lines of the input, 1 item = 1 line, into INSERT INTO
+// statements.
+type driver struct{}
+
+func (driver) NewDecoder(r io.Reader) bulk_load.Decoder {
+	return &decoder{bulk_load.NewLineDecoder(r, maxSqlLength, countValues)}
+}
+
+func (driver) NewEncoder() bulk_load.Encoder {
+	return &encoder{bulk_load.LineEncoder{Prefix: insertInto, Separator: []byte(" ")}}
+}
+
+func (driver) NewWriter(worker int, url string) bulk_load.Writer {
+	return NewHTTPWriter(HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("worker #%d, dest url: %s", worker, url),
+		Host:      url,
+		Database:  dbName,
+		User:      user,
+		Password:  password,
+	})
+}
+
+// Measurement returns the super table of a line.
+func (driver) Measurement(line []byte) []byte {
+	name, err := superTableName(line)
+	if err != nil {
+		log.Fatalf("Error reading input: %s", err.Error())
+	}
+	return name
+}
+
+// CountPoints counts the rows of the sub tables of superTable.
+func (driver) CountPoints(daemonUrl, superTable string) (int64, error) {
+	w := NewHTTPWriter(HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("verify, dest url: %s", daemonUrl),
+		Host:      daemonUrl,
+		Database:  dbName,
+		User:      user,
+		Password:  password,
+	})
+	rows, err := w.QuerySQL([]byte("SELECT COUNT(*) FROM " + superTable))
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		// a super table without sub tables has no row
+		return 0, nil
+	}
+	count, ok := rows[0][0].(float64)
+	if !ok {
+		return 0, fmt.Errorf("bad count of %s: %v", superTable, rows[0][0])
+	}
+	return int64(count), nil
+}
+
+// decoder creates the super table of each line before it is written.
+type decoder struct {
+	*bulk_load.LineDecoder
+}
+
+func (d *decoder) Decode() ([]byte, int, error) {
+	line, values, err := d.LineDecoder.Decode()
+	if err == nil && loader.DoLoad {
+		ensureSuperTable(line)
+	}
+	return line, values, err
+}
+
+// encoder sends a statement early rather than exceed max-sql-length.
+type encoder struct {
+	bulk_load.LineEncoder
+}
+
+func (e *encoder) Append(buf *bytes.Buffer, n int, line []byte) bool {
+	if n > 0 && buf.Len()+len(line)+1 > maxSqlLength {
+		return false
+	}
+	return e.LineEncoder.Append(buf, n, line)
+}
+
+var insertInto = []byte("INSERT INTO ")
+
+var (
+	valuesStart = []byte("(`ts`")
+	valuesEnd   = []byte(") VALUES (")
+)
+
+// countValues returns the number of field values of a line, the number of
+// columns but the timestamp.
+func countValues(line []byte) int {
+	i := bytes.LastIndex(line, valuesStart)
+	j := bytes.LastIndex(line, valuesEnd)
+	if i < 0 || j < i {
+		log.Fatalf("Error reading input, malformed line: %s", line)
+	}
+	return bytes.Count(line[i:j], []byte{','})
+}
+
+// ensureSuperTable creates the super table of line, once.
+func ensureSuperTable(line []byte) {
+	name, err := superTableName(line)
+	if err != nil {
+		log.Fatalf("Error reading input: %s", err.Error())
+	}
+	superTablesMu.RLock()
+	created := superTables[string(name)]
+	superTablesMu.RUnlock()
+	if created {
+		return
+	}
+
+	superTablesMu.Lock()
+	defer superTablesMu.Unlock()
+	if superTables[string(name)] {
+		return
+	}
+
+	l, err := parseInsertLine(string(line))
+	if err != nil {
+		log.Fatalf("Error reading input: %s", err.Error())
+	}
+	sql := createSuperTableSql(l)
+	if _, err := schemaWriter.ExecSQL([]byte(sql)); err != nil {
+		log.Fatalf("Error creating super table %s: %s", l.superTable, err.Error())
+	}
+	log.Printf("created super table: %s\n", sql)
+	superTables[l.superTable] = true
+}
+
+// createDatabase creates the database, with a millisecond precision.
+func createDatabase(daemonUrl string) {
+	w := NewHTTPWriter(HTTPWriterConfig{
+		DebugInfo: fmt.Sprintf("create database, dest url: %s", daemonUrl),
+		Host:      daemonUrl,
+		User:      user,
+		Password:  password,
+	})
+	sql := fmt.Sprintf("CREATE DATABASE %s PRECISION 'ms'", dbName)
+	if _, err := w.ExecSQL([]byte(sql)); err != nil {
+		log.Fatalf("Error creating database %s: %s\nIf you know what you are doing, drop it with:\ncurl -u %s:<password> -d 'DROP DATABASE %s' %s/rest/sql\nor run with -do-db-create=false\n", dbName, err.Error(), user, dbName, daemonUrl)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+)
+
+// serialize returns the points of hosts hosts of the measurements, at n
+// timestamps, in the tdengine format of bulk_data_gen, with the dataset size
+// marker.
+func serialize(t *testing.T, measurements []string, hosts, n int) []byte {
+	var buf bytes.Buffer
+	s := common.NewSerializerTDengine()
+	p := common.MakeUsablePoint()
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := int64(0)
+	for i := 0; i < n; i++ {
+		ts := start.Add(time.Duration(i) * time.Second)
+		for _, m := range measurements {
+			for h := 0; h < hosts; h++ {
+				p.Reset()
+				p.SetMeasurementName([]byte(m))
+				p.SetTimestamp(&ts)
+				p.AppendTag([]byte("hostname"), []byte(fmt.Sprintf("host_%d", h)))
+				p.AppendTag([]byte("region"), []byte("eu-west-1"))
+				p.AppendFieldInt([]byte("usage"), int64(i))
+				p.AppendFieldFloat([]byte("ratio"), 0.5)
+				p.AppendFieldBytes([]byte("state"), []byte("it's ok"))
+				if err := s.SerializePoint(&buf, p); err != nil {
+					t.Fatal(err)
+				}
+				points++
+			}
+		}
+	}
+	if err := s.SerializeSize(&buf, points, points*3); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCreateSuperTableSql(t *testing.T) {
+	in := serialize(t, []string{"cpu"}, 1, 1)
+	line := string(in[:bytes.IndexByte(in, '\n')])
+
+	l, err := parseInsertLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := superTableName([]byte(line))
+	if err != nil || string(name) != l.superTable {
+		t.Errorf("super table %s, %v, want %s", name, err, l.superTable)
+	}
+	if got := countValues([]byte(line)); got != 3 {
+		t.Errorf("%d values, want 3", got)
+	}
+	want := "CREATE STABLE IF NOT EXISTS cpu (`ts` TIMESTAMP,`usage` BIGINT,`ratio` DOUBLE,`state` BINARY(256)) TAGS (`hostname` BINARY(256),`region` BINARY(256))"
+	if got := createSuperTableSql(l); got != want {
+		t.Errorf("createSuperTableSql:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDecoderSkipsMarker(t *testing.T) {
+	in := serialize(t, []string{"cpu", "mem"}, 2, 1)
+	dec := driver{}.NewDecoder(bytes.NewReader(in))
+
+	saved := loader.DoLoad
+	loader.DoLoad = false
+	defer func() { loader.DoLoad = saved }()
+
+	lines := 0
+	for {
+		line, values, err := dec.Decode()
+		if err != nil {
+			break
+		}
+		if bytes.HasPrefix(line, []byte(common.DatasetSizeMarker)) || values != 3 {
+			t.Errorf("decoded %q, %d values", line, values)
+		}
+		lines++
+	}
+	if lines != 4 {
+		t.Errorf("decoded %d lines, want 4", lines)
+	}
+	points, values := dec.(bulk_load.DatasetSizer).DatasetSize()
+	if points != 4 || values != 12 {
+		t.Errorf("dataset size %d, %d, want 4, 12", points, values)
+	}
+}
+
+func TestEncoderMaxSqlLength(t *testing.T) {
+	saved := maxSqlLength
+	maxSqlLength = 40
+	defer func() { maxSqlLength = saved }()
+
+	e := driver{}.NewEncoder()
+	var buf bytes.Buffer
+	e.Reset(&buf)
+	line := []byte("t1 USING st (`a`) TAGS ('x') (`ts`) VALUES (1)")
+	if !e.Append(&buf, 0, line) {
+		t.Fatal("the first line of a statement is not appended")
+	}
+	if e.Append(&buf, 1, line) {
+		t.Error("a line exceeding max-sql-length is appended")
+	}
+}
+
+var (
+	insertedTable = regexp.MustCompile(" USING (\\S+) ")
+	countTable    = regexp.MustCompile("^SELECT COUNT\\(\\*\\) FROM (\\S+)$")
+)
+
+// standIn is a TDengine REST API stand-in: it creates super tables, counts
+// the rows inserted into them, and answers the INSERT statements with
+// statuses, then with success.
+type standIn struct {
+	mu       sync.Mutex
+	statuses []int
+	creates  map[string]int
+	rows     map[string]int64
+	err      string
+}
+
+func newStandIn(statuses ...int) *standIn {
+	return &standIn{statuses: statuses, creates: map[string]int{}, rows: map[string]int64{}}
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	sql := string(body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user, password, ok := r.BasicAuth(); !ok || user != "root" || password != "taosdata" {
+		s.err = "missing credentials"
+	}
+	switch {
+	case strings.HasPrefix(sql, "CREATE STABLE IF NOT EXISTS "):
+		name := strings.Fields(sql)[5]
+		s.creates[name]++
+		if s.rows[name] > 0 {
+			s.err = "rows inserted before the creation of " + name
+		}
+	case strings.HasPrefix(sql, string(insertInto)):
+		if r.URL.Path != "/rest/sql/benchmark_db" {
+			s.err = "insert into the wrong database: " + r.URL.Path
+		}
+		if len(s.statuses) > 0 {
+			w.WriteHeader(s.statuses[0])
+			s.statuses = s.statuses[1:]
+			return
+		}
+		for _, m := range insertedTable.FindAllStringSubmatch(sql, -1) {
+			if s.creates[m[1]] == 0 {
+				s.err = "insert into the missing super table " + m[1]
+			}
+			s.rows[m[1]]++
+		}
+	case countTable.MatchString(sql):
+		name := countTable.FindStringSubmatch(sql)[1]
+		fmt.Fprintf(w, `{"code":0,"column_meta":[["count(*)","BIGINT",8]],"data":[[%d]],"rows":1}`, s.rows[name])
+		return
+	default:
+		s.err = "unexpected statement: " + sql
+	}
+	fmt.Fprint(w, `{"code":0,"column_meta":[["affected_rows","INT",4]],"data":[[0]],"rows":1}`)
+}
+
+// TestLoad loads two input files of the same super tables through the
+// stand-in, which first asks to back off, and verifies the rows stored.
+func TestLoad(t *testing.T) {
+	s := newStandIn(http.StatusServiceUnavailable)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"a", "b"} {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, serialize(t, []string{"cpu", "mem", "disk"}, 3, 5), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	saved := loader
+	defer func() { loader = saved }()
+	loader = &bulk_load.Loader{Config: bulk_load.Config{BatchSize: 7}, DBType: "TDengine", Verifiable: true}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader.AddFlags(fs)
+	args := []string{"-urls", srv.URL, "-input", strings.Join(files, ","), "-workers", "2", "-backoff", "1ms", "-verify", "-verify-delay", "0", "-print-interval", "0"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	loader.Init()
+	schemaWriter = NewHTTPWriter(HTTPWriterConfig{Host: srv.URL, Database: dbName, User: user, Password: password})
+	res := loader.Run(driver{})
+
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if res.ItemsRead != 90 || res.ValuesRead != 270 || res.DatasetPoints != 90 {
+		t.Errorf("read %d items, %d values, %d in the markers, want 90, 270, 90", res.ItemsRead, res.ValuesRead, res.DatasetPoints)
+	}
+	if res.Backoffs != 1 {
+		t.Errorf("%d backoffs, want 1", res.Backoffs)
+	}
+	if v := res.Verify; v == nil || v.Mismatches != 0 || v.StoredPoints != 90 || v.ExpectedPoints != 90 {
+		t.Errorf("verify: %+v, want 90 points stored and expected", v)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != "" {
+		t.Error(s.err)
+	}
+	for _, name := range []string{"cpu", "mem", "disk"} {
+		if s.creates[name] != 1 || s.rows[name] != 30 {
+			t.Errorf("super table %s: created %d times, %d rows, want 1, 30", name, s.creates[name], s.rows[name])
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+var (
+	usingKeyword  = []byte(" USING ")
+	tagsKeyword   = " TAGS "
+	valuesKeyword = " VALUES "
+)
+
+// insertLine is a line of the tdengine format of bulk_data_gen, split into
+// its parts:
+// <sub table> USING <super table> (<tags>) TAGS (<tag values>) (<columns>) VALUES (<values>)
+type insertLine struct {
+	subTable   string
+	superTable string
+	tags       []string
+	tagValues  []string
+	columns    []string
+	values     []string
+}
+
+// superTableName returns the super table of an insert line without parsing
+// the rest of it.
+func superTableName(line []byte) ([]byte, error) {
+	i := bytes.Index(line, usingKeyword)
+	if i < 0 {
+		return nil, fmt.Errorf("missing USING clause: %s", line)
+	}
+	name := line[i+len(usingKeyword):]
+	j := bytes.IndexByte(name, ' ')
+	if j < 0 {
+		return nil, fmt.Errorf("truncated line: %s", line)
+	}
+	return name[:j], nil
+}
+
+// parseInsertLine splits an insert line into its parts.
+func parseInsertLine(line string) (*insertLine, error) {
+	l := &insertLine{}
+	rest := line
+
+	i := strings.Index(rest, string(usingKeyword))
+	if i < 0 {
+		return nil, fmt.Errorf("missing USING clause: %s", line)
+	}
+	l.subTable = rest[:i]
+	rest = rest[i+len(usingKeyword):]
+
+	i = strings.IndexByte(rest, ' ')
+	if i < 0 {
+		return nil, fmt.Errorf("truncated line: %s", line)
+	}
+	l.superTable = rest[:i]
+	rest = rest[i+1:]
+
+	var err error
+	if l.tags, rest, err = splitList(rest); err != nil {
+		return nil, fmt.Errorf("bad tags (%s): %s", err, line)
+	}
+	if !strings.HasPrefix(rest, tagsKeyword) {
+		return nil, fmt.Errorf("missing TAGS clause: %s", line)
+	}
+	if l.tagValues, rest, err = splitList(rest[len(tagsKeyword):]); err != nil {
+		return nil, fmt.Errorf("bad tag values (%s): %s", err, line)
+	}
+	if !strings.HasPrefix(rest, " ") {
+		return nil, fmt.Errorf("missing columns: %s", line)
+	}
+	if l.columns, rest, err = splitList(rest[1:]); err != nil {
+		return nil, fmt.Errorf("bad columns (%s): %s", err, line)
+	}
+	if !strings.HasPrefix(rest, valuesKeyword) {
+		return nil, fmt.Errorf("missing VALUES clause: %s", line)
+	}
+	if l.values, _, err = splitList(rest[len(valuesKeyword):]); err != nil {
+		return nil, fmt.Errorf("bad values (%s): %s", err, line)
+	}
+
+	if len(l.tags) != len(l.tagValues) || len(l.columns) != len(l.values) || len(l.columns) == 0 {
+		return nil, fmt.Errorf("mismatched names and values: %s", line)
+	}
+	return l, nil
+}
+
+// splitList splits the parenthesized, comma separated list at the start of s,
+// leaving the commas of quoted strings alone. It returns the items and what
+// follows the list.
+func splitList(s string) ([]string, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, s, fmt.Errorf("expected (")
+	}
+	var items []string
+	start := 1
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quoted && c == '\\':
+			i++
+		case c == '\'':
+			quoted = !quoted
+		case !quoted && c == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		case !quoted && c == ')':
+			items = append(items, s[start:i])
+			return items, s[i+1:], nil
+		}
+	}
+	return nil, s, fmt.Errorf("unterminated list")
+}
+
+// columnType infers the TDengine type of a column from one of its values.
+func columnType(value string) string {
+	switch {
+	case strings.HasPrefix(value, "'"):
+		return fmt.Sprintf("BINARY(%d)", binaryLength)
+	case value == "true" || value == "false":
+		return "BOOL"
+	case strings.ContainsAny(value, ".eEIN"):
+		return "DOUBLE"
+	default:
+		return "BIGINT"
+	}
+}
+
+// createSuperTableSql returns the statement creating the super table of l,
+// the types of the columns are those of the values of l. Tags are strings.
+func createSuperTableSql(l *insertLine) string {
+	var b strings.Builder
+	b.WriteString("CREATE STABLE IF NOT EXISTS ")
+	b.WriteString(l.superTable)
+	b.WriteString(" (")
+	for i, column := range l.columns {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(column)
+		b.WriteString(" ")
+		if i == 0 {
+			// the first column is the timestamp
+			b.WriteString("TIMESTAMP")
+		} else {
+			b.WriteString(columnType(l.values[i]))
+		}
+	}
+	b.WriteString(") TAGS (")
+	for i, tag := range l.tags {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(tag)
+		fmt.Fprintf(&b, " BINARY(%d)", binaryLength)
+	}
+	b.WriteString(")")
+	return b.String()
+}
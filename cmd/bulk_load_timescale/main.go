@@ -1,4 +1,4 @@
-// bulk_load_timescale loads a PostgreSQL with TimeScaleDB  with data from stdin.
+// bulk_load_timescale loads a PostgreSQL with TimeScaleDB  with data from stdin or files.
 //
 // The caller is responsible for assuring that the database is empty before
 // bulk load.
@@ -6,24 +6,21 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"os"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/caict-benchmark/BDC-TS/bulk_load"
+	"github.com/caict-benchmark/BDC-TS/timescale_serializaition"
 	"github.com/caict-benchmark/BDC-TS/util/report"
 	"github.com/jackc/pgx"
-
-	"bytes"
-	"context"
-	"encoding/binary"
-	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
-	"github.com/caict-benchmark/BDC-TS/timescale_serializaition"
-	"io"
 )
 
 // TODO VH: This should be calculated from available simulation data
@@ -32,49 +29,32 @@ const DatabaseName = "benchmark_db"
 
 // Program option vars:
 var (
-	daemonUrl           string
-	workers             int
-	batchSize           int
-	doLoad              bool
+	loader = &bulk_load.Loader{
+		Config: bulk_load.Config{
+			Urls:      "localhost:5432",
+			BatchSize: 100,
+		},
+		DBType:        "TimeScaleDB",
+		ValuesPerItem: ValuesPerMeasurement,
+		Verifiable:    true,
+	}
 	doDbCreate          bool
-	reportDatabase      string
-	reportHost          string
-	reportUser          string
-	reportPassword      string
-	reportTagsCSV       string
 	psUser              string
 	psPassword          string
-	file                string
 	chunkDuration       time.Duration
 	usePostgresBatching bool
+	schemaMigrate       bool
 )
 
 // Global vars
 var (
-	bufPool        sync.Pool
-	batchChan      chan *bytes.Buffer
-	batchChanBin   chan []FlatPoint
-	batchChanBatch chan []string
-	inputDone      chan struct{}
-	workersGroup   sync.WaitGroup
-	reportTags     [][2]string
-	reportHostname string
-	format         string
-	sourceReader   *os.File
+	format   string
+	migrator *schemaMigrator
 )
 
 // Output data format choices:
 var formatChoices = []string{"timescaledb-sql", "timescaledb-copyFrom"}
 
-var processes = map[string]struct {
-	scan    func(int, io.Reader) (int64, int64, int64)
-	process func(*pgx.Conn) int64
-}{
-	formatChoices[0]:           {scan, processBatches},
-	formatChoices[1]:           {scanBin, processBatchesBin},
-	"timescaledb-sql-batching": {scanBatch, processBatchesBatch},
-}
-
 type FlatPoint struct {
 	MeasurementName string
 	Columns         []string
@@ -83,448 +63,388 @@ type FlatPoint struct {
 
 // Parse args:
 func init() {
-	flag.StringVar(&daemonUrl, "url", "localhost:5432", "Timescale DB URL.")
+	loader.AddFlags(flag.CommandLine)
+	flag.StringVar(&loader.Urls, "url", loader.Urls, "Deprecated, use -urls.")
+	flag.StringVar(&loader.Input.Files, "file", "", "Deprecated, use -input.")
 	flag.StringVar(&psUser, "user", "postgres", "Postgresql user")
 	flag.StringVar(&psPassword, "password", "", "Postgresql password")
-	flag.StringVar(&file, "file", "", "Input file")
 
 	flag.StringVar(&format, "format", formatChoices[1], "Input data format. One of: "+strings.Join(formatChoices, ","))
-	flag.IntVar(&batchSize, "batch-size", 100, "Batch size (input items).")
-	flag.IntVar(&workers, "workers", 1, "Number of parallel requests to make.")
 	flag.BoolVar(&usePostgresBatching, "postgresql-batching", false, "Whether to use Postgresql batching feature. Works only for '"+formatChoices[0]+"' format")
 
-	flag.BoolVar(&doLoad, "do-load", true, "Whether to write data. Set this flag to false to check input read speed.")
 	flag.BoolVar(&doDbCreate, "do-db-create", true, "Whether to create database. Set this flag to false to write data to existing database")
 	flag.DurationVar(&chunkDuration, "chunk-interval", time.Hour*24, "Timescale chunk interval")
-
-	flag.StringVar(&reportDatabase, "report-database", "database_benchmarks", "Database name where to store result metrics")
-	flag.StringVar(&reportHost, "report-host", "", "Host to send result metrics")
-	flag.StringVar(&reportUser, "report-user", "", "User for host to send result metrics")
-	flag.StringVar(&reportPassword, "report-password", "", "User password for Host to send result metrics")
-	flag.StringVar(&reportTagsCSV, "report-tags", "", "Comma separated k:v tags to send  alongside result metrics")
+	flag.BoolVar(&schemaMigrate, "schema-migrate", false, "Whether to add or widen table columns when the input carries new fields or changed field types (see bulk_data_gen --schema-changes).")
 
 	flag.Parse()
 
-	if _, ok := processes[format]; !ok {
+	switch format {
+	case formatChoices[0], formatChoices[1]:
+	default:
 		log.Fatal("Invalid format choice '", format, "'. Available are: ", strings.Join(formatChoices, ","))
 	}
-	if usePostgresBatching {
-		if format == formatChoices[1] {
-			log.Fatal("Cannot use Postgresql batching when using format '", formatChoices[1], "'")
-		} else {
-			format = "timescaledb-sql-batching"
-		}
+	if usePostgresBatching && format == formatChoices[1] {
+		log.Fatal("Cannot use Postgresql batching when using format '", formatChoices[1], "'")
 	}
-	if reportHost != "" {
-		fmt.Printf("results report destination: %v\n", reportHost)
-		fmt.Printf("results report database: %v\n", reportDatabase)
 
-		var err error
-		reportHostname, err = os.Hostname()
+	loader.Init()
+}
+
+func main() {
+	if loader.DoLoad && doDbCreate {
+		createDatabase(loader.DaemonUrls[0])
+	}
+	if loader.DoLoad && schemaMigrate {
+		conn, err := connect(loader.DaemonUrls[0])
 		if err != nil {
-			log.Fatalf("os.Hostname() error: %s", err.Error())
+			log.Fatal(err)
 		}
-		fmt.Printf("hostname for results report: %v\n", reportHostname)
-
-		if reportTagsCSV != "" {
-			pairs := strings.Split(reportTagsCSV, ",")
-			for _, pair := range pairs {
-				fields := strings.SplitN(pair, ":", 2)
-				tagpair := [2]string{fields[0], fields[1]}
-				reportTags = append(reportTags, tagpair)
-			}
+		migrator, err = newSchemaMigrator(conn)
+		if err != nil {
+			log.Fatal(err)
 		}
-		fmt.Printf("results report tags: %v\n", reportTags)
+		conn.Close()
 	}
 
-	if file != "" {
-		if f, err := os.Open(file); err == nil {
-			sourceReader = f
-		} else {
-			log.Fatalf("Error opening %s: %v\n", file, err)
-		}
+	res := loader.Run(driver{})
+
+	var extraVals []report.ExtraVal
+	if migrator != nil {
+		fmt.Printf("performed %d schema migrations\n", migrator.Migrations())
+		extraVals = append(extraVals, report.ExtraVal{Name: "schema_migrations", Value: migrator.Migrations()})
 	}
-	if sourceReader == nil {
-		sourceReader = os.Stdin
+	tags := [][2]string{
+		{"format", format},
+		{"postgresql_batching", strconv.FormatBool(usePostgresBatching)},
+		{"chunk_interval", chunkDuration.String()},
 	}
+	loader.Finish(res, false, tags, extraVals...)
+}
 
+// connect opens a connection to the benchmark database at daemonUrl.
+func connect(daemonUrl string) (*pgx.Conn, error) {
+	hostPort := strings.Split(daemonUrl, ":")
+	port, _ := strconv.Atoi(hostPort[1])
+	return pgx.Connect(pgx.ConnConfig{
+		Host:     hostPort[0],
+		Port:     uint16(port),
+		User:     psUser,
+		Password: psPassword,
+		Database: DatabaseName,
+	})
 }
 
-func main() {
-	if doLoad && doDbCreate {
-		createDatabase(daemonUrl)
-	}
+// driver reads INSERT statements, one per line, which are executed at once
+// or, with -postgresql-batching, queued in a batch, or it reads GOP encoded
+// points which are written with COPY. Each worker has its own connection.
+type driver struct{}
 
-	bufPool = sync.Pool{
-		New: func() interface{} {
-			return bytes.NewBuffer(make([]byte, 0, 4*1024*1024))
-		},
+func (driver) NewDecoder(r io.Reader) bulk_load.Decoder {
+	if format == formatChoices[1] {
+		return &copyDecoder{r: bufio.NewReaderSize(r, 4*1024*1024)}
 	}
+	return bulk_load.NewLineDecoder(r, 0, countValues)
+}
 
-	batchChan = make(chan *bytes.Buffer, workers)
-	batchChanBin = make(chan []FlatPoint, workers)
-	batchChanBatch = make(chan []string, workers)
-	inputDone = make(chan struct{})
-
-	procs := processes[format]
+func (driver) NewEncoder() bulk_load.Encoder {
+	if format == formatChoices[1] {
+		return frameEncoder{}
+	}
+	return bulk_load.NewlineEncoder
+}
 
-	procReads := make([]int64, workers)
-	for i := 0; i < workers; i++ {
-		workersGroup.Add(1)
-		var conn *pgx.Conn
+func (driver) NewWriter(worker int, url string) bulk_load.Writer {
+	w := &writer{}
+	if loader.DoLoad {
 		var err error
-		if doLoad {
-			hostPort := strings.Split(daemonUrl, ":")
-			port, _ := strconv.Atoi(hostPort[1])
-			conn, err = pgx.Connect(pgx.ConnConfig{
-				Host:     hostPort[0],
-				Port:     uint16(port),
-				User:     psUser,
-				Password: psPassword,
-				Database: DatabaseName,
-			})
-			if err != nil {
-				log.Fatal(err)
-			}
-		}
-		go func(ind int, connection *pgx.Conn) {
-			if doLoad {
-				defer connection.Close()
-			}
-			procReads[ind] = procs.process(connection)
-		}(i, conn)
-	}
-
-	start := time.Now()
-	itemsRead, bytesRead, valuesRead := procs.scan(batchSize, sourceReader)
-
-	<-inputDone
-	close(batchChan)
-	close(batchChanBin)
-	close(batchChanBatch)
-	workersGroup.Wait()
-	end := time.Now()
-	took := end.Sub(start)
-	itemsRate := float64(itemsRead) / float64(took.Seconds())
-	bytesRate := float64(bytesRead) / float64(took.Seconds())
-
-	valuesRate := float64(valuesRead) / float64(took.Seconds())
-
-	fmt.Printf("loaded %d items in %fsec with %d workers (mean point rate %f/sec, mean value rate %f/sec,  %.2fMB/sec from stdin)\n", itemsRead, took.Seconds(), workers, itemsRate, valuesRate, bytesRate/(1<<20))
-	if file != "" {
-		sourceReader.Close()
-	}
-
-	if reportHost != "" && doLoad {
-		reportTags = append(reportTags, [2]string{"format", format})
-		reportTags = append(reportTags, [2]string{"postgresql_batching", strconv.FormatBool(usePostgresBatching)})
-		reportTags = append(reportTags, [2]string{"chunk_interval", chunkDuration.String()})
-		reportParams := &report.LoadReportParams{
-			ReportParams: report.ReportParams{
-				DBType:             "TimeScaleDB",
-				ReportDatabaseName: reportDatabase,
-				ReportHost:         reportHost,
-				ReportUser:         reportUser,
-				ReportPassword:     reportPassword,
-				ReportTags:         reportTags,
-				Hostname:           reportHostname,
-				DestinationUrl:     daemonUrl,
-				Workers:            workers,
-				ItemLimit:          -1,
-			},
-			IsGzip:    false,
-			BatchSize: batchSize,
-		}
-		err := report.ReportLoadResult(reportParams, itemsRead, valuesRate, bytesRate, took)
-
-		if err != nil {
+		if w.conn, err = connect(url); err != nil {
 			log.Fatal(err)
 		}
 	}
+	return w
 }
 
-// scan reads lines from stdin. It expects input in the postgresql sql format.
-func scan(itemsPerBatch int, reader io.Reader) (int64, int64, int64) {
-	var n int
-	var linesRead, bytesRead int64
-	var totalPoints, totalValues int64
+// Measurement returns the table of an item.
+func (driver) Measurement(item []byte) []byte {
+	if format == formatChoices[1] {
+		return flatPointMeasurement(item)
+	}
+	return []byte(insertTable(string(item)))
+}
 
-	buff := bufPool.Get().(*bytes.Buffer)
-	newline := []byte("\n")
+func (driver) CountPoints(url, table string) (int64, error) {
+	conn, err := connect(url)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return countRows(conn, table)
+}
 
-	scanner := bufio.NewScanner(bufio.NewReaderSize(reader, 4*1024*1024))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, common.DatasetSizeMarker) {
-			parts := common.DatasetSizeMarkerRE.FindAllStringSubmatch(line, -1)
-			if parts == nil || len(parts[0]) != 3 {
-				log.Fatalf("Incorrent number of matched groups: %#v", parts)
-			}
-			if i, err := strconv.Atoi(parts[0][1]); err == nil {
-				totalPoints = int64(i)
-			} else {
-				log.Fatal(err)
-			}
-			if i, err := strconv.Atoi(parts[0][2]); err == nil {
-				totalValues = int64(i)
-			} else {
-				log.Fatal(err)
-			}
-			continue
+// countValues returns the number of field values inserted by a statement of
+// the timescaledb-sql serializer. The tags come first and are quoted, so the
+// quoted values before the first unquoted one, after the time, are taken as
+// tags.
+func countValues(line []byte) int {
+	i := bytes.Index(line, []byte(") VALUES ("))
+	if i < 0 {
+		return 0
+	}
+	values, first, fields := 0, true, false
+	eachLiteral(string(line[i+len(") VALUES ("):]), func(literal string) {
+		if first {
+			first = false
+			return
+		}
+		fields = fields || !strings.HasPrefix(literal, "'")
+		if fields {
+			values++
 		}
-		linesRead++
+	})
+	return values
+}
 
-		buff.Write(scanner.Bytes())
-		buff.Write(newline)
+// copyDecoder reads the length-delimited FlatPoints of the input. A
+// FlatPoint with more values than columns holds the rows of a batch,
+// column-major (see bulk_data_gen --batch-points), and is decoded into a
+// FlatPoint per row.
+type copyDecoder struct {
+	r      *bufio.Reader
+	lenBuf [8]byte
+	frame  []byte
+	tsfp   timescale_serialization.FlatPoint
+	// the next row of tsfp, and its number of rows
+	row, rows int
+	item      []byte
+}
 
-		n++
-		if n >= itemsPerBatch {
-			bytesRead += int64(buff.Len())
-			batchChan <- buff
-			buff = bufPool.Get().(*bytes.Buffer)
-			n = 0
+func (d *copyDecoder) Decode() ([]byte, int, error) {
+	if d.row >= d.rows {
+		if _, err := io.ReadFull(d.r, d.lenBuf[:]); err != nil {
+			return nil, 0, err
+		}
+		size := binary.LittleEndian.Uint64(d.lenBuf[:])
+		if uint64(cap(d.frame)) < size {
+			d.frame = make([]byte, size)
+		}
+		d.frame = d.frame[:size]
+		if _, err := io.ReadFull(d.r, d.frame); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, 0, err
+		}
+		d.tsfp = timescale_serialization.FlatPoint{}
+		if err := d.tsfp.Unmarshal(d.frame); err != nil {
+			return nil, 0, fmt.Errorf("cannot unmarshall item: %v", err)
+		}
+		d.row, d.rows = 0, 1
+		if len(d.tsfp.Columns) > 0 && len(d.tsfp.Values) > len(d.tsfp.Columns) {
+			d.rows = len(d.tsfp.Values) / len(d.tsfp.Columns)
+		}
+		if d.rows == 1 {
+			d.row++
+			return d.frame, flatPointValues(d.tsfp.Values), nil
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("Error reading input: %s", err.Error())
+	p := timescale_serialization.FlatPoint{
+		MeasurementName: d.tsfp.MeasurementName,
+		Columns:         d.tsfp.Columns,
+		Values:          make([]*timescale_serialization.FlatPoint_FlatPointValue, len(d.tsfp.Values)/d.rows),
 	}
-
-	// Finished reading input, make sure last batch goes out.
-	if n > 0 {
-		batchChan <- buff
+	for i := range p.Values {
+		p.Values[i] = d.tsfp.Values[i*d.rows+d.row]
+	}
+	d.row++
+	var err error
+	if d.item, err = p.Marshal(); err != nil {
+		return nil, 0, err
 	}
+	return d.item, flatPointValues(p.Values), nil
+}
 
-	// Closing inputDone signals to the application that we've read everything and can now shut down.
-	close(inputDone)
+// flatPointValues returns the number of field values of the values of a
+// row, the last of which is the time. Like in countValues, the string values
+// before the first number are taken as tags.
+func flatPointValues(values []*timescale_serialization.FlatPoint_FlatPointValue) int {
+	n, fields := 0, false
+	for _, v := range values[:len(values)-1] {
+		fields = fields || v.Type != timescale_serialization.FlatPoint_STRING
+		if fields {
+			n++
+		}
+	}
+	return n
+}
 
-	if linesRead != totalPoints {
-		log.Fatalf("Incorrent number of read points: %d, expected: %d:", linesRead, totalPoints)
+// flatPointMeasurement returns the measurement of a marshalled FlatPoint,
+// its first field.
+func flatPointMeasurement(item []byte) []byte {
+	if len(item) == 0 || item[0] != 0x0a {
+		return nil
 	}
+	l, n := binary.Uvarint(item[1:])
+	if n <= 0 || uint64(len(item)-1-n) < l {
+		return nil
+	}
+	return item[1+n : 1+n+int(l)]
+}
 
-	// The timescaledb format uses 1 line per item:
-	itemsRead := linesRead
+// frameEncoder joins FlatPoints with their length, like in the input.
+type frameEncoder struct{}
 
-	return itemsRead, bytesRead, totalValues
+func (frameEncoder) Reset(buf *bytes.Buffer) {}
+
+func (frameEncoder) Append(buf *bytes.Buffer, n int, item []byte) bool {
+	var lenBuf [8]byte
+	binary.LittleEndian.PutUint64(lenBuf[:], uint64(len(item)))
+	buf.Write(lenBuf[:])
+	buf.Write(item)
+	return true
 }
 
-// scan reads lines from stdin. It expects input in the postgresql sql format.
-func scanBatch(itemsPerBatch int, reader io.Reader) (int64, int64, int64) {
-	var n int
-	var linesRead, bytesRead int64
-	var err error
-	var totalPoints, totalValues int64
+func (frameEncoder) Finish(buf *bytes.Buffer, n int) {}
 
-	scanner := bufio.NewScanner(bufio.NewReaderSize(reader, 4*1024*1024))
-	var buff = make([]string, 0, itemsPerBatch)
-	for scanner.Scan() {
-		line := scanner.Text()
+// writer writes batches over the connection of a worker, in the way of the
+// format.
+type writer struct {
+	conn *pgx.Conn
+}
+
+func (w *writer) WriteBatch(b *bulk_load.Batch) error {
+	switch {
+	case format == formatChoices[1]:
+		return w.copyFrom(b.Buffer.Bytes())
+	case usePostgresBatching:
+		return w.execBatch(strings.Split(strings.TrimSuffix(b.Buffer.String(), "\n"), "\n"))
+	}
+	if migrator != nil {
+		if err := migrateLines(w.conn, strings.Split(b.Buffer.String(), "\n")); err != nil {
+			return err
+		}
+	}
+	_, err := w.conn.Exec(b.Buffer.String())
+	return err
+}
 
-		totalPoints, totalValues, err = common.CheckTotalValues(line)
-		if totalPoints > 0 || totalValues > 0 {
+func (w *writer) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	return w.conn.Close()
+}
+
+// migrateLines runs the schema migrations needed by a batch of INSERT
+// statements.
+func migrateLines(conn *pgx.Conn, lines []string) error {
+	for _, line := range lines {
+		if line == "" {
 			continue
 		}
+		table, columns, types, err := parseInsert(line)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-
-		linesRead++
-		buff = append(buff, line)
-		bytesRead += int64(len(line))
-		n++
-		if n >= itemsPerBatch {
-			batchChanBatch <- buff
-			buff = make([]string, 0, itemsPerBatch)
-			n = 0
+		if err := migrator.ensure(conn, table, columns, types); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("Error reading input: %s", err.Error())
+// execBatch sends the statements with the Postgresql batching feature.
+func (w *writer) execBatch(lines []string) error {
+	if migrator != nil {
+		if err := migrateLines(w.conn, lines); err != nil {
+			return err
+		}
 	}
 
-	// Finished reading input, make sure last batch goes out.
-	if n > 0 {
-		batchChanBatch <- buff
+	sqlBatch := w.conn.BeginBatch()
+	defer sqlBatch.Close()
+	for _, line := range lines {
+		sqlBatch.Queue(line, nil, nil, nil)
 	}
-
-	// Closing inputDone signals to the application that we've read everything and can now shut down.
-	close(inputDone)
-
-	if linesRead != totalPoints {
-		log.Fatalf("Incorrent number of read points: %d, expected: %d:", linesRead, totalPoints)
+	if err := sqlBatch.Send(context.Background(), nil); err != nil {
+		return err
 	}
-
-	// The timescaledb format uses 1 line per item:
-	itemsRead := linesRead
-
-	return itemsRead, bytesRead, totalValues
+	for i := range lines {
+		if _, err := sqlBatch.ExecResults(); err != nil {
+			return fmt.Errorf("line %d of the batch: %s", i, err.Error())
+		}
+	}
+	return nil
 }
 
-// scan reads data from stdin. It expects gop encoded points
-func scanBin(itemsPerBatch int, origReader io.Reader) (int64, int64, int64) {
-
-	var n int
-	var itemsRead, bytesRead int64
-	var err error
-	var lastMeasurement string
-	var p FlatPoint
+// copyFrom writes the FlatPoints of body with COPY. COPY takes one table
+// and columns, so the points are grouped by measurement and columns (which
+// differ within a measurement with --schema-changes), in the order of their
+// first point.
+func (w *writer) copyFrom(body []byte) error {
+	var groups [][]FlatPoint
+	index := map[string]int{}
 	var tsfp timescale_serialization.FlatPoint
-	var size uint64
-
-	buff := make([]FlatPoint, 0, itemsPerBatch)
-	byteBuff := make([]byte, 100*1024)
-	reader := bufio.NewReaderSize(origReader, 4*1024*1024)
-	for {
-		err = binary.Read(reader, binary.LittleEndian, &size)
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatalf("cannot read size of %d item: %v\n", itemsRead, err)
+	for len(body) >= 8 {
+		size := binary.LittleEndian.Uint64(body)
+		body = body[8:]
+		tsfp = timescale_serialization.FlatPoint{}
+		if err := tsfp.Unmarshal(body[:size]); err != nil {
+			return err
 		}
+		body = body[size:]
 
-		if uint64(cap(byteBuff)) < size {
-			byteBuff = make([]byte, size)
+		p := FlatPoint{
+			MeasurementName: tsfp.MeasurementName,
+			Columns:         tsfp.Columns,
+			Values:          make([]interface{}, len(tsfp.Values)),
 		}
-
-		bytesPerItem := uint64(0)
-		for i := 10; i > 0; i-- {
-			r, err := reader.Read(byteBuff[bytesPerItem:size])
-			if err != nil && err != io.EOF {
-				log.Fatalf("cannot read %d item: %v\n", itemsRead, err)
-			}
-			bytesPerItem += uint64(r)
-			if bytesPerItem == size {
-				break
-			}
-
-		}
-		if bytesPerItem != size {
-			log.Fatalf("cannot read %d item: read %d, expected %d\n", itemsRead, bytesPerItem, size)
-		}
-		err = tsfp.Unmarshal(byteBuff[:size])
-		if err != nil {
-			log.Fatalf("cannot unmarshall %d item: %v\n", itemsRead, err)
-		}
-
-		bytesRead += int64(size) + 8
-
-		p.MeasurementName = tsfp.MeasurementName
-		p.Columns = tsfp.Columns
-		p.Values = make([]interface{}, len(tsfp.Values))
 		for i, f := range tsfp.Values {
 			switch f.Type {
 			case timescale_serialization.FlatPoint_FLOAT:
 				p.Values[i] = f.DoubleVal
-				break
 			case timescale_serialization.FlatPoint_INTEGER:
 				p.Values[i] = f.IntVal
-				break
 			case timescale_serialization.FlatPoint_STRING:
 				p.Values[i] = f.StringVal
-				break
 			default:
-				log.Fatalf("invalid type of %d item: %d", itemsRead, f.Type)
+				return fmt.Errorf("invalid type of a value of %s: %d", p.MeasurementName, f.Type)
 			}
 		}
 
-		//log.Printf("Decoded %d point\n",itemsRead+1)
-		newMeasurement := itemsRead > 1 && p.MeasurementName != lastMeasurement
-		if !newMeasurement {
-			buff = append(buff, p)
-			itemsRead++
-			n++
-		}
-		if n > 0 && (n >= itemsPerBatch || newMeasurement) {
-			batchChanBin <- buff
-			n = 0
-			buff = nil
-			buff = make([]FlatPoint, 0, itemsPerBatch)
-		}
-		if newMeasurement {
-			buff = append(buff, p)
-			itemsRead++
-			n++
-		}
-		lastMeasurement = p.MeasurementName
-		p = FlatPoint{}
-		tsfp = timescale_serialization.FlatPoint{}
-	}
-
-	if err != nil && err != io.EOF {
-		log.Fatalf("Error reading input after %d items: %s", itemsRead, err.Error())
-	}
-
-	// Finished reading input, make sure last batch goes out.
-	if n > 0 {
-		batchChanBin <- buff
-		buff = nil
-	}
-
-	// Closing inputDone signals to the application that we've read everything and can now shut down.
-	close(inputDone)
-
-	return itemsRead, bytesRead, int64(float64(itemsRead) * ValuesPerMeasurement)
-}
-
-// processBatches reads byte buffers from batchChan and writes them to the target server, while tracking stats on the write.
-func processBatches(conn *pgx.Conn) int64 {
-	var total int64
-	for batch := range batchChan {
-		if !doLoad {
-			continue
-		}
-
-		// Write the batch.
-		_, err := conn.Exec(string(batch.Bytes()))
-		if err != nil {
-			log.Fatalf("Error writing: %s\n", err.Error())
+		key := p.MeasurementName + "\x00" + strings.Join(p.Columns, "\x00")
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
 		}
-
-		// Return the batch buffer to the pool.
-		batch.Reset()
-		bufPool.Put(batch)
-		total += int64(batch.Len())
+		groups[i] = append(groups[i], p)
 	}
-	workersGroup.Done()
-	return total
-}
 
-// processBatches reads byte buffers from batchChan and writes them to the target server, while tracking stats on the write.
-func processBatchesBatch(conn *pgx.Conn) int64 {
-	var total int64
-	var batches int64
-	for batch := range batchChanBatch {
-		if !doLoad {
-			continue
-		}
-
-		// Write the batch.
-		sqlBatch := conn.BeginBatch()
-		for _, line := range batch {
-			sqlBatch.Queue(line, nil, nil, nil)
+	for _, batch := range groups {
+		if migrator != nil {
+			types := make([]string, len(batch[0].Columns))
+			for _, p := range batch {
+				for i, v := range p.Values {
+					types[i] = valueColumnType(v)
+				}
+				if err := migrator.ensure(w.conn, p.MeasurementName, p.Columns, types); err != nil {
+					return err
+				}
+				migrator.coerce(p.MeasurementName, p.Columns, p.Values)
+			}
 		}
-
-		err := sqlBatch.Send(context.Background(), nil)
-
+		c := NewCopyFromPoint(batch)
+		rows, err := w.conn.CopyFrom(pgx.Identifier{batch[0].MeasurementName}, batch[0].Columns, c)
 		if err != nil {
-			log.Fatalf("Error writing: %s\n", err.Error())
+			return fmt.Errorf("writing '%s' of size %d in position %d: %s", batch[0].MeasurementName, len(batch), c.Position(), err.Error())
 		}
-
-		for i := 0; i < len(batch); i++ {
-			_, err = sqlBatch.ExecResults()
-			if err != nil {
-				log.Fatalf("Error line %d of batch %d: %s\n", i, batch, err.Error())
-			}
+		if rows != len(batch) {
+			log.Printf("Problem writing '%s': Written only %d rows of %d", batch[0].MeasurementName, rows, len(batch))
 		}
-		sqlBatch.Close()
-		// Return the batch buffer to the pool.
-		total += int64(len(batch))
-		batches++
 	}
-	workersGroup.Done()
-	return total
+	return nil
 }
 
 // CopyFromPoint is implementation of the interface CopyFromSource  used by *Conn.CopyFrom as the source for copy data.
@@ -536,7 +456,6 @@ type CopyFromPoint struct {
 }
 
 func NewCopyFromPoint(points []FlatPoint) *CopyFromPoint {
-	//log.Printf("NewCopyFromPoint\n")
 	cp := &CopyFromPoint{}
 	cp.points = points
 	cp.i = -1
@@ -550,7 +469,6 @@ func (c *CopyFromPoint) Next() bool {
 }
 
 func (c *CopyFromPoint) Values() ([]interface{}, error) {
-	//log.Printf("Copying %dth values\n",c.i)
 	return c.points[c.i].Values, nil
 }
 
@@ -562,32 +480,6 @@ func (c *CopyFromPoint) Position() int {
 	return c.i
 }
 
-// processBatches reads byte buffers from batchChan and writes them to the target server, while tracking stats on the write.
-func processBatchesBin(conn *pgx.Conn) int64 {
-	n := 0
-	var total int64
-	for batch := range batchChanBin {
-		if !doLoad {
-			continue
-		}
-		//log.Printf("CopyFrom %d of %s\n", n, batch[0].MeasurementName)
-		// Write the batch.
-		c := NewCopyFromPoint(batch)
-		rows, err := conn.CopyFrom(pgx.Identifier{batch[0].MeasurementName}, batch[0].Columns, c)
-		//log.Println("CopyFrom End")
-		if err != nil {
-			log.Fatalf("Error writing %d batch of '%s' of size %d in position %d: %s\n", n, batch[0].MeasurementName, len(batch), c.Position(), err.Error())
-		}
-		if rows != len(batch) {
-			log.Printf("Problem writing of %d batch: Written only %d rows of %d", n, rows, len(batch))
-		}
-		total += int64(len(batch))
-		n++
-	}
-	workersGroup.Done()
-	return total
-}
-
 const createDatabaseSql = "create database " + DatabaseName + ";"
 const createExtensionSql = "CREATE EXTENSION IF NOT EXISTS timescaledb CASCADE;"
 
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx"
+)
+
+// Data generated with --schema-changes may carry columns the tables do not
+// have, or values that do not fit a column type. With --schema-migrate the
+// loader alters the tables before writing such rows: missing columns are
+// added and columns are widened along bigint -> double precision -> text.
+
+// Column types, as reported by information_schema.columns.
+const (
+	columnBigint = "bigint"
+	columnDouble = "double precision"
+	columnText   = "text"
+)
+
+var columnTypeRank = map[string]int{
+	columnBigint: 0,
+	columnDouble: 1,
+	columnText:   2,
+}
+
+// schemaMigrator tracks the columns of the tables and alters them when
+// needed. It is shared by all workers.
+type schemaMigrator struct {
+	mu         sync.RWMutex
+	tables     map[string]map[string]string // table -> column -> type
+	migrations int64
+}
+
+func newSchemaMigrator(conn *pgx.Conn) (*schemaMigrator, error) {
+	m := &schemaMigrator{tables: map[string]map[string]string{}}
+	rows, err := conn.Query("SELECT table_name, column_name, data_type FROM information_schema.columns WHERE table_schema = 'public'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, column, dataType string
+		if err := rows.Scan(&table, &column, &dataType); err != nil {
+			return nil, err
+		}
+		if m.tables[table] == nil {
+			m.tables[table] = map[string]string{}
+		}
+		m.tables[table][column] = dataType
+	}
+	return m, rows.Err()
+}
+
+// Migrations returns the number of ALTER TABLE statements executed.
+func (m *schemaMigrator) Migrations() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.migrations
+}
+
+// ensure alters table so that it has the given columns with at least the
+// given types. Unknown tables are left for the write to fail on.
+func (m *schemaMigrator) ensure(conn *pgx.Conn, table string, columns, types []string) error {
+	table = strings.ToLower(table)
+
+	m.mu.RLock()
+	ok := m.fits(table, columns, types)
+	m.mu.RUnlock()
+	if ok {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, column := range columns {
+		sql := m.migration(table, strings.ToLower(column), types[i])
+		if sql == "" {
+			continue
+		}
+		if _, err := conn.Exec(sql); err != nil {
+			return fmt.Errorf("schema migration %q: %v", sql, err)
+		}
+		log.Printf("schema migration: %s\n", sql)
+		m.tables[table][strings.ToLower(column)] = types[i]
+		m.migrations++
+	}
+	return nil
+}
+
+func (m *schemaMigrator) fits(table string, columns, types []string) bool {
+	for i, column := range columns {
+		if m.migration(table, strings.ToLower(column), types[i]) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// migration returns the statement needed to store a value of type want in
+// column, or "" when none is needed.
+func (m *schemaMigrator) migration(table, column, want string) string {
+	columns, ok := m.tables[table]
+	if !ok {
+		return ""
+	}
+	have, ok := columns[column]
+	if !ok {
+		return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, want)
+	}
+	haveRank, ok := columnTypeRank[have]
+	if ok && columnTypeRank[want] > haveRank {
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s", table, column, want, column, want)
+	}
+	return ""
+}
+
+// coerce converts values in place to the current types of their columns, as
+// COPY does not cast. It must be called after ensure.
+func (m *schemaMigrator) coerce(table string, columns []string, values []interface{}) {
+	table = strings.ToLower(table)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i, column := range columns {
+		switch m.tables[table][strings.ToLower(column)] {
+		case columnDouble:
+			if v, ok := values[i].(int64); ok {
+				values[i] = float64(v)
+			}
+		case columnText:
+			switch v := values[i].(type) {
+			case int64:
+				values[i] = strconv.FormatInt(v, 10)
+			case float64:
+				values[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+	}
+}
+
+// valueColumnType returns the column type needed to store a COPY value.
+func valueColumnType(v interface{}) string {
+	switch v.(type) {
+	case int64:
+		return columnBigint
+	case float64:
+		return columnDouble
+	default:
+		return columnText
+	}
+}
+
+// parseInsert extracts the table, the columns and the column types needed
+// by the values of an INSERT statement written by the timescaledb-sql
+// serializer.
+func parseInsert(line string) (table string, columns, types []string, err error) {
+	const prefix = "INSERT INTO "
+	if !strings.HasPrefix(line, prefix) {
+		return "", nil, nil, fmt.Errorf("not an INSERT statement: %s", line)
+	}
+	rest := line[len(prefix):]
+	open := strings.Index(rest, " (")
+	closing := strings.Index(rest, ") VALUES (")
+	if open < 0 || closing < open {
+		return "", nil, nil, fmt.Errorf("malformed INSERT statement: %s", line)
+	}
+	table = rest[:open]
+	columns = strings.Split(rest[open+2:closing], ",")
+
+	types = make([]string, 0, len(columns))
+	eachLiteral(rest[closing+len(") VALUES ("):], func(literal string) {
+		types = append(types, literalColumnType(literal))
+	})
+	if len(types) != len(columns) {
+		return "", nil, nil, fmt.Errorf("%d columns but %d values: %s", len(columns), len(types), line)
+	}
+	return table, columns, types, nil
+}
+
+// eachLiteral calls fn with each value literal of the VALUES list of an
+// INSERT statement, given from after its opening parenthesis.
+func eachLiteral(list string, fn func(literal string)) {
+	values := strings.TrimSuffix(strings.TrimSpace(list), ");")
+	inQuotes := false
+	start := 0
+	for i := 0; i <= len(values); i++ {
+		if i < len(values) {
+			if values[i] == '\'' {
+				inQuotes = !inQuotes
+			}
+			if inQuotes || values[i] != ',' {
+				continue
+			}
+		}
+		fn(values[start:i])
+		start = i + 1
+	}
+}
+
+func literalColumnType(literal string) string {
+	switch {
+	case strings.HasPrefix(literal, "'"):
+		return columnText
+	case strings.ContainsAny(literal, ".eE"):
+		return columnDouble
+	default:
+		return columnBigint
+	}
+}
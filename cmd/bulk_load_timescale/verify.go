@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/jackc/pgx"
+)
+
+// With --verify the rows read of each table are compared with the rows of
+// the table, counted with count(*).
+
+// insertTable returns the table of an INSERT statement, empty if it is not
+// one.
+func insertTable(line string) string {
+	const prefix = "INSERT INTO "
+	if !strings.HasPrefix(line, prefix) {
+		return ""
+	}
+	rest := line[len(prefix):]
+	if i := strings.IndexAny(rest, " ("); i >= 0 {
+		return rest[:i]
+	}
+	return rest
+}
+
+// countRows counts the rows of table.
+func countRows(conn *pgx.Conn, table string) (int64, error) {
+	var count int64
+	err := conn.QueryRow("SELECT count(*) FROM " + pgx.Identifier{table}.Sanitize()).Scan(&count)
+	return count, err
+}
@@ -9,12 +9,17 @@ import (
 	"fmt"
 	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
 	bulkQueryGen "github.com/caict-benchmark/BDC-TS/bulk_query_gen"
+	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/bcetsdb"
 	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/cassandra"
+	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/clickhouse"
 	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/elasticsearch"
 	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/graphite"
 	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/influxdb"
+	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/iotdb"
 	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/mongodb"
 	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/opentsdb"
+	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/prometheus"
+	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/tdengine"
 	"github.com/caict-benchmark/BDC-TS/bulk_query_gen/timescaledb"
 	"log"
 	"math/rand"
@@ -49,8 +54,12 @@ const (
 	DashboardSystemLoad             = "system-load"
 	DashboardThroughput             = "throughput"
 
-	VehicleReadTime = "vehicle-real-time"
-	VehicleAverage  = "vehicle-average"
+	VehicleReadTime       = "vehicle-real-time"
+	VehicleFleetGroupBy   = "vehicle-fleet-groupby"
+	VehicleFleetFilter    = "vehicle-fleet-filter"
+	VehicleGeoBoundingBox = "vehicle-geo-bbox"
+	VehicleGeoRadius      = "vehicle-geo-radius"
+	VehicleGeoAreaWindow  = "vehicle-geo-area-window"
 )
 
 // query generator choices {use-case, query-type, format}
@@ -66,6 +75,11 @@ var useCaseMatrix = map[string]map[string]map[string]bulkQueryGen.QueryGenerator
 			"opentsdb":         opentsdb.NewOpenTSDBDevopsSingleHost,
 			"timescaledb":      timescaledb.NewTimescaleDevopsSingleHost,
 			"graphite":         graphite.NewGraphiteDevopsSingleHost,
+			"prometheus":       prometheus.NewPrometheusDevopsSingleHost,
+			"tdengine":         tdengine.NewTDengineDevopsSingleHost,
+			"iotdb":            iotdb.NewIoTDBDevopsSingleHost,
+			"clickhouse":       clickhouse.NewClickHouseDevopsSingleHost,
+			"kairosdb":         bcetsdb.NewBceTSDBDevopsSingleHost,
 		},
 		DevOpsOneHostTwelveHours: {
 			"cassandra":        cassandra.NewCassandraDevopsSingleHost12hr,
@@ -76,6 +90,11 @@ var useCaseMatrix = map[string]map[string]map[string]bulkQueryGen.QueryGenerator
 			"opentsdb":         opentsdb.NewOpenTSDBDevopsSingleHost12hr,
 			"timescaledb":      timescaledb.NewTimescaleDevopsSingleHost12hr,
 			"graphite":         graphite.NewGraphiteDevopsSingleHost12hr,
+			"prometheus":       prometheus.NewPrometheusDevopsSingleHost12hr,
+			"tdengine":         tdengine.NewTDengineDevopsSingleHost12hr,
+			"iotdb":            iotdb.NewIoTDBDevopsSingleHost12hr,
+			"clickhouse":       clickhouse.NewClickHouseDevopsSingleHost12hr,
+			"kairosdb":         bcetsdb.NewBceTSDBDevopsSingleHost12hr,
 		},
 		DevOpsEightHostsOneHour: {
 			"cassandra":        cassandra.NewCassandraDevops8Hosts,
@@ -86,6 +105,11 @@ var useCaseMatrix = map[string]map[string]map[string]bulkQueryGen.QueryGenerator
 			"opentsdb":         opentsdb.NewOpenTSDBDevops8Hosts,
 			"timescaledb":      timescaledb.NewTimescaleDevops8Hosts1Hr,
 			"graphite":         graphite.NewGraphiteDevops8Hosts,
+			"prometheus":       prometheus.NewPrometheusDevops8Hosts,
+			"tdengine":         tdengine.NewTDengineDevops8Hosts,
+			"iotdb":            iotdb.NewIoTDBDevops8Hosts,
+			"clickhouse":       clickhouse.NewClickHouseDevops8Hosts,
+			"kairosdb":         bcetsdb.NewBceTSDBDevops8Hosts,
 		},
 		DevOpsGroupBy: {
 			"cassandra":        cassandra.NewCassandraDevopsGroupBy,
@@ -94,6 +118,11 @@ var useCaseMatrix = map[string]map[string]map[string]bulkQueryGen.QueryGenerator
 			"influx-http":      influxdb.NewInfluxQLDevopsGroupBy,
 			"timescaledb":      timescaledb.NewTimescaleDevopsGroupby,
 			"graphite":         graphite.NewGraphiteDevopsGroupBy,
+			"prometheus":       prometheus.NewPrometheusDevopsGroupBy,
+			"tdengine":         tdengine.NewTDengineDevopsGroupBy,
+			"iotdb":            iotdb.NewIoTDBDevopsGroupBy,
+			"clickhouse":       clickhouse.NewClickHouseDevopsGroupBy,
+			"kairosdb":         bcetsdb.NewBceTSDBDevopsGroupBy,
 		},
 	},
 	common.UseCaseIot: {
@@ -134,7 +163,52 @@ var useCaseMatrix = map[string]map[string]map[string]bulkQueryGen.QueryGenerator
 	},
 	common.UseCaseVehicle: {
 		VehicleReadTime: {
-			"es-http": elasticsearch.NewElasticSearchVehicleRealTime,
+			"es-http":    elasticsearch.NewElasticSearchVehicleRealTime,
+			"prometheus": prometheus.NewPrometheusVehicleRealTime,
+			"tdengine":   tdengine.NewTDengineVehicleRealTime,
+			"iotdb":      iotdb.NewIoTDBVehicleRealTime,
+			"clickhouse": clickhouse.NewClickHouseVehicleRealTime,
+			"kairosdb":   bcetsdb.NewBceTSDBVehicleRealTime,
+		},
+		VehicleFleetGroupBy: {
+			"es-http":          elasticsearch.NewElasticSearchVehicleFleetGroupBy,
+			"influx-flux-http": influxdb.NewFluxVehicleFleetGroupBy,
+			"influx-http":      influxdb.NewInfluxQLVehicleFleetGroupBy,
+			"prometheus":       prometheus.NewPrometheusVehicleFleetGroupBy,
+			"tdengine":         tdengine.NewTDengineVehicleFleetGroupBy,
+			"iotdb":            iotdb.NewIoTDBVehicleFleetGroupBy,
+			"clickhouse":       clickhouse.NewClickHouseVehicleFleetGroupBy,
+			"kairosdb":         bcetsdb.NewBceTSDBVehicleFleetGroupBy,
+		},
+		VehicleFleetFilter: {
+			"es-http":          elasticsearch.NewElasticSearchVehicleFleetFilter,
+			"influx-flux-http": influxdb.NewFluxVehicleFleetFilter,
+			"influx-http":      influxdb.NewInfluxQLVehicleFleetFilter,
+			"prometheus":       prometheus.NewPrometheusVehicleFleetFilter,
+			"tdengine":         tdengine.NewTDengineVehicleFleetFilter,
+			"iotdb":            iotdb.NewIoTDBVehicleFleetFilter,
+			"clickhouse":       clickhouse.NewClickHouseVehicleFleetFilter,
+			"kairosdb":         bcetsdb.NewBceTSDBVehicleFleetFilter,
+		},
+		VehicleGeoBoundingBox: {
+			"es-http":     elasticsearch.NewElasticSearchVehicleGeoBoundingBox,
+			"influx-http": influxdb.NewInfluxQLVehicleGeoBoundingBox,
+			"prometheus":  prometheus.NewPrometheusVehicleGeoBoundingBox,
+			"tdengine":    tdengine.NewTDengineVehicleGeoBoundingBox,
+			"iotdb":       iotdb.NewIoTDBVehicleGeoBoundingBox,
+			"clickhouse":  clickhouse.NewClickHouseVehicleGeoBoundingBox,
+		},
+		VehicleGeoRadius: {
+			"es-http":    elasticsearch.NewElasticSearchVehicleGeoRadius,
+			"clickhouse": clickhouse.NewClickHouseVehicleGeoRadius,
+		},
+		VehicleGeoAreaWindow: {
+			"es-http":     elasticsearch.NewElasticSearchVehicleGeoAreaWindow,
+			"influx-http": influxdb.NewInfluxQLVehicleGeoAreaWindow,
+			"prometheus":  prometheus.NewPrometheusVehicleGeoAreaWindow,
+			"tdengine":    tdengine.NewTDengineVehicleGeoAreaWindow,
+			"iotdb":       iotdb.NewIoTDBVehicleGeoAreaWindow,
+			"clickhouse":  clickhouse.NewClickHouseVehicleGeoAreaWindow,
 		},
 	},
 }
@@ -165,6 +239,8 @@ var (
 
 	interleavedGenerationGroupID uint
 	interleavedGenerationGroups  uint
+
+	fleetConfig = bulkQueryGen.VehicleFleet
 )
 
 // Parse args:
@@ -206,6 +282,10 @@ func init() {
 	flag.UintVar(&interleavedGenerationGroupID, "interleaved-generation-group-id", 0, "Group (0-indexed) to perform round-robin serialization within. Use this to scale up data generation to multiple processes.")
 	flag.UintVar(&interleavedGenerationGroups, "interleaved-generation-groups", 1, "The number of round-robin serialization groups. Use this to scale up data generation to multiple processes.")
 
+	flag.IntVar(&fleetConfig.Manufacturers, "fleet-manufacturers", fleetConfig.Manufacturers, "Number of distinct vehicle manufacturers (must match data generation).")
+	flag.IntVar(&fleetConfig.Models, "fleet-models", fleetConfig.Models, "Number of distinct models per manufacturer (must match data generation).")
+	flag.IntVar(&fleetConfig.Cities, "fleet-cities", fleetConfig.Cities, "Number of distinct cities (must match data generation).")
+
 	flag.Parse()
 
 	if queryType == DevOpsEightHostsOneHour && scaleVar < 8 {
@@ -228,6 +308,13 @@ func init() {
 		log.Fatal("invalid format specifier")
 	}
 
+	if useCase == common.UseCaseVehicle {
+		if err := fleetConfig.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		bulkQueryGen.VehicleFleet = fleetConfig
+	}
+
 	//hourGroupInterval := 1
 	//
 	//if queryType == DevOpsOneHostTwelveHours {
@@ -49,6 +49,7 @@ var (
 	reportUser           string
 	reportPassword       string
 	reportTagsCSV        string
+	resultsFile          string
 	file                 string
 )
 
@@ -62,17 +63,19 @@ var (
 
 // Global vars:
 var (
-	queryPool       sync.Pool
-	hlQueryChan     chan *HLQuery
-	statPool        sync.Pool
-	statChan        chan *Stat
-	workersGroup    sync.WaitGroup
-	statGroup       sync.WaitGroup
-	aggrPlan        int
-	reportTags      [][2]string
-	reportHostname  string
-	reportQueryStat StatGroup
-	sourceReader    *os.File
+	queryPool         sync.Pool
+	hlQueryChan       chan *HLQuery
+	statPool          sync.Pool
+	statChan          chan *Stat
+	workersGroup      sync.WaitGroup
+	statGroup         sync.WaitGroup
+	aggrPlan          int
+	reportTags        [][2]string
+	reportHostname    string
+	reportQueryStat   StatGroup
+	statMapping       statsMap
+	workerStatMapping map[int]*StatGroup
+	sourceReader      *os.File
 )
 
 type statsMap map[string]*StatGroup
@@ -97,6 +100,7 @@ func init() {
 	flag.StringVar(&reportUser, "report-user", "", "User for Host to send result metrics.")
 	flag.StringVar(&reportPassword, "report-password", "", "User password for Host to send result metrics.")
 	flag.StringVar(&reportTagsCSV, "report-tags", "", "Comma separated k:v tags to send  alongside result metrics.")
+	flag.StringVar(&resultsFile, "results-file", "", "Write a summary of the run to this file, as JSON or as CSV if it ends with .csv.")
 
 	flag.Parse()
 
@@ -178,7 +182,7 @@ func main() {
 	qe := NewHLQueryExecutor(session, debug)
 	for i := 0; i < workers; i++ {
 		workersGroup.Add(1)
-		go processQueries(qe)
+		go processQueries(qe, i)
 	}
 
 	// Read in jobs, closing the job channel when done:
@@ -240,6 +244,15 @@ func main() {
 			log.Fatal(err)
 		}
 	}
+
+	if resultsFile != "" {
+		s := querySummary("Cassandra", wallStart, wallTook, &reportQueryStat, statMapping, workerStatMapping)
+		s.AddTags(reportTags)
+		s.AddExtraVals([]report.ExtraVal{{Name: "burn_in", Value: int64(burnIn)}})
+		if err := s.WriteFile(resultsFile); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 // scan reads encoded Queries and places them onto the workqueue.
@@ -272,7 +285,7 @@ func scan(r io.Reader) {
 
 // processQueries reads byte buffers from hlQueryChan and writes them to the
 // target server, while tracking latency.
-func processQueries(qc *HLQueryExecutor) {
+func processQueries(qc *HLQueryExecutor, worker int) {
 	opts := HLQueryExecutorDoOptions{
 		AggregationPlan:      aggrPlan,
 		Debug:                debug,
@@ -295,6 +308,7 @@ func processQueries(qc *HLQueryExecutor) {
 		// total lag stat:
 		stat := statPool.Get().(*Stat)
 		stat.Init(ls[0], qpLagMs+reqLagMs, true)
+		stat.Worker = worker
 		statChan <- stat
 
 		// qp lag stat:
@@ -318,7 +332,8 @@ func processQueries(qc *HLQueryExecutor) {
 // processStats collects latency results, aggregating them into summary
 // statistics. Optionally, they are printed to stderr at regular intervals.
 func processStats() {
-	statMapping := statsMap{}
+	statMapping = statsMap{}
+	workerStatMapping = map[int]*StatGroup{}
 
 	i := uint64(0)
 	for stat := range statChan {
@@ -341,6 +356,10 @@ func processStats() {
 		if stat.IsActual {
 			i++
 			reportQueryStat.Push(stat.Value)
+			if _, ok := workerStatMapping[stat.Worker]; !ok {
+				workerStatMapping[stat.Worker] = &StatGroup{}
+			}
+			workerStatMapping[stat.Worker].Push(stat.Value)
 		}
 
 		statPool.Put(stat)
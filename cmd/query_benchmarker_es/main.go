@@ -45,6 +45,7 @@ var (
 	reportUser           string
 	reportPassword       string
 	reportTagsCSV        string
+	resultsFile          string
 )
 
 // Global vars:
@@ -60,6 +61,7 @@ var (
 	telemetrySrcAddr    string
 	telemetryTags       [][2]string
 	statMapping         statsMap
+	workerStatMapping   map[int]*StatGroup
 	reportTags          [][2]string
 	reportHostname      string
 )
@@ -88,6 +90,7 @@ func init() {
 	flag.StringVar(&reportUser, "report-user", "", "User for Host to send result metrics.")
 	flag.StringVar(&reportPassword, "report-password", "", "User password for Host to send result metrics.")
 	flag.StringVar(&reportTagsCSV, "report-tags", "", "Comma separated k:v tags to send  alongside result metrics.")
+	flag.StringVar(&resultsFile, "results-file", "", "Write a summary of the run to this file, as JSON or as CSV if it ends with .csv.")
 
 	flag.Parse()
 
@@ -185,7 +188,7 @@ func main() {
 		daemonUrl := daemonUrls[i%len(daemonUrls)]
 		workersGroup.Add(1)
 		w := NewHTTPClient(daemonUrl, debug)
-		go processQueries(w, telemetryChanPoints, fmt.Sprintf("%d", i))
+		go processQueries(w, i, telemetryChanPoints)
 	}
 
 	// Read in jobs, closing the job channel when done:
@@ -249,6 +252,15 @@ func main() {
 			log.Fatal(err)
 		}
 	}
+
+	if resultsFile != "" {
+		s := querySummary("ElasticSearch", wallStart, wallTook, statMapping[allQueriesLabel], statMapping, workerStatMapping)
+		s.AddTags(reportTags)
+		s.AddExtraVals([]report.ExtraVal{{Name: "burn_in", Value: int64(burnIn)}})
+		if err := s.WriteFile(resultsFile); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 // scan reads encoded Queries and places them onto the workqueue.
@@ -281,7 +293,8 @@ func scan(r io.Reader) {
 
 // processQueries reads byte buffers from queryChan and writes them to the
 // target server, while tracking latency.
-func processQueries(w *HTTPClient, telemetrySink chan *report.Point, telemetryWorkerLabel string) {
+func processQueries(w *HTTPClient, worker int, telemetrySink chan *report.Point) {
+	telemetryWorkerLabel := fmt.Sprintf("%d", worker)
 	opts := &HTTPClientDoOptions{
 		Debug:                debug,
 		PrettyPrintResponses: prettyPrintResponses,
@@ -293,6 +306,7 @@ func processQueries(w *HTTPClient, telemetrySink chan *report.Point, telemetryWo
 
 		stat := statPool.Get().(*Stat)
 		stat.Init(q.HumanLabel, lagMillis)
+		stat.Worker = worker
 		statChan <- stat
 
 		queryPool.Put(q)
@@ -322,6 +336,7 @@ func processStats() {
 	statMapping = statsMap{
 		allQueriesLabel: &StatGroup{},
 	}
+	workerStatMapping = map[int]*StatGroup{}
 
 	i := uint64(0)
 	for stat := range statChan {
@@ -342,6 +357,10 @@ func processStats() {
 
 		statMapping[allQueriesLabel].Push(stat.Value)
 		statMapping[string(stat.Label)].Push(stat.Value)
+		if _, ok := workerStatMapping[stat.Worker]; !ok {
+			workerStatMapping[stat.Worker] = &StatGroup{}
+		}
+		workerStatMapping[stat.Worker].Push(stat.Value)
 
 		statPool.Put(stat)
 
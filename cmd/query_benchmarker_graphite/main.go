@@ -45,6 +45,7 @@ var (
 	reportUser             string
 	reportPassword         string
 	reportTagsCSV          string
+	resultsFile            string
 	useCase                string
 	queriesBatch           int
 	waitInterval           time.Duration
@@ -78,6 +79,7 @@ var (
 	telemetrySrcAddr    string
 	telemetryTags       [][2]string
 	statMapping         statsMap
+	workerStatMapping   map[int]*StatGroup
 	reportTags          [][2]string
 	reportHostname      string
 	batchSize           int
@@ -107,6 +109,7 @@ func init() {
 	flag.StringVar(&reportUser, "report-user", "", "User for Host to send result metrics.")
 	flag.StringVar(&reportPassword, "report-password", "", "User password for Host to send result metrics.")
 	flag.StringVar(&reportTagsCSV, "report-tags", "", "Comma separated k:v tags to send  alongside result metrics.")
+	flag.StringVar(&resultsFile, "results-file", "", "Write a summary of the run to this file, as JSON or as CSV if it ends with .csv.")
 	flag.StringVar(&useCase, "use-case", "", "Enables use-case specific behavior. Empty for default behavior. Additional use-cases: "+Dashboard)
 	flag.IntVar(&queriesBatch, "batch-size", 18, "Number of queries in batch per worker for Dashboard use-case")
 	flag.DurationVar(&waitInterval, "wait-interval", time.Second*0, "Delay between sending batches of queries in the dashboard use-case")
@@ -254,7 +257,7 @@ func main() {
 	for i := 0; i < workers; i++ {
 		workersGroup.Add(1)
 		w := NewHTTPClient(graphiteUrl, debug, dialTimeout, readTimeout, writeTimeout)
-		go processQueries(w)
+		go processQueries(w, i)
 	}
 	log.Printf("Started querying with %d workers\n", workers)
 
@@ -304,7 +307,7 @@ loop:
 						//fmt.Printf("Adding worker %d\n", workers)
 						workersGroup.Add(1)
 						w := NewHTTPClient(graphiteUrl, debug, dialTimeout, readTimeout, writeTimeout)
-						go processQueries(w)
+						go processQueries(w, workers)
 						workers++
 					}
 					log.Printf("Added %d workers, total: %d\n", workersIncreaseStep, workers)
@@ -477,6 +480,18 @@ waitLoop:
 
 	}
 
+	if resultsFile != "" {
+		s := querySummary("Graphite", wallStart, wallTook, statMapping[allQueriesLabel], statMapping, workerStatMapping)
+		s.AddTags(reportTags)
+		s.AddExtraVals([]report.ExtraVal{{Name: "burn_in", Value: int64(burnIn)}, {Name: "workers", Value: int64(workers)}})
+		if responseTimeLimitReached {
+			s.AddExtraVals([]report.ExtraVal{{Name: "response_time_limit_workers", Value: int64(reponseTimeLimitWorkers)}})
+		}
+		if err := s.WriteFile(resultsFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// (Optional) create a memory profile:
 	if memProfile != "" {
 		f, err := os.Create(memProfile)
@@ -537,7 +552,7 @@ loop:
 
 // processQueries reads byte buffers from queryChan and writes them to the
 // target server, while tracking latency.
-func processQueries(w HTTPClient) error {
+func processQueries(w HTTPClient, worker int) error {
 	opts := &HTTPClientDoOptions{
 		Debug:                debug,
 		PrettyPrintResponses: prettyPrintResponses,
@@ -545,7 +560,7 @@ func processQueries(w HTTPClient) error {
 	var queriesSeen int64
 	for queries := range queryChan {
 		if len(queries) == 1 {
-			if err := processSingleQuery(w, queries[0], opts, nil, nil); err != nil {
+			if err := processSingleQuery(w, worker, queries[0], opts, nil, nil); err != nil {
 				log.Fatal(err)
 			}
 			queriesSeen++
@@ -556,7 +571,7 @@ func processQueries(w HTTPClient) error {
 			errCh := make(chan error)
 			doneCh := make(chan int, len(queries))
 			for _, q := range queries {
-				go processSingleQuery(w, q, opts, errCh, doneCh)
+				go processSingleQuery(w, worker, q, opts, errCh, doneCh)
 				queriesSeen++
 			}
 
@@ -586,7 +601,7 @@ func processQueries(w HTTPClient) error {
 	return nil
 }
 
-func processSingleQuery(w HTTPClient, q *Query, opts *HTTPClientDoOptions, errCh chan error, doneCh chan int) error {
+func processSingleQuery(w HTTPClient, worker int, q *Query, opts *HTTPClientDoOptions, errCh chan error, doneCh chan int) error {
 	defer func() {
 		if doneCh != nil {
 			doneCh <- 1
@@ -595,6 +610,7 @@ func processSingleQuery(w HTTPClient, q *Query, opts *HTTPClientDoOptions, errCh
 	lagMillis, err := w.Do(q, opts)
 	stat := statPool.Get().(*Stat)
 	stat.Init(q.HumanLabel, lagMillis)
+	stat.Worker = worker
 	statChan <- stat
 	queryPool.Put(q)
 	if err != nil {
@@ -617,6 +633,7 @@ func processStats(telemetrySink chan *report.Point) {
 	statMapping = statsMap{
 		allQueriesLabel: &StatGroup{},
 	}
+	workerStatMapping = map[int]*StatGroup{}
 
 	lastRefresh := time.Time{}
 	i := uint64(0)
@@ -642,6 +659,10 @@ func processStats(telemetrySink chan *report.Point) {
 		movingAverageStat.Push(now, stat.Value)
 		statMapping[allQueriesLabel].Push(stat.Value)
 		statMapping[string(stat.Label)].Push(stat.Value)
+		if _, ok := workerStatMapping[stat.Worker]; !ok {
+			workerStatMapping[stat.Worker] = &StatGroup{}
+		}
+		workerStatMapping[stat.Worker].Push(stat.Value)
 
 		statPool.Put(stat)
 
@@ -5,12 +5,16 @@ import (
 	"math"
 	"sort"
 	"time"
+
+	"github.com/caict-benchmark/BDC-TS/util/report"
 )
 
 // Stat represents one statistical measurement.
 type Stat struct {
 	Label []byte
 	Value float64
+	// Worker is the index of the worker that made the measurement.
+	Worker int
 }
 
 // Init safely initializes a stat while minimizing heap allocations.
@@ -28,10 +32,18 @@ type StatGroup struct {
 	Sum  float64
 
 	Count int64
+
+	// Latencies holds the distribution of the pushed values, in milliseconds.
+	Latencies *report.Histogram
 }
 
 // Push updates a StatGroup with a new value.
 func (s *StatGroup) Push(n float64) {
+	if s.Latencies == nil {
+		s.Latencies = report.NewHistogram()
+	}
+	s.Latencies.Record(time.Duration(n * float64(time.Millisecond)))
+
 	if s.Count == 0 {
 		s.Min = n
 		s.Max = n
@@ -62,6 +74,29 @@ func (s *StatGroup) String() string {
 	return fmt.Sprintf("min: %f, max: %f, mean: %f, count: %d, sum: %f", s.Min, s.Max, s.Mean, s.Count, s.Sum)
 }
 
+// querySummary returns the Summary of a query run from the stats of all
+// queries, the stats of each query type and the stats of each worker.
+func querySummary(dbType string, start time.Time, took time.Duration, all *StatGroup, queries statsMap, workers map[int]*StatGroup) *report.Summary {
+	s := report.NewQuerySummary(dbType, start, took, all.Count)
+	s.Latency = all.Latencies.Summary()
+	s.Queries = map[string]*report.LatencySummary{}
+	for label, stat := range queries {
+		if stat != all {
+			s.Queries[label] = stat.Latencies.Summary()
+		}
+	}
+	ids := make([]int, 0, len(workers))
+	for i := range workers {
+		ids = append(ids, i)
+	}
+	sort.Ints(ids)
+	for _, i := range ids {
+		stat := workers[i]
+		s.Workers = append(s.Workers, report.WorkerSummary{Worker: i, Requests: stat.Count, Latency: stat.Latencies.Summary()})
+	}
+	return s
+}
+
 type timedStat struct {
 	timestamp time.Time
 	value     float64
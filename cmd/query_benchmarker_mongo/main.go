@@ -40,19 +40,21 @@ var (
 	reportUser           string
 	reportPassword       string
 	reportTagsCSV        string
+	resultsFile          string
 )
 
 // Global vars:
 var (
-	queryPool      sync.Pool
-	queryChan      chan *Query
-	statPool       sync.Pool
-	statChan       chan *Stat
-	workersGroup   sync.WaitGroup
-	statGroup      sync.WaitGroup
-	statMapping    statsMap
-	reportTags     [][2]string
-	reportHostname string
+	queryPool         sync.Pool
+	queryChan         chan *Query
+	statPool          sync.Pool
+	statChan          chan *Stat
+	workersGroup      sync.WaitGroup
+	statGroup         sync.WaitGroup
+	statMapping       statsMap
+	workerStatMapping map[int]*StatGroup
+	reportTags        [][2]string
+	reportHostname    string
 )
 
 type statsMap map[string]*StatGroup
@@ -80,6 +82,7 @@ func init() {
 	flag.StringVar(&reportUser, "report-user", "", "User for Host to send result metrics.")
 	flag.StringVar(&reportPassword, "report-password", "", "User password for Host to send result metrics.")
 	flag.StringVar(&reportTagsCSV, "report-tags", "", "Comma separated k:v tags to send  alongside result metrics.")
+	flag.StringVar(&resultsFile, "results-file", "", "Write a summary of the run to this file, as JSON or as CSV if it ends with .csv.")
 
 	flag.Parse()
 
@@ -144,7 +147,7 @@ func main() {
 	// Launch the query processors:
 	for i := 0; i < workers; i++ {
 		workersGroup.Add(1)
-		go processQueries(session)
+		go processQueries(session, i)
 	}
 
 	// Read in jobs, closing the job channel when done:
@@ -202,6 +205,15 @@ func main() {
 			log.Fatal(err)
 		}
 	}
+
+	if resultsFile != "" {
+		s := querySummary("MongoDB", wallStart, wallTook, statMapping[allQueriesLabel], statMapping, workerStatMapping)
+		s.AddTags(reportTags)
+		s.AddExtraVals([]report.ExtraVal{{Name: "burn_in", Value: int64(burnIn)}})
+		if err := s.WriteFile(resultsFile); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 // scan reads encoded Queries and places them onto the workqueue.
@@ -234,12 +246,13 @@ func scan(r io.Reader) {
 
 // processQueries reads byte buffers from queryChan and writes them to the
 // target server, while tracking latency.
-func processQueries(session *mgo.Session) {
+func processQueries(session *mgo.Session, worker int) {
 	for q := range queryChan {
 		lag, err := oneQuery(session, q)
 
 		stat := statPool.Get().(*Stat)
 		stat.Init(q.HumanLabel, lag)
+		stat.Worker = worker
 		statChan <- stat
 
 		queryPool.Put(q)
@@ -290,6 +303,7 @@ func processStats() {
 	statMapping = statsMap{
 		allQueriesLabel: &StatGroup{},
 	}
+	workerStatMapping = map[int]*StatGroup{}
 
 	i := uint64(0)
 	for stat := range statChan {
@@ -310,6 +324,10 @@ func processStats() {
 
 		statMapping[allQueriesLabel].Push(stat.Value)
 		statMapping[string(stat.Label)].Push(stat.Value)
+		if _, ok := workerStatMapping[stat.Worker]; !ok {
+			workerStatMapping[stat.Worker] = &StatGroup{}
+		}
+		workerStatMapping[stat.Worker].Push(stat.Value)
 
 		statPool.Put(stat)
 
@@ -41,6 +41,7 @@ var (
 	reportUser           string
 	reportPassword       string
 	reportTagsCSV        string
+	resultsFile          string
 	psUser               string
 	psPassword           string
 	batchSize            int
@@ -48,15 +49,16 @@ var (
 
 // Global vars:
 var (
-	queryPool      sync.Pool
-	queryChan      chan []*Query
-	statPool       sync.Pool
-	statChan       chan *Stat
-	workersGroup   sync.WaitGroup
-	statGroup      sync.WaitGroup
-	statMapping    statsMap
-	reportTags     [][2]string
-	reportHostname string
+	queryPool         sync.Pool
+	queryChan         chan []*Query
+	statPool          sync.Pool
+	statChan          chan *Stat
+	workersGroup      sync.WaitGroup
+	statGroup         sync.WaitGroup
+	statMapping       statsMap
+	workerStatMapping map[int]*StatGroup
+	reportTags        [][2]string
+	reportHostname    string
 )
 
 type statsMap map[string]*StatGroup
@@ -84,6 +86,7 @@ func init() {
 	flag.StringVar(&reportUser, "report-user", "", "User for Host to send result metrics.")
 	flag.StringVar(&reportPassword, "report-password", "", "User password for Host to send result metrics.")
 	flag.StringVar(&reportTagsCSV, "report-tags", "", "Comma separated k:v tags to send  alongside result metrics.")
+	flag.StringVar(&resultsFile, "results-file", "", "Write a summary of the run to this file, as JSON or as CSV if it ends with .csv.")
 
 	flag.Parse()
 
@@ -160,12 +163,12 @@ func main() {
 
 		}
 		workersGroup.Add(1)
-		go func(connection *pgx.Conn) {
+		go func(connection *pgx.Conn, worker int) {
 			if doQueries {
 				defer connection.Close()
 			}
-			processQueries(connection)
-		}(conn)
+			processQueries(connection, worker)
+		}(conn, i)
 	}
 
 	// Read in jobs, closing the job channel when done:
@@ -223,6 +226,15 @@ func main() {
 			log.Fatal(err)
 		}
 	}
+
+	if resultsFile != "" {
+		s := querySummary("TimescaleDB", wallStart, wallTook, statMapping[allQueriesLabel], statMapping, workerStatMapping)
+		s.AddTags(reportTags)
+		s.AddExtraVals([]report.ExtraVal{{Name: "burn_in", Value: int64(burnIn)}})
+		if err := s.WriteFile(resultsFile); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 // scan reads encoded Queries and places them onto the workqueue.
@@ -266,7 +278,7 @@ func scan(r io.Reader) {
 
 // processQueries reads byte buffers from queryChan and writes them to the
 // target server, while tracking latency.
-func processQueries(conn *pgx.Conn) {
+func processQueries(conn *pgx.Conn, worker int) {
 	var lag float64
 	var err error
 	for qb := range queryChan {
@@ -274,6 +286,7 @@ func processQueries(conn *pgx.Conn) {
 			lag, err = oneQuery(conn, qb[0])
 			stat := statPool.Get().(*Stat)
 			stat.Init(qb[0].HumanLabel, lag)
+			stat.Worker = worker
 			statChan <- stat
 			queryPool.Put(qb[0])
 		} else {
@@ -282,6 +295,7 @@ func processQueries(conn *pgx.Conn) {
 			for _, q := range qb {
 				stat := statPool.Get().(*Stat)
 				stat.Init(q.HumanLabel, lagPerQuery)
+				stat.Worker = worker
 				statChan <- stat
 				queryPool.Put(q)
 			}
@@ -369,6 +383,7 @@ func processStats() {
 	statMapping = statsMap{
 		allQueriesLabel: &StatGroup{},
 	}
+	workerStatMapping = map[int]*StatGroup{}
 
 	i := uint64(0)
 	for stat := range statChan {
@@ -389,6 +404,10 @@ func processStats() {
 
 		statMapping[allQueriesLabel].Push(stat.Value)
 		statMapping[string(stat.Label)].Push(stat.Value)
+		if _, ok := workerStatMapping[stat.Worker]; !ok {
+			workerStatMapping[stat.Worker] = &StatGroup{}
+		}
+		workerStatMapping[stat.Worker].Push(stat.Value)
 
 		statPool.Put(stat)
 
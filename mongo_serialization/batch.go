@@ -0,0 +1,6 @@
+package mongo_serialization
+
+// BatchFrameFlag is set in the little endian uint64 length prefix of the
+// frames holding an ItemBatch rather than a single Item. The other bits are
+// the length.
+const BatchFrameFlag = uint64(1) << 63
@@ -74,10 +74,12 @@ if [[ -z "$_URLS" ]]; then
 fi
 
 $GOPATH/bin/bulk_data_gen --seed=123 --use-case=vehicle --scale-var=1 --format=${_FORMAT}-bulk --timestamp-start=2017-01-01T00:00:00Z --timestamp-end=2017-01-01T00:00:01Z | $GOPATH/bin/bulk_load_${_FORMAT}  -workers 10
-rm -f ${_INPUT}/load_log
+rm -f ${_INPUT}/load_log ${_INPUT}/load_result_*.json
+n=0
 for file in ${_INPUT}/${_FORMAT}_seed_123_*
 do
     echo ${file}
-    cat ${file} | $GOPATH/bin/bulk_load_${_FORMAT} --batch-size=${_BATCH_SIZE} --workers=${_WORKERS} --urls=${_URLS} --do-db-create=false >> ${_INPUT}/load_log 2>&1 &
+    $GOPATH/bin/bulk_load_${_FORMAT} --input=${file} --batch-size=${_BATCH_SIZE} --workers=${_WORKERS} --urls=${_URLS} --do-db-create=false --results-file=${_INPUT}/load_result_${n}.json >> ${_INPUT}/load_log 2>&1 &
+    n=$(($n+1))
     sleep ${_SLEEP}
 done
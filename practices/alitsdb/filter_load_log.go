@@ -1,18 +1,16 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
-	"io"
-	"os"
-	"regexp"
+	"log"
+	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/caict-benchmark/BDC-TS/bulk_data_gen/common"
+	"github.com/caict-benchmark/BDC-TS/util/report"
 )
 
 // program options
@@ -38,14 +36,9 @@ func (is TimeSlice) Len() int {
 	return len(is)
 }
 
-func filterLoadLog(fileName string) ([]string, int64, int64, float64, time.Time, time.Time, int, int, float64, float64) {
-	f, err := os.Open(fileName)
-	if err != nil {
-		fmt.Printf("failed to open \"%s\", error: %v\n", fileName, err)
-		return nil, 0, 0, 0, time.Unix(0, 0), time.Unix(0, 0), 0, 0, 0, 0
-	}
-	buf := bufio.NewReader(f)
-	var result []string
+// filterLoadResults sums up the summaries written by the loader processes
+// with --results-file. fileNames is a comma-separated list of files or globs.
+func filterLoadResults(fileNames string) (int64, int64, float64, time.Time, time.Time, int, int, float64, float64) {
 	var items = int64(0)
 	var values = int64(0)
 	var timeToken = float64(0)
@@ -55,44 +48,36 @@ func filterLoadLog(fileName string) ([]string, int64, int64, float64, time.Time,
 	var count = int(0)
 	startTimes := TimeSlice(make([]time.Time, 0, 32))
 	endTimes := TimeSlice(make([]time.Time, 0, 32))
-	var earlyStartTime, lateEndTime time.Time
 
-	const regx = `^loaded\s([\d]+)\sitems\sand\s([\d]+)\svalues\sin\s(\d+\.\d+)sec\s.*start\s(\d+),\send\s(\d+)\)\swith\s(\d+)\sworkers.*rate\s(\d+\.\d+)\sitems.*rate\s(\d+\.\d+)/s\)$`
-	for {
-		line, err := buf.ReadString('\n')
-		line = strings.TrimSpace(line)
+	for _, pattern := range strings.Split(fileNames, ",") {
+		files, err := filepath.Glob(strings.TrimSpace(pattern))
 		if err != nil {
-			if err == io.EOF { //读取结束，会报EOF
-				earlyStartTime, lateEndTime = getEarlyStartAndLateEnd(startTimes, endTimes, fileName)
-				return result, items, values, timeToken, earlyStartTime, lateEndTime, count, workers / count, pointsRate / float64(count), valuesRate / float64(count)
-			}
-			fmt.Printf("error occurred: %v\n", err)
-			earlyStartTime, lateEndTime = getEarlyStartAndLateEnd(startTimes, endTimes, fileName)
-			return result, items, values, timeToken, earlyStartTime, lateEndTime, count, workers / count, pointsRate / float64(count), valuesRate / float64(count)
+			fmt.Printf("bad results file pattern \"%s\", error: %v\n", pattern, err)
+			continue
 		}
-		result = append(result, line)
-		flySnowRegexp := regexp.MustCompile(regx)
-		params := flySnowRegexp.FindStringSubmatch(line)
-		if params != nil {
+		for _, file := range files {
+			s, err := report.ReadSummary(file)
+			if err != nil {
+				fmt.Printf("failed to read \"%s\", error: %v\n", file, err)
+				continue
+			}
 			count++
-			item, _ := strconv.ParseInt(params[1], 10, 64)
-			items += item
-			value, _ := strconv.ParseInt(params[2], 10, 64)
-			values += value
-			token, _ := strconv.ParseFloat(params[3], 32)
-			timeToken += token
-			start, _ := strconv.ParseInt(params[4], 10, 64)
-			startTimes = append(startTimes, time.Unix(start, 0))
-			end, _ := strconv.ParseInt(params[5], 10, 64)
-			endTimes = append(endTimes, time.Unix(end, 0))
-			worker, _ := strconv.Atoi(params[6])
-			workers += worker
-			point, _ := strconv.ParseFloat(params[7], 32)
-			pointsRate += point
-			rate, _ := strconv.ParseFloat(params[8], 32)
-			valuesRate += rate
+			items += s.Totals.Items
+			values += s.Totals.Values
+			timeToken += s.Totals.DurationSecs
+			startTimes = append(startTimes, s.Start)
+			endTimes = append(endTimes, s.End)
+			workers += len(s.Workers)
+			pointsRate += s.Rates.ItemsPerSec
+			valuesRate += s.Rates.ValuesPerSec
 		}
 	}
+	if count == 0 {
+		log.Fatalf("no results files read from %s", fileNames)
+	}
+
+	earlyStartTime, lateEndTime := getEarlyStartAndLateEnd(startTimes, endTimes, fileNames)
+	return items, values, timeToken, earlyStartTime, lateEndTime, count, workers / count, pointsRate / float64(count), valuesRate / float64(count)
 }
 
 func getEarlyStartAndLateEnd(startTimes, endTimes TimeSlice, fileName string) (start, end time.Time) {
@@ -108,7 +93,7 @@ func getEarlyStartAndLateEnd(startTimes, endTimes TimeSlice, fileName string) (s
 
 func main() {
 	flag.StringVar(&scene, "scene", sceneChoice[1], "The test secne (options:\"realtime\" or \"history\")")
-	flag.StringVar(&filePath, "filePath", "unknown", "Input result file path")
+	flag.StringVar(&filePath, "filePath", "unknown", "Results files written by the loaders with --results-file, comma-separated, globs allowed")
 	flag.Parse()
 	var isHistory bool
 
@@ -121,7 +106,7 @@ func main() {
 		panic(fmt.Sprintf("scene \"%s\" not supported", scene))
 	}
 
-	_, items, values, timeToken, start, end, processes, workers, itemsRate, valueRate := filterLoadLog(filePath)
+	items, values, timeToken, start, end, processes, workers, itemsRate, valueRate := filterLoadResults(filePath)
 	startTimestamp := start.Format(common.DateTimeStdFormat)
 	endTimestamp := end.Format(common.DateTimeStdFormat)
 	fmt.Printf("Items written: %d\n", items)
@@ -109,7 +109,10 @@ if [[ -z "$_USECASE" ]]; then
     _USECASE="vehicle"
 fi
 
-rm -f ${_INPUT}/load_log
+# host:port of each alitsdb host
+_ALITSDB_URLS=$(echo ${_HOSTS} | sed -e "s/,/:${_PORT},/g" -e "s/$/:${_PORT}/")
+
+rm -f ${_INPUT}/load_log ${_INPUT}/load_result_*.json
 debug_port=8000
 for file in ${_INPUT}/${_FORMAT}_seed_123_*
 do
@@ -117,12 +120,12 @@ do
     echo "Loading data from ${file}" >> ${_INPUT}/load_log
     if [ ${_FORMAT} = 'alitsdb' -o ${_FORMAT} = 'alitsdb-http' ]; then
         if [ ${_FORMAT} = 'alitsdb' ]; then
-            cat ${file} | $GOPATH/bin/bulk_load_alitsdb -batch-size=${_BATCH_SIZE} --debug_port=${debug_port}  -workers=${_WORKERS} -hosts=${_HOSTS} -port=${_PORT} -do-load=$_DOLOAD -use-case=$_USECASE -json-format=false -viahttp=false >> ${_INPUT}/load_log 2>&1 &
+            $GOPATH/bin/bulk_load_alitsdb --input=${file} -batch-size=${_BATCH_SIZE} --debug_port=${debug_port} -results-file=${_INPUT}/load_result_${debug_port}.json -workers=${_WORKERS} -urls=${_ALITSDB_URLS} -do-load=$_DOLOAD -json-format=false -viahttp=false >> ${_INPUT}/load_log 2>&1 &
         else
-            cat ${file} | $GOPATH/bin/bulk_load_alitsdb -batch-size=${_BATCH_SIZE} --debug_port=${debug_port} -workers=${_WORKERS} -hosts=${_HOSTS} -port=${_PORT} -do-load=$_DOLOAD -use-case=$_USECASE -json-format=true -viahttp=true >> ${_INPUT}/load_log 2>&1 &
+            $GOPATH/bin/bulk_load_alitsdb --input=${file} -batch-size=${_BATCH_SIZE} --debug_port=${debug_port} -results-file=${_INPUT}/load_result_${debug_port}.json -workers=${_WORKERS} -urls=${_ALITSDB_URLS} -do-load=$_DOLOAD -json-format=true -viahttp=true >> ${_INPUT}/load_log 2>&1 &
         fi
     else
-        cat ${file} | $GOPATH/bin/bulk_load_${_FORMAT} --batch-size=${_BATCH_SIZE} --debug_port=${debug_port} --workers=${_WORKERS} --urls=${_URLS} --do-db-create=false -use-case=$_USECASE >> ${_INPUT}/load_log 2>&1 &
+        $GOPATH/bin/bulk_load_${_FORMAT} --input=${file} --batch-size=${_BATCH_SIZE} --debug_port=${debug_port} --results-file=${_INPUT}/load_result_${debug_port}.json --workers=${_WORKERS} --urls=${_URLS} --do-db-create=false -use-case=$_USECASE >> ${_INPUT}/load_log 2>&1 &
     fi
 	debug_port=$(($debug_port+1))
     sleep ${_SLEEP}
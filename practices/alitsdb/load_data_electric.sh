@@ -105,8 +105,15 @@ IFS=', ' read -r -a array <<< "$_INPUT"
 logfile="${array[0]}"/load_log
 echo "logfile: $logfile"
 
+# host:port of each alitsdb host
+_ALITSDB_URLS=$(echo ${_HOSTS} | sed -e "s/,/:${_PORT},/g" -e "s/$/:${_PORT}/")
+
 rm -f $logfile
 for _INPUT in "${array[@]}"
+do
+  rm -f ${_INPUT}/load_result_*.json
+done
+for _INPUT in "${array[@]}"
 do
   debug_port=8000
   for file in ${_INPUT}/datapoints_*
@@ -114,12 +121,12 @@ do
       echo "Loading data from ${file}" >> $logfile
       if [ ${_FORMAT} = 'alitsdb' -o ${_FORMAT} = 'alitsdb-http' ]; then
           if [ ${_FORMAT} = 'alitsdb' ]; then
-              nohup cat ${file} | $GOPATH/bin/bulk_load_alitsdb -batch-size=${_BATCH_SIZE} --debug_port=${debug_port}  -workers=${_WORKERS} -hosts=${_HOSTS} -port=${_PORT} -do-load=$_DOLOAD -json-format=false -viahttp=false --use-case=electricity  >> ${logfile} 2>&1 &
+              nohup $GOPATH/bin/bulk_load_alitsdb --input=${file} -batch-size=${_BATCH_SIZE} --debug_port=${debug_port} -results-file=${_INPUT}/load_result_${debug_port}.json -workers=${_WORKERS} -urls=${_ALITSDB_URLS} -do-load=$_DOLOAD -json-format=false -viahttp=false >> ${logfile} 2>&1 &
           else
-              nohup cat ${file} | $GOPATH/bin/bulk_load_alitsdb -batch-size=${_BATCH_SIZE} --debug_port=${debug_port} -workers=${_WORKERS} -hosts=${_HOSTS} -port=${_PORT} -do-load=$_DOLOAD -json-format=true -viahttp=true --use-case=electricity >> ${logfile} 2>&1 &
+              nohup $GOPATH/bin/bulk_load_alitsdb --input=${file} -batch-size=${_BATCH_SIZE} --debug_port=${debug_port} -results-file=${_INPUT}/load_result_${debug_port}.json -workers=${_WORKERS} -urls=${_ALITSDB_URLS} -do-load=$_DOLOAD -json-format=true -viahttp=true >> ${logfile} 2>&1 &
           fi
       else
-          nohup cat ${file} | $GOPATH/bin/bulk_load_${_FORMAT} --batch-size=${_BATCH_SIZE} --debug_port=${debug_port} --workers=${_WORKERS} --urls=${_URLS} --do-db-create=false --use-case=electricity >> ${logfile} 2>&1 &
+          nohup $GOPATH/bin/bulk_load_${_FORMAT} --input=${file} --batch-size=${_BATCH_SIZE} --debug_port=${debug_port} --results-file=${_INPUT}/load_result_${debug_port}.json --workers=${_WORKERS} --urls=${_URLS} --do-db-create=false --use-case=electricity >> ${logfile} 2>&1 &
       fi
     debug_port=$(($debug_port+1))
 #      sleep ${_SLEEP}
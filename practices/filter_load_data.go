@@ -1,71 +1,70 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
-	"io"
-	"os"
-	"regexp"
-	"strconv"
+	"log"
+	"path/filepath"
 	"strings"
+
+	"github.com/caict-benchmark/BDC-TS/util/report"
 )
 
-func FilterLoadLog(fileName string) ([]string, int, float64, int, float64, float64, float64) {
-	f, err := os.Open(fileName)
-	if err != nil {
-		return nil, 0, 0, 0, 0, 0, 0
-	}
-	buf := bufio.NewReader(f)
-	var result []string
-	var items = int(0)
-	var timeToken = float64(0)
-	var workers = int(0)
-	var points = float64(0)
-	var values = float64(0)
-	var data = float64(0)
-	var count = int(0)
-	const regx = `^loaded\s([\d]+)\sitems\sin\s(\d+\.\d+)sec\swith\s(\d+)\sworkers.*rate\s(\d+\.\d+)\sitems.*rate\s(\d+\.\d+)/s,\s(\d+\.\d+)MB/sec\sfrom\sstdin\)$`
-	for {
-		line, err := buf.ReadString('\n')
-		line = strings.TrimSpace(line)
+// ReadSummaries reads the summaries written by the loaders with
+// --results-file. paths is a comma-separated list of files or globs.
+func ReadSummaries(paths string) ([]*report.Summary, error) {
+	var summaries []*report.Summary
+	for _, pattern := range strings.Split(paths, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		files, err := filepath.Glob(pattern)
 		if err != nil {
-			if err == io.EOF { //读取结束，会报EOF
-				return result, items, timeToken, workers / count, points / float64(count), values / float64(count), data / float64(count)
-			}
-			return result, items, timeToken, workers / count, points / float64(count), values / float64(count), data / float64(count)
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no results file matches %s", pattern)
 		}
-		result = append(result, line)
-		flySnowRegexp := regexp.MustCompile(regx)
-		params := flySnowRegexp.FindStringSubmatch(line)
-		if params != nil {
-			count += 1
-			value, _ := strconv.Atoi(params[1])
-			items = items + value
-			token, _ := strconv.ParseFloat(params[2], 32)
-			timeToken = timeToken + token
-			worker, _ := strconv.Atoi(params[3])
-			workers = workers + worker
-			point, _ := strconv.ParseFloat(params[4], 32)
-			points = points + point
-			v, _ := strconv.ParseFloat(params[5], 32)
-			values = values + v
-			d, _ := strconv.ParseFloat(params[6], 32)
-			data = data + d
+		for _, file := range files {
+			s, err := report.ReadSummary(file)
+			if err != nil {
+				return nil, err
+			}
+			summaries = append(summaries, s)
 		}
 	}
-	return result, items, timeToken, workers / count, points / float64(count), values / float64(count), data / float64(count)
+	return summaries, nil
 }
 
 func main() {
 	var filePath string
-	flag.StringVar(&filePath, "filePath", "unknown", "Input result file path")
+	flag.StringVar(&filePath, "filePath", "unknown", "Results files written with --results-file, comma-separated, globs allowed")
 	flag.Parse()
-	_, items, timeToken, workers, itemsRate, valueRate, dataRate := FilterLoadLog(filePath)
+
+	summaries, err := ReadSummaries(filePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(summaries) == 0 {
+		log.Fatal("no results files given")
+	}
+
+	var items, workers int64
+	var timeToken, itemsRate, valueRate, dataRate float64
+	for _, s := range summaries {
+		items += s.Totals.Items
+		timeToken += s.Totals.DurationSecs
+		workers += int64(len(s.Workers))
+		itemsRate += s.Rates.ItemsPerSec
+		valueRate += s.Rates.ValuesPerSec
+		dataRate += s.Rates.BytesPerSec / (1 << 20)
+	}
+	count := float64(len(summaries))
 	fmt.Printf("Items: %d\n", items)
 	fmt.Printf("Time token: %f sec\n", timeToken)
-	fmt.Printf("Workers: %d\n", workers)
-	fmt.Printf("Items rate: %f items/sec\n", itemsRate)
-	fmt.Printf("Values rate: %f values/sec\n", valueRate)
-	fmt.Printf("Data rate: %f MB/sec\n", dataRate)
+	fmt.Printf("Workers: %d\n", workers/int64(len(summaries)))
+	fmt.Printf("Items rate: %f items/sec\n", itemsRate/count)
+	fmt.Printf("Values rate: %f values/sec\n", valueRate/count)
+	fmt.Printf("Data rate: %f MB/sec\n", dataRate/count)
 }
@@ -0,0 +1,263 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyPercentiles are the percentiles of the write latency in summaries,
+// telemetry and reports.
+var LatencyPercentiles = []float64{50, 90, 95, 99, 99.9}
+
+const (
+	// histogramSubBucketBits sets the precision of a Histogram: values are
+	// kept with 6 significant bits, an error below 1/64.
+	histogramSubBucketBits = 7
+	histogramSubBuckets    = 1 << histogramSubBucketBits
+	histogramHalfBuckets   = histogramSubBuckets / 2
+	// histogramMaxExp covers values up to 2^(histogramMaxExp+7) µs, larger
+	// values are counted in the last bucket.
+	histogramMaxExp = 40
+	histogramSize   = (histogramMaxExp + 2) * histogramHalfBuckets
+)
+
+// Histogram is an HDR-style histogram of durations, recorded in microseconds
+// in log-linear buckets: the percentiles are exact up to 128µs and within
+// 1/64 above. The zero value is not usable, see NewHistogram. A Histogram is
+// not safe for concurrent use, see LatencyRecorder.
+type Histogram struct {
+	counts []int64
+	count  int64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]int64, histogramSize), min: math.MaxInt64}
+}
+
+func histogramIndex(v int64) int {
+	if v < histogramSubBuckets {
+		return int(v)
+	}
+	exp := bits.Len64(uint64(v)) - histogramSubBucketBits
+	if exp > histogramMaxExp {
+		return histogramSize - 1
+	}
+	return exp*histogramHalfBuckets + int(v>>uint(exp))
+}
+
+// histogramValue returns the highest value counted in bucket i.
+func histogramValue(i int) int64 {
+	if i < histogramSubBuckets {
+		return int64(i)
+	}
+	exp := uint(i/histogramHalfBuckets - 1)
+	sub := int64(i%histogramHalfBuckets + histogramHalfBuckets)
+	return (sub+1)<<exp - 1
+}
+
+// Record counts d.
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d / time.Microsecond)
+	if v < 0 {
+		v = 0
+	}
+	h.counts[histogramIndex(v)]++
+	h.count++
+	h.sum += v
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// Merge adds the counts of o to h.
+func (h *Histogram) Merge(o *Histogram) {
+	if o.count == 0 {
+		return
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.count += o.count
+	h.sum += o.sum
+	if o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+}
+
+func (h *Histogram) Reset() {
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.count = 0
+	h.sum = 0
+	h.min = math.MaxInt64
+	h.max = 0
+}
+
+func (h *Histogram) Count() int64 {
+	return h.count
+}
+
+func (h *Histogram) Min() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.min) * time.Microsecond
+}
+
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max) * time.Microsecond
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.sum/h.count) * time.Microsecond
+}
+
+// Percentile returns the value below which p percent of the recorded values
+// are, 0 if there are none.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	target := int64(math.Ceil(p / 100 * float64(h.count)))
+	if target < 1 {
+		target = 1
+	}
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			v := histogramValue(i)
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return time.Duration(v) * time.Microsecond
+		}
+	}
+	return h.Max()
+}
+
+// percentileName returns the field name of percentile p, like p99 or p999
+// for 99.9.
+func percentileName(p float64) string {
+	return "p" + strings.Replace(fmt.Sprintf("%g", p), ".", "", 1)
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// String describes h in milliseconds.
+func (h *Histogram) String() string {
+	s := fmt.Sprintf("min: %.2fms, mean: %.2fms", millis(h.Min()), millis(h.Mean()))
+	for _, p := range LatencyPercentiles {
+		s += fmt.Sprintf(", %s: %.2fms", percentileName(p), millis(h.Percentile(p)))
+	}
+	return s + fmt.Sprintf(", max: %.2fms, count: %d", millis(h.Max()), h.count)
+}
+
+// AddFields adds the percentiles and the max of h to p, in milliseconds, as
+// fields named like <prefix>_p99_ms.
+func (h *Histogram) AddFields(p *Point, prefix string) {
+	for _, pct := range LatencyPercentiles {
+		p.AddFloat64Field(prefix+"_"+percentileName(pct)+"_ms", millis(h.Percentile(pct)))
+	}
+	p.AddFloat64Field(prefix+"_max_ms", millis(h.Max()))
+}
+
+// ExtraVals returns the mean, the percentiles and the max of h, in
+// milliseconds, as report values named like <prefix>_p99_ms.
+func (h *Histogram) ExtraVals(prefix string) []ExtraVal {
+	vals := []ExtraVal{{Name: prefix + "_mean_ms", Value: millis(h.Mean())}}
+	for _, pct := range LatencyPercentiles {
+		vals = append(vals, ExtraVal{Name: prefix + "_" + percentileName(pct) + "_ms", Value: millis(h.Percentile(pct))})
+	}
+	return append(vals, ExtraVal{Name: prefix + "_max_ms", Value: millis(h.Max())})
+}
+
+// Summary describes h for a Summary, nil if h is empty.
+func (h *Histogram) Summary() *LatencySummary {
+	if h == nil || h.count == 0 {
+		return nil
+	}
+	ls := &LatencySummary{
+		Count:       h.count,
+		MinMs:       millis(h.Min()),
+		MeanMs:      millis(h.Mean()),
+		MaxMs:       millis(h.Max()),
+		Percentiles: map[string]float64{},
+	}
+	for _, p := range LatencyPercentiles {
+		ls.Percentiles[percentileName(p)] = millis(h.Percentile(p))
+	}
+	return ls
+}
+
+// LatencyRecorder records the write latencies of a worker, both for the whole
+// load and for the current interval. It is safe for concurrent use.
+type LatencyRecorder struct {
+	mu       sync.Mutex
+	total    *Histogram
+	interval *Histogram
+}
+
+func NewLatencyRecorder() *LatencyRecorder {
+	return &LatencyRecorder{total: NewHistogram(), interval: NewHistogram()}
+}
+
+func (r *LatencyRecorder) Record(d time.Duration) {
+	r.mu.Lock()
+	r.total.Record(d)
+	r.interval.Record(d)
+	r.mu.Unlock()
+}
+
+// TakeInterval adds the latencies recorded since the previous call to dst.
+func (r *LatencyRecorder) TakeInterval(dst *Histogram) {
+	r.mu.Lock()
+	dst.Merge(r.interval)
+	r.interval.Reset()
+	r.mu.Unlock()
+}
+
+// AddTotal adds all the latencies recorded to dst.
+func (r *LatencyRecorder) AddTotal(dst *Histogram) {
+	r.mu.Lock()
+	dst.Merge(r.total)
+	r.mu.Unlock()
+}
+
+// Total returns all the latencies recorded.
+func (r *LatencyRecorder) Total() *Histogram {
+	h := NewHistogram()
+	r.AddTotal(h)
+	return h
+}
+
+// TotalLatencies returns all the latencies recorded by recorders.
+func TotalLatencies(recorders []*LatencyRecorder) *Histogram {
+	h := NewHistogram()
+	for _, r := range recorders {
+		r.AddTotal(h)
+	}
+	return h
+}
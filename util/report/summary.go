@@ -0,0 +1,259 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Summary is the machine readable summary of a load or query run, written
+// with --results-file. The practices scripts read it instead of parsing the
+// output of the commands.
+type Summary struct {
+	Tool   string    `json:"tool"`
+	Kind   string    `json:"kind"`
+	DBType string    `json:"db_type"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	// Config holds the command line flags, with secrets masked.
+	Config  map[string]string `json:"config"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Dataset Dataset           `json:"dataset"`
+	Totals  Totals            `json:"totals"`
+	Rates   Rates             `json:"rates"`
+	// Latency is the latency of the writes of a load or of all the queries.
+	Latency *LatencySummary `json:"latency,omitempty"`
+	// Queries is the latency of each query type.
+	Queries map[string]*LatencySummary `json:"queries,omitempty"`
+	Errors  map[string]int64           `json:"errors"`
+	Workers []WorkerSummary            `json:"workers,omitempty"`
+	Extra   map[string]interface{}     `json:"extra,omitempty"`
+}
+
+// Dataset describes the input of a run.
+type Dataset struct {
+	Source string `json:"source"`
+	// ExpectedItems and ExpectedValues come from the dataset-size marker
+	// written by bulk_data_gen, they are 0 if the input has none.
+	ExpectedItems  int64 `json:"expected_items"`
+	ExpectedValues int64 `json:"expected_values"`
+}
+
+type Totals struct {
+	Items        int64   `json:"items"`
+	Values       int64   `json:"values"`
+	Bytes        int64   `json:"bytes"`
+	Queries      int64   `json:"queries"`
+	DurationSecs float64 `json:"duration_secs"`
+}
+
+type Rates struct {
+	ItemsPerSec   float64 `json:"items_per_sec"`
+	ValuesPerSec  float64 `json:"values_per_sec"`
+	BytesPerSec   float64 `json:"bytes_per_sec"`
+	QueriesPerSec float64 `json:"queries_per_sec"`
+}
+
+// LatencySummary describes a Histogram in milliseconds.
+type LatencySummary struct {
+	Count  int64   `json:"count"`
+	MinMs  float64 `json:"min_ms"`
+	MeanMs float64 `json:"mean_ms"`
+	MaxMs  float64 `json:"max_ms"`
+	// Percentiles are named like p99 or p999.
+	Percentiles map[string]float64 `json:"percentiles_ms"`
+}
+
+// WorkerSummary is the share of a worker in a run.
+type WorkerSummary struct {
+	Worker   int             `json:"worker"`
+	Requests int64           `json:"requests"`
+	Items    int64           `json:"items,omitempty"`
+	Values   int64           `json:"values,omitempty"`
+	Bytes    int64           `json:"bytes,omitempty"`
+	Errors   int64           `json:"errors"`
+	Latency  *LatencySummary `json:"latency,omitempty"`
+}
+
+// NewLoadSummary returns the Summary of a load of items that took took.
+func NewLoadSummary(dbType string, start time.Time, took time.Duration, items, values, bytes int64) *Summary {
+	s := newSummary("load", dbType, start, took)
+	s.Totals.Items = items
+	s.Totals.Values = values
+	s.Totals.Bytes = bytes
+	if secs := took.Seconds(); secs > 0 {
+		s.Rates.ItemsPerSec = float64(items) / secs
+		s.Rates.ValuesPerSec = float64(values) / secs
+		s.Rates.BytesPerSec = float64(bytes) / secs
+	}
+	return s
+}
+
+// NewQuerySummary returns the Summary of a query run that took took.
+func NewQuerySummary(dbType string, start time.Time, took time.Duration, queries int64) *Summary {
+	s := newSummary("query", dbType, start, took)
+	s.Totals.Queries = queries
+	if secs := took.Seconds(); secs > 0 {
+		s.Rates.QueriesPerSec = float64(queries) / secs
+	}
+	return s
+}
+
+func newSummary(kind, dbType string, start time.Time, took time.Duration) *Summary {
+	return &Summary{
+		Tool:    filepath.Base(os.Args[0]),
+		Kind:    kind,
+		DBType:  dbType,
+		Start:   start,
+		End:     time.Now(),
+		Config:  FlagConfig(flag.CommandLine),
+		Dataset: Dataset{Source: "stdin"},
+		Totals:  Totals{DurationSecs: took.Seconds()},
+		Errors:  map[string]int64{},
+	}
+}
+
+// FlagConfig returns the values of the flags of fs. The values of flags
+// named like passwords or credentials are masked.
+func FlagConfig(fs *flag.FlagSet) map[string]string {
+	config := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		v := f.Value.String()
+		name := strings.ToLower(f.Name)
+		if v != "" && (strings.Contains(name, "password") || strings.Contains(name, "auth")) {
+			v = "****"
+		}
+		config[f.Name] = v
+	})
+	return config
+}
+
+// AddTags adds report tags to s.
+func (s *Summary) AddTags(tags [][2]string) {
+	if len(tags) == 0 {
+		return
+	}
+	if s.Tags == nil {
+		s.Tags = map[string]string{}
+	}
+	for _, t := range tags {
+		s.Tags[t[0]] = t[1]
+	}
+}
+
+// AddExtraVals adds report values to s.
+func (s *Summary) AddExtraVals(vals []ExtraVal) {
+	if len(vals) == 0 {
+		return
+	}
+	if s.Extra == nil {
+		s.Extra = map[string]interface{}{}
+	}
+	for _, v := range vals {
+		s.Extra[v.Name] = v.Value
+	}
+}
+
+// AddWorkers adds the requests and the latencies of each worker, recorded
+// by its recorder.
+func (s *Summary) AddWorkers(recorders []*LatencyRecorder) {
+	for i, r := range recorders {
+		h := r.Total()
+		s.Workers = append(s.Workers, WorkerSummary{Worker: i, Requests: h.Count(), Latency: h.Summary()})
+	}
+}
+
+// WriteFile writes s to path as JSON, or as name,value CSV rows if path ends
+// with .csv.
+func (s *Summary) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = s.writeCSV(f)
+	} else {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(s)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeCSV flattens s into rows like totals.items,200000 or
+// workers.0.latency.percentiles_ms.p99,12.5.
+func (s *Summary) writeCSV(f *os.File) error {
+	buf, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"name", "value"}); err != nil {
+		return err
+	}
+	if err := writeCSVRows(w, "", doc); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeCSVRows(w *csv.Writer, name string, v interface{}) error {
+	prefix := name
+	if prefix != "" {
+		prefix += "."
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := writeCSVRows(w, prefix+k, v[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		for i, e := range v {
+			if err := writeCSVRows(w, prefix+strconv.Itoa(i), e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case float64:
+		return w.Write([]string{name, strconv.FormatFloat(v, 'f', -1, 64)})
+	default:
+		return w.Write([]string{name, fmt.Sprint(v)})
+	}
+}
+
+// ReadSummary reads a Summary written as JSON by WriteFile.
+func ReadSummary(path string) (*Summary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := &Summary{}
+	if err := json.NewDecoder(f).Decode(s); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}